@@ -0,0 +1,28 @@
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPI is the OpenAPI 3 description of the REST API. Unlike the swagger
+// 2.0 files it is maintained by hand, keep it in sync when routes change.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+//go:embed swagger-ui.html
+var swaggerUI []byte
+
+// OpenAPIHandler serves the OpenAPI 3 document.
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPI)
+}
+
+// SwaggerUIHandler serves a Swagger UI page that explores the OpenAPI 3
+// document.
+func SwaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(swaggerUI)
+}
@@ -0,0 +1,358 @@
+// Package client is a Go client of the REST API of a phone book server, so
+// other programs can use a phone book without writing the HTTP calls:
+//
+//	c, err := client.New("http://localhost:8001", os.Getenv("PHONEBOOK_TOKEN"))
+//	...
+//	entries, appErr := c.List(ctx)
+//
+// On a server hosting several books, the base URL is that of the book, like
+// http://localhost:8001/books/family. A Client implements storage.Storage,
+// storage.Updater and storage.VersionedDeleter, so it can also stand in for
+// a backend.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// The types of the API, re-exported so programs outside this module can name
+// them.
+type (
+	Entry          = model.Entry
+	Error          = model.PhoeBookError
+	PhoneNumber    = model.PhoneNumber
+	ListResponse   = model.ListResponse
+	LookupResponse = model.LookupResponse
+	SpamReport     = model.SpamReport
+	BatchResponse  = model.BatchResponse
+	BatchResult    = model.BatchResult
+	JobStatus      = model.JobStatus
+	Readiness      = model.Readiness
+	HealthCheck    = model.HealthCheck
+)
+
+// requestTimeout bounds every request of the http.Client New sets up, so a
+// server that stops answering doesn't hang its caller.
+const requestTimeout = 30 * time.Second
+
+// Client talks to the server at a base URL like http://localhost:8001.
+type Client struct {
+	// HTTPClient sends the requests. New sets one that gives up after 30
+	// seconds; replace it before the first request for other timeouts or
+	// TLS settings.
+	HTTPClient *http.Client
+
+	base  string
+	token string
+}
+
+// New returns a client of the server at baseURL, sending token as the bearer
+// token when it is not empty.
+func New(baseURL, token string) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%q is not the http or https URL of a phone book server", baseURL)
+	}
+
+	return &Client{HTTPClient: &http.Client{Timeout: requestTimeout}, base: strings.TrimSuffix(baseURL, "/"), token: token}, nil
+}
+
+// Query narrows down Entries. The zero Query returns every entry that is not
+// archived in one page.
+type Query struct {
+	// Filter is a filter expression like "surname=Smith AND company~Acme".
+	Filter string
+	// Search ranks the entries by how well they match, best first.
+	Search string
+	// Limit returns at most this many entries and the NextCursor of the
+	// rest, which goes in Cursor to fetch the next page.
+	Limit  int
+	Cursor string
+	// Archived queries the archived entries instead.
+	Archived bool
+}
+
+// List returns every entry of the book, archived ones included.
+func (c *Client) List(ctx context.Context) ([]Entry, *Error) {
+	var entries []Entry
+	for _, path := range []string{"/list", "/list?archived=true"} {
+		var list ListResponse
+		if _, appErr := c.do(ctx, http.MethodGet, path, nil, &list); appErr != nil {
+			return nil, appErr
+		}
+
+		entries = append(entries, list.Entries...)
+	}
+
+	return entries, nil
+}
+
+// Entries returns the entries matching query, one page of them when it has a
+// Limit.
+func (c *Client) Entries(ctx context.Context, query Query) (*ListResponse, *Error) {
+	values := url.Values{}
+	if query.Filter != "" {
+		values.Set("q", query.Filter)
+	}
+
+	if query.Search != "" {
+		values.Set("search", query.Search)
+	}
+
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+
+	if query.Cursor != "" {
+		values.Set("cursor", query.Cursor)
+	}
+
+	if query.Archived {
+		values.Set("archived", "true")
+	}
+
+	path := "/entries"
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+
+	var list ListResponse
+	if _, appErr := c.do(ctx, http.MethodGet, path, nil, &list); appErr != nil {
+		return nil, appErr
+	}
+
+	return &list, nil
+}
+
+// Entry returns the entry with id.
+func (c *Client) Entry(ctx context.Context, id int64) (*Entry, *Error) {
+	var entry Entry
+	if _, appErr := c.do(ctx, http.MethodGet, "/entries/"+strconv.FormatInt(id, 10), nil, &entry); appErr != nil {
+		return nil, appErr
+	}
+
+	return &entry, nil
+}
+
+// Search returns the entry with number.
+func (c *Client) Search(ctx context.Context, number string) (*Entry, *Error) {
+	var entry Entry
+	if _, appErr := c.do(ctx, http.MethodGet, "/search?"+url.Values{"phone-number": {number}}.Encode(), nil, &entry); appErr != nil {
+		return nil, appErr
+	}
+
+	return &entry, nil
+}
+
+// Insert adds entry to the book and returns its ID. The server picks the ID
+// and starts the version, and the photo is left out.
+func (c *Client) Insert(ctx context.Context, entry *Entry) (int64, *Error) {
+	sent := *entry
+	sent.ID, sent.Version, sent.Photo = 0, 0, ""
+
+	var response model.InsertResponse
+	if _, appErr := c.do(ctx, http.MethodPost, "/insert", sent, &response); appErr != nil {
+		return 0, appErr
+	}
+
+	return response.ID, nil
+}
+
+// Update replaces the entry with entry.ID, if it still has entry.Version
+// unless that is 0, and sets entry.Version to its new version.
+func (c *Client) Update(ctx context.Context, entry *Entry) *Error {
+	header, appErr := c.do(ctx, http.MethodPut, "/entries/"+strconv.FormatInt(entry.ID, 10), entry, nil)
+	if appErr != nil {
+		return appErr
+	}
+
+	// The ETag of an entry is "<id>.<version>".
+	_, version, _ := strings.Cut(strings.Trim(header.Get("ETag"), `"`), ".")
+	if v, err := strconv.ParseInt(version, 10, 64); err == nil {
+		entry.Version = v
+	} else {
+		entry.Version++
+	}
+
+	return nil
+}
+
+// Delete deletes the entry with id.
+func (c *Client) Delete(ctx context.Context, id int64) *Error {
+	return c.DeleteVersion(ctx, id, 0)
+}
+
+// DeleteVersion deletes the entry with id if it still has version, unless
+// that is 0.
+func (c *Client) DeleteVersion(ctx context.Context, id, version int64) *Error {
+	path := "/delete/" + strconv.FormatInt(id, 10)
+	if version != 0 {
+		path += "?version=" + strconv.FormatInt(version, 10)
+	}
+
+	_, appErr := c.do(ctx, http.MethodDelete, path, nil, nil)
+	return appErr
+}
+
+// BatchCreate inserts entries in one transaction. When one of them fails
+// none is inserted: the response tells which one and why, and is returned
+// along with the error.
+func (c *Client) BatchCreate(ctx context.Context, entries []Entry) (*BatchResponse, *Error) {
+	return c.batch(ctx, "/entries:batchCreate", entries)
+}
+
+// BatchDelete deletes the entries with ids in one transaction, all of them
+// or none like BatchCreate.
+func (c *Client) BatchDelete(ctx context.Context, ids []int64) (*BatchResponse, *Error) {
+	return c.batch(ctx, "/entries:batchDelete", ids)
+}
+
+func (c *Client) batch(ctx context.Context, path string, items any) (*BatchResponse, *Error) {
+	var response BatchResponse
+	_, appErr := c.do(ctx, http.MethodPost, path, items, &response)
+	if appErr != nil && response.Results == nil {
+		return nil, appErr
+	}
+
+	return &response, appErr
+}
+
+// Blocked reports whether number is on the book's blocklist.
+func (c *Client) Blocked(ctx context.Context, number string) (bool, *Error) {
+	var response model.BlockedResponse
+	if _, appErr := c.do(ctx, http.MethodGet, "/blocked/"+url.PathEscape(number), nil, &response); appErr != nil {
+		return false, appErr
+	}
+
+	return response.Blocked, nil
+}
+
+// Lookup tells everything the book knows about number: whose it is, whether
+// it is blocked and how likely it is spam.
+func (c *Client) Lookup(ctx context.Context, number string) (*LookupResponse, *Error) {
+	var response LookupResponse
+	if _, appErr := c.do(ctx, http.MethodGet, "/lookup/"+url.PathEscape(number), nil, &response); appErr != nil {
+		return nil, appErr
+	}
+
+	return &response, nil
+}
+
+// ReportSpam reports report.Number as spam. The server records when, and
+// who unless report.Reporter says.
+func (c *Client) ReportSpam(ctx context.Context, report SpamReport) *Error {
+	_, appErr := c.do(ctx, http.MethodPost, "/spam", report, nil)
+	return appErr
+}
+
+// Photo returns the image of the photo of the entry with id.
+func (c *Client) Photo(ctx context.Context, id int64) ([]byte, *Error) {
+	var photo []byte
+	if _, appErr := c.do(ctx, http.MethodGet, "/photo/"+strconv.FormatInt(id, 10), nil, &photo); appErr != nil {
+		return nil, appErr
+	}
+
+	return photo, nil
+}
+
+// Jobs lists the scheduled jobs of the server.
+func (c *Client) Jobs(ctx context.Context) ([]JobStatus, *Error) {
+	var jobs []JobStatus
+	if _, appErr := c.do(ctx, http.MethodGet, "/jobs", nil, &jobs); appErr != nil {
+		return nil, appErr
+	}
+
+	return jobs, nil
+}
+
+// RunJob starts the job called name now, without waiting for it to finish.
+func (c *Client) RunJob(ctx context.Context, name string) *Error {
+	_, appErr := c.do(ctx, http.MethodPost, "/jobs/"+url.PathEscape(name)+"/run", nil, nil)
+	return appErr
+}
+
+// Ready asks the server whether it is ready to serve requests. A server
+// that is not is returned along with the error, with the checks that failed.
+func (c *Client) Ready(ctx context.Context) (*Readiness, *Error) {
+	var readiness Readiness
+	_, appErr := c.do(ctx, http.MethodGet, "/readyz", nil, &readiness)
+	if appErr != nil && readiness.Status == "" {
+		return nil, appErr
+	}
+
+	return &readiness, appErr
+}
+
+// Close closes the idle connections to the server.
+func (c *Client) Close() error {
+	c.HTTPClient.CloseIdleConnections()
+	return nil
+}
+
+// do sends a request with body, when not nil, as JSON and decodes the
+// response into out, when not nil, or reads it into out when it is a
+// *[]byte. A status other than 200 and 202 is returned as an error, after
+// decoding a JSON response into out all the same.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) (http.Header, *Error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, &Error{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return nil, &Error{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, &Error{Message: fmt.Sprintf("cannot reach the phone book server: %v", err), StatusCode: http.StatusBadGateway}
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if out != nil && strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") && json.Unmarshal(message, out) == nil {
+			return nil, &Error{Message: fmt.Sprintf("the server answered %s %s with %s", method, path, resp.Status), StatusCode: int32(resp.StatusCode)}
+		}
+
+		return nil, &Error{Message: fmt.Sprintf("the server answered %s %s with %s: %s", method, path, resp.Status, strings.TrimSpace(string(message))), StatusCode: int32(resp.StatusCode)}
+	}
+
+	if photo, ok := out.(*[]byte); ok {
+		if *photo, err = io.ReadAll(resp.Body); err != nil {
+			return nil, &Error{Message: fmt.Sprintf("cannot read the answer to %s %s: %v", method, path, err), StatusCode: http.StatusBadGateway}
+		}
+	} else if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return nil, &Error{Message: fmt.Sprintf("the server answered %s %s with something other than a phone book's answer: %v", method, path, err), StatusCode: http.StatusBadGateway}
+		}
+	}
+
+	return resp.Header, nil
+}
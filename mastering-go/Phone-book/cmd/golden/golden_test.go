@@ -0,0 +1,293 @@
+// Package golden runs the phone book's command line against the cases in
+// testdata and compares what it prints with the golden files there, so a
+// change to a table or to the JSON of a command shows up before it ships.
+// It has only TestGolden:
+//
+//	go test ./cmd/golden                          check every case
+//	go test ./cmd/golden -run TestGolden/search   check the search case
+//	go test ./cmd/golden -update                  write what the commands print now as golden
+//
+// A case is a directory of testdata. Its commands file has a command line
+// per line, without the program name, which is run with -storage csv -dsn
+// book.csv unless the line names other ones; blank lines and lines starting
+// with # are skipped. Arguments are split on spaces, and quoted with ' or "
+// to have spaces in them; a line ending in "< file" reads its standard input
+// from that file. The commands run one after the other in a new directory
+// holding the other files of the case, with an empty home directory, no
+// config file unless the case has a config.json, English messages and UTC
+// times. What they print, their standard output, their standard error and
+// exit status when they aren't empty or 0, is compared with the case's
+// golden file. Paths of the directory are written $WORK in it, and times
+// <time>, since they change from run to run.
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the output of the commands as the golden files instead of comparing them")
+
+// times matches the times commands print, like the updated_at of entries.
+var times = regexp.MustCompile(`\d{4}-\d\d-\d\d[T ]\d\d:\d\d:\d\d(\.\d+)?(Z|[+-]\d\d:\d\d| \+0000 UTC)?`)
+
+func TestGolden(t *testing.T) {
+	cases, err := os.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary := filepath.Join(t.TempDir(), "phonebook")
+	build := exec.Command("go", "build", "-o", binary, "..")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("building the phone book: %v\n%s", err, out)
+	}
+
+	for _, c := range cases {
+		if !c.IsDir() {
+			continue
+		}
+
+		t.Run(c.Name(), func(t *testing.T) {
+			dir := filepath.Join("testdata", c.Name())
+			got, err := runCase(binary, dir, t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join(dir, "golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to write it)", err)
+			}
+
+			if !bytes.Equal(got, want) {
+				t.Errorf("the output differs from %s (-golden +got):\n%s", golden, diff(string(want), string(got)))
+			}
+		})
+	}
+}
+
+// runCase runs the commands of the case in dir, in a copy of its files in
+// work, and returns what they printed.
+func runCase(binary, dir, work string) ([]byte, error) {
+	script, err := os.ReadFile(filepath.Join(dir, "commands"))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := copyFiles(dir, work); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	for n, line := range strings.Split(string(script), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		args, input, err := splitLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("commands:%d: %v", n+1, err)
+		}
+
+		fmt.Fprintf(&out, "$ %s\n", line)
+		if err := runCommand(&out, binary, work, args, input); err != nil {
+			return nil, fmt.Errorf("commands:%d: %v", n+1, err)
+		}
+	}
+
+	return bytes.ReplaceAll(times.ReplaceAll(out.Bytes(), []byte("<time>")), []byte(work), []byte("$WORK")), nil
+}
+
+// runCommand runs the phone book with args in work, reading the standard
+// input from input unless it is empty, and writes what it printed to out.
+func runCommand(out *bytes.Buffer, binary, work string, args []string, input string) error {
+	cmd := exec.Command(binary, append([]string{"-storage", "csv", "-dsn", "book.csv"}, args...)...)
+	cmd.Dir = work
+	cmd.Env = environment(work)
+
+	if input != "" {
+		f, err := os.Open(filepath.Join(work, input))
+		if err != nil {
+			return err
+		}
+
+		defer f.Close()
+		cmd.Stdin = f
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	status := 0
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		status = exitErr.ExitCode()
+	} else if err != nil {
+		return err
+	}
+
+	out.Write(stdout.Bytes())
+	if stderr.Len() > 0 {
+		fmt.Fprintf(out, "[stderr]\n%s", stderr.Bytes())
+	}
+
+	if status != 0 {
+		fmt.Fprintf(out, "[exit status %d]\n", status)
+	}
+
+	return nil
+}
+
+// environment is the environment of the commands: only PATH is kept from
+// the harness's own, everything else a command could pick up points into
+// work or is fixed.
+func environment(work string) []string {
+	return []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + work,
+		"XDG_CONFIG_HOME=" + filepath.Join(work, ".config"),
+		"XDG_CACHE_HOME=" + filepath.Join(work, ".cache"),
+		"XDG_RUNTIME_DIR=" + work,
+		"TMPDIR=" + work,
+		"PHONEBOOK_CONFIG=" + filepath.Join(work, "config.json"),
+		"LANG=en",
+		"TZ=UTC",
+	}
+}
+
+// copyFiles copies the files of the case in dir, besides its commands and
+// golden file, into work.
+func copyFiles(dir, work string) error {
+	if err := os.MkdirAll(work, 0755); err != nil {
+		return err
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || file.Name() == "commands" || file.Name() == "golden" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(filepath.Join(work, file.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitLine splits a command line into its arguments and the file after a
+// final "<", if any.
+func splitLine(line string) ([]string, string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, "", fmt.Errorf("unterminated %c quote", quote)
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	if n := len(args); n >= 2 && args[n-2] == "<" {
+		return args[:n-2], args[n-1], nil
+	}
+
+	return args, "", nil
+}
+
+// diff returns the lines of want and got, marking those only in want with -
+// and those only in got with +.
+func diff(want, got string) string {
+	// Every line ends in a newline, so the last ones are marked on lines of
+	// their own too.
+	a := strings.SplitAfter(strings.TrimSuffix(want, "\n")+"\n", "\n")
+	b := strings.SplitAfter(strings.TrimSuffix(got, "\n")+"\n", "\n")
+	a, b = a[:len(a)-1], b[:len(b)-1]
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&out, "  %s", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&out, "- %s", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+ %s", b[j])
+			j++
+		}
+	}
+
+	return out.String() + "\n"
+}
@@ -1,10 +1,46 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
 	_ "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/api"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/auth"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
 	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/controller"
+	_ "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/csvfile"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/daemon"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/db"
+	_ "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/eventlog"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/jobs"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/mdns"
 	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/offline"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/phone"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/reminders"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/retention"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/systemd"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/tlscert"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/tracing"
 	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/metrics"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/middleware"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+	_ "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage/memory"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -12,12 +48,830 @@ type PhoneBook []model.Entry
 
 const CSVFILE = "../data/data.csv"
 
+// strictExitStatus is the exit status of a command -strict made refuse what
+// it would only warn about otherwise.
+const strictExitStatus = 3
+
+// usageExitStatus is the exit status of a command line that is not one, as
+// for flags the flag package doesn't know.
+const usageExitStatus = 2
+
 func main() {
-	// Register prometheus metrics
+	backend := flag.String("storage", "postgres", fmt.Sprintf("storage backend, one of %v", storage.Backends()))
+	dsn := flag.String("dsn", "", "data source passed to the storage backend (file path for csv)")
+	book := flag.String("book", "", "use this phone book from the books of the config file")
+	token := flag.String("token", "", "require this bearer token on API requests")
+	rateLimit := flag.Float64("rate-limit", 0, "allowed requests per second per client, 0 disables the limit")
+	corsOrigins := flag.String("cors", "", "comma separated origins allowed to call the API from a browser")
+	readOnly := flag.Bool("read-only", false, "refuse every operation that would change the phone book")
+	timeout := flag.Duration("timeout", 0, "give up on a command line request after this long, e.g. 30s (0 waits as long as it takes)")
+	socket := flag.String("socket", "", "Unix socket of the daemon, by default one derived from -storage and -dsn")
+	direct := flag.Bool("direct", false, "read the phone book directly even when its daemon is running")
+	strict := flag.Bool("strict", false, fmt.Sprintf("refuse what commands only warn about otherwise, like a number that cannot be normalized, and exit with status %d", strictExitStatus))
+	profiling := flag.Bool("pprof", false, "serve net/http/pprof under /debug/pprof/, which needs -token or sign-in")
+	journal := flag.Bool("systemd", false, "log for the systemd journal: no timestamps and a priority on every line")
+	tlsCert := flag.String("tls-cert", "", "serve HTTPS with this certificate file (PEM), given with -tls-key")
+	tlsKey := flag.String("tls-key", "", "private key file (PEM) of -tls-cert")
+	selfSigned := flag.Bool("tls-self-signed", false, "serve HTTPS with a self-signed certificate for localhost, for development")
+	acmeHosts := flag.String("acme-hosts", "", "serve HTTPS on :443 with certificates from Let's Encrypt for these comma separated host names")
+	acmeCache := flag.String("acme-cache", "", "directory keeping the Let's Encrypt certificates, by default one under the user's cache directory")
+	acmeEmail := flag.String("acme-email", "", "address Let's Encrypt writes to about expiring certificates")
+	redirectAddr := flag.String("http-redirect", "", "with HTTPS, also listen on this address, e.g. :8080, redirecting plain HTTP requests to HTTPS")
+	advertise := flag.Bool("mdns", false, "advertise the server on the local network over mDNS, for discover to find")
+	mdnsName := flag.String("mdns-name", "", "name the server is advertised under, by default \"Phone book on <host name>\"")
+	flag.Usage = func() {
+		controller.Usage(flag.CommandLine.Output(), filepath.Base(os.Args[0]))
+		fmt.Fprintln(flag.CommandLine.Output())
+		fmt.Fprintln(flag.CommandLine.Output(), "flags:")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	serverOptions := serveOptions{
+		certFile:   *tlsCert,
+		keyFile:    *tlsKey,
+		selfSigned: *selfSigned,
+		acmeHosts:  *acmeHosts,
+		acmeCache:  *acmeCache,
+		acmeEmail:  *acmeEmail,
+		redirect:   *redirectAddr,
+	}
+
+	if *advertise {
+		serverOptions.mdnsName = *mdnsName
+		if serverOptions.mdnsName == "" {
+			hostname, _ := os.Hostname()
+			hostname, _, _ = strings.Cut(hostname, ".")
+			serverOptions.mdnsName = "Phone book on " + hostname
+		}
+	} else if *mdnsName != "" {
+		fail("-mdns-name needs -mdns")
+	}
+
+	if *journal {
+		log.SetFlags(0)
+		log.SetOutput(systemd.Journal(os.Stderr))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fail(err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fail(err)
+	}
+
+	if shutdownTracing != nil {
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			shutdownTracing(ctx)
+		}()
+	}
+
+	if err := middleware.SetLogLevel(cfg.LogLevel); err != nil {
+		fail(err)
+	}
+
+	i18n.SetLocale(i18n.Detect(cfg.Locale))
+	if cfg.DefaultRegion != "" {
+		phone.DefaultRegion = cfg.DefaultRegion
+	}
+
+	if *book != "" {
+		bookConfig, ok := cfg.Books[*book]
+		if !ok {
+			fail(i18n.T("there is no phone book named %q in %s", *book, config.Path()))
+		}
+
+		*backend, *dsn = bookConfig.Storage, bookConfig.DSN
+		*readOnly = *readOnly || bookConfig.ReadOnly
+	}
+
+	// With books configured and none picked, the server hosts all of them.
+	if flag.NArg() == 0 && *book == "" && len(cfg.Books) > 0 {
+		books := make(map[string]controller.Book, len(cfg.Books))
+		for name, bookConfig := range cfg.Books {
+			// Both would want their own bearer token on every request.
+			if cfg.OIDC != nil && len(bookConfig.Tokens) > 0 {
+				fail(fmt.Sprintf("book %s: tokens cannot be used with oidc, which signs everyone in", name))
+			}
+
+			store, err := openStore(bookConfig.Storage, bookConfig.DSN, *readOnly || cfg.ReadOnly || bookConfig.ReadOnly)
+			if err != nil {
+				fail(fmt.Sprintf("book %s: %v", name, err))
+			}
+
+			defer store.Close()
+
+			if !*readOnly && !cfg.ReadOnly && !bookConfig.ReadOnly {
+				startRetention(cfg, store)
+			}
+
+			books[name] = controller.Book{Store: store, Tokens: bookConfig.Tokens}
+		}
+
+		limiter := rateLimiter(*rateLimit, cfg.RateLimit)
+		extra, wrap := serverMiddleware(cfg, *corsOrigins, limiter, *token)
+		api := withJobs(cfg, withProfiling(*profiling, cfg, *token, controller.BooksHandler(books, extra...), extra), extra, func(name string) (storage.Storage, error) {
+			book, ok := books[name]
+			if !ok {
+				return nil, fmt.Errorf("there is no phone book named %q", name)
+			}
+
+			return book.Store, nil
+		})
+
+		stores := make(map[string]storage.Storage, len(books))
+		for name, book := range books {
+			stores[name] = book.Store
+		}
+
+		registerMetrics()
+		reloadOnHangup(cfg, *rateLimit, limiter)
+		serve(wrap(api), stores, serverOptions)
+		return
+	}
+
+	var command controller.Command
+	if flag.NArg() > 0 {
+		var ok bool
+		if command, ok = controller.Lookup(flag.Arg(0)); !ok {
+			fmt.Fprintln(os.Stderr, i18n.T("unknown command %q, run help for the list", flag.Arg(0)))
+			os.Exit(usageExitStatus)
+		}
+	}
+
+	// Commands like discover, which looks for servers on the local network,
+	// need no phone book.
+	if command.NoBook {
+		ctx, cancel := commandContext(*timeout)
+		defer cancel()
+
+		if status := exitStatus(command.Run(ctx, nil, append([]string{os.Args[0]}, flag.Args()...)), false); status != 0 {
+			cancel()
+			os.Exit(status)
+		}
+
+		return
+	}
+
+	var store storage.Storage
+	if flag.NArg() > 0 && !*direct && !command.Direct {
+		store = dialDaemon(*backend, *dsn, *socket, *readOnly || cfg.ReadOnly)
+	}
+
+	if store == nil {
+		store, err = openStore(*backend, *dsn, *readOnly || cfg.ReadOnly)
+		if err != nil {
+			fail(err)
+		}
+	}
+
+	defer store.Close()
+
+	// "daemon" keeps the phone book loaded for command line requests made
+	// with -storage daemon.
+	if flag.NArg() == 1 && flag.Arg(0) == "daemon" {
+		path := *socket
+		if path == "" {
+			path = daemon.SocketPath(*backend, dataSource(*backend, *dsn))
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		reloadOnHangup(cfg, *rateLimit, nil)
+		if cfg.Reminders != nil {
+			go reminders.Run(ctx, store, cfg.Reminders, cfg.RequireConsent, reminderState(path))
+		}
+
+		startJobs(ctx, cfg, func(name string) (storage.Storage, error) {
+			if name != "" {
+				return nil, fmt.Errorf("the jobs of a daemon cannot name a book")
+			}
+
+			return store, nil
+		})
+
+		if err := daemon.Serve(ctx, store, path); err != nil {
+			fail(err)
+		}
+
+		return
+	}
+
+	// Any arguments left after the flags are a command line request,
+	// otherwise the phone book is served over HTTP.
+	if flag.NArg() > 0 {
+		ctx, cancel := commandContext(*timeout)
+		defer cancel()
+
+		refused := false
+		if *strict {
+			ctx = controller.WithStrict(ctx, &refused)
+		}
+
+		if len(cfg.Books) > 0 {
+			names := make([]string, 0, len(cfg.Books))
+			for name := range cfg.Books {
+				names = append(names, name)
+			}
+
+			ctx = controller.WithBooks(ctx, names, func(name string) (storage.Storage, error) {
+				bookConfig := cfg.Books[name]
+				return openStore(bookConfig.Storage, bookConfig.DSN, true)
+			})
+		}
+
+		// sync keeps what it paired up with each peer per book.
+		if flag.Arg(0) == "sync" {
+			path := *socket
+			if path == "" {
+				path = daemon.SocketPath(*backend, dataSource(*backend, *dsn))
+			}
+
+			err = controller.SyncCommand(ctx, store, syncState(path), append([]string{os.Args[0]}, flag.Args()...))
+		} else {
+			err = controller.CommandLineHandler(ctx, store, append([]string{os.Args[0]}, flag.Args()...))
+		}
+
+		if status := exitStatus(err, refused); status != 0 {
+			// os.Exit skips the deferred calls.
+			cancel()
+			store.Close()
+			os.Exit(status)
+		}
+
+		return
+	}
+
+	if !*readOnly && !cfg.ReadOnly {
+		startRetention(cfg, store)
+	}
+
+	limiter := rateLimiter(*rateLimit, cfg.RateLimit)
+	extra, wrap := serverMiddleware(cfg, *corsOrigins, limiter, *token)
+	api := withJobs(cfg, withProfiling(*profiling, cfg, *token, controller.Handler(store, extra...), extra), extra, func(name string) (storage.Storage, error) {
+		if name != "" {
+			return nil, fmt.Errorf("jobs can only name a book when the server hosts several")
+		}
+
+		return store, nil
+	})
+
+	registerMetrics()
+	reloadOnHangup(cfg, *rateLimit, limiter)
+	serve(wrap(api), map[string]storage.Storage{"": store}, serverOptions)
+}
+
+// serve serves root, and the health probes of stores in front of it, on the
+// socket systemd passed when it started the server through socket
+// activation, on :8001 otherwise, or :443 with Let's Encrypt certificates.
+// It serves HTTPS as options say, and advertises itself over mDNS when
+// they name it.
+func serve(root http.Handler, stores map[string]storage.Storage, options serveOptions) {
+	health := controller.HealthHandler(stores)
+
+	mux := http.NewServeMux()
+	mux.Handle("/livez", health)
+	mux.Handle("/readyz", health)
+	mux.Handle("/", root)
+	root = middleware.Tracing(mux)
+
+	tlsConfig, challenge := options.config()
+
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		fail(err)
+	}
+
+	addr := ":8001"
+	if options.acmeHosts != "" {
+		addr = ":443"
+	}
+
+	var listener net.Listener
+	if len(listeners) > 0 {
+		listener = listeners[0]
+	} else if listener, err = net.Listen("tcp", addr); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+		if options.redirect != "" {
+			go redirectToHTTPS(options.redirect, listener.Addr(), challenge)
+		}
+	}
+
+	if options.mdnsName != "" {
+		scheme := "http"
+		if tlsConfig != nil {
+			scheme = "https"
+		}
+
+		go advertise(options.mdnsName, listener.Addr(), scheme)
+	}
+
+	controller.ServeListener(listener, root)
+}
+
+// advertise advertises the server listening on addr under name until it is
+// interrupted or terminated, when it says goodbye, so the server drops out of
+// discover at once, and exits.
+func advertise(name string, addr net.Addr, scheme string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	_, port, _ := net.SplitHostPort(addr.String())
+	portNumber, _ := strconv.Atoi(port)
+	err := mdns.Advertise(ctx, mdns.Instance{Name: name, Port: portNumber, Text: []string{"scheme=" + scheme}})
+	stop()
+
+	if err != nil {
+		log.Printf("cannot advertise over mDNS: %v", err)
+		return
+	}
+
+	os.Exit(0)
+}
+
+// serveOptions are the flags picking how the server serves HTTPS: with the
+// certificate in certFile and keyFile, a self-signed one, or ones from Let's
+// Encrypt for acmeHosts. redirect is the address plain HTTP requests are
+// redirected from. mdnsName, when set, is the name the server is advertised
+// under on the local network.
+type serveOptions struct {
+	certFile, keyFile               string
+	selfSigned                      bool
+	acmeHosts, acmeCache, acmeEmail string
+	redirect                        string
+	mdnsName                        string
+}
+
+// config returns the TLS config of options, or nil to serve plain HTTP.
+// challenge, for Let's Encrypt, wraps the handler of the redirect address
+// to answer its checks there.
+func (o serveOptions) config() (tlsConfig *tls.Config, challenge func(http.Handler) http.Handler) {
+	sources := 0
+	for _, set := range []bool{o.certFile != "" || o.keyFile != "", o.selfSigned, o.acmeHosts != ""} {
+		if set {
+			sources++
+		}
+	}
+
+	switch {
+	case (o.certFile == "") != (o.keyFile == ""):
+		fail("-tls-cert and -tls-key go together")
+	case sources > 1:
+		fail("only one of -tls-cert, -tls-self-signed and -acme-hosts can be used")
+	case o.redirect != "" && sources == 0:
+		fail("-http-redirect needs -tls-cert, -tls-self-signed or -acme-hosts")
+	case (o.acmeCache != "" || o.acmeEmail != "") && o.acmeHosts == "":
+		fail("-acme-cache and -acme-email need -acme-hosts")
+	}
+
+	cacheDir := func(name string) string {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			dir = os.TempDir()
+		}
+
+		return filepath.Join(dir, "phonebook", name)
+	}
+
+	var err error
+	switch {
+	case o.certFile != "":
+		tlsConfig, err = tlscert.Files(o.certFile, o.keyFile)
+	case o.selfSigned:
+		tlsConfig, err = tlscert.SelfSigned(cacheDir("tls"))
+	case o.acmeHosts != "":
+		dir := o.acmeCache
+		if dir == "" {
+			dir = cacheDir("acme")
+		}
+
+		tlsConfig, challenge = tlscert.ACME(strings.Split(o.acmeHosts, ","), dir, o.acmeEmail)
+	}
+
+	if err != nil {
+		fail(err)
+	}
+
+	return tlsConfig, challenge
+}
+
+// redirectToHTTPS answers plain HTTP requests on addr with a redirect to
+// the same URL on the HTTPS port of tlsAddr, and Let's Encrypt's checks with
+// challenge when given. The redirect keeps the method, so API clients still
+// pointed at the plain address aren't turned into GETs.
+func redirectToHTTPS(addr string, tlsAddr net.Addr, challenge func(http.Handler) http.Handler) {
+	_, port, _ := net.SplitHostPort(tlsAddr.String())
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+
+	if challenge != nil {
+		handler = challenge(handler)
+	}
+
+	server := &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	if err := server.ListenAndServe(); err != nil {
+		log.Printf("http redirect: %v", err)
+	}
+}
+
+// withProfiling mounts the profiling routes next to api when enabled. As
+// they expose the internals of the server, they are refused unless the API
+// needs a token or sign-in, and with sign-in to users who are not editors.
+func withProfiling(enabled bool, cfg *config.Config, token string, api http.Handler, extra []middleware.Middleware) http.Handler {
+	if !enabled {
+		return api
+	}
+
+	if token == "" && cfg.OIDC == nil {
+		fail("-pprof needs -token or sign-in configured, the profiles would be open to anyone")
+	}
+
+	if cfg.OIDC != nil {
+		extra = append(slices.Clip(extra), auth.EditorsOnly)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", controller.ProfilingHandler(extra...))
+	mux.Handle("/", api)
+
+	return mux
+}
+
+// withJobs starts the scheduled jobs of the config file and mounts their
+// admin routes next to api. store returns the phone book a job names.
+func withJobs(cfg *config.Config, api http.Handler, extra []middleware.Middleware, store func(book string) (storage.Storage, error)) http.Handler {
+	scheduler := startJobs(context.Background(), cfg, store)
+	if scheduler == nil {
+		return api
+	}
+
+	jobsHandler := controller.JobsHandler(scheduler, extra...)
+
+	mux := http.NewServeMux()
+	mux.Handle("/jobs", jobsHandler)
+	mux.Handle("/jobs/", jobsHandler)
+	mux.Handle("/", api)
+
+	return mux
+}
+
+// startJobs runs the jobs of the config file on their schedules until ctx is
+// done, and returns their scheduler, or nil when there are none.
+func startJobs(ctx context.Context, cfg *config.Config, store func(book string) (storage.Storage, error)) *jobs.Scheduler {
+	if len(cfg.Jobs) == 0 {
+		return nil
+	}
+
+	scheduler, err := jobs.New(cfg, store)
+	if err != nil {
+		fail(err)
+	}
+
+	scheduler.Start(ctx)
+
+	return scheduler
+}
+
+// startRetention purges the entries of store the retention policy of the
+// config file no longer keeps, now and then once a day, while the server
+// runs. Purges that would delete more of the book than the destructive
+// threshold allows are skipped.
+func startRetention(cfg *config.Config, store storage.Storage) {
+	if cfg.Retention == nil {
+		return
+	}
+
+	policy := retention.Policy{TooMany: cfg.TooDestructive}
+	var err error
+	if cfg.Retention.PurgeAfter != "" {
+		if policy.MaxAge, err = retention.ParseAge(cfg.Retention.PurgeAfter); err != nil {
+			fail(err)
+		}
+	}
+
+	if cfg.Retention.PurgeArchivedAfter != "" {
+		if policy.ArchivedMaxAge, err = retention.ParseAge(cfg.Retention.PurgeArchivedAfter); err != nil {
+			fail(err)
+		}
+	}
+
+	if policy.MaxAge == 0 && policy.ArchivedMaxAge == 0 {
+		return
+	}
+
+	go retention.Run(context.Background(), store, policy, 24*time.Hour)
+}
+
+func openStore(backend, dsn string, readOnly bool) (storage.Storage, error) {
+	open := func() (storage.Storage, error) {
+		store, err := storage.Open(backend, dataSource(backend, dsn))
+		if err != nil {
+			return nil, err
+		}
+
+		if tracing.Enabled() {
+			store = tracing.Storage(store, backend)
+		}
+
+		return store, nil
+	}
+
+	// Changes to a database that cannot be reached are queued until it can.
+	if backend == "postgres" && !readOnly {
+		store, err := offline.Open(open, db.Unreachable, queuePath(backend, dsn))
+		if err != nil {
+			return nil, err
+		}
+
+		return store, nil
+	}
+
+	store, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	if readOnly {
+		store = storage.ReadOnly(store)
+	}
+
+	return store, nil
+}
+
+// reminderState is where the daemon listening on socket records the
+// reminders it sent, in the user's cache directory as the socket itself
+// may be gone after a reboot.
+func reminderState(socket string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "phonebook", strings.TrimSuffix(filepath.Base(socket), ".sock")+".reminders.json")
+}
+
+// syncState is where sync keeps what it paired up with each peer for the
+// book whose daemon listens on socket, next to the reminders the daemon sent.
+func syncState(socket string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "phonebook", strings.TrimSuffix(filepath.Base(socket), ".sock")+".sync.json")
+}
+
+// queuePath is where the changes to the book that could not be made while its
+// backend was unreachable wait, in the user's cache directory.
+func queuePath(backend, dsn string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "phonebook", strings.TrimSuffix(filepath.Base(daemon.SocketPath(backend, dataSource(backend, dsn))), ".sock")+".queue.jsonl")
+}
+
+// dialDaemon connects a command line request to the daemon of the phone
+// book if one is running, and returns nil if not so the request reads the
+// book directly. The Direct commands always do: imports and generate, to
+// insert in transactions, queue, which looks at the book's own offline
+// queue, and history, undo and restore, which use the history the daemon
+// does not pass on.
+func dialDaemon(backend, dsn, socket string, readOnly bool) storage.Storage {
+	if backend == "daemon" {
+		return nil
+	}
+
+	if socket == "" {
+		socket = daemon.SocketPath(backend, dataSource(backend, dsn))
+	}
+
+	if _, err := os.Stat(socket); err != nil {
+		return nil
+	}
+
+	// A socket nobody answers on is left over from a daemon that crashed.
+	store, err := storage.Open("daemon", socket)
+	if err != nil {
+		return nil
+	}
+
+	if readOnly {
+		store = storage.ReadOnly(store)
+	}
+
+	return store
+}
+
+// dataSource is the data source of backend, dsn unless that is empty and
+// the backend has a default.
+func dataSource(backend, dsn string) string {
+	if backend == "csv" && dsn == "" {
+		return CSVFILE
+	}
+
+	return dsn
+}
+
+// fail reports what keeps the program from going on, on the standard error,
+// and exits with status 1. Deferred calls are skipped.
+func fail(err any) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// exitStatus reports err, why a command failed, on the standard error and
+// returns the status to exit with: strictExitStatus when -strict made it
+// refuse something, usageExitStatus when it was not given the right
+// arguments, 1 for any other failure and 0 when it succeeded.
+func exitStatus(err error, refused bool) int {
+	if err != nil && err.Error() != "" {
+		fmt.Fprintln(os.Stderr, err)
+	}
+
+	var usage *controller.UsageError
+	switch {
+	case refused:
+		return strictExitStatus
+	case errors.As(err, &usage):
+		return usageExitStatus
+	case err != nil:
+		return 1
+	}
+
+	return 0
+}
+
+// commandContext is the context of a command line request: canceled by the
+// first Ctrl-C so the command can stop cleanly, while a second one kills the
+// process as usual, and ended after timeout if that is not 0.
+func commandContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	cancel := stop
+	if timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+		cancel = func() {
+			cancelTimeout()
+			stop()
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx, cancel
+}
+
+// Register prometheus metrics
+func registerMetrics() {
 	metrics := metrics.RegisterMetrics()
 	for _, metric := range metrics {
 		prometheus.MustRegister(metric)
 	}
+}
+
+// rateLimiter returns the limiter of the -rate-limit flag, or of the config
+// file when the flag isn't given. It is in place even without a limit, so a
+// reloaded config can add one.
+func rateLimiter(flagRate, configRate float64) *middleware.Limiter {
+	rate := configRate
+	if flagRate > 0 {
+		rate = flagRate
+	}
+
+	return middleware.NewLimiter(rate, int(rate)+1)
+}
+
+// reloadOnHangup re-reads the config file whenever the process gets SIGHUP
+// and applies the log level and, unless -rate-limit set it, the rate limit
+// of limiter. The other settings are only read on start, so changes to them
+// are reported and left for the next restart. An invalid file changes
+// nothing.
+func reloadOnHangup(cfg *config.Config, flagRate float64, limiter *middleware.Limiter) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	running := *cfg
+	go func() {
+		for range hangup {
+			next, err := config.Load()
+			if err == nil && next.RateLimit < 0 {
+				err = fmt.Errorf("rate_limit cannot be negative")
+			}
+
+			if err == nil {
+				err = middleware.SetLogLevel(next.LogLevel)
+			}
+
+			if err != nil {
+				log.Printf("config: not reloaded: %v", err)
+				continue
+			}
+
+			if limiter != nil && next.RateLimit != running.RateLimit {
+				if flagRate > 0 {
+					log.Printf("config: rate_limit is ignored, -rate-limit %v overrides it", flagRate)
+				} else {
+					limiter.SetRate(next.RateLimit, int(next.RateLimit)+1)
+				}
+			}
+
+			if names := running.NeedRestart(next); len(names) > 0 {
+				log.Printf("config: changes to %s need a restart, the old values stay in effect until then", strings.Join(names, ", "))
+			}
+
+			running.LogLevel, running.RateLimit = next.LogLevel, next.RateLimit
+			log.Printf("config: reloaded %s", config.Path())
+		}
+	}()
+}
+
+// serverMiddleware returns the middleware the flags and config ask for, and
+// a wrapper adding the login routes around the whole server when OIDC is
+// configured.
+func serverMiddleware(cfg *config.Config, corsOrigins string, limiter *middleware.Limiter, token string) ([]middleware.Middleware, func(http.Handler) http.Handler) {
+	var extra []middleware.Middleware
+	wrap := func(h http.Handler) http.Handler { return h }
+	var cors middleware.CORSOptions
+	if cfg.CORS != nil {
+		cors = middleware.CORSOptions{
+			Origins:        cfg.CORS.AllowedOrigins,
+			Methods:        cfg.CORS.AllowedMethods,
+			Headers:        cfg.CORS.AllowedHeaders,
+			ExposedHeaders: cfg.CORS.ExposedHeaders,
+			Credentials:    cfg.CORS.AllowCredentials,
+			MaxAge:         time.Duration(cfg.CORS.MaxAge) * time.Second,
+		}
+	}
+
+	if corsOrigins != "" {
+		cors.Origins = strings.Split(corsOrigins, ",")
+	}
+
+	// Any site could then act as the signed in user.
+	if cors.Credentials && slices.Contains(cors.Origins, "*") {
+		fail("cors: allow_credentials needs the allowed origins listed, not \"*\"")
+	}
+
+	if len(cors.Origins) > 0 {
+		extra = append(extra, middleware.CORSWith(cors))
+	}
+
+	extra = append(extra, limiter.Middleware)
+
+	if token != "" {
+		extra = append(extra, middleware.Auth(token))
+	}
+
+	if cfg.OIDC != nil {
+		// Both would want their own bearer token on every request.
+		if token != "" {
+			fail("-token cannot be used with oidc, which signs everyone in")
+		}
+
+		authenticator, err := auth.New(context.Background(), *cfg.OIDC)
+		if err != nil {
+			fail(err)
+		}
+
+		extra = append(extra, authenticator.Middleware())
+		wrap = authenticator.Wrap
+	}
 
-	controller.StartHander()
+	return extra, wrap
 }
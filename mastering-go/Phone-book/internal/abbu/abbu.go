@@ -0,0 +1,174 @@
+// Package abbu reads the contacts of a macOS Contacts archive, the .abbu
+// bundle Contacts.app writes with File > Export > Contacts Archive, as a
+// directory or zipped.
+//
+// The contacts are read from the .abcdp file the archive keeps for each one,
+// a binary property list, rather than from its SQLite database, which would
+// need a driver. Groups are left out.
+package abbu
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// mobileLabels are the labels of a number meaning a mobile phone, which is
+// used as the entry's number when a contact has several.
+var mobileLabels = []string{"_$!<Mobile>!$_", "iPhone", "mobile"}
+
+// noYear is the year Contacts.app gives birthdays whose year isn't known.
+const noYear = 1604
+
+// Open reads the archive at name, a .abbu directory or a zip file of one.
+func Open(name string) ([]model.Entry, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return Read(os.DirFS(name))
+	}
+
+	archive, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, fmt.Errorf("not a Contacts archive: %v", err)
+	}
+
+	defer archive.Close()
+
+	return Read(archive)
+}
+
+// Read reads the contacts of the archive in fsys, one .abcdp file each,
+// anywhere in it. An entry keeps one number, the first mobile one if any.
+// Contacts without a name or a number are returned too, for the caller to
+// report.
+func Read(fsys fs.FS) ([]model.Entry, error) {
+	var entries []model.Entry
+	seen := make(map[string]bool)
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// The same contact may be in the archive twice, under its account
+		// and in the merged view; the file is named after its ID.
+		base := path.Base(name)
+		if d.IsDir() || !strings.HasSuffix(base, ".abcdp") || strings.HasPrefix(base, "._") || seen[base] {
+			return nil
+		}
+
+		seen[base] = true
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		parsed, err := parsePlist(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+
+		if person, ok := parsed.(map[string]any); ok {
+			entries = append(entries, toEntry(person))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(seen) == 0 {
+		return nil, errors.New("no .abcdp files, not a Contacts archive")
+	}
+
+	return entries, nil
+}
+
+func toEntry(person map[string]any) model.Entry {
+	entry := model.Entry{
+		Name:     text(person, "First"),
+		Surname:  text(person, "Last"),
+		Nickname: text(person, "Nickname"),
+		Company:  text(person, "Organization"),
+		Title:    text(person, "JobTitle"),
+	}
+
+	if middle := text(person, "Middle"); middle != "" && entry.Name != "" {
+		entry.Name += " " + middle
+	}
+
+	// Companies are contacts of their own, with no person's name.
+	if entry.Name == "" && entry.Surname == "" {
+		entry.Name = entry.Company
+	}
+
+	entry.PhoneNumber = model.PhoneNumber(number(person))
+
+	if birthday, ok := person["Birthday"].(time.Time); ok {
+		// Birthdays are kept at noon GMT, the day is the same everywhere.
+		birthday = birthday.UTC()
+		if birthday.Year() == noYear {
+			entry.Birthday = birthday.Format("01-02")
+		} else {
+			entry.Birthday = birthday.Format(time.DateOnly)
+		}
+	}
+
+	return entry
+}
+
+func text(person map[string]any, key string) string {
+	value, _ := person[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// number returns the first mobile number of person, or else its first
+// number. Numbers are a multi-value: parallel lists of values and labels.
+func number(person map[string]any) string {
+	phones, _ := person["Phone"].(map[string]any)
+	values, _ := phones["values"].([]any)
+	labels, _ := phones["labels"].([]any)
+
+	var first string
+	for i, value := range values {
+		number, _ := value.(string)
+		if number = strings.TrimSpace(number); number == "" {
+			continue
+		}
+
+		if i < len(labels) {
+			if label, _ := labels[i].(string); isMobile(label) {
+				return number
+			}
+		}
+
+		if first == "" {
+			first = number
+		}
+	}
+
+	return first
+}
+
+func isMobile(label string) bool {
+	for _, mobile := range mobileLabels {
+		if strings.EqualFold(label, mobile) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,243 @@
+package abbu
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+// plistEpoch is the time binary property lists count dates from, in Unix
+// seconds.
+const plistEpoch = 978307200
+
+// parsePlist decodes a binary property list ("bplist00"), the format of the
+// .abcdp files, into strings, int64s, float64s, time.Times, []byte, bools,
+// []any and map[string]any.
+func parsePlist(data []byte) (any, error) {
+	if len(data) < 8+32 || !bytes.HasPrefix(data, []byte("bplist00")) {
+		return nil, errors.New("not a binary property list")
+	}
+
+	trailer := data[len(data)-32:]
+	p := &plist{
+		data:       data,
+		offsetSize: int(trailer[6]),
+		refSize:    int(trailer[7]),
+	}
+
+	count := binary.BigEndian.Uint64(trailer[8:])
+	top := binary.BigEndian.Uint64(trailer[16:])
+	tableOffset := binary.BigEndian.Uint64(trailer[24:])
+
+	if p.offsetSize < 1 || p.offsetSize > 8 || p.refSize < 1 || p.refSize > 8 || count == 0 || top >= count || tableOffset >= uint64(len(data)) || count > uint64(len(data))/uint64(p.offsetSize) {
+		return nil, errors.New("damaged binary property list")
+	}
+
+	p.offsets = make([]uint64, count)
+	for i := range p.offsets {
+		at := tableOffset + uint64(i*p.offsetSize)
+		if at+uint64(p.offsetSize) > uint64(len(data)) {
+			return nil, errors.New("damaged binary property list")
+		}
+
+		p.offsets[i] = readUint(data[at : at+uint64(p.offsetSize)])
+	}
+
+	return p.object(top, 0)
+}
+
+type plist struct {
+	data                []byte
+	offsetSize, refSize int
+	offsets             []uint64
+}
+
+// maxDepth bounds how deep arrays and dictionaries may nest, so a damaged
+// file referring to itself cannot recurse forever.
+const maxDepth = 32
+
+func (p *plist) object(ref uint64, depth int) (any, error) {
+	if ref >= uint64(len(p.offsets)) || depth > maxDepth {
+		return nil, errors.New("damaged binary property list")
+	}
+
+	at := p.offsets[ref]
+	if at >= uint64(len(p.data)) {
+		return nil, errors.New("damaged binary property list")
+	}
+
+	marker := p.data[at]
+	kind, info := marker>>4, int(marker&0x0f)
+	at++
+
+	switch kind {
+	case 0x0:
+		switch info {
+		case 0x8:
+			return false, nil
+		case 0x9:
+			return true, nil
+		}
+
+		return nil, nil
+
+	case 0x1:
+		size := uint64(1) << info
+		raw, err := p.slice(at, size)
+		if err != nil {
+			return nil, err
+		}
+
+		return int64(readUint(raw)), nil
+
+	case 0x2, 0x3:
+		size := uint64(1) << info
+		raw, err := p.slice(at, size)
+		if err != nil {
+			return nil, err
+		}
+
+		var f float64
+		switch size {
+		case 4:
+			f = float64(math.Float32frombits(binary.BigEndian.Uint32(raw)))
+		case 8:
+			f = math.Float64frombits(binary.BigEndian.Uint64(raw))
+		default:
+			return nil, errors.New("damaged binary property list")
+		}
+
+		if kind == 0x3 {
+			// Birthdays go back further than a time.Duration does.
+			seconds, fraction := math.Modf(f)
+			return time.Unix(plistEpoch+int64(seconds), int64(fraction*1e9)).UTC(), nil
+		}
+
+		return f, nil
+	}
+
+	length, at, err := p.length(info, at)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case 0x4:
+		return p.slice(at, length)
+
+	case 0x5:
+		raw, err := p.slice(at, length)
+		return string(raw), err
+
+	case 0x6:
+		raw, err := p.slice(at, length*2)
+		if err != nil {
+			return nil, err
+		}
+
+		units := make([]uint16, length)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(raw[i*2:])
+		}
+
+		return string(utf16.Decode(units)), nil
+
+	case 0xa:
+		refs, err := p.refs(at, length)
+		if err != nil {
+			return nil, err
+		}
+
+		array := make([]any, len(refs))
+		for i, ref := range refs {
+			if array[i], err = p.object(ref, depth+1); err != nil {
+				return nil, err
+			}
+		}
+
+		return array, nil
+
+	case 0xd:
+		refs, err := p.refs(at, length*2)
+		if err != nil {
+			return nil, err
+		}
+
+		dict := make(map[string]any, length)
+		for i := uint64(0); i < length; i++ {
+			key, err := p.object(refs[i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := p.object(refs[length+i], depth+1)
+			if err != nil {
+				return nil, err
+			}
+
+			if name, ok := key.(string); ok {
+				dict[name] = value
+			}
+		}
+
+		return dict, nil
+	}
+
+	// UIDs and sets are not used by contacts.
+	return nil, nil
+}
+
+// length reads the length of an object, given in the marker or, when that
+// has 0xf, as an integer object right after it.
+func (p *plist) length(info int, at uint64) (uint64, uint64, error) {
+	if info != 0xf {
+		return uint64(info), at, nil
+	}
+
+	if at >= uint64(len(p.data)) || p.data[at]>>4 != 0x1 {
+		return 0, 0, errors.New("damaged binary property list")
+	}
+
+	size := uint64(1) << (p.data[at] & 0x0f)
+	raw, err := p.slice(at+1, size)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return readUint(raw), at + 1 + size, nil
+}
+
+func (p *plist) refs(at, n uint64) ([]uint64, error) {
+	raw, err := p.slice(at, n*uint64(p.refSize))
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]uint64, n)
+	for i := range refs {
+		refs[i] = readUint(raw[i*p.refSize : (i+1)*p.refSize])
+	}
+
+	return refs, nil
+}
+
+func (p *plist) slice(at, n uint64) ([]byte, error) {
+	if n > uint64(len(p.data)) || at > uint64(len(p.data))-n {
+		return nil, fmt.Errorf("damaged binary property list: object at %d runs past the end", at)
+	}
+
+	return p.data[at : at+n], nil
+}
+
+func readUint(raw []byte) uint64 {
+	var n uint64
+	for _, b := range raw {
+		n = n<<8 | uint64(b)
+	}
+
+	return n
+}
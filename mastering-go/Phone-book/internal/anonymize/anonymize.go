@@ -0,0 +1,139 @@
+// Package anonymize replaces the personal data in phone book entries with
+// made-up data that looks alike, so books can be shared as test fixtures.
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"unicode"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// keptDigits is how many digits of the national number are kept, enough for
+// the area code or mobile operator prefix.
+const keptDigits = 3
+
+// Anonymizer makes up the replacements. It is deterministic: with the same
+// seed a value is always replaced by the same fake one, so the same person
+// stays the same person across the entries and across exports, and without
+// the seed the fake values cannot be traced back.
+type Anonymizer struct {
+	seed []byte
+}
+
+// New returns an Anonymizer for seed.
+func New(seed string) *Anonymizer {
+	return &Anonymizer{seed: []byte(seed)}
+}
+
+// Entry returns entry with its name, surname, phone number and company made
+// up. The ID, country, title, consent and preferred channel are kept; the
+// nickname, photo, birthday, anniversary and access notes are dropped.
+func (a *Anonymizer) Entry(entry model.Entry) model.Entry {
+	entry.Name = a.pick("name", entry.Name, names)
+	entry.Surname = a.pick("surname", entry.Surname, surnames)
+	entry.PhoneNumber = a.Phone(entry.PhoneNumber)
+	entry.Company = a.pick("company", entry.Company, companies)
+	entry.Nickname = ""
+	entry.Birthday, entry.Anniversary, entry.RemindDays = "", "", 0
+	entry.Photo = ""
+	entry.AccessNotes = ""
+
+	return entry
+}
+
+// Phone masks a phone number: the country code and the first digits of the
+// national number stay, the others are replaced, and any spaces, dashes and
+// parentheses are left where they were.
+func (a *Anonymizer) Phone(number model.PhoneNumber) model.PhoneNumber {
+	if number == "" {
+		return ""
+	}
+
+	// Numbers that cannot be parsed keep their first few digits.
+	keep := keptDigits
+	if national := number.National(); national != "" {
+		keep = countDigits(string(number)) - len(national) + keptDigits
+	}
+
+	hash := a.hash("phone", string(number))
+
+	masked := []rune(string(number))
+	seen := 0
+	for i, r := range masked {
+		if r < '0' || r > '9' {
+			continue
+		}
+
+		if seen >= keep {
+			masked[i] = rune('0' + hash[seen%len(hash)]%10)
+		}
+
+		seen++
+	}
+
+	return model.PhoneNumber(masked)
+}
+
+// pick replaces value with one of the fake values of its kind, in the same
+// script: names written in Persian get Persian fake names.
+func (a *Anonymizer) pick(kind, value string, fakes map[bool][]string) string {
+	if value == "" {
+		return ""
+	}
+
+	choices := fakes[isArabicScript(value)]
+	hash := a.hash(kind, value)
+
+	return choices[binary.BigEndian.Uint64(hash[:8])%uint64(len(choices))]
+}
+
+func (a *Anonymizer) hash(kind, value string) []byte {
+	mac := hmac.New(sha256.New, a.seed)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+
+	return mac.Sum(nil)
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+
+	return n
+}
+
+func isArabicScript(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Arabic, r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The fake values, keyed by whether they are written in the Arabic script.
+var (
+	names = map[bool][]string{
+		false: {"Alex", "Ana", "Ben", "Clara", "David", "Elena", "Farid", "Grace", "Hana", "Ivan", "Julia", "Karim", "Laura", "Leo", "Maya", "Nadia", "Omar", "Paula", "Reza", "Sara", "Tom", "Vera", "Yusuf", "Zoe"},
+		true:  {"آرش", "بهار", "پویا", "ترانه", "جواد", "حمید", "داریوش", "رویا", "زهره", "سامان", "شیرین", "فرهاد", "کاوه", "گلناز", "لیلا", "مهدی", "نازنین", "نیما", "هستی", "یاسمن"},
+	}
+
+	surnames = map[bool][]string{
+		false: {"Adams", "Baker", "Carter", "Diaz", "Evans", "Fischer", "Garcia", "Hansen", "Ito", "Jensen", "Khan", "Lopez", "Meyer", "Novak", "Olsen", "Park", "Quinn", "Rossi", "Silva", "Tanaka", "Urban", "Weber", "Young", "Zimmer"},
+		true:  {"احمدی", "باقری", "تهرانی", "جعفری", "حسینی", "رحیمی", "زمانی", "سلیمانی", "شریفی", "صادقی", "عباسی", "فراهانی", "قاسمی", "کریمی", "محمدی", "موسوی", "نوری", "هاشمی", "یزدانی"},
+	}
+
+	companies = map[bool][]string{
+		false: {"Acme", "Blue Harbor", "Copperfield", "Delta Works", "Evergreen", "Foxglove", "Granite Labs", "Hillside", "Ironwood", "Juniper", "Keystone", "Lakeshore", "Maple & Co", "Northwind", "Oakridge", "Pinecrest"},
+		true:  {"آسمان", "البرز", "بهاران", "پارس", "دماوند", "زاگرس", "سپهر", "فردا", "کاوش", "مهرگان", "نوآوران", "هامون"},
+	}
+)
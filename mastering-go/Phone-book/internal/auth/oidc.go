@@ -0,0 +1,330 @@
+// Package auth signs users of the web UI and the API in through an OpenID
+// Connect provider, such as a company SSO, and maps their groups to roles.
+//
+// Browsers go through /login and come back to /callback with a session
+// cookie. API clients can instead send an ID token from the same provider as
+// "Authorization: Bearer <token>". Viewers may only read; editors may also
+// change the phone book.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/middleware"
+)
+
+// Roles a user can be given.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+)
+
+const (
+	sessionCookie = "phonebook_session"
+	stateCookie   = "phonebook_oidc_state"
+	sessionLength = 12 * time.Hour
+)
+
+// Authenticator runs the OIDC login flow and checks the sessions it hands
+// out.
+type Authenticator struct {
+	config   config.OIDC
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	key      []byte
+}
+
+// session is what the session cookie carries, signed with the session key.
+type session struct {
+	Subject string    `json:"sub"`
+	Name    string    `json:"name"`
+	Role    string    `json:"role"`
+	Expires time.Time `json:"exp"`
+}
+
+// New discovers the provider at cfg.Issuer.
+func New(ctx context.Context, cfg config.OIDC) (*Authenticator, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach the OIDC provider: %v", err)
+	}
+
+	key := []byte(cfg.SessionKey)
+	if len(key) == 0 {
+		// Sessions then end with the process, which is fine for a single
+		// server.
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	return &Authenticator{
+		config: cfg,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       append([]string{oidc.ScopeOpenID, "profile", "email"}, cfg.Scopes...),
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		key:      key,
+	}, nil
+}
+
+// Wrap serves /login, /callback and /logout and passes everything else to
+// next.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login", a.login)
+	mux.HandleFunc("GET /callback", a.callback)
+	mux.HandleFunc("GET /logout", a.logout)
+	mux.Handle("/", next)
+
+	return mux
+}
+
+// Middleware rejects requests without a session or ID token (401) and
+// changes from users who are not editors (403). The handlers get the subject
+// of the user from middleware.User.
+func (a *Authenticator) Middleware() middleware.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current, err := a.session(r)
+			if err != nil {
+				// Tells the web UI where to send the user.
+				w.Header().Set("X-Login-URL", "/login")
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, "please log in")
+				return
+			}
+
+			if current.Role == "" {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprintf(w, "%s is not allowed to use the phone book", current.Name)
+				return
+			}
+
+			if current.Role != RoleEditor && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				w.WriteHeader(http.StatusForbidden)
+				fmt.Fprintf(w, "%s may only view the phone book", current.Name)
+				return
+			}
+
+			ctx := context.WithValue(middleware.WithUser(r.Context(), current.Subject), roleKey{}, current.Role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+type roleKey struct{}
+
+// EditorsOnly rejects the requests of users who are not editors (403), reads
+// too, for routes like the profiling ones that viewers have no business
+// seeing. It goes after Middleware, which tells it the role of the user.
+func EditorsOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if role, _ := r.Context().Value(roleKey{}).(string); role != RoleEditor {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, "only editors may use this")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authenticator) login(w http.ResponseWriter, r *http.Request) {
+	state := make([]byte, 16)
+	if _, err := rand.Read(state); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	// The state also remembers where to go back to after logging in.
+	next := r.URL.Query().Get("next")
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		next = "/"
+	}
+
+	value := base64.RawURLEncoding.EncodeToString(state) + "|" + next
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Value: value, Path: "/", MaxAge: 600, HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteLaxMode})
+	http.Redirect(w, r, a.oauth.AuthCodeURL(value), http.StatusFound)
+}
+
+func (a *Authenticator) callback(w http.ResponseWriter, r *http.Request) {
+	state, err := r.Cookie(stateCookie)
+	if err != nil || r.URL.Query().Get("state") != state.Value {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "invalid login state, please try again")
+		return
+	}
+
+	token, err := a.oauth.Exchange(r.Context(), r.URL.Query().Get("code"))
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, "the provider returned no ID token")
+		return
+	}
+
+	current, err := a.verify(r.Context(), rawIDToken)
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: "/", MaxAge: -1})
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: a.sign(current), Path: "/", Expires: current.Expires, HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteLaxMode})
+
+	_, next, _ := strings.Cut(state.Value, "|")
+	http.Redirect(w, r, next, http.StatusFound)
+}
+
+func (a *Authenticator) logout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+// session returns the user of a request, from its ID token or its session
+// cookie.
+func (a *Authenticator) session(r *http.Request) (*session, error) {
+	if rawIDToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return a.verify(r.Context(), rawIDToken)
+	}
+
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(a.signature(payload))) {
+		return nil, errors.New("invalid session")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var current session
+	if err := json.Unmarshal(data, &current); err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(current.Expires) {
+		return nil, errors.New("session expired")
+	}
+
+	return &current, nil
+}
+
+// verify checks an ID token and works out the role of its user.
+func (a *Authenticator) verify(ctx context.Context, rawIDToken string) (*session, error) {
+	idToken, err := a.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %v", err)
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	name, _ := claims["email"].(string)
+	if name == "" {
+		name = idToken.Subject
+	}
+
+	return &session{
+		Subject: idToken.Subject,
+		Name:    name,
+		Role:    a.role(groups(claims[a.config.GroupsClaim])),
+		Expires: time.Now().Add(sessionLength),
+	}, nil
+}
+
+// role maps groups to the strongest role any of them grants. Without any
+// viewer groups configured, every signed in user may view.
+func (a *Authenticator) role(groups []string) string {
+	for _, group := range groups {
+		if slices.Contains(a.config.EditorGroups, group) {
+			return RoleEditor
+		}
+	}
+
+	if len(a.config.ViewerGroups) == 0 {
+		return RoleViewer
+	}
+
+	for _, group := range groups {
+		if slices.Contains(a.config.ViewerGroups, group) {
+			return RoleViewer
+		}
+	}
+
+	return ""
+}
+
+// groups reads a groups claim, which providers send as a list or as a
+// single string.
+func groups(claim any) []string {
+	switch claim := claim.(type) {
+	case string:
+		return []string{claim}
+	case []any:
+		var names []string
+		for _, group := range claim {
+			if name, ok := group.(string); ok {
+				names = append(names, name)
+			}
+		}
+
+		return names
+	}
+
+	return nil
+}
+
+func (a *Authenticator) sign(current *session) string {
+	data, _ := json.Marshal(current)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + a.signature(payload)
+}
+
+func (a *Authenticator) signature(payload string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
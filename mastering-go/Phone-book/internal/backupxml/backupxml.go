@@ -0,0 +1,227 @@
+// Package backupxml reads the contacts of the XML files Android backup apps,
+// such as SMS/Contacts Backup, save.
+package backupxml
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// node is an XML element with everything in it.
+type node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []node     `xml:",any"`
+}
+
+// Field names used by the backup apps, lower case, for each field of an
+// entry.
+var (
+	displayNames = []string{"display_name", "displayname", "name", "contact_name"}
+	givenNames   = []string{"given_name", "first_name", "firstname", "given"}
+	familyNames  = []string{"family_name", "last_name", "lastname", "surname", "family"}
+	nicknames    = []string{"nickname", "nick"}
+	companies    = []string{"company", "organization", "organisation", "org"}
+	titles       = []string{"title", "job_title", "jobtitle"}
+	birthdays    = []string{"birthday", "bday"}
+	anniversary  = []string{"anniversary"}
+	phoneNames   = []string{"number", "phone", "phone_number", "tel"}
+)
+
+// mobileTypes are the values of a number's type meaning a mobile phone,
+// which is used as the entry's number when a contact has several. "2" is
+// Android's Phone.TYPE_MOBILE.
+var mobileTypes = []string{"mobile", "cell", "2"}
+
+// Read reads the contacts of r, a <contact> element each, anywhere in the
+// file. Their fields may be attributes or child elements, and the phone
+// numbers repeated <phone> (or <number>) elements with a type; an entry keeps
+// one number, the first mobile one if any. Contacts without a name or a
+// number are returned too, for the caller to report.
+func Read(r io.Reader) ([]model.Entry, error) {
+	decoder := xml.NewDecoder(r)
+	// Backup apps write files in UTF-8 even when they say otherwise.
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var entries []model.Entry
+	found := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || !strings.EqualFold(start.Name.Local, "contact") {
+			continue
+		}
+
+		var contact node
+		if err := decoder.DecodeElement(&contact, &start); err != nil {
+			return nil, err
+		}
+
+		found = true
+		entries = append(entries, toEntry(contact))
+	}
+
+	if !found {
+		return nil, errors.New("no <contact> elements, not a contacts backup")
+	}
+
+	return entries, nil
+}
+
+func toEntry(contact node) model.Entry {
+	entry := model.Entry{
+		Name:     contact.field(givenNames),
+		Surname:  contact.field(familyNames),
+		Nickname: contact.field(nicknames),
+		Company:  contact.field(companies),
+		Title:    contact.field(titles),
+	}
+
+	// Only the display name may be given, "Morteza Shahrabi".
+	if entry.Name == "" && entry.Surname == "" {
+		name := contact.field(displayNames)
+		if i := strings.LastIndexByte(name, ' '); i > 0 {
+			entry.Name, entry.Surname = name[:i], name[i+1:]
+		} else {
+			entry.Name = name
+		}
+	}
+
+	entry.PhoneNumber = model.PhoneNumber(contact.number())
+	entry.Birthday = date(contact.field(birthdays))
+	entry.Anniversary = date(contact.field(anniversary))
+
+	return entry
+}
+
+// field returns the first of names n has as an attribute or child element,
+// or one of its children as an attribute: <organization company="Acme"/>.
+func (n node) field(names []string) string {
+	for _, name := range names {
+		if value, ok := n.attr(name); ok && value != "" {
+			return value
+		}
+
+		for _, child := range n.Children {
+			if strings.EqualFold(child.XMLName.Local, name) {
+				if value := child.value(); value != "" {
+					return value
+				}
+			}
+		}
+	}
+
+	for _, name := range names {
+		for _, child := range n.Children {
+			if value, ok := child.attr(name); ok && value != "" {
+				return value
+			}
+		}
+	}
+
+	return ""
+}
+
+func (n node) attr(name string) (string, bool) {
+	for _, attr := range n.Attrs {
+		if strings.EqualFold(attr.Name.Local, name) {
+			return strings.TrimSpace(attr.Value), true
+		}
+	}
+
+	return "", false
+}
+
+// value is the text of n, or its value attribute: <phone>+98…</phone> or
+// <phone value="+98…"/>.
+func (n node) value() string {
+	if text := strings.TrimSpace(n.Text); text != "" {
+		return text
+	}
+
+	if value, _ := n.attr("value"); value != "" {
+		return value
+	}
+
+	value, _ := n.attr("number")
+	return value
+}
+
+// number returns the first mobile number of n, or else its first number.
+func (n node) number() string {
+	var first string
+	for _, child := range n.Children {
+		if !isPhone(child.XMLName.Local) {
+			continue
+		}
+
+		number := child.value()
+		if number == "" {
+			continue
+		}
+
+		if kind, _ := child.attr("type"); hasMobileType(kind) {
+			return number
+		}
+
+		if first == "" {
+			first = number
+		}
+	}
+
+	if first != "" {
+		return first
+	}
+
+	return n.field(phoneNames)
+}
+
+func isPhone(name string) bool {
+	for _, phoneName := range phoneNames {
+		if strings.EqualFold(name, phoneName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasMobileType(kind string) bool {
+	for _, mobile := range mobileTypes {
+		if strings.EqualFold(kind, mobile) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// date returns s as a day the phone book keeps, "1990-05-17" or "05-17", or
+// "" when it is none. Android writes days without a year as "--05-17".
+func date(s string) string {
+	s = strings.TrimPrefix(s, "--")
+	if len(s) > len("2006-01-02") {
+		s = s[:len("2006-01-02")]
+	}
+
+	if _, _, _, err := model.ParseDate(s); err != nil {
+		return ""
+	}
+
+	return s
+}
@@ -0,0 +1,275 @@
+// Package bundle reads and writes the complete state of a phone book as one
+// tar.gz file, to move it between backends and machines.
+//
+// A bundle holds manifest.json first, then entries.json, calls.json,
+// blocked.json, spam_reports.json and links.json, events.json for books with
+// a history, and photos/<id> for each photo, the id being that of the entry
+// in entries.json. The manifest records how many of
+// each there are and the SHA-256 of every other file, which Read checks.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Format and Version identify bundles in their manifest. Version goes up
+// when a bundle changes in a way older readers would get wrong: bundles
+// with events are version 2, as version 1 readers would drop the history.
+// The others are still written as version 1.
+const (
+	Format  = "phonebook-dump"
+	Version = 2
+)
+
+// maxFileSize bounds each file read from a bundle.
+const maxFileSize = 1 << 30
+
+// Bundle is the state of a phone book. Photos are by entry ID.
+type Bundle struct {
+	Manifest    Manifest
+	Entries     []model.Entry
+	Photos      map[int64][]byte
+	Calls       []model.Call
+	Blocked     []string
+	SpamReports []model.SpamReport
+	Links       []model.Link
+	// Events is the history of books that keep one, see storage.History.
+	Events []model.Event
+}
+
+// Manifest describes a bundle.
+type Manifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	// Counts has the number of entries, photos, calls, blocked numbers,
+	// spam reports, links and events, by file name without extension.
+	Counts map[string]int `json:"counts"`
+	// Files has the SHA-256 of every file but the manifest, in hex.
+	Files map[string]string `json:"files"`
+}
+
+// Write writes b to w, filling in its manifest.
+func Write(w io.Writer, b *Bundle) error {
+	type part struct {
+		name  string
+		value any
+	}
+
+	files := []part{
+		{"entries.json", b.Entries},
+		{"calls.json", b.Calls},
+		{"blocked.json", b.Blocked},
+		{"spam_reports.json", b.SpamReports},
+		{"links.json", b.Links},
+	}
+
+	if len(b.Events) > 0 {
+		files = append(files, part{"events.json", b.Events})
+	}
+
+	data := make(map[string][]byte, len(files)+len(b.Photos))
+	names := make([]string, 0, len(files)+len(b.Photos))
+	for _, file := range files {
+		encoded, err := json.MarshalIndent(file.value, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		// Empty lists are written [] rather than null.
+		if string(encoded) == "null" {
+			encoded = []byte("[]")
+		}
+
+		data[file.name] = append(encoded, '\n')
+		names = append(names, file.name)
+	}
+
+	ids := make([]int64, 0, len(b.Photos))
+	for id := range b.Photos {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		name := "photos/" + strconv.FormatInt(id, 10)
+		data[name] = b.Photos[id]
+		names = append(names, name)
+	}
+
+	version := 1
+	if len(b.Events) > 0 {
+		version = Version
+	}
+
+	b.Manifest = Manifest{
+		Format:    Format,
+		Version:   version,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Counts: map[string]int{
+			"entries":      len(b.Entries),
+			"photos":       len(b.Photos),
+			"calls":        len(b.Calls),
+			"blocked":      len(b.Blocked),
+			"spam_reports": len(b.SpamReports),
+			"links":        len(b.Links),
+		},
+		Files: make(map[string]string, len(data)),
+	}
+
+	if len(b.Events) > 0 {
+		b.Manifest.Counts["events"] = len(b.Events)
+	}
+
+	for name, content := range data {
+		sum := sha256.Sum256(content)
+		b.Manifest.Files[name] = hex.EncodeToString(sum[:])
+	}
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	zipped := gzip.NewWriter(w)
+	archive := tar.NewWriter(zipped)
+
+	add := func(name string, content []byte) error {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: b.Manifest.CreatedAt, Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+
+		_, err := archive.Write(content)
+		return err
+	}
+
+	if err := add("manifest.json", append(manifest, '\n')); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := add(name, data[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	return zipped.Close()
+}
+
+// Read reads a bundle written by Write, checking it against its manifest.
+func Read(r io.Reader) (*Bundle, error) {
+	zipped, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a phone book dump: %v", err)
+	}
+
+	defer zipped.Close()
+
+	files := make(map[string][]byte)
+	archive := tar.NewReader(zipped)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("not a phone book dump: %v", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if header.Size > maxFileSize {
+			return nil, fmt.Errorf("%s in the dump is too big", header.Name)
+		}
+
+		content, err := io.ReadAll(archive)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s in the dump: %v", header.Name, err)
+		}
+
+		files[header.Name] = content
+	}
+
+	b := &Bundle{Photos: make(map[int64][]byte)}
+
+	manifest, ok := files["manifest.json"]
+	if !ok {
+		return nil, errors.New("not a phone book dump: it has no manifest.json")
+	}
+
+	if err := json.Unmarshal(manifest, &b.Manifest); err != nil || b.Manifest.Format != Format {
+		return nil, errors.New("not a phone book dump: manifest.json is not one of a dump")
+	}
+
+	if b.Manifest.Version > Version {
+		return nil, fmt.Errorf("the dump has version %d, this phone book only reads up to %d", b.Manifest.Version, Version)
+	}
+
+	for name, sum := range b.Manifest.Files {
+		content, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("the dump is incomplete: %s is missing", name)
+		}
+
+		got := sha256.Sum256(content)
+		if hex.EncodeToString(got[:]) != sum {
+			return nil, fmt.Errorf("the dump is damaged: %s does not match its checksum", name)
+		}
+	}
+
+	for name, content := range files {
+		if _, ok := b.Manifest.Files[name]; !ok && name != "manifest.json" {
+			return nil, fmt.Errorf("the dump has %s, which its manifest does not list", name)
+		}
+
+		var err error
+		switch {
+		case name == "entries.json":
+			err = json.Unmarshal(content, &b.Entries)
+		case name == "calls.json":
+			err = json.Unmarshal(content, &b.Calls)
+		case name == "blocked.json":
+			err = json.Unmarshal(content, &b.Blocked)
+		case name == "spam_reports.json":
+			err = json.Unmarshal(content, &b.SpamReports)
+		case name == "links.json":
+			err = json.Unmarshal(content, &b.Links)
+		case name == "events.json":
+			err = json.Unmarshal(content, &b.Events)
+		case strings.HasPrefix(name, "photos/"):
+			id, parseErr := strconv.ParseInt(strings.TrimPrefix(name, "photos/"), 10, 64)
+			if parseErr != nil {
+				return nil, fmt.Errorf("the dump has a photo of an invalid id, %s", name)
+			}
+
+			b.Photos[id] = content
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s in the dump: %v", name, err)
+		}
+	}
+
+	return b, nil
+}
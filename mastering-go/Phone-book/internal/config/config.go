@@ -0,0 +1,249 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Config holds the settings read from the phone book config file. Every
+// field is optional, a missing file behaves like an empty one.
+type Config struct {
+	Locale        string `json:"locale"`
+	DefaultRegion string `json:"default_region"`
+	ReadOnly      bool   `json:"read_only"`
+	// LogLevel picks the requests the server logs: "info" (all of them, the
+	// default), "warn" or "error".
+	LogLevel string `json:"log_level"`
+	// RateLimit is the requests per second allowed per client when
+	// -rate-limit isn't given, 0 for no limit.
+	RateLimit float64 `json:"rate_limit"`
+	// Books turns the server into a multi-tenant one hosting each of these
+	// phone books under /books/{name}/.
+	Books map[string]Book `json:"books"`
+	// OIDC, when set, signs users in through an OpenID Connect provider.
+	OIDC *OIDC `json:"oidc"`
+	// Retention, when set, is enforced by the purge command and every day by
+	// the server.
+	Retention *Retention `json:"retention"`
+	// Jobs are run by the server on their schedules.
+	Jobs []Job `json:"jobs"`
+	// Reminders, when set, makes the daemon remind of the birthdays and
+	// anniversaries coming up.
+	Reminders *Reminders `json:"reminders"`
+	// Tracing, when set, exports OpenTelemetry traces of requests and
+	// storage operations.
+	Tracing *Tracing `json:"tracing"`
+	// CORS, when set, lets web front ends hosted elsewhere call the API.
+	CORS *CORS `json:"cors"`
+	// DestructiveThreshold is the percentage of the entries one command may
+	// delete, archive or purge without --force, 20 when it is 0; 100 turns
+	// the check off.
+	DestructiveThreshold float64 `json:"destructive_threshold"`
+	// RequireConsent refuses calls and reminders for contacts who were never
+	// asked for consent, not only for those who refused.
+	RequireConsent bool `json:"require_consent"`
+}
+
+// reloadable are the settings a running server applies when it reloads the
+// config file; the others are only read on start.
+var reloadable = []string{"log_level", "rate_limit"}
+
+// DefaultDestructiveThreshold is the DestructiveThreshold of a config file
+// that doesn't set one.
+const DefaultDestructiveThreshold = 20
+
+// Threshold returns the DestructiveThreshold of c, or
+// DefaultDestructiveThreshold when it isn't set.
+func (c *Config) Threshold() float64 {
+	if c.DestructiveThreshold > 0 {
+		return c.DestructiveThreshold
+	}
+
+	return DefaultDestructiveThreshold
+}
+
+// TooDestructive tells whether deleting, archiving or purging affected of
+// the total entries at once is more than the threshold of c allows. A single
+// entry never is.
+func (c *Config) TooDestructive(affected, total int) bool {
+	return affected > 1 && total > 0 && float64(affected)*100 > c.Threshold()*float64(total)
+}
+
+// NeedRestart returns the settings that differ between c and next but only
+// take effect on start, by their names in the config file.
+func (c *Config) NeedRestart(next *Config) []string {
+	var names []string
+	current, changed := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < current.NumField(); i++ {
+		name, _, _ := strings.Cut(current.Type().Field(i).Tag.Get("json"), ",")
+		if slices.Contains(reloadable, name) {
+			continue
+		}
+
+		if !reflect.DeepEqual(current.Field(i).Interface(), changed.Field(i).Interface()) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Reminders configures how the daemon reminds of birthdays and
+// anniversaries, DaysBefore days ahead (by default 7) unless an entry's
+// remind_days says otherwise. Each reminder is sent once through every
+// channel given: Command is run with sh -c, with the reminder in the
+// PHONEBOOK_MESSAGE, PHONEBOOK_NAME, PHONEBOOK_OCCASION, PHONEBOOK_DATE and
+// PHONEBOOK_DAYS variables, Email sends it over SMTP and Webhook is POSTed
+// the reminder as JSON.
+type Reminders struct {
+	DaysBefore int    `json:"days_before"`
+	Command    string `json:"command"`
+	Email      *Email `json:"email"`
+	Webhook    string `json:"webhook"`
+	// OverrideConsent reminds of contacts who didn't agree to be contacted
+	// too, like log call --override.
+	OverrideConsent bool `json:"override_consent"`
+}
+
+// Email configures sending mail through the SMTP server at Host, a
+// host:port like "smtp.example.com:587". Username and Password, when set,
+// sign in with PLAIN authentication, which needs STARTTLS unless the server
+// is local.
+type Email struct {
+	Host     string   `json:"host"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// Tracing configures the OTLP/HTTP export of traces. Endpoint is a
+// host:port like "localhost:4318"; the OTEL_EXPORTER_OTLP_* variables are
+// used for what is left empty. SampleRatio is the share of traces kept,
+// all of them when 0. Traces started by a caller that sent a traceparent
+// header follow the caller's decision.
+type Tracing struct {
+	Endpoint    string  `json:"endpoint"`
+	Insecure    bool    `json:"insecure"`
+	ServiceName string  `json:"service_name"`
+	SampleRatio float64 `json:"sample_ratio"`
+}
+
+// CORS configures which web front ends may call the API from a browser.
+// AllowedOrigins are like "https://app.example.com", "*" for any; the -cors
+// flag replaces them. The methods and headers default to those the API uses,
+// and MaxAge, how long browsers may cache a preflight answer, to 600
+// seconds. AllowCredentials lets browsers send cookies, which a front end
+// signing in through OIDC needs.
+type CORS struct {
+	AllowedOrigins   []string `json:"allowed_origins"`
+	AllowedMethods   []string `json:"allowed_methods"`
+	AllowedHeaders   []string `json:"allowed_headers"`
+	ExposedHeaders   []string `json:"exposed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	MaxAge           int      `json:"max_age"`
+}
+
+// Job is a task the server runs on a cron schedule like "0 3 * * *" or
+// "@daily". Task is "backup" (export the book to Output), "dedupe-report"
+// (write the suspected duplicates to Output), "purge" (delete the entries
+// not modified for OlderThan, by default the retention's PurgeAfter) or
+// "report" (write the digest of the changes made over Since, by default a
+// week, to Output). Output may contain {date} and {time}, and is written as
+// JSON when it ends in .json and as CSV otherwise, reports as HTML when it
+// ends in .html and as Markdown otherwise. Upload is a destination like s3://bucket/book.json or
+// sftp://user@host/backups/book.json the output is also copied to, or only
+// written to when there is no Output; see the upload package. Book picks the
+// phone book on multi-tenant servers.
+type Job struct {
+	Name      string `json:"name"`
+	Schedule  string `json:"schedule"`
+	Task      string `json:"task"`
+	Output    string `json:"output"`
+	Upload    string `json:"upload"`
+	OlderThan string `json:"older_than"`
+	Since     string `json:"since"`
+	Book      string `json:"book"`
+}
+
+// Retention is how long entries are kept. PurgeAfter is an age like "3y" or
+// "18m": entries nobody modified for that long are deleted. Archived entries
+// are deleted once they have been archived for PurgeArchivedAfter, which
+// empties the archive like a trash.
+type Retention struct {
+	PurgeAfter         string `json:"purge_after"`
+	PurgeArchivedAfter string `json:"purge_archived_after"`
+}
+
+// OIDC configures login through an OpenID Connect provider. Users in one of
+// EditorGroups may change the phone book; users in ViewerGroups, or anyone
+// signed in when it is empty, may read it.
+type OIDC struct {
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+	GroupsClaim  string   `json:"groups_claim"`
+	EditorGroups []string `json:"editor_groups"`
+	ViewerGroups []string `json:"viewer_groups"`
+	// SessionKey signs session cookies. Set it when several servers share
+	// logins or sessions should survive restarts.
+	SessionKey string `json:"session_key"`
+}
+
+// Book configures one phone book of a multi-tenant server. Requests to it
+// need one of Tokens when any are given.
+type Book struct {
+	Storage  string   `json:"storage"`
+	DSN      string   `json:"dsn"`
+	Tokens   []string `json:"tokens"`
+	ReadOnly bool     `json:"read_only"`
+}
+
+// Path returns the config file location: $PHONEBOOK_CONFIG if set, otherwise
+// phonebook/config.json under the user's config directory.
+func Path() string {
+	if path := os.Getenv("PHONEBOOK_CONFIG"); path != "" {
+		return path
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "phonebook", "config.json")
+}
+
+// Load reads the config file from Path.
+func Load() (*Config, error) {
+	config := &Config{}
+
+	path := Path()
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %v", err)
+	}
+
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	return config, nil
+}
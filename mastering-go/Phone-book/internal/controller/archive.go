@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/filter"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/retention"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// archiveCommand handles "archive <id>..." and "archive [--where EXPR]
+// [--older-than AGE] [--dry-run] [--force]", which archive entries: they are
+// kept, but list and search only show them with --archived. The second form
+// archives every entry matching the filter expression and not modified for
+// AGE; archiving more of the book than the destructive threshold needs
+// --force, see checkDestructive.
+// "unarchive" takes the same arguments and brings entries back; unarchive is
+// set for it.
+func archiveCommand(ctx context.Context, store storage.Storage, arguments []string, unarchive bool) error {
+	usage := i18n.T("usage: archive <id>... or archive [--where EXPR] [--older-than AGE] [--dry-run] [--force]")
+	if unarchive {
+		usage = i18n.T("usage: unarchive <id>... or unarchive [--where EXPR] [--older-than AGE] [--dry-run]")
+	}
+
+	flags := flag.NewFlagSet(arguments[1], flag.ContinueOnError)
+	where := flags.String("where", "", i18n.T("only the entries matching a filter, e.g. \"company=Acme\""))
+	olderThan := flags.String("older-than", "", i18n.T("only the entries not modified for this long, e.g. 2y or 18m"))
+	dryRun := flags.Bool("dry-run", false, i18n.T("only list the entries that would change"))
+	force := flags.Bool("force", false, i18n.T("archive even more of the book than the destructive threshold allows"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	bulk := *where != "" || *olderThan != ""
+	if bulk == (flags.NArg() > 0) {
+		return &UsageError{Message: usage}
+	}
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	var targets []model.Entry
+	if bulk {
+		var err error
+		if targets, err = archiveTargets(entries, *where, *olderThan); err != nil {
+			return err
+		}
+	} else {
+		byID := make(map[int64]model.Entry, len(entries))
+		for _, entry := range entries {
+			byID[entry.ID] = entry
+		}
+
+		for _, argument := range flags.Args() {
+			id, err := strconv.ParseInt(argument, 10, 64)
+			if err != nil {
+				return errors.New(i18n.T("invalid id %q", argument))
+			}
+
+			entry, ok := byID[id]
+			if !ok {
+				return errors.New(i18n.T("there is no record with id %d", id))
+			}
+
+			targets = append(targets, entry)
+		}
+	}
+
+	// Entries already in the state asked for are left alone.
+	var changed []model.Entry
+	for _, entry := range targets {
+		if (entry.ArchivedAt != nil) != !unarchive {
+			changed = append(changed, entry)
+		}
+	}
+
+	// Unarchiving destroys nothing.
+	var tooMany error
+	if !unarchive {
+		tooMany = checkDestructive("archive", len(changed), len(entries), *force)
+	}
+
+	if *dryRun {
+		if len(changed) > 0 {
+			output.Table(os.Stdout, changed, output.Options{})
+		}
+
+		if unarchive {
+			fmt.Println(i18n.T("%d entries would be unarchived", len(changed)))
+		} else {
+			fmt.Println(i18n.T("%d entries would be archived", len(changed)))
+		}
+
+		if tooMany != nil {
+			fmt.Println(tooMany)
+		}
+
+		return nil
+	}
+
+	if tooMany != nil {
+		return tooMany
+	}
+
+	var archivedAt *time.Time
+	if !unarchive {
+		now := time.Now().UTC().Truncate(time.Second)
+		archivedAt = &now
+	}
+
+	for i := range changed {
+		changed[i].ArchivedAt = archivedAt
+	}
+
+	if updated, appErr := updateEntries(ctx, store, changed); appErr != nil {
+		if updated > 0 {
+			return errors.Join(errors.New(i18n.T(appErr.Message)), errors.New(i18n.T("only %d of the %d entries were changed", updated, len(changed))))
+		}
+
+		return errors.Join(errors.New(i18n.T(appErr.Message)), errors.New(i18n.T("nothing was changed")))
+	}
+
+	if unarchive {
+		fmt.Println(i18n.T("unarchived %d entries", len(changed)))
+	} else {
+		fmt.Println(i18n.T("archived %d entries", len(changed)))
+	}
+
+	return nil
+}
+
+// archiveTargets returns the entries matching the filter expression where
+// and not modified for olderThan, either of which may be empty. Entries
+// whose last change is not known are never older than anything.
+func archiveTargets(entries []model.Entry, where, olderThan string) ([]model.Entry, error) {
+	if where != "" {
+		expr, err := filter.Parse(where)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = filter.Apply(expr, entries)
+	}
+
+	if olderThan == "" {
+		return entries, nil
+	}
+
+	age, err := retention.ParseAge(olderThan)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-age)
+
+	var stale []model.Entry
+	for _, entry := range entries {
+		if entry.UpdatedAt != nil && entry.UpdatedAt.Before(cutoff) {
+			stale = append(stale, entry)
+		}
+	}
+
+	return stale, nil
+}
+
+// updateEntries updates entries, all together on backends with
+// transactions, and returns how many it updated: on the others the ones
+// before a failure stay updated. An entry changed by someone else since it
+// was listed fails the update with a conflict.
+func updateEntries(ctx context.Context, store storage.Storage, entries []model.Entry) (int, *model.PhoeBookError) {
+	updated := 0
+	atomic, appErr := storage.BatchOrEach(ctx, store, func(w storage.Writer) *model.PhoeBookError {
+		for i := range entries {
+			if appErr := w.Update(ctx, &entries[i]); appErr != nil {
+				return appErr
+			}
+
+			updated++
+		}
+
+		return nil
+	})
+
+	if appErr != nil && atomic {
+		updated = 0
+	}
+
+	return updated, appErr
+}
+
+// archiveView is which entries list and search show.
+type archiveView int
+
+const (
+	// activeEntries, those not archived, are shown by default.
+	activeEntries archiveView = iota
+	archivedEntries
+	// allEntries are shown by search --include-archived.
+	allEntries
+)
+
+// viewOf returns the view asked for by --archived or ?archived=true.
+func viewOf(archived bool) archiveView {
+	if archived {
+		return archivedEntries
+	}
+
+	return activeEntries
+}
+
+// visible returns the entries of entries in view.
+func visible(entries []model.Entry, view archiveView) []model.Entry {
+	shown := make([]model.Entry, 0, len(entries))
+	for _, entry := range entries {
+		if view == allEntries || (entry.ArchivedAt != nil) == (view == archivedEntries) {
+			shown = append(shown, entry)
+		}
+	}
+
+	return shown
+}
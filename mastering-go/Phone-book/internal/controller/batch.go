@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// maxBatchItems is how many entries one batch request may carry.
+const maxBatchItems = 10000
+
+// maxBatchBody bounds the body of a batch request.
+const maxBatchBody = 16 << 20
+
+// batchCreateHandler
+// @Summary      Insert many phonebook entries
+// @Description  Insert an array of entries in one transaction: either all of them are added or, if any fails, none
+// @Tags         phonebook
+// @Accept       json
+// @Produce      json
+// @Param        entries  body      []phonebook.Entry  true  "Entries to insert"
+// @Success      200      {object}  phonebook.BatchResponse
+// @Failure      400      {object}  phonebook.BatchResponse
+// @Failure      413      {string}  string  "Too many entries"
+// @Router       /entries:batchCreate [post]
+func (h *handlers) batchCreateHandler(w http.ResponseWriter, r *http.Request) {
+	var entries []model.Entry
+	if !readBatch(w, r, &entries, func() int { return len(entries) }) {
+		return
+	}
+
+	results := make([]model.BatchResult, len(entries))
+	writeBatch(w, r, h.store, results, func(tx storage.Tx) {
+		for i := range entries {
+			entry := entries[i]
+			if strings.TrimSpace(string(entry.PhoneNumber)) == "" {
+				results[i] = model.BatchResult{Status: http.StatusBadRequest, Error: "the entry has no phone number"}
+				continue
+			}
+
+			if err := checkEntry(entry); err != nil {
+				results[i] = model.BatchResult{Status: http.StatusBadRequest, Error: err.Error()}
+				continue
+			}
+
+			// Like POST /insert, the backend picks IDs and starts versions.
+			entry.ID, entry.Version, entry.Photo = 0, 0, ""
+			prepareEntry(&entry)
+
+			id, appErr := tx.Insert(r.Context(), &entry)
+			if appErr != nil {
+				results[i] = model.BatchResult{Status: int(appErr.StatusCode), Error: appErr.Message}
+				continue
+			}
+
+			results[i] = model.BatchResult{ID: id, Status: http.StatusCreated}
+		}
+	})
+}
+
+// batchDeleteHandler
+// @Summary      Delete many phonebook entries
+// @Description  Delete an array of entry IDs in one transaction: either all of them are deleted or, if any fails, none
+// @Tags         phonebook
+// @Accept       json
+// @Produce      json
+// @Param        ids  body      []int  true  "IDs of the entries to delete"
+// @Success      200  {object}  phonebook.BatchResponse
+// @Failure      404  {object}  phonebook.BatchResponse
+// @Failure      413  {string}  string  "Too many entries"
+// @Router       /entries:batchDelete [post]
+func (h *handlers) batchDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []int64
+	if !readBatch(w, r, &ids, func() int { return len(ids) }) {
+		return
+	}
+
+	results := make([]model.BatchResult, len(ids))
+	writeBatch(w, r, h.store, results, func(tx storage.Tx) {
+		for i, id := range ids {
+			if appErr := tx.Delete(r.Context(), id); appErr != nil {
+				results[i] = model.BatchResult{Status: int(appErr.StatusCode), Error: appErr.Message}
+				continue
+			}
+
+			results[i] = model.BatchResult{Status: http.StatusOK}
+		}
+	})
+}
+
+// readBatch decodes the JSON array of a batch request into items, answering
+// the request itself when it cannot.
+func readBatch(w http.ResponseWriter, r *http.Request, items any, count func() int) bool {
+	body, ok := readBody(w, r, maxBatchBody)
+	if !ok {
+		return false
+	}
+
+	if err := json.Unmarshal(body, items); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return false
+	}
+
+	if count() > maxBatchItems {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "a batch holds at most %d items", maxBatchItems)
+		return false
+	}
+
+	return true
+}
+
+// writeBatch runs apply in a transaction and answers with the result of
+// every item, in the order of the request. apply goes through all items even after one failed, so the
+// client learns about every problem at once; then nothing is committed, the
+// items that did work are reported as 424 Failed Dependency, and the response
+// has the status of the first failure.
+func writeBatch(w http.ResponseWriter, r *http.Request, store storage.Storage, results []model.BatchResult, apply func(tx storage.Tx)) {
+	failed := -1
+	appErr := storage.Batch(r.Context(), store, func(tx storage.Tx) *model.PhoeBookError {
+		apply(tx)
+
+		for i, result := range results {
+			if result.Error != "" {
+				failed = i
+				return &model.PhoeBookError{Message: result.Error, StatusCode: int32(result.Status)}
+			}
+		}
+
+		return nil
+	})
+
+	status := http.StatusOK
+	switch {
+	case failed >= 0:
+		status = results[failed].Status
+		for i := range results {
+			if results[i].Error == "" {
+				results[i] = model.BatchResult{Status: http.StatusFailedDependency, Error: fmt.Sprintf("not applied because item %d failed", failed)}
+			}
+		}
+	case appErr != nil:
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	jsonResponse, err := json.MarshalIndent(model.BatchResponse{Committed: failed < 0, Results: results}, "", " ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, string(jsonResponse))
+}
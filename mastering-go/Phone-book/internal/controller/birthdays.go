@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/reminders"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// birthdaysCommand handles "birthdays [--days N]", which lists the
+// birthdays and anniversaries of the next N days, by default those the
+// daemon would remind of now.
+func birthdaysCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("birthdays", flag.ContinueOnError)
+	days := flags.Int("days", 0, i18n.T("look this many days ahead instead of each contact's reminder period"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || *days < 0 {
+		return usageError("usage: birthdays [--days N]")
+	}
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	defaultDays := reminders.DefaultDays
+	if cfg, err := config.Load(); err == nil && cfg.Reminders != nil && cfg.Reminders.DaysBefore > 0 {
+		defaultDays = cfg.Reminders.DaysBefore
+	}
+
+	upcoming := reminders.Upcoming(entries, time.Now(), defaultDays, *days)
+	if len(upcoming) == 0 {
+		fmt.Println(i18n.T("no birthdays or anniversaries coming up"))
+		return nil
+	}
+
+	output.Reminders(os.Stdout, upcoming)
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/phone"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// blockCommand handles "block add|remove <number>" and "block list".
+func blockCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	blocklist, ok := store.(storage.Blocklist)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("blocklists").Message))
+	}
+
+	if len(arguments) < 3 {
+		return usageError("usage: block add|remove <number> or block list")
+	}
+
+	switch arguments[2] {
+	case "list":
+		numbers, err := blocklist.Blocked(ctx)
+		if err != nil {
+			return errors.New(i18n.T(err.Message))
+		}
+
+		for _, number := range numbers {
+			fmt.Println(number)
+		}
+
+	case "add", "remove":
+		if len(arguments) != 4 {
+			return usageError("usage: block add|remove <number> or block list")
+		}
+
+		number := phone.Canonical(arguments[3])
+		if arguments[2] == "add" {
+			if err := blocklist.Block(ctx, number); err != nil {
+				return errors.New(i18n.T(err.Message))
+			}
+
+			fmt.Println(i18n.T("%s is blocked", number))
+			return nil
+		}
+
+		if err := blocklist.Unblock(ctx, number); err != nil {
+			return errors.New(i18n.T(err.Message))
+		}
+
+		fmt.Println(i18n.T("%s is no longer blocked", number))
+
+	default:
+		return usageError("usage: block add|remove <number> or block list")
+	}
+
+	return nil
+}
+
+// blockedSet returns the blocked numbers as a set, or nil when the backend
+// has no blocklist.
+func blockedSet(ctx context.Context, store storage.Storage) map[string]bool {
+	blocklist, ok := store.(storage.Blocklist)
+	if !ok {
+		return nil
+	}
+
+	numbers, err := blocklist.Blocked(ctx)
+	if err != nil {
+		return nil
+	}
+
+	set := make(map[string]bool, len(numbers))
+	for _, number := range numbers {
+		set[number] = true
+	}
+
+	return set
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/middleware"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// BookHeader selects the phone book of a request when its path doesn't
+// start with /books/{book}/.
+const BookHeader = "X-Phonebook-Book"
+
+// Book is one of the phone books hosted by a multi-tenant server. When
+// Tokens is not empty, requests need one of them as a bearer token.
+type Book struct {
+	Store  storage.Storage
+	Tokens []string
+}
+
+// BooksHandler serves isolated phone books side by side. A request picks its
+// book with the /books/{book}/ prefix, e.g. /books/sales/entries, or with
+// the X-Phonebook-Book header and the usual paths. Each book gets its own
+// Handler, so it only ever sees its own storage. GET /search without a book
+// searches all the books the request may read at once.
+func BooksHandler(books map[string]Book, extra ...middleware.Middleware) http.Handler {
+	searchAll := middleware.Chain(booksSearchHandler(books), append([]middleware.Middleware{middleware.Recovery, middleware.Logging}, extra...)...)
+
+	handlers := make(map[string]http.Handler, len(books))
+	for name, book := range books {
+		bookExtra := slices.Clip(extra)
+		if len(book.Tokens) > 0 {
+			bookExtra = append(bookExtra, middleware.Auth(book.Tokens...))
+		}
+
+		handlers[name] = Handler(book.Store, bookExtra...)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, path := r.Header.Get(BookHeader), r.URL.Path
+		if rest, ok := strings.CutPrefix(path, "/books/"); ok {
+			var slash bool
+			name, path, slash = strings.Cut(rest, "/")
+			if !slash {
+				// The web UI uses relative URLs, which need the slash.
+				http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+				return
+			}
+
+			path = "/" + path
+		}
+
+		if name == "" && path == "/search" {
+			searchAll.ServeHTTP(w, r)
+			return
+		}
+
+		handler, ok := handlers[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "there is no phone book named %q", name)
+			return
+		}
+
+		inner := new(http.Request)
+		*inner = *r
+		inner.URL = new(url.URL)
+		*inner.URL = *r.URL
+		inner.URL.Path = path
+		inner.URL.RawPath = ""
+
+		handler.ServeHTTP(w, inner)
+	})
+}
+
+// booksSearchHandler
+// @Summary      Search every book
+// @Description  Search the books the bearer token may read for q, or list their entries without q, the best hits first with the book of each
+// @Tags         books
+// @Param        q         query     string  false  "Search term"
+// @Param        archived  query     bool    false  "Search the archived entries instead"
+// @Produce      json
+// @Success      200  {object}  phonebook.SearchResponse
+// @Failure      401  {string}  string  "Unauthorized"
+// @Router       /search [get]
+func booksSearchHandler(books map[string]Book) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		readable := make(map[string]storage.Storage, len(books))
+		for name, book := range books {
+			if len(book.Tokens) == 0 || middleware.ValidToken(book.Tokens, token) {
+				readable[name] = book.Store
+			}
+		}
+
+		if len(readable) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, "missing or invalid token")
+			return
+		}
+
+		archived, _ := strconv.ParseBool(r.URL.Query().Get("archived"))
+		hits, appErr := searchBooks(r.Context(), readable, r.URL.Query().Get("q"), viewOf(archived))
+		if appErr != nil {
+			w.WriteHeader(int(appErr.StatusCode))
+			fmt.Fprint(w, appErr.Message)
+			return
+		}
+
+		jsonResponse, err := json.MarshalIndent(model.SearchResponse{Hits: append([]model.SearchHit{}, hits...)}, "", " ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, string(jsonResponse))
+	})
+}
+
+type booksKey struct{}
+
+// bookOpener opens the books of the config file for a command.
+type bookOpener struct {
+	names []string
+	open  func(name string) (storage.Storage, error)
+}
+
+// WithBooks returns a context on which commands can reach the books of the
+// config file besides the one they run on, e.g. search --books. open opens
+// the book of one of names for reading; the command closes it.
+func WithBooks(ctx context.Context, names []string, open func(name string) (storage.Storage, error)) context.Context {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+
+	return context.WithValue(ctx, booksKey{}, bookOpener{names: sorted, open: open})
+}
+
+// openBooks opens the books of the config file named in list, a
+// comma-separated list or "all" for every one of them. The caller closes
+// them, also when an error is returned.
+func openBooks(ctx context.Context, list string) (map[string]storage.Storage, error) {
+	opener, ok := ctx.Value(booksKey{}).(bookOpener)
+	if !ok || len(opener.names) == 0 {
+		return nil, errors.New(i18n.T("there are no books in the config file"))
+	}
+
+	names := opener.names
+	if list != "all" {
+		names = nil
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(opener.names, name) {
+				return nil, errors.New(i18n.T("there is no phone book named %q in the config file", name))
+			}
+
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	books := make(map[string]storage.Storage, len(names))
+	for _, name := range names {
+		store, err := opener.open(name)
+		if err != nil {
+			return books, errors.New(i18n.T("book %s: %v", name, err))
+		}
+
+		books[name] = store
+	}
+
+	return books, nil
+}
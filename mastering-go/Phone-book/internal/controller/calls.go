@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/retention"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// logCommand handles "log call <id> [--duration 3m] [--note "..."]
+// [--override]", which records a call with the entry with id in the call
+// log, made now. Calls with contacts who didn't agree to be contacted are
+// refused unless --override is given.
+func logCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	callLog, ok := store.(storage.CallLog)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("call logs").Message))
+	}
+
+	usage := i18n.T("usage: log call <id> [--duration 3m] [--note \"...\"] [--override]")
+	if len(arguments) < 4 || arguments[2] != "call" {
+		return &UsageError{Message: usage}
+	}
+
+	flags := flag.NewFlagSet("log call", flag.ContinueOnError)
+	duration := flags.Duration("duration", 0, i18n.T("how long the call took, e.g. 3m or 1h20m"))
+	note := flags.String("note", "", i18n.T("what the call was about"))
+	override := flags.Bool("override", false, i18n.T("log the call even though the contact didn't agree to be contacted"))
+
+	// The id may come before the flags or after them.
+	if err := flags.Parse(arguments[3:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() == 0 {
+		return &UsageError{Message: usage}
+	}
+
+	idArgument := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || *duration < 0 {
+		return &UsageError{Message: usage}
+	}
+
+	id, err := strconv.ParseInt(idArgument, 10, 64)
+	if err != nil {
+		return errors.New(i18n.T("invalid id %q", idArgument))
+	}
+
+	entries, appErr := entriesByID(ctx, store)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	entry, ok := entries[id]
+	if !ok {
+		return errors.New(i18n.T("there is no record with given id"))
+	}
+
+	if !entry.MayContact(consentRequired()) && !*override {
+		return errors.New(noConsent(entry))
+	}
+
+	call := model.Call{EntryID: id, At: time.Now().UTC().Truncate(time.Second), Duration: duration.Round(time.Second), Note: *note}
+	if appErr := callLog.LogCall(ctx, call); appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	fmt.Println(i18n.T("logged a call with entry %d", id))
+
+	return nil
+}
+
+// consentRequired tells whether the config requires consent before
+// contacting anyone, see config.Config.RequireConsent.
+func consentRequired() bool {
+	cfg, err := config.Load()
+	return err == nil && cfg.RequireConsent
+}
+
+// noConsent explains why entry may not be contacted.
+func noConsent(entry model.Entry) string {
+	switch {
+	case entry.Consent == model.ConsentNo && entry.ConsentDate != "":
+		return i18n.T("entry %d refused to be contacted on %s, use --override to go ahead anyway", entry.ID, entry.ConsentDate)
+	case entry.Consent == model.ConsentNo:
+		return i18n.T("entry %d refused to be contacted, use --override to go ahead anyway", entry.ID)
+	}
+
+	return i18n.T("entry %d has not agreed to be contacted, use --override to go ahead anyway", entry.ID)
+}
+
+// callsCommand handles "calls list [--contact <id>] [--since WHEN]
+// [--until WHEN]", which shows the call log, oldest call first. WHEN is a
+// day like 2024-05-01, or an age like 7d or 2w for that long ago.
+func callsCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	callLog, ok := store.(storage.CallLog)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("call logs").Message))
+	}
+
+	usage := i18n.T("usage: calls list [--contact <id>] [--since WHEN] [--until WHEN]")
+	if len(arguments) < 3 || arguments[2] != "list" {
+		return &UsageError{Message: usage}
+	}
+
+	flags := flag.NewFlagSet("calls list", flag.ContinueOnError)
+	contact := flags.Int64("contact", 0, i18n.T("only show the calls with the entry with this id"))
+	sinceFlag := flags.String("since", "", i18n.T("only show calls from this day on, e.g. 2024-05-01, or from this long ago, e.g. 7d"))
+	untilFlag := flags.String("until", "", i18n.T("only show calls up to the end of this day, or up to this long ago"))
+	if err := flags.Parse(arguments[3:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 {
+		return &UsageError{Message: usage}
+	}
+
+	now := time.Now()
+	var since, until time.Time
+	var err error
+	if *sinceFlag != "" {
+		if since, err = callTime(*sinceFlag, now, false); err != nil {
+			return err
+		}
+	}
+
+	if *untilFlag != "" {
+		if until, err = callTime(*untilFlag, now, true); err != nil {
+			return err
+		}
+	}
+
+	calls, appErr := callLog.Calls(ctx, *contact)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	var shown []model.Call
+	for _, call := range calls {
+		if (since.IsZero() || !call.At.Before(since)) && (until.IsZero() || call.At.Before(until)) {
+			shown = append(shown, call)
+		}
+	}
+
+	if len(shown) == 0 {
+		fmt.Println(i18n.T("no calls logged"))
+		return nil
+	}
+
+	entries, appErr := entriesByID(ctx, store)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	output.Calls(os.Stdout, shown, entries)
+
+	return nil
+}
+
+// callTime parses the --since and --until of calls list. A day starts at
+// local midnight; for --until (end set) the whole day is included.
+func callTime(s string, now time.Time, end bool) (time.Time, error) {
+	if day, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		if end {
+			day = day.AddDate(0, 0, 1)
+		}
+
+		return day, nil
+	}
+
+	age, err := retention.ParseAge(s)
+	if err != nil {
+		return time.Time{}, errors.New(i18n.T("invalid time %q, use a day like 2024-05-01 or an age like 7d", s))
+	}
+
+	return now.Add(-age), nil
+}
+
+// lastContacted returns when the last call with each entry was logged, or
+// nil when store keeps no call log or it cannot be read; listings then go
+// without the column.
+func lastContacted(ctx context.Context, store storage.Storage) map[int64]time.Time {
+	callLog, ok := store.(storage.CallLog)
+	if !ok {
+		return nil
+	}
+
+	calls, appErr := callLog.Calls(ctx, 0)
+	if appErr != nil {
+		return nil
+	}
+
+	last := make(map[int64]time.Time, len(calls))
+	for _, call := range calls {
+		if call.At.After(last[call.EntryID]) {
+			last[call.EntryID] = call.At
+		}
+	}
+
+	return last
+}
+
+func entriesByID(ctx context.Context, store storage.Storage) (map[int64]model.Entry, *model.PhoeBookError) {
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	byID := make(map[int64]model.Entry, len(entries))
+	for _, entry := range entries {
+		byID[entry.ID] = entry
+	}
+
+	return byID, nil
+}
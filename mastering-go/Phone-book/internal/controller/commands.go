@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/version"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Command is a command of the command line, the first argument after the
+// flags.
+type Command struct {
+	Name string
+	// Summary is what help says about the command, translated when shown.
+	Summary string
+	// Run runs the command, arguments being the whole command line as in
+	// CommandLineHandler, and returns why it failed, a *UsageError when it
+	// was not given the right arguments. It is nil for the commands package
+	// main runs itself, as they need more than a phone book.
+	Run func(ctx context.Context, store storage.Storage, arguments []string) error
+	// NoBook commands don't work on a phone book: they run before one is
+	// opened, with a nil store.
+	NoBook bool
+	// Direct commands open the book themselves even when its daemon runs,
+	// as they need more of the backend than a daemon passes on.
+	Direct bool
+}
+
+// commands are all the commands, in the order help lists them. New
+// commands are added here. It is filled by init because search --last runs
+// CommandLineHandler again, which looks in it.
+var commands []Command
+
+func init() {
+	commands = []Command{
+		{Name: "search", Summary: "show the entries matching a term or fields, best first", Run: searchCommand},
+		{Name: "list", Summary: "show the entries of the book, all of them or those of a filter", Run: listCommand},
+		{Name: "get", Summary: "show one entry and the entries related to it", Run: getCommand},
+		{Name: "stats", Summary: "count the entries by country", Run: statsCommand},
+		{Name: "insert", Summary: "add an entry, or those of the standard input", Run: insertCommand},
+		{Name: "delete", Summary: "delete entries by id or filter", Run: deleteCommand},
+		{Name: "archive", Summary: "hide entries from lists and searches without deleting them", Run: func(ctx context.Context, store storage.Storage, arguments []string) error {
+			return archiveCommand(ctx, store, arguments, false)
+		}},
+		{Name: "unarchive", Summary: "bring archived entries back", Run: func(ctx context.Context, store storage.Storage, arguments []string) error {
+			return archiveCommand(ctx, store, arguments, true)
+		}},
+		{Name: "link", Summary: "relate two entries, or list how entries are related", Run: linkCommand},
+		{Name: "photo", Summary: "set or get the photo of an entry", Run: photoCommand},
+		{Name: "block", Summary: "manage the blocklist of numbers", Run: blockCommand},
+		{Name: "spam", Summary: "report a number as spam or show its reports", Run: spamCommand},
+		{Name: "lookup", Summary: "tell who a number belongs to", Run: lookupCommand},
+		{Name: "birthdays", Summary: "list the birthdays and anniversaries coming up", Run: birthdaysCommand},
+		{Name: "log", Summary: "record a call with an entry", Run: logCommand},
+		{Name: "calls", Summary: "show the call log", Run: callsCommand},
+		{Name: "import", Summary: "add the entries of CSV, JSON or vCard files", Run: importCommand, Direct: true},
+		{Name: "export", Summary: "write the entries as CSV or JSON", Run: exportCommand},
+		{Name: "export-contact", Summary: "write an entry as a vCard", Run: exportContactCommand},
+		{Name: "diff", Summary: "compare the book with a saved copy", Run: diffCommand},
+		{Name: "dedupe", Summary: "find the entries that look like duplicates", Run: dedupeCommand},
+		{Name: "report", Summary: "write a digest of the recent changes", Run: reportCommand},
+		{Name: "generate", Summary: "add made-up entries, for benchmarks and demos", Run: generateCommand, Direct: true},
+		{Name: "dump", Summary: "write everything the book keeps as a bundle", Run: dumpCommand},
+		{Name: "load", Summary: "fill an empty book from a bundle of dump", Run: loadCommand},
+		{Name: "privacy", Summary: "export or erase what the book keeps about a person", Run: privacyCommand, Direct: true},
+		{Name: "purge", Summary: "delete the entries the retention policy no longer keeps", Run: purgeCommand},
+		{Name: "history", Summary: "list the changes made to the book or an entry", Run: historyCommand, Direct: true},
+		{Name: "undo", Summary: "take the book back to how it was after a change", Run: undoCommand, Direct: true},
+		{Name: "restore", Summary: "bring the book back to how it was at a time", Run: restoreCommand, Direct: true},
+		{Name: "queue", Summary: "show or replay the changes waiting for an unreachable backend", Run: queueCommand, Direct: true},
+		{Name: "repair", Summary: "salvage what can be read of a damaged CSV file", Run: func(ctx context.Context, store storage.Storage, arguments []string) error {
+			return repairCommand(arguments)
+		}, NoBook: true},
+		{Name: "sync", Summary: "exchange the changes made since the last sync with another server"},
+		{Name: "daemon", Summary: "keep the book loaded for the commands run after it", Direct: true},
+		{Name: "discover", Summary: "list the phone book servers on the local network", Run: func(ctx context.Context, store storage.Storage, arguments []string) error {
+			return DiscoverCommand(ctx, arguments)
+		}, NoBook: true},
+		{Name: "debug", Summary: "show the sizes and memory of the book and the process", Run: debugCommand},
+		{Name: "version", Summary: "show which build of the phone book this is", Run: versionCommand, NoBook: true},
+		{Name: "help", Summary: "list the commands", Run: helpCommand, NoBook: true},
+	}
+}
+
+// UsageError is the error of a command given arguments it does not take.
+// Message is empty when the flag package already told what was wrong.
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+// usageError returns the UsageError of the translated message format.
+func usageError(format string, args ...any) error {
+	return &UsageError{Message: i18n.T(format, args...)}
+}
+
+// flagError returns the error of parsing the flags of a command, which the
+// flag package has already printed: nil for -h, which shows the usage as
+// asked.
+func flagError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	return &UsageError{}
+}
+
+// Lookup returns the command named name.
+func Lookup(name string) (Command, bool) {
+	for _, command := range commands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+
+	return Command{}, false
+}
+
+// Usage writes the command line usage with the list of commands to w.
+func Usage(w io.Writer, program string) {
+	fmt.Fprintln(w, i18n.T("usage: %s [flags] <command> [arguments]", program))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, i18n.T("commands:"))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, command := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", command.Name, i18n.T(command.Summary))
+	}
+
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, i18n.T("Run %s -h for the flags, and %s <command> -h for those of a command.", program, program))
+}
+
+// helpCommand handles "help", which lists the commands.
+func helpCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	Usage(os.Stdout, filepath.Base(arguments[0]))
+
+	return nil
+}
+
+// versionCommand handles "version [--format text|json]", which shows the
+// release, commit and build date of the binary.
+func versionCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	format := flags.String("format", "text", i18n.T("output format, text or json"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || (*format != "text" && *format != "json") {
+		return usageError("usage: version [--format text|json]")
+	}
+
+	info := version.Get()
+	if *format == "text" {
+		fmt.Println("phonebook", info)
+		return nil
+	}
+
+	jsonResponse, err := json.MarshalIndent(info, "", " ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(jsonResponse))
+
+	return nil
+}
@@ -1,98 +1,441 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"net/http"
+	"os"
 	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
-	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/db"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/filter"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
 	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
 )
 
-func CommandLineHandler(arguments []string) {
+// CommandLineHandler runs the command in arguments against store. ctx ends
+// the command early when it is canceled, e.g. on Ctrl-C. The commands are
+// those of the table in commands.go.
+func CommandLineHandler(ctx context.Context, store storage.Storage, arguments []string) error {
 	if err := checkArgumentsLength(arguments); err != nil {
-		return
+		return err
 	}
 
-	switch arguments[1] {
-	case "search":
-		if len(arguments) != 3 {
-			fmt.Println("Please provide a search term")
-			return
+	command, ok := Lookup(arguments[1])
+	if !ok {
+		return errors.New(i18n.T("not a valid command"))
+	}
+
+	if command.Run == nil {
+		return errors.New(i18n.T("%s cannot be run here", command.Name))
+	}
+
+	return command.Run(ctx, store, arguments)
+}
+
+// searchCommand handles "search [flags] <term>", which shows the entries
+// matching term best first, or those matching the field flags without one.
+// "search save", "search saved" and "search forget" manage saved searches,
+// see savedSearchCommand.
+func searchCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	if ok, err := savedSearchCommand(arguments); ok {
+		return err
+	}
+
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := flags.Int("limit", 0, i18n.T("show at most this many results, best first (0 shows all)"))
+	archived := flags.Bool("archived", false, i18n.T("search the archived entries instead"))
+	includeArchived := flags.Bool("include-archived", false, i18n.T("search the archived entries too, marking them in the results"))
+	format := flags.String("template", "", i18n.T("write each entry through this Go template instead of a table, e.g. '{{.Name}}: {{.PhoneNumber}}'"))
+	saved := flags.String("saved", "", i18n.T("only show entries matching this saved search"))
+	last := flags.Bool("last", false, i18n.T("run the previous search again"))
+	recent := flags.Bool("recent", false, i18n.T("list the recent searches"))
+	books := flags.String("books", "", i18n.T("search these books of the config file instead, \"all\" or a comma-separated list"))
+	fields := make(map[string]*string, len(searchFields))
+	for _, field := range searchFields {
+		fields[field] = flags.String(field, "", i18n.T("only show entries whose %s starts with this", field))
+	}
+
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if *last || *recent {
+		if flags.NFlag() > 1 || flags.NArg() > 0 {
+			return usageError("--last and --recent take no other arguments")
 		}
 
-		usersList, appErr := db.GetList()
-		if appErr != nil {
-			fmt.Println(appErr.Message)
-			return
+		if *recent {
+			return printSearchHistory()
 		}
 
-		result, err := db.Serach(usersList, arguments[2])
+		history, err := loadSearchHistory()
 		if err != nil {
-			fmt.Println(err.Message)
-			return
+			return errors.New(i18n.T("cannot read the search history: %v", err))
+		}
+
+		if len(history) == 0 {
+			return errors.New(i18n.T("there is no previous search"))
 		}
 
-		fmt.Println(result)
+		previous := history[len(history)-1].Args
+		fmt.Fprintln(os.Stderr, "search", quoteArgs(previous))
+		return CommandLineHandler(ctx, store, append([]string{arguments[0], "search"}, previous...))
+	}
+
+	expr, err := fieldFilter(fields)
+	if err != nil {
+		return err
+	}
 
-	case "list":
-		usersList, err := db.GetList()
+	if *saved != "" {
+		savedExpr, err := savedFilter(*saved)
 		if err != nil {
-			fmt.Println(err.Message)
-			return
+			return err
 		}
 
-		fmt.Println(usersList)
+		expr = filter.And(expr, savedExpr)
+	}
 
-	case "insert":
-		if err := validateInsert(arguments); err != nil {
-			fmt.Println(err)
-			return
+	view := viewOf(*archived)
+	if *includeArchived {
+		if *archived {
+			return usageError("--archived and --include-archived cannot be used together")
 		}
 
-		id, err := db.Insert(&model.Entry{Name: arguments[2], Surname: arguments[3], PhoneNumber: arguments[4]})
-		if err != nil {
-			fmt.Println(err.Message)
-			return
+		view = allEntries
+	}
+
+	tmpl, err := parseTemplate(*format)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 && expr == nil {
+		return usageError("Please provide a search term")
+	}
+
+	term := strings.Join(flags.Args(), " ")
+	recordSearch(arguments[2:])
+
+	if *books != "" {
+		return searchBooksCommand(ctx, *books, term, view, expr, *limit, tmpl)
+	}
+
+	var results []search.Result
+	var usersList []model.Entry
+	var appErr *model.PhoeBookError
+	if term == "" {
+		usersList, appErr = store.List(ctx)
+		usersList = visible(usersList, view)
+		for _, entry := range usersList {
+			results = append(results, search.Result{Entry: entry})
 		}
+	} else {
+		results, usersList, appErr = searchEntries(ctx, store, term, view)
+	}
 
-		fmt.Printf("successfully inserted with id = %d \n", id)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
 
-	case "delete":
-		if err := validateDelete(arguments); err != nil {
-			fmt.Println(err)
-			return
+	if expr != nil {
+		var matched []search.Result
+		for _, result := range results {
+			if expr.Match(result.Entry) {
+				matched = append(matched, result)
+			}
 		}
 
-		id, err := strconv.Atoi(arguments[2])
-		if err != nil {
-			fmt.Println(err)
-			return
+		if len(matched) == 0 && (term == "" || len(results) > 0) {
+			fmt.Println(i18n.T("there is no record matching the given fields"))
+			return nil
+		}
+
+		results = matched
+	}
+
+	if len(results) == 0 {
+		suggestions := search.Suggest(usersList, term)
+		if len(suggestions) == 0 {
+			fmt.Println(i18n.T("there is no record matching %q", term))
+			return nil
+		}
+
+		quoted := make([]string, len(suggestions))
+		for i, suggestion := range suggestions {
+			quoted[i] = strconv.Quote(suggestion)
+		}
+
+		fmt.Println(i18n.T("No match for %q. Did you mean %s?", term, strings.Join(quoted, i18n.T(" or "))))
+		return nil
+	}
+
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	if tmpl != nil {
+		return output.Template(os.Stdout, search.Entries(results), tmpl)
+	}
+
+	options := output.Options{
+		Blocked: blockedSet(ctx, store),
+		Scores:  make(map[int64]int, len(results)),
+		Matches: make(map[int64][]string, len(results)),
+		Term:    term,
+		Color:   output.ColorEnabled(os.Stdout),
+	}
+
+	for _, result := range results {
+		options.Scores[result.Entry.ID] = result.Score
+		options.Matches[result.Entry.ID] = result.Fields
+	}
+
+	// Without a term every entry shown matched the fields alike.
+	if term == "" {
+		options.Scores, options.Matches = nil, nil
+	}
+
+	output.Table(os.Stdout, search.Entries(results), options)
+
+	return nil
+}
+
+// listCommand handles "list [flags]", which shows the entries of the book,
+// all of them or those of a filter, as a table, grouped or through a
+// template.
+func listCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	country := flags.String("country", "", i18n.T("only list entries from this country (ISO code, e.g. IR)"))
+	groupBy := flags.String("group-by", "", i18n.T("group the entries by company, title or country"))
+	where := flags.String("where", "", i18n.T("only list entries matching a filter, e.g. \"surname=Smith AND company~Acme\""))
+	archived := flags.Bool("archived", false, i18n.T("list the archived entries instead"))
+	format := flags.String("template", "", i18n.T("write each entry through this Go template instead of a table, e.g. '{{.Name}}: {{.PhoneNumber}}'"))
+	saved := flags.String("saved", "", i18n.T("only list entries matching this saved search"))
+	columnList := flags.String("columns", "", i18n.T("only show these columns, in this order, e.g. name,phone,company"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	tmpl, err := parseTemplate(*format)
+	if err != nil {
+		return err
+	}
+
+	if tmpl != nil && *groupBy != "" {
+		return usageError("--template and --group-by cannot be used together")
+	}
+
+	var columns []string
+	if *columnList != "" {
+		if tmpl != nil {
+			return usageError("--template and --columns cannot be used together")
 		}
 
-		appErr := db.Delete(int64(id))
+		if columns, err = output.ParseColumns(*columnList); err != nil {
+			return err
+		}
+	}
+
+	var expr filter.Expr
+	if *where != "" {
+		var parseErr error
+		if expr, parseErr = filter.Parse(*where); parseErr != nil {
+			return parseErr
+		}
+	}
+
+	if *saved != "" {
+		savedExpr, err := savedFilter(*saved)
 		if err != nil {
-			fmt.Println(appErr.Message)
-			return
+			return err
 		}
 
-		fmt.Println("successfully deleted")
+		expr = filter.And(expr, savedExpr)
+	}
 
-	default:
-		fmt.Println("not valid option")
+	usersList, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
 	}
+
+	usersList = visible(usersList, viewOf(*archived))
+
+	if *country != "" {
+		var filtered []model.Entry
+		for _, entry := range usersList {
+			if strings.EqualFold(output.Country(entry), *country) {
+				filtered = append(filtered, entry)
+			}
+		}
+
+		usersList = filtered
+	}
+
+	if expr != nil {
+		usersList = filter.Apply(expr, usersList)
+	}
+
+	if tmpl != nil {
+		return output.Template(os.Stdout, usersList, tmpl)
+	}
+
+	options := output.Options{LastContacted: lastContacted(ctx, store), Columns: columns}
+	if *groupBy == "" {
+		output.Table(os.Stdout, usersList, options)
+		return nil
+	}
+
+	key, ok := groupKeys[*groupBy]
+	if !ok {
+		return errors.New(i18n.T("cannot group by %q, use company, title or country", *groupBy))
+	}
+
+	output.Groups(os.Stdout, usersList, key, options)
+
+	return nil
 }
 
-func validateDelete(arguments []string) error {
-	if len(arguments) != 3 {
-		return fmt.Errorf("not enought arguments for delete")
+// statsCommand handles "stats", which counts the entries of the book by
+// country.
+func statsCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	usersList, err := store.List(ctx)
+	if err != nil {
+		return errors.New(i18n.T(err.Message))
+	}
+
+	countries := make(map[string]int)
+	for _, entry := range usersList {
+		countries[output.Country(entry)]++
 	}
 
+	output.Stats(os.Stdout, len(usersList), countries)
+
 	return nil
 }
 
+// insertCommand handles "insert [flags] <name> <surname> <number>", or
+// "insert -" reading entries from the standard input.
+func insertCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("insert", flag.ContinueOnError)
+	company := flags.String("company", "", i18n.T("company the contact works for"))
+	title := flags.String("title", "", i18n.T("job title of the contact"))
+	nickname := flags.String("nickname", "", i18n.T("what people call the contact, found by search like the name"))
+	birthday := flags.String("birthday", "", i18n.T("birthday of the contact, e.g. 1990-05-17, or 05-17 without the year"))
+	anniversary := flags.String("anniversary", "", i18n.T("anniversary of the contact, written like the birthday"))
+	remindDays := flags.Int("remind-days", 0, i18n.T("remind this many days before the birthday and anniversary (0 uses the config)"))
+	consent := flags.String("consent", "", i18n.T("whether the contact agreed to be contacted, yes or no"))
+	consentDate := flags.String("consent-date", "", i18n.T("day the contact answered about consent, e.g. 2024-05-17 (today with --consent)"))
+	channel := flags.String("channel", "", i18n.T("how the contact prefers to be contacted: %s", strings.Join(model.Channels, ", ")))
+	accessNotes := flags.String("access-notes", "", i18n.T("what to know before contacting the contact, e.g. \"mornings only\""))
+	stdin := flags.Bool("stdin", false, i18n.T("insert the entries read from the standard input instead"))
+	format := flags.String("format", "csv", i18n.T("format of the standard input: %s", strings.Join(inputFormats, ", ")))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if *stdin {
+		if flags.NArg() != 0 {
+			return usageError("insert --stdin takes no arguments")
+		}
+
+		return insertStdin(ctx, store, os.Stdin, *format)
+	}
+
+	entry := model.Entry{Name: flags.Arg(0), Surname: flags.Arg(1), PhoneNumber: model.PhoneNumber(flags.Arg(2)), Nickname: *nickname, Company: *company, Title: *title, Birthday: *birthday, Anniversary: *anniversary, RemindDays: *remindDays, Consent: *consent, ConsentDate: *consentDate, Channel: *channel, AccessNotes: *accessNotes}
+	if entry.Consent != "" && entry.ConsentDate == "" {
+		entry.ConsentDate = time.Now().Format(time.DateOnly)
+	}
+
+	// Without arguments a person at a terminal is asked for the fields.
+	interactive := output.IsTerminal(os.Stdin)
+	w := newWizard(os.Stdin, os.Stdout)
+	if flags.NArg() == 0 && interactive {
+		if err := insertWizard(ctx, store, w, &entry); err != nil {
+			return errors.New(i18n.T(err.Error()))
+		}
+	} else if err := validateInsert(flags.Args()); err != nil {
+		return err
+	}
+
+	if err := checkEntry(entry); err != nil {
+		return err
+	}
+
+	prepareEntry(&entry)
+
+	if !entry.PhoneNumber.Valid() && !warn(ctx, "%q cannot be normalized as a phone number", string(entry.PhoneNumber)) {
+		return nil
+	}
+
+	// At a terminal offerMerge shows a similar contact and offers to
+	// merge with it, otherwise or with --strict warnSimilar warns.
+	if interactive && !strict(ctx) && offerMerge(ctx, store, w, entry) {
+		return nil
+	}
+
+	if (!interactive || strict(ctx)) && !warnSimilar(ctx, store, entry) {
+		return nil
+	}
+
+	id, err := store.Insert(ctx, &entry)
+	if queued(err) {
+		fmt.Println(i18n.T(err.Message))
+		return nil
+	}
+
+	if err != nil {
+		return errors.New(i18n.T(err.Message))
+	}
+
+	fmt.Println(i18n.T("successfully inserted with id = %d", id))
+
+	return nil
+}
+
+// searchFields are the fields search has a flag for.
+var searchFields = []string{"name", "surname", "nickname", "phone", "company", "title", "country"}
+
+// fieldFilter combines the field flags of search that were given, each
+// matching the entries whose field starts with the value, with AND. It
+// returns nil when none was.
+func fieldFilter(fields map[string]*string) (filter.Expr, error) {
+	var exprs []filter.Expr
+	for _, field := range searchFields {
+		if *fields[field] == "" {
+			continue
+		}
+
+		expr, err := filter.Compare(field, "~", *fields[field])
+		if err != nil {
+			return nil, err
+		}
+
+		exprs = append(exprs, expr)
+	}
+
+	return filter.And(exprs...), nil
+}
+
+var groupKeys = map[string]func(model.Entry) string{
+	"company": func(entry model.Entry) string { return entry.Company },
+	"title":   func(entry model.Entry) string { return entry.Title },
+	"country": output.Country,
+}
+
+// validateInsert checks the positional name, surname and phone number
+// arguments left after the insert flags.
 func validateInsert(arguments []string) error {
-	if len(arguments) != 5 {
-		return fmt.Errorf("not enought arguments for insert")
+	if len(arguments) != 3 {
+		return usageError("not enough arguments for insert")
 	}
 
 	return nil
@@ -100,7 +443,110 @@ func validateInsert(arguments []string) error {
 
 func checkArgumentsLength(arguments []string) error {
 	if len(arguments) == 1 {
-		return fmt.Errorf("Please enter required arguments!!")
+		return usageError("Please enter required arguments")
+	}
+
+	return nil
+}
+
+// checkEntry checks the fields of entry that only take some values, see
+// model.Entry.Validate, translating why they are not valid.
+func checkEntry(entry model.Entry) error {
+	var invalid *model.ValidationError
+	if err := entry.Validate(); errors.As(err, &invalid) {
+		return errors.New(i18n.T(invalid.Format, invalid.Args...))
+	}
+
+	return nil
+}
+
+// queued tells whether appErr is a change queued to be made once the
+// backend can be reached again, like offline.QueuedError: it is not a failure.
+func queued(appErr *model.PhoeBookError) bool {
+	return appErr != nil && appErr.StatusCode == http.StatusAccepted
+}
+
+// prepareEntry brings a new entry into its stored form: the number in E.164
+// and the country derived from it.
+func prepareEntry(entry *model.Entry) {
+	entry.PhoneNumber = entry.PhoneNumber.Canonical()
+	entry.Country = entry.PhoneNumber.Country()
+}
+
+// parseTemplate parses the --template of a command, nil when there is none.
+func parseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := output.ParseTemplate(text)
+	if err != nil {
+		return nil, errors.New(i18n.T("invalid template: %v", err))
+	}
+
+	return tmpl, nil
+}
+
+// getCommand handles "get <id> [--template T]", which shows one entry and,
+// in a table below it, the entries related to it.
+func getCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	usage := i18n.T("usage: get <id> [--template T]")
+
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	format := flags.String("template", "", i18n.T("write the entry through this Go template instead of a table, e.g. '{{.Name}}: {{.PhoneNumber}}'"))
+
+	// The id may come before the flags or after them.
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() == 0 {
+		return &UsageError{Message: usage}
+	}
+
+	idArgument := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 {
+		return &UsageError{Message: usage}
+	}
+
+	id, err := strconv.ParseInt(idArgument, 10, 64)
+	if err != nil {
+		return errors.New(i18n.T("invalid id %q", idArgument))
+	}
+
+	tmpl, err := parseTemplate(*format)
+	if err != nil {
+		return err
+	}
+
+	entries, appErr := entriesByID(ctx, store)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	entry, ok := entries[id]
+	if !ok {
+		return errors.New(i18n.T("there is no record with given id"))
+	}
+
+	if tmpl != nil {
+		return output.Template(os.Stdout, []model.Entry{entry}, tmpl)
+	}
+
+	output.Table(os.Stdout, []model.Entry{entry}, output.Options{LastContacted: lastContacted(ctx, store)})
+
+	related, appErr := relations(ctx, store, entry, entries)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	if len(related) > 0 {
+		fmt.Println()
+		output.Relations(os.Stdout, related)
 	}
 
 	return nil
@@ -0,0 +1,169 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage/memory"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage/storagemock"
+)
+
+// run runs the command line arguments, without the program name, against
+// store.
+func run(store storage.Storage, arguments ...string) error {
+	return CommandLineHandler(context.Background(), store, append([]string{"phonebook"}, arguments...))
+}
+
+func TestCommandLineErrors(t *testing.T) {
+	for _, test := range []struct {
+		arguments []string
+		usage     bool
+		message   string
+	}{
+		{arguments: []string{"nosuch"}, message: "not a valid command"},
+		{arguments: []string{"delete"}, usage: true, message: "not enough arguments for delete"},
+		{arguments: []string{"delete", "abc"}, message: `invalid id "abc"`},
+		{arguments: []string{"delete", "42"}, message: "there is no record with given id"},
+		{arguments: []string{"insert", "a", "b"}, usage: true, message: "not enough arguments for insert"},
+		{arguments: []string{"insert", "--consent", "maybe", "Ali", "Ahmadi", "09121234567"}, message: "consent must be yes or no"},
+		{arguments: []string{"search"}, usage: true, message: "Please provide a search term"},
+		{arguments: []string{"list", "--nosuch"}, usage: true},
+	} {
+		store := memory.New(storage.Entry{ID: 1, Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"})
+
+		err := run(store, test.arguments...)
+		if err == nil {
+			t.Errorf("%v: got no error", test.arguments)
+			continue
+		}
+
+		var usage *UsageError
+		if errors.As(err, &usage) != test.usage {
+			t.Errorf("%v: got %T, want a usage error %v", test.arguments, err, test.usage)
+		}
+
+		if err.Error() != test.message {
+			t.Errorf("%v: got %q, want %q", test.arguments, err, test.message)
+		}
+	}
+}
+
+func TestInsertAndDelete(t *testing.T) {
+	store := memory.New()
+
+	if err := run(store, "insert", "--company", "Acme", "John", "Smith", "+1 (415) 555-0100"); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, appErr := store.List(context.Background())
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+
+	if got := entries[0]; got.PhoneNumber != "+14155550100" || got.Country != "US" || got.Company != "Acme" {
+		t.Errorf("got %+v, want the number in E.164 with its country", got)
+	}
+
+	if err := run(store, "delete", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, _ := store.List(context.Background()); len(entries) != 0 {
+		t.Errorf("got %d entries after the delete, want none", len(entries))
+	}
+}
+
+func TestDeleteBackendFailure(t *testing.T) {
+	store := &storagemock.Storage{
+		ListFunc: func(ctx context.Context) ([]storage.Entry, *storage.Error) {
+			return []storage.Entry{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 6}, {ID: 7}, {ID: 8}, {ID: 9}, {ID: 10}}, nil
+		},
+		DeleteFunc: func(ctx context.Context, id int64) *storage.Error {
+			return &storage.Error{Message: "the database is down", StatusCode: http.StatusServiceUnavailable}
+		},
+	}
+
+	err := run(store, "delete", "1", "2")
+	if err == nil || !strings.Contains(err.Error(), "the database is down") || !strings.Contains(err.Error(), "nothing was deleted") {
+		t.Errorf("got %v, want the backend's error and that nothing was deleted", err)
+	}
+
+	if got := store.Called("Delete"); got != 1 {
+		t.Errorf("Delete was called %d times, want 1: the delete stops at the first failure", got)
+	}
+}
+
+func TestDeletePartialFailure(t *testing.T) {
+	store := &storagemock.Storage{
+		ListFunc: func(ctx context.Context) ([]storage.Entry, *storage.Error) {
+			return []storage.Entry{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 6}, {ID: 7}, {ID: 8}, {ID: 9}, {ID: 10}}, nil
+		},
+		DeleteFunc: func(ctx context.Context, id int64) *storage.Error {
+			if id == 2 {
+				return &storage.Error{Message: "the database is down", StatusCode: http.StatusServiceUnavailable}
+			}
+
+			return nil
+		},
+	}
+
+	// Without transactions the first delete stays, and the error says so.
+	err := run(store, "delete", "1", "2")
+	if err == nil || !strings.Contains(err.Error(), "only 1 of the 2 entries were deleted") {
+		t.Errorf("got %v, want that only the first entry was deleted", err)
+	}
+}
+
+func TestArchivePartialFailure(t *testing.T) {
+	store := &struct {
+		storagemock.Storage
+		storagemock.Updater
+	}{
+		Storage: storagemock.Storage{
+			ListFunc: func(ctx context.Context) ([]storage.Entry, *storage.Error) {
+				return []storage.Entry{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 6}, {ID: 7}, {ID: 8}, {ID: 9}, {ID: 10}}, nil
+			},
+		},
+		Updater: storagemock.Updater{
+			UpdateFunc: func(ctx context.Context, entry *storage.Entry) *storage.Error {
+				if entry.ID == 2 {
+					return storage.ConflictError()
+				}
+
+				return nil
+			},
+		},
+	}
+
+	err := run(store, "archive", "1", "2")
+	if err == nil || !strings.Contains(err.Error(), "only 1 of the 2 entries were changed") {
+		t.Errorf("got %v, want that only the first entry was archived", err)
+	}
+}
+
+func TestQueuedChanges(t *testing.T) {
+	queuedErr := &storage.Error{Message: "the phone book cannot be reached, the change is queued and will be made once it can", StatusCode: http.StatusAccepted}
+	store := &storagemock.Storage{
+		InsertFunc: func(ctx context.Context, entry *storage.Entry) (int64, *storage.Error) {
+			return 0, queuedErr
+		},
+		DeleteFunc: func(ctx context.Context, id int64) *storage.Error {
+			return queuedErr
+		},
+	}
+
+	// A queued change will be made, so it is not a failure.
+	for _, arguments := range [][]string{{"insert", "John", "Smith", "+1 (415) 555-0100"}, {"delete", "1"}} {
+		if err := run(store, arguments...); err != nil {
+			t.Errorf("%v: got %v, want no error", arguments, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/diagnostics"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/middleware"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// debugCommand handles "debug stats [--format text|json]". It reports the
+// number of entries, the memory and goroutines of the process, and of the
+// daemon when the request went through one, and the size of the search
+// indexes.
+func debugCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	if len(arguments) < 3 || arguments[2] != "stats" {
+		return usageError("usage: debug stats [--format text|json]")
+	}
+
+	flags := flag.NewFlagSet("debug stats", flag.ContinueOnError)
+	format := flags.String("format", "text", i18n.T("report format, text or json"))
+	if err := flags.Parse(arguments[3:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || (*format != "text" && *format != "json") {
+		return usageError("usage: debug stats [--format text|json]")
+	}
+
+	stats, appErr := diagnostics.Stats(ctx, store)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	if *format == "text" {
+		output.Debug(os.Stdout, stats)
+		return nil
+	}
+
+	jsonResponse, err := json.MarshalIndent(stats, "", " ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(jsonResponse))
+
+	return nil
+}
+
+// ProfilingHandler returns the net/http/pprof routes under /debug/pprof/,
+// behind the same middleware as the API so only holders of a token or a
+// sign-in reach them.
+func ProfilingHandler(extra ...middleware.Middleware) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return middleware.Chain(mux, append([]middleware.Middleware{middleware.Recovery, middleware.Logging}, extra...)...)
+}
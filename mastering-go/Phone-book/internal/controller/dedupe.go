@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/dedupe"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// dedupeCommand handles "dedupe --report [--format json|csv] [--min-score S]
+// [--output file]". It writes the groups of entries that look like
+// duplicates, with a similarity score, for review before anything is merged.
+// The book itself is not changed.
+func dedupeCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("dedupe", flag.ContinueOnError)
+	report := flags.Bool("report", false, i18n.T("only report suspected duplicates, without changing anything"))
+	format := flags.String("format", "json", i18n.T("report format, json or csv"))
+	minScore := flags.Float64("min-score", dedupe.DefaultMinScore, i18n.T("lowest similarity, from 0 to 1, to report as a duplicate"))
+	outputPath := flags.String("output", "", i18n.T("write the report to this file instead of the standard output"))
+	every := flags.Duration("progress", 2*time.Second, i18n.T("how often to log progress when not on a terminal (0 turns progress off)"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if !*report || flags.NArg() != 0 || (*format != "json" && *format != "csv") || *minScore < 0 || *minScore > 1 {
+		return usageError("usage: dedupe --report [--format json|csv] [--min-score S] [--output file]")
+	}
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	// The entries are compared before anything is written, so the bar is
+	// done by the time the report goes to a terminal.
+	progress := output.NewProgress(os.Stderr, len(entries), *every)
+	groups := dedupe.FindProgress(entries, *minScore, func(done int) { progress.Update(done, 0) })
+	progress.Finish()
+
+	var w io.Writer = os.Stdout
+	if *outputPath != "" {
+		file, err := os.OpenFile(*outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+		}
+
+		defer file.Close()
+		w = file
+	}
+
+	if err := dedupe.Write(w, *format, groups); err != nil {
+		return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+	}
+
+	if *outputPath != "" {
+		fmt.Println(i18n.T("found %d groups of suspected duplicates", len(groups)))
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/filter"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// deleteCommand handles "delete <id>..." and "delete --where EXPR
+// [--dry-run]", which delete the entries with the IDs or those matching the
+// filter expression, all together on backends with transactions. Deleting
+// more than the destructive threshold of the book at once needs --force,
+// see checkDestructive.
+func deleteCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("delete", flag.ContinueOnError)
+	where := flags.String("where", "", i18n.T("delete the entries matching a filter, e.g. \"company=Acme\""))
+	dryRun := flags.Bool("dry-run", false, i18n.T("only list the entries that would be deleted"))
+	force := flags.Bool("force", false, i18n.T("delete even more of the book than the destructive threshold allows"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if *where == "" && flags.NArg() == 0 {
+		return usageError("not enough arguments for delete")
+	}
+
+	if *where != "" && flags.NArg() > 0 {
+		return usageError("usage: delete <id>... or delete --where EXPR [--dry-run] [--force]")
+	}
+
+	ids := make([]int64, flags.NArg())
+	for i, argument := range flags.Args() {
+		id, err := strconv.ParseInt(argument, 10, 64)
+		if err != nil {
+			return errors.New(i18n.T("invalid id %q", argument))
+		}
+
+		ids[i] = id
+	}
+
+	// A single entry named by its ID is deleted as it always was, without
+	// listing the book first.
+	if len(ids) == 1 && !*dryRun {
+		appErr := store.Delete(ctx, ids[0])
+		if queued(appErr) {
+			fmt.Println(i18n.T(appErr.Message))
+			return nil
+		}
+
+		if appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		fmt.Println(i18n.T("successfully deleted"))
+		return nil
+	}
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	var targets []model.Entry
+	if *where != "" {
+		expr, err := filter.Parse(*where)
+		if err != nil {
+			return err
+		}
+
+		targets = filter.Apply(expr, entries)
+	} else {
+		byID := make(map[int64]model.Entry, len(entries))
+		for _, entry := range entries {
+			byID[entry.ID] = entry
+		}
+
+		for _, id := range ids {
+			entry, ok := byID[id]
+			if !ok {
+				return errors.New(i18n.T("there is no record with id %d", id))
+			}
+
+			targets = append(targets, entry)
+		}
+	}
+
+	if *dryRun {
+		if len(targets) > 0 {
+			output.Table(os.Stdout, targets, output.Options{})
+		}
+
+		fmt.Println(i18n.T("%d entries would be deleted", len(targets)))
+		if err := checkDestructive("delete", len(targets), len(entries), *force); err != nil {
+			fmt.Println(err)
+		}
+
+		return nil
+	}
+
+	if err := checkDestructive("delete", len(targets), len(entries), *force); err != nil {
+		return err
+	}
+
+	deleted, appErr := deleteEntries(ctx, store, targets)
+	if queued(appErr) {
+		fmt.Println(i18n.T(appErr.Message))
+		return nil
+	}
+
+	if appErr != nil {
+		if deleted > 0 {
+			return errors.Join(errors.New(i18n.T(appErr.Message)), errors.New(i18n.T("only %d of the %d entries were deleted", deleted, len(targets))))
+		}
+
+		return errors.Join(errors.New(i18n.T(appErr.Message)), errors.New(i18n.T("nothing was deleted")))
+	}
+
+	fmt.Println(i18n.T("deleted %d entries", len(targets)))
+
+	return nil
+}
+
+// deleteEntries deletes entries, all together on backends with
+// transactions, and returns how many it deleted: on the others the ones
+// before a failure stay deleted. Deletes that are queued count as made, and
+// when nothing else fails their error is returned.
+func deleteEntries(ctx context.Context, store storage.Storage, entries []model.Entry) (int, *model.PhoeBookError) {
+	deleted := 0
+	var queuedErr *model.PhoeBookError
+	atomic, appErr := storage.BatchOrEach(ctx, store, func(w storage.Writer) *model.PhoeBookError {
+		for _, entry := range entries {
+			appErr := w.Delete(ctx, entry.ID)
+			if queued(appErr) {
+				queuedErr = appErr
+			} else if appErr != nil {
+				return appErr
+			}
+
+			deleted++
+		}
+
+		return nil
+	})
+
+	if appErr != nil && atomic {
+		deleted = 0
+	}
+
+	if appErr == nil {
+		appErr = queuedErr
+	}
+
+	return deleted, appErr
+}
+
+// checkDestructive refuses to verb, "delete", "archive" or "purge", affected
+// of the total entries of the book when that is more than one entry and
+// more than the destructive threshold allows, unless force is set. It is
+// called before anything is changed, so a mistaken filter expression or age
+// matching most of the book changes nothing.
+func checkDestructive(verb string, affected, total int, force bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	if force || !cfg.TooDestructive(affected, total) {
+		return nil
+	}
+
+	return errors.New(i18n.T("this would %s %d of the %d entries, more than the %g%% allowed at once; check them with --dry-run and add --force to go ahead", i18n.T(verb), affected, total, cfg.Threshold()))
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/diff"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// diffCommand handles "diff [--by id|phone] [--format text|json] <file>".
+// It compares the phone book with another one saved as JSON, either an array
+// of entries or the body of GET /list, or as CSV, and reports the entries
+// only one of them has and the fields that changed. Nothing is changed.
+func diffCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	by := flags.String("by", "id", i18n.T("pair entries by id or by phone number"))
+	format := flags.String("format", "text", i18n.T("output format, text or json"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	keys := map[string]diff.Key{"id": diff.ByID, "phone": diff.ByPhone}
+	key, ok := keys[*by]
+	if flags.NArg() != 1 || !ok || (*format != "text" && *format != "json") {
+		return usageError("usage: diff [--by id|phone] [--format text|json] <file>")
+	}
+
+	path := flags.Arg(0)
+	other, err := readBook(path)
+	if err != nil {
+		return errors.New(i18n.T("cannot read %s: %v", path, err))
+	}
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	result := diff.Compare(entries, other, key)
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(result)
+		return nil
+	}
+
+	if result.Empty() {
+		fmt.Println(i18n.T("the phone books hold the same entries"))
+		return nil
+	}
+
+	if len(result.OnlyInA) > 0 {
+		fmt.Println(i18n.T("only in this phone book:"))
+		output.Table(os.Stdout, result.OnlyInA, output.Options{})
+		fmt.Println()
+	}
+
+	if len(result.OnlyInB) > 0 {
+		fmt.Println(i18n.T("only in %s:", path))
+		output.Table(os.Stdout, result.OnlyInB, output.Options{})
+		fmt.Println()
+	}
+
+	if len(result.Changed) > 0 {
+		fmt.Println(i18n.T("changed:"))
+		for _, change := range result.Changed {
+			var fields []string
+			for _, field := range change.Fields {
+				fields = append(fields, fmt.Sprintf("%s %q -> %q", field.Field, field.A, field.B))
+			}
+
+			fmt.Printf("  %s: %s\n", change.Key, strings.Join(fields, ", "))
+		}
+	}
+
+	return nil
+}
+
+// readBook reads the entries of a phone book saved in a file: JSON when the
+// name ends in .json, CSV otherwise.
+func readBook(path string) ([]model.Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	format := "csv"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+	}
+
+	entries, _, err := readEntries(file, format)
+
+	return entries, err
+}
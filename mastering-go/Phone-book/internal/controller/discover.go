@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/mdns"
+)
+
+// DiscoverCommand handles "discover [--timeout 2s]", which lists the phone
+// book servers advertising themselves on the local network with -mdns, with
+// the URL each answers on. It needs no phone book, so it is run before one
+// is opened.
+func DiscoverCommand(ctx context.Context, arguments []string) error {
+	flags := flag.NewFlagSet("discover", flag.ContinueOnError)
+	wait := flags.Duration("timeout", 2*time.Second, i18n.T("how long to wait for servers to answer"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || *wait <= 0 {
+		return usageError("usage: discover [--timeout 2s]")
+	}
+
+	instances, err := mdns.Browse(ctx, *wait)
+	if err != nil {
+		return errors.New(i18n.T("cannot search the local network: %v", err))
+	}
+
+	if len(instances) == 0 {
+		fmt.Println(i18n.T("no phone book server answered"))
+		return nil
+	}
+
+	for _, instance := range instances {
+		fmt.Printf("%s\t%s\t%s\n", instance.Name, instanceURL(instance), strings.TrimSuffix(instance.Host, "."))
+	}
+
+	return nil
+}
+
+// instanceURL returns the URL of instance at its first address, https when
+// its TXT record says so.
+func instanceURL(instance mdns.Instance) string {
+	scheme := "http"
+	for _, text := range instance.Text {
+		if value, ok := strings.CutPrefix(text, "scheme="); ok {
+			scheme = value
+		}
+	}
+
+	host := strings.TrimSuffix(instance.Host, ".")
+	if len(instance.Addrs) > 0 {
+		host = instance.Addrs[0].String()
+	}
+
+	return scheme + "://" + net.JoinHostPort(host, strconv.Itoa(instance.Port))
+}
@@ -0,0 +1,340 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/bundle"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// dumpCommand handles "dump [--output book.tar.gz]", which writes everything
+// the book keeps as a bundle: the entries with their photos, the call log,
+// the blocklist, the spam reports and, on backends with a history, its
+// events. load reads it back into any backend.
+func dumpCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	outputPath := flags.String("output", "", i18n.T("write to this file instead of the standard output"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || (*outputPath == "" && output.IsTerminal(os.Stdout)) {
+		return usageError("usage: dump [--output book.tar.gz], or redirect the standard output to a file")
+	}
+
+	b, appErr := gatherBundle(ctx, store)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	var w io.Writer = os.Stdout
+	name := "stdout"
+	if *outputPath != "" {
+		file, err := os.OpenFile(*outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+		}
+
+		defer file.Close()
+		w, name = file, *outputPath
+	}
+
+	if err := bundle.Write(w, b); err != nil {
+		return errors.New(i18n.T("cannot write %s: %v", name, err))
+	}
+
+	fmt.Fprintln(os.Stderr, i18n.T("dumped %d entries, %d photos, %d calls, %d blocked numbers, %d spam reports and %d links", len(b.Entries), len(b.Photos), len(b.Calls), len(b.Blocked), len(b.SpamReports), len(b.Links)))
+	if len(b.Events) > 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("dumped the history of %d events", len(b.Events)))
+	}
+
+	return nil
+}
+
+// gatherBundle collects the state of store, leaving out what its backend
+// doesn't keep.
+func gatherBundle(ctx context.Context, store storage.Storage) (*bundle.Bundle, *model.PhoeBookError) {
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	b := &bundle.Bundle{Entries: entries, Photos: make(map[int64][]byte)}
+
+	if photos, ok := store.(storage.Photos); ok {
+		for _, entry := range entries {
+			if entry.Photo == "" {
+				continue
+			}
+
+			photo, appErr := photos.Photo(ctx, entry.ID)
+			if appErr != nil && appErr.StatusCode != http.StatusNotFound {
+				return nil, appErr
+			}
+
+			if photo != nil {
+				b.Photos[entry.ID] = photo
+			}
+		}
+	}
+
+	if callLog, ok := store.(storage.CallLog); ok {
+		if b.Calls, appErr = callLog.Calls(ctx, 0); appErr != nil {
+			return nil, appErr
+		}
+	}
+
+	if blocklist, ok := store.(storage.Blocklist); ok {
+		if b.Blocked, appErr = blocklist.Blocked(ctx); appErr != nil {
+			return nil, appErr
+		}
+	}
+
+	if spamReports, ok := store.(storage.SpamReports); ok {
+		if b.SpamReports, appErr = spamReports.SpamReports(ctx, ""); appErr != nil {
+			return nil, appErr
+		}
+	}
+
+	if relationships, ok := store.(storage.Relationships); ok {
+		if b.Links, appErr = relationships.Links(ctx, 0); appErr != nil {
+			return nil, appErr
+		}
+	}
+
+	if history, ok := store.(storage.History); ok {
+		events, appErr := history.Events(ctx, 0)
+		if appErr != nil && appErr.StatusCode != http.StatusNotImplemented {
+			return nil, appErr
+		}
+
+		b.Events = events
+	}
+
+	return b, nil
+}
+
+// loadCommand handles "load <book.tar.gz>", "-" reading the standard input.
+// It fills an empty book with a bundle written by dump. Entries get new IDs
+// from the backend, and their calls, photos and links follow them. Backends
+// that can load a history get the events of the bundle instead, which bring
+// the entries back with their IDs and the blocklist.
+func loadCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	if len(arguments) != 3 {
+		return usageError("usage: load <book.tar.gz>, - for the standard input")
+	}
+
+	var r io.Reader = os.Stdin
+	if path := arguments[2]; path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return errors.New(i18n.T("cannot read %s: %v", path, err))
+		}
+
+		defer file.Close()
+		r = file
+	}
+
+	b, err := bundle.Read(r)
+	if err != nil {
+		return errors.New(i18n.T(err.Error()))
+	}
+
+	existing, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	if len(existing) > 0 {
+		return errors.New(i18n.T("the book already has %d entries, load only fills an empty one", len(existing)))
+	}
+
+	if loader, ok := store.(storage.HistoryLoader); ok && len(b.Events) > 0 {
+		ids, appErr := loadHistory(ctx, loader, store, b.Events)
+		if appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		fmt.Println(i18n.T("loaded %d entries with their history of %d events", len(ids), len(b.Events)))
+
+		if appErr := loadRest(ctx, store, b, ids, true); appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		return nil
+	}
+
+	ids, appErr := loadEntries(ctx, store, b.Entries)
+	if appErr != nil {
+		if len(ids) > 0 {
+			return errors.Join(errors.New(i18n.T(appErr.Message)), errors.New(i18n.T("only %d of the %d entries were loaded", len(ids), len(b.Entries))))
+		}
+
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	fmt.Println(i18n.T("loaded %d entries", len(ids)))
+
+	if appErr := loadRest(ctx, store, b, ids, false); appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	return nil
+}
+
+// loadHistory records events in the history of store, and returns the IDs
+// of the entries they brought back by themselves, as the entries keep their
+// IDs.
+func loadHistory(ctx context.Context, loader storage.HistoryLoader, store storage.Storage, events []model.Event) (map[int64]int64, *model.PhoeBookError) {
+	if appErr := loader.LoadHistory(ctx, events); appErr != nil {
+		return nil, appErr
+	}
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	ids := make(map[int64]int64, len(entries))
+	for _, entry := range entries {
+		ids[entry.ID] = entry.ID
+	}
+
+	return ids, nil
+}
+
+// loadEntries inserts entries, in one transaction when the backend has
+// them, and returns the new ID of each by its ID in the bundle: on the
+// others, those of the ones inserted before a failure.
+func loadEntries(ctx context.Context, store storage.Storage, entries []model.Entry) (map[int64]int64, *model.PhoeBookError) {
+	var ids map[int64]int64
+	atomic, appErr := storage.BatchOrEach(ctx, store, func(w storage.Writer) *model.PhoeBookError {
+		ids = make(map[int64]int64, len(entries))
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				return storage.ContextError(ctx)
+			}
+
+			// The photo is set after the insert, which records it.
+			oldID := entry.ID
+			entry.ID, entry.Photo = 0, ""
+			id, appErr := w.Insert(ctx, &entry)
+			if appErr != nil {
+				return appErr
+			}
+
+			ids[oldID] = id
+		}
+
+		return nil
+	})
+
+	if appErr != nil && atomic {
+		ids = nil
+	}
+
+	return ids, appErr
+}
+
+// loadRest loads the photos, calls, blocklist, spam reports and links of
+// b, the entries being loaded with ids. With history the events of b were
+// loaded, and the blocklist with them. What the backend doesn't keep is
+// reported and left out.
+func loadRest(ctx context.Context, store storage.Storage, b *bundle.Bundle, ids map[int64]int64, history bool) *model.PhoeBookError {
+	skipped := func(n int, what, feature string) {
+		if n > 0 {
+			fmt.Println(i18n.T("%d %s not loaded: %s", n, i18n.T(what), i18n.T(storage.Unsupported(feature).Message)))
+		}
+	}
+
+	var photosLoaded, callsLoaded, blockedLoaded, reportsLoaded, linksLoaded int
+	if photos, ok := store.(storage.Photos); ok {
+		for oldID, photo := range b.Photos {
+			if id, ok := ids[oldID]; ok {
+				if appErr := photos.SetPhoto(ctx, id, photo); appErr != nil {
+					return appErr
+				}
+
+				photosLoaded++
+			}
+		}
+	} else {
+		skipped(len(b.Photos), "photos", "photos")
+	}
+
+	if callLog, ok := store.(storage.CallLog); ok {
+		for _, call := range b.Calls {
+			if id, ok := ids[call.EntryID]; ok {
+				call.EntryID = id
+				if appErr := callLog.LogCall(ctx, call); appErr != nil {
+					return appErr
+				}
+
+				callsLoaded++
+			}
+		}
+	} else {
+		skipped(len(b.Calls), "calls", "call logs")
+	}
+
+	if !history {
+		skipped(len(b.Events), "events", "loading a history")
+	}
+
+	if history {
+		blockedLoaded = len(b.Blocked)
+	} else if blocklist, ok := store.(storage.Blocklist); ok {
+		for _, number := range b.Blocked {
+			if appErr := blocklist.Block(ctx, number); appErr != nil {
+				return appErr
+			}
+
+			blockedLoaded++
+		}
+	} else {
+		skipped(len(b.Blocked), "blocked numbers", "blocking numbers")
+	}
+
+	if spamReports, ok := store.(storage.SpamReports); ok {
+		for _, report := range b.SpamReports {
+			if appErr := spamReports.ReportSpam(ctx, report); appErr != nil {
+				return appErr
+			}
+
+			reportsLoaded++
+		}
+	} else {
+		skipped(len(b.SpamReports), "spam reports", "spam reports")
+	}
+
+	if relationships, ok := store.(storage.Relationships); ok {
+		for _, link := range b.Links {
+			from, fromOK := ids[link.From]
+			to, toOK := ids[link.To]
+			if !fromOK || !toOK {
+				continue
+			}
+
+			if appErr := relationships.Link(ctx, model.Link{From: from, To: to, Type: link.Type}); appErr != nil {
+				return appErr
+			}
+
+			linksLoaded++
+		}
+	} else {
+		skipped(len(b.Links), "links", "links")
+	}
+
+	fmt.Println(i18n.T("loaded %d photos, %d calls, %d blocked numbers, %d spam reports and %d links", photosLoaded, callsLoaded, blockedLoaded, reportsLoaded, linksLoaded))
+
+	return nil
+}
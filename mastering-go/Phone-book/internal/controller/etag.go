@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// entryETag is the entity tag of one entry. It changes with the entry's
+// version, so If-Match on it maps onto the versioned update and delete.
+func entryETag(entry model.Entry) string {
+	return fmt.Sprintf(`"%d.%d"`, entry.ID, entry.Version)
+}
+
+// bodyETag is the entity tag of a response that is not a single entry, like
+// a list: a hash of the body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// writeTagged writes a JSON body with its entity tag, or only 304 Not
+// Modified when the client's If-None-Match says it already has it.
+func writeTagged(w http.ResponseWriter, r *http.Request, etag string, body []byte) {
+	w.Header().Set("ETag", etag)
+
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" {
+		for _, tag := range etagList(noneMatch) {
+			// If-None-Match compares weakly, W/ prefixes don't matter.
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(body))
+}
+
+// ifMatchVersion turns the If-Match header of a request changing entry id
+// into the version the change must find, 0 when there is no header or it is
+// "*". ok is false when no tag can match, which answers 412.
+func ifMatchVersion(ctx context.Context, store storage.Storage, r *http.Request, id int64) (version int64, ok bool, appErr *model.PhoeBookError) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return 0, true, nil
+	}
+
+	var versions []int64
+	for _, tag := range etagList(header) {
+		if tag == "*" {
+			return 0, true, nil
+		}
+
+		// If-Match compares strongly, weak tags never match.
+		idPart, versionPart, found := strings.Cut(strings.Trim(tag, `"`), ".")
+		if strings.HasPrefix(tag, "W/") || !found || idPart != strconv.FormatInt(id, 10) {
+			continue
+		}
+
+		if v, err := strconv.ParseInt(versionPart, 10, 64); err == nil && v > 0 {
+			versions = append(versions, v)
+		}
+	}
+
+	switch len(versions) {
+	case 0:
+		return 0, false, nil
+	case 1:
+		return versions[0], true, nil
+	}
+
+	// Several tags: the change has to find whichever of them is current.
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return 0, false, appErr
+	}
+
+	for _, entry := range entries {
+		if entry.ID != id {
+			continue
+		}
+
+		for _, v := range versions {
+			if v == entry.Version {
+				return v, true, nil
+			}
+		}
+	}
+
+	return 0, false, nil
+}
+
+func etagList(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// preconditionFailed answers a change whose If-Match did not hold.
+func preconditionFailed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusPreconditionFailed)
+	fmt.Fprint(w, "the entry does not match If-Match, reload it and try again")
+}
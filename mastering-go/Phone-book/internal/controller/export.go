@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/anonymize"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/csvfile"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/filter"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// exportCommand handles "export [--format csv|json] [--anonymize [--seed S]]
+// [--saved NAME] [--columns LIST] [--output file]". It writes every entry,
+// or those matching the saved search, as CSV in the layout of a data file,
+// or as a JSON array, both of which import and diff read back. --columns
+// only writes the fields listed, CSV then starting with a header row naming
+// them.
+//
+// --anonymize replaces names, companies and most of each phone number with
+// made-up values derived from the seed, so the export can be shared as test
+// data. Without --seed a random one is used and printed, to get the same
+// fake values again later.
+func exportCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "csv", i18n.T("output format, csv or json"))
+	anonymized := flags.Bool("anonymize", false, i18n.T("replace names and phone numbers with made-up ones"))
+	seed := flags.String("seed", "", i18n.T("seed for --anonymize, the same seed gives the same made-up values"))
+	outputPath := flags.String("output", "", i18n.T("write to this file instead of the standard output"))
+	saved := flags.String("saved", "", i18n.T("only export the entries matching this saved search"))
+	columnList := flags.String("columns", "", i18n.T("only export these fields, in this order, e.g. name,phone,company"))
+	every := flags.Duration("progress", 2*time.Second, i18n.T("how often to log progress when not on a terminal (0 turns progress off)"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || (*format != "csv" && *format != "json") || (*seed != "" && !*anonymized) {
+		return usageError("usage: export [--format csv|json] [--anonymize [--seed S]] [--saved NAME] [--columns LIST] [--output file]")
+	}
+
+	var columns []string
+	if *columnList != "" {
+		var err error
+		if columns, err = output.ParseColumns(*columnList); err != nil {
+			return err
+		}
+	}
+
+	var expr filter.Expr
+	if *saved != "" {
+		var err error
+		if expr, err = savedFilter(*saved); err != nil {
+			return err
+		}
+	}
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	if expr != nil {
+		entries = filter.Apply(expr, entries)
+	}
+
+	if *anonymized {
+		if *seed == "" {
+			random := make([]byte, 16)
+			rand.Read(random)
+			*seed = hex.EncodeToString(random)
+			fmt.Fprintln(os.Stderr, i18n.T("anonymized with seed %s", *seed))
+		}
+
+		anonymizer := anonymize.New(*seed)
+		for i := range entries {
+			entries[i] = anonymizer.Entry(entries[i])
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputPath != "" {
+		file, err := os.OpenFile(*outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+		}
+
+		defer file.Close()
+		w = file
+	} else if output.IsTerminal(os.Stdout) {
+		// The bar would be drawn over the entries.
+		*every = 0
+	}
+
+	progress := output.NewProgress(os.Stderr, len(entries), *every)
+	err := writeEntries(w, *format, entries, columns, func(done int) { progress.Update(done, 0) })
+	progress.Finish()
+	if err != nil {
+		return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+	}
+
+	if *outputPath != "" {
+		fmt.Println(i18n.T("exported %d entries to %s", len(entries), *outputPath))
+	}
+
+	return nil
+}
+
+// exportBatch is how many entries of a data file writeEntries writes at a
+// time.
+const exportBatch = 1000
+
+// writeEntries writes entries in format, only the fields in columns when
+// there are any. It calls progress, unless it is nil, with the number of
+// entries written so far as it goes.
+func writeEntries(w io.Writer, format string, entries []model.Entry, columns []string, progress func(done int)) error {
+	switch {
+	case columns != nil && format == "csv":
+		return output.CSV(w, entries, columns, progress)
+	case columns != nil:
+		return output.JSON(w, entries, columns, progress)
+	case format == "csv":
+		// Data files have no header row, so the batches add up to one.
+		for start := 0; start < len(entries); start += exportBatch {
+			end := min(start+exportBatch, len(entries))
+			if err := csvfile.Write(w, entries[start:end]); err != nil {
+				return err
+			}
+
+			if progress != nil {
+				progress(end)
+			}
+		}
+
+		return nil
+	}
+
+	if len(entries) == 0 {
+		_, err := io.WriteString(w, "[]\n")
+		return err
+	}
+
+	// The entries are encoded one by one, the way a json.Encoder indenting
+	// by two spaces writes the array.
+	buffered := bufio.NewWriter(w)
+	buffered.WriteString("[\n")
+	for i, entry := range entries {
+		data, err := json.MarshalIndent(entry, "  ", "  ")
+		if err != nil {
+			return err
+		}
+
+		if i > 0 {
+			buffered.WriteString(",\n")
+		}
+
+		buffered.WriteString("  ")
+		buffered.Write(data)
+		if progress != nil {
+			progress(i + 1)
+		}
+	}
+
+	buffered.WriteString("\n]\n")
+
+	return buffered.Flush()
+}
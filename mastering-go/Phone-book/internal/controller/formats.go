@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/abbu"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/backupxml"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/csvfile"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/vcard"
+)
+
+// inputFormats are the formats readEntries reads.
+var inputFormats = []string{"csv", "json", "xml", "vcard", "plain"}
+
+// formatOf is the format of the file at path by its extension, CSV unless
+// it says otherwise. A macOS Contacts archive, a .abbu directory or a zip
+// of one, is "abbu".
+func formatOf(path string) string {
+	lower := strings.ToLower(strings.TrimSuffix(path, string(filepath.Separator)))
+	if strings.HasSuffix(lower, ".abbu") || strings.HasSuffix(lower, ".abbu.zip") {
+		return "abbu"
+	}
+
+	switch filepath.Ext(lower) {
+	case ".json":
+		return "json"
+	case ".xml":
+		return "xml"
+	case ".vcf", ".vcard":
+		return "vcard"
+	case ".txt":
+		return "plain"
+	}
+
+	return "csv"
+}
+
+// readFile reads the entries of the file at path in its format, see
+// formatOf and readEntries.
+func readFile(path string) ([]model.Entry, []string, error) {
+	format := formatOf(path)
+	if format == "abbu" {
+		entries, err := abbu.Open(path)
+		return entries, nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer file.Close()
+
+	return readEntries(file, format)
+}
+
+// readEntries reads the entries of r in format: "csv" as csvfile.Read
+// understands it, "json" as an array of entries or a list response, "xml"
+// as an Android contacts backup, "vcard" as phones and Contacts.app export
+// contacts, or "plain" with one "Name Surname number"
+// per line. It also returns the names of the columns of a CSV header row
+// that hold no field of an entry and are left out.
+func readEntries(r io.Reader, format string) ([]model.Entry, []string, error) {
+	var entries []model.Entry
+	var err error
+
+	switch format {
+	case "csv":
+		return csvfile.ReadUnknown(r)
+
+	case "xml":
+		entries, err = backupxml.Read(r)
+
+	case "vcard":
+		entries, err = vcard.Read(r)
+
+	case "plain":
+		entries, err = readPlain(r)
+
+	case "json":
+		var raw json.RawMessage
+		if err := json.NewDecoder(r).Decode(&raw); err != nil {
+			return nil, nil, err
+		}
+
+		if err := json.Unmarshal(raw, &entries); err == nil {
+			return entries, nil, nil
+		}
+
+		var list model.ListResponse
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, nil, err
+		}
+
+		return list.Entries, nil, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown format %q, use %s", format, strings.Join(inputFormats, ", "))
+	}
+
+	return entries, nil, err
+}
+
+// readPlain reads lines like "John Smith 555-0100": the number starts at
+// the first word beginning with a digit, "+" or "(", the last word before it
+// is the surname and the rest the name. Empty lines and lines starting with
+// "#" are skipped.
+func readPlain(r io.Reader) ([]model.Entry, error) {
+	var entries []model.Entry
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		words := strings.Fields(line)
+		at := len(words)
+		for i, word := range words {
+			if c := word[0]; c >= '0' && c <= '9' || c == '+' || c == '(' {
+				at = i
+				break
+			}
+		}
+
+		entry := model.Entry{PhoneNumber: model.PhoneNumber(strings.Join(words[at:], " "))}
+		switch names := words[:at]; len(names) {
+		case 0:
+		case 1:
+			entry.Name = names[0]
+		default:
+			entry.Name, entry.Surname = strings.Join(names[:len(names)-1], " "), names[len(names)-1]
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+)
+
+// FuzzReadJSONEntries checks that decoding and checking the JSON entries of
+// an import or a request body returns an error or entries, and never
+// panics.
+func FuzzReadJSONEntries(f *testing.F) {
+	for _, seed := range []string{
+		`[{"name":"John","surname":"Smith","phone_number":"+14155550100"}]`,
+		`{"entries":[{"id":3,"name":"Sara","birthday":"--05-01","remind_days":-1}]}`,
+		`[{"consent":"maybe","consent_date":"yesterday","preferred_channel":"fax"}]`,
+		`[{"phone_number":"00000000000000000000000000000000"}]`,
+		`{"entries":null}`,
+		`[`,
+		``,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		entries, _, err := readEntries(strings.NewReader(data), "json")
+		if err != nil {
+			if entries != nil {
+				t.Fatalf("readEntries returned entries with the error %v", err)
+			}
+
+			return
+		}
+
+		for i := range entries {
+			prepareEntry(&entries[i])
+			checkEntry(entries[i])
+			entries[i].PhoneNumber.Valid()
+			entries[i].PhoneNumber.Format()
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/generate"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// generateBatch is how many made-up entries are inserted per transaction.
+const generateBatch = 1000
+
+// generateCommand handles "generate [--n N] [--seed S] [--locale en|fa|de]",
+// which adds N made-up entries to the book, for benchmarks and demos. The
+// same seed gives the same entries; without one a random seed is used and
+// printed, to make the book again.
+func generateCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	n := flags.Int("n", 100, i18n.T("how many entries to make up"))
+	seed := flags.Uint64("seed", 0, i18n.T("seed of the made-up entries, the same seed gives the same entries"))
+	locale := flags.String("locale", "en", i18n.T("language of the names and country of the numbers: %s", strings.Join(generate.LocaleNames(), ", ")))
+	every := flags.Duration("progress", 2*time.Second, i18n.T("how often to log progress when not on a terminal (0 turns progress off)"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || *n < 1 {
+		return usageError("usage: generate [--n N] [--seed S] [--locale %s]", strings.Join(generate.LocaleNames(), "|"))
+	}
+
+	seeded := false
+	flags.Visit(func(f *flag.Flag) {
+		seeded = seeded || f.Name == "seed"
+	})
+
+	if !seeded {
+		var random [8]byte
+		rand.Read(random[:])
+		*seed = binary.BigEndian.Uint64(random[:])
+	}
+
+	generator, err := generate.New(*locale, *seed)
+	if err != nil {
+		return err
+	}
+
+	if !seeded {
+		fmt.Fprintln(os.Stderr, i18n.T("generated with seed %d", *seed))
+	}
+
+	file := &importFile{path: "generate"}
+	items := make([]importItem, *n)
+	for i := range items {
+		entry := generator.Entry()
+		prepareEntry(&entry)
+		items[i] = importItem{file: file, entry: &entry}
+	}
+
+	progress := output.NewProgress(os.Stderr, len(items), *every)
+	advanced := make(chan struct{})
+	done := make(chan *model.PhoeBookError)
+	go func() {
+		defer close(done)
+		for start := 0; start < len(items); start += generateBatch {
+			inserted, appErr := insertBatch(ctx, store, items[start:min(start+generateBatch, len(items))], advanced)
+			file.inserted = start + inserted
+			if appErr != nil {
+				done <- appErr
+				return
+			}
+		}
+	}()
+
+	processed := 0
+	var failed *model.PhoeBookError
+	for {
+		select {
+		case <-advanced:
+			processed++
+			progress.Update(processed, 0)
+			continue
+
+		case failed = <-done:
+			progress.Finish()
+		}
+
+		break
+	}
+
+	fmt.Println(i18n.T("generated %d entries", file.inserted))
+	if failed != nil {
+		return errors.New(i18n.T(failed.Message))
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/middleware"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// readinessTimeout bounds the checks of GET /readyz, so a hanging database
+// makes the server not ready instead of hanging the probe.
+const readinessTimeout = 5 * time.Second
+
+// HealthHandler returns the probes of the server, GET /livez and GET
+// /readyz, for orchestrators. They need no token or sign-in and are not
+// logged. books are the phone books served by name, the single one under "".
+func HealthHandler(books map[string]storage.Storage) http.Handler {
+	h := &healthHandlers{books: books}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /livez", http.HandlerFunc(h.livenessHandler))
+	mux.Handle("GET /readyz", http.HandlerFunc(h.readinessHandler))
+
+	return middleware.Chain(mux, middleware.Recovery)
+}
+
+type healthHandlers struct {
+	books map[string]storage.Storage
+}
+
+// livenessHandler
+// @Summary      Liveness probe
+// @Description  Answer as long as the server process runs, without checking anything it depends on
+// @Tags         health
+// @Produce      plain
+// @Success      200  {string}  string  "ok"
+// @Router       /livez [get]
+func (h *healthHandlers) livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "ok")
+}
+
+// readinessHandler
+// @Summary      Readiness probe
+// @Description  Check the storage of every phone book, e.g. the database connection or whether the data files can be written, and report each check
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  phonebook.Readiness
+// @Failure      503  {object}  phonebook.Readiness
+// @Router       /readyz [get]
+func (h *healthHandlers) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(h.books))
+	for name := range h.books {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	readiness := model.Readiness{Status: "ready", Checks: []model.HealthCheck{}}
+	for _, name := range names {
+		for _, check := range storage.Health(ctx, h.books[name]) {
+			check.Book = name
+			if check.Status == model.HealthFailing {
+				readiness.Status = "not ready"
+			}
+
+			readiness.Checks = append(readiness.Checks, check)
+		}
+	}
+
+	jsonResponse, err := json.MarshalIndent(readiness, "", " ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if readiness.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	fmt.Fprint(w, string(jsonResponse))
+}
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// historyCommand handles "history [<id>]": it lists the changes made to the
+// book, or to the entry with id, oldest first, on backends that keep them.
+func historyCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	history, ok := store.(storage.History)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("history").Message))
+	}
+
+	var id int64
+	if len(arguments) > 3 {
+		return usageError("usage: history [<id>]")
+	}
+
+	if len(arguments) == 3 {
+		var err error
+		if id, err = strconv.ParseInt(arguments[2], 10, 64); err != nil || id <= 0 {
+			return usageError("usage: history [<id>]")
+		}
+	}
+
+	events, appErr := history.Events(ctx, id)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	for _, event := range events {
+		fmt.Printf("%d\t%s\t%s\n", event.Seq, event.At.Local().Format(time.DateTime), describeEvent(event))
+	}
+
+	return nil
+}
+
+// undoCommand handles "undo --to <seq> [--dry-run]": it takes the book back
+// to how it was right after the event seq of its history by recording the
+// changes that undo the later ones, so the undo can be undone too.
+// --dry-run lists those changes instead.
+func undoCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	history, ok := store.(storage.History)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("history").Message))
+	}
+
+	flags := flag.NewFlagSet("undo", flag.ContinueOnError)
+	to := flags.Int64("to", -1, i18n.T("the event of the history to go back to, 0 for the empty book"))
+	dryRun := flags.Bool("dry-run", false, i18n.T("only list what would be undone"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || *to < 0 {
+		return usageError("usage: undo --to <event> [--dry-run]")
+	}
+
+	events, appErr := history.Revert(ctx, *to, *dryRun)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	for _, event := range events {
+		fmt.Println(describeEvent(event))
+	}
+
+	switch {
+	case len(events) == 0:
+		fmt.Println(i18n.T("the book is already as it was after event %d", *to))
+	case *dryRun:
+		fmt.Println(i18n.T("%d changes would take the book back to how it was after event %d", len(events), *to))
+	default:
+		fmt.Println(i18n.T("made %d changes to take the book back to how it was after event %d", len(events), *to))
+	}
+
+	return nil
+}
+
+// describeEvent describes event for history and undo.
+func describeEvent(event model.Event) string {
+	var description string
+	switch event.Type {
+	case model.EventInsert:
+		description = fmt.Sprintf("insert %d %s %s, %s", event.ID, event.Entry.Name, event.Entry.Surname, event.Entry.PhoneNumber)
+	case model.EventUpdate:
+		description = fmt.Sprintf("update %d to %s %s, %s", event.ID, event.Entry.Name, event.Entry.Surname, event.Entry.PhoneNumber)
+	case model.EventDelete:
+		description = fmt.Sprintf("delete %d", event.ID)
+	case model.EventBlock, model.EventUnblock:
+		description = event.Type + " " + event.Number
+	case model.EventErased:
+		description = "erased"
+		if event.ID != 0 {
+			description += fmt.Sprintf(" %d", event.ID)
+		}
+	default:
+		description = event.Type
+	}
+
+	if event.Reverts != nil {
+		description += fmt.Sprintf(" (undo to %d)", *event.Reverts)
+	}
+
+	return description
+}
@@ -0,0 +1,349 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// importCommand handles "import [--batch-size N] [--delay D] [--workers N]
+// [--dry-run] <file>...": it adds the entries of CSV files, with or without a
+// header row and separated by commas, semicolons or tabs, as new entries.
+// Files ending in .json, .xml, .vcf or .txt are read as JSON, Android
+// contacts backups, vCards or plain lines instead, see readEntries, and
+// macOS Contacts archives (.abbu, or .abbu.zip) with the abbu package. Entries without a phone
+// number are skipped, and so are those with invalid fields, with a warning. --dry-run lists the entries instead of adding them.
+//
+// On backends with transactions the files are imported all together or, if
+// anything fails or the import is interrupted, not at all. With --batch-size
+// every batch is a transaction of its own instead: a failed batch is reported
+// and the import goes on with the next one, pausing --delay in between to
+// spare a remote database.
+//
+// Rows go through a pipeline: the files are parsed, then --workers goroutines
+// validate and normalize the rows and insert the batches, several at a time.
+// Failures are still reported in the order of the rows.
+func importCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	batchSize := flags.Int("batch-size", 0, i18n.T("commit every this many entries instead of all at once (0 imports everything in one go)"))
+	delay := flags.Duration("delay", 0, i18n.T("pause between batches, e.g. 200ms"))
+	every := flags.Duration("progress", 2*time.Second, i18n.T("how often to log progress when not on a terminal (0 turns progress off)"))
+	workers := flags.Int("workers", 1, i18n.T("how many rows or batches to work on at the same time"))
+	dryRun := flags.Bool("dry-run", false, i18n.T("only list what would be imported"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() == 0 || *batchSize < 0 || *workers < 1 {
+		return usageError("usage: import [--batch-size N] [--delay D] [--workers N] [--dry-run] <file>...")
+	}
+
+	var files []*importFile
+	for _, path := range flags.Args() {
+		rows, unknown, err := readFile(path)
+		if err != nil {
+			return errors.New(i18n.T("cannot import %s: %v", path, err))
+		}
+
+		files = append(files, &importFile{path: path, rows: rows, unknown: unknown})
+	}
+
+	items := normalizeRows(files, *workers)
+	if !warnImport(ctx, files, items) {
+		return errors.New(i18n.T("nothing was imported"))
+	}
+
+	if *dryRun {
+		previewImport(files, items)
+		return nil
+	}
+
+	return runImport(ctx, store, files, items, *batchSize, *delay, *every, *workers)
+}
+
+// insertStdin handles "insert --stdin [--format F]": it inserts the entries
+// read from in, all together on backends with transactions, like an import
+// of a file would.
+func insertStdin(ctx context.Context, store storage.Storage, in io.Reader, format string) error {
+	rows, unknown, err := readEntries(in, format)
+	if err != nil {
+		return errors.New(i18n.T("cannot read the standard input: %v", err))
+	}
+
+	files := []*importFile{{path: "stdin", rows: rows, unknown: unknown}}
+	items := normalizeRows(files, 1)
+	if !warnImport(ctx, files, items) {
+		return errors.New(i18n.T("nothing was imported"))
+	}
+
+	return runImport(ctx, store, files, items, 0, 0, 2*time.Second, 1)
+}
+
+// runImport inserts items, the rows of files, batchSize at a time on
+// workers goroutines or all in one go when batchSize is 0, and reports how
+// it went. It returns why the entries it did not insert failed.
+func runImport(ctx context.Context, store storage.Storage, files []*importFile, items []importItem, batchSize int, delay, every time.Duration, workers int) error {
+	size := batchSize
+	if size == 0 {
+		size = max(len(items), 1)
+	}
+
+	progress := output.NewProgress(os.Stderr, len(items), every)
+
+	// Batches are handed to the workers in order as they become free, and
+	// their outcomes put back in order before they are reported.
+	type outcome struct {
+		start, end int
+		inserted   int
+		err        *model.PhoeBookError
+	}
+
+	jobs := make(chan int)
+	outcomes := make(chan outcome)
+	advanced := make(chan struct{}, 1024)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range jobs {
+				end := min(start+size, len(items))
+				inserted, appErr := insertBatch(ctx, store, items[start:end], advanced)
+				outcomes <- outcome{start: start, end: end, inserted: inserted, err: appErr}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for start := 0; start < len(items); start += size {
+			if start > 0 && delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+				}
+			}
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			jobs <- start
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(outcomes)
+	}()
+
+	pending := make(map[int]outcome)
+	next, processed, failed := 0, 0, 0
+	canceled := false
+	var errs []error
+	for outcomes != nil {
+		select {
+		case <-advanced:
+			processed++
+			progress.Update(processed, failed)
+
+		case o, ok := <-outcomes:
+			if !ok {
+				outcomes = nil
+				break
+			}
+
+			pending[o.start] = o
+			for o, ok := pending[next]; ok; o, ok = pending[next] {
+				delete(pending, next)
+				next = o.end
+
+				for _, item := range items[o.start : o.start+o.inserted] {
+					item.file.inserted++
+				}
+
+				if o.err == nil {
+					continue
+				}
+
+				failed += o.end - o.start - o.inserted
+				if batchSize == 0 && o.inserted == 0 {
+					progress.Finish()
+					return errors.Join(errors.New(i18n.T(o.err.Message)), errors.New(i18n.T("nothing was imported")))
+				}
+
+				// A Ctrl-C or timeout fails every batch in flight, say why
+				// only once.
+				if !canceled || ctx.Err() == nil {
+					errs = append(errs, errors.New(i18n.T(o.err.Message)))
+				}
+
+				canceled = canceled || ctx.Err() != nil
+				errs = append(errs, errors.New(i18n.T("entries %d to %d were not imported", o.start+o.inserted+1, o.end)))
+			}
+		}
+	}
+
+	if next < len(items) {
+		if !canceled {
+			errs = append(errs, errors.New(i18n.T(storage.ContextError(ctx).Message)))
+		}
+
+		errs = append(errs, errors.New(i18n.T("entries %d to %d were not imported", next+1, len(items))))
+	}
+
+	progress.Update(processed, failed)
+	progress.Finish()
+
+	for _, file := range files {
+		fmt.Println(i18n.T("imported %d entries from %s", file.inserted, file.path))
+		if file.skipped > 0 {
+			fmt.Println(i18n.T("skipped %d entries without a phone number", file.skipped))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// previewImport lists the entries importing files would add, and how many
+// of them come from each file.
+func previewImport(files []*importFile, items []importItem) {
+	entries := make([]model.Entry, len(items))
+	counts := make(map[*importFile]int)
+	for i, item := range items {
+		entries[i] = *item.entry
+		counts[item.file]++
+	}
+
+	if len(entries) > 0 {
+		output.Table(os.Stdout, entries, output.Options{})
+	}
+
+	for _, file := range files {
+		fmt.Println(i18n.T("%d entries from %s would be imported", counts[file], file.path))
+		if file.skipped > 0 {
+			fmt.Println(i18n.T("skipped %d entries without a phone number", file.skipped))
+		}
+	}
+}
+
+// importFile is one of the files of an import. unknown are the columns of
+// its header row that are left out, and invalid why the rows skipped for
+// invalid fields are not valid.
+type importFile struct {
+	path     string
+	rows     []model.Entry
+	unknown  []string
+	invalid  []string
+	skipped  int
+	inserted int
+}
+
+// importItem is an entry ready to be inserted, and the file it counts for.
+type importItem struct {
+	file  *importFile
+	entry *model.Entry
+}
+
+// normalizeRows validates and normalizes the rows of files on workers
+// goroutines, and returns the entries to insert in the order of the rows.
+func normalizeRows(files []*importFile, workers int) []importItem {
+	type row struct {
+		file  *importFile
+		entry model.Entry
+		ok    bool
+		err   error
+	}
+
+	var rows []row
+	for _, file := range files {
+		for _, entry := range file.rows {
+			rows = append(rows, row{file: file, entry: entry})
+		}
+	}
+
+	var wg sync.WaitGroup
+	chunk := (len(rows) + workers - 1) / workers
+	for start := 0; start < len(rows); start += chunk {
+		wg.Add(1)
+		go func(rows []row) {
+			defer wg.Done()
+			for i := range rows {
+				if rows[i].entry.PhoneNumber == "" {
+					continue
+				}
+
+				// IDs and photo paths only mean something in the book the
+				// file came from.
+				entry := rows[i].entry
+				rows[i].entry = model.Entry{Name: entry.Name, Surname: entry.Surname, Nickname: entry.Nickname, PhoneNumber: entry.PhoneNumber, Company: entry.Company, Title: entry.Title, Birthday: entry.Birthday, Anniversary: entry.Anniversary, RemindDays: entry.RemindDays, ArchivedAt: entry.ArchivedAt, Consent: entry.Consent, ConsentDate: entry.ConsentDate, Channel: entry.Channel, AccessNotes: entry.AccessNotes}
+				if err := checkEntry(rows[i].entry); err != nil {
+					rows[i].err = err
+					continue
+				}
+
+				prepareEntry(&rows[i].entry)
+				rows[i].ok = true
+			}
+		}(rows[start:min(start+chunk, len(rows))])
+	}
+
+	wg.Wait()
+
+	items := make([]importItem, 0, len(rows))
+	for i := range rows {
+		if rows[i].err != nil {
+			rows[i].file.invalid = append(rows[i].file.invalid, rows[i].err.Error())
+			continue
+		}
+
+		if !rows[i].ok {
+			rows[i].file.skipped++
+			continue
+		}
+
+		items = append(items, importItem{file: rows[i].file, entry: &rows[i].entry})
+	}
+
+	return items
+}
+
+// insertBatch inserts batch, in one transaction when the backend has them,
+// and signals advanced after each entry. It returns how many it inserted: on
+// backends without transactions the ones before a failure stay.
+func insertBatch(ctx context.Context, store storage.Storage, batch []importItem, advanced chan<- struct{}) (int, *model.PhoeBookError) {
+	inserted := 0
+	atomic, appErr := storage.BatchOrEach(ctx, store, func(w storage.Writer) *model.PhoeBookError {
+		for _, item := range batch {
+			if ctx.Err() != nil {
+				return storage.ContextError(ctx)
+			}
+
+			if _, appErr := w.Insert(ctx, item.entry); appErr != nil {
+				return appErr
+			}
+
+			inserted++
+			advanced <- struct{}{}
+		}
+
+		return nil
+	})
+
+	if appErr != nil && atomic {
+		inserted = 0
+	}
+
+	return inserted, appErr
+}
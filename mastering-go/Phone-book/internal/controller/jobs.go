@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/jobs"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/middleware"
+)
+
+// JobsHandler returns the admin routes of the scheduled jobs, GET /jobs and
+// POST /jobs/{name}/run, behind the same middleware as the API so the same
+// tokens or sign-in protect them.
+func JobsHandler(scheduler *jobs.Scheduler, extra ...middleware.Middleware) http.Handler {
+	h := &jobHandlers{scheduler: scheduler}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /jobs", http.HandlerFunc(h.listHandler))
+	mux.Handle("POST /jobs/{name}/run", http.HandlerFunc(h.runHandler))
+
+	return middleware.Chain(mux, append([]middleware.Middleware{middleware.Recovery, middleware.Logging}, extra...)...)
+}
+
+type jobHandlers struct {
+	scheduler *jobs.Scheduler
+}
+
+// listHandler
+// @Summary      List the scheduled jobs
+// @Description  Return every job of the config file with its schedule, next run and the outcome of its last run
+// @Tags         jobs
+// @Produce      json
+// @Success      200  {array}   phonebook.JobStatus
+// @Router       /jobs [get]
+func (h *jobHandlers) listHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse, err := json.MarshalIndent(h.scheduler.List(), "", " ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(jsonResponse))
+}
+
+// runHandler
+// @Summary      Run a job now
+// @Description  Start a scheduled job right away in the background; GET /jobs tells when it is done
+// @Tags         jobs
+// @Param        name  path      string  true  "Job name"
+// @Success      202  {string}  string  "Started"
+// @Failure      404  {string}  string  "Not Found"
+// @Failure      409  {string}  string  "Already running"
+// @Router       /jobs/{name}/run [post]
+func (h *jobHandlers) runHandler(w http.ResponseWriter, r *http.Request) {
+	if appErr := h.scheduler.Run(r.PathValue("name")); appErr != nil {
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprint(w, "job started")
+}
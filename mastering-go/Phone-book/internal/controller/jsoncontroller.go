@@ -1,23 +1,43 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
-	"net/http/pprof"
+	"slices"
 	"strconv"
 	"time"
 
 	httpSwagger "github.com/swaggo/http-swagger"
 
+	docs "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/api"
 	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/db"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/filter"
 	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/systemd"
 	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/metrics"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/middleware"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/web"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// maxBodySize bounds the body of a request carrying one entry or report, so
+// a client cannot make the server read an endless one into memory.
+const maxBodySize = 64 << 10
+
+// handlers holds what the HTTP handlers need to serve a phone book.
+type handlers struct {
+	store   storage.Storage
+	lookups *lookupCache
+}
+
 // @title Phonebook API
 // @version 1.0
 // @description This is a sample phonebook API server.
@@ -45,54 +65,341 @@ func defaultHandler(w http.ResponseWriter, r *http.Request) {
 // @Summary      Delete a phonebook entry
 // @Description  Delete an entry by its ID
 // @Tags         phonebook
-// @Param        id   path      int   true  "Entry ID"
+// @Param        id        path      int     true   "Entry ID"
+// @Param        version   query     int     false  "Only delete the entry if it still has this version"
+// @Param        If-Match  header    string  false  "Only delete the entry if it still has this ETag"
 // @Produce      plain
 // @Success      200  {string}  string  "Deleted successfully"
+// @Failure      409  {string}  string  "Conflict"
+// @Failure      412  {string}  string  "Precondition Failed"
 // @Failure      500  {string}  string  "Internal Server Error"
 // @Router       /delete/{id} [delete]
-func deleteHandler(w http.ResponseWriter, r *http.Request) {
+func (h *handlers) deleteHandler(w http.ResponseWriter, r *http.Request) {
 	inputParameter := r.PathValue("id")
 
 	id, err := strconv.Atoi(inputParameter)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
+		return
 	}
 
-	appErr := db.Delete(int64(id))
-	if err != nil {
+	var version int64
+	if raw := r.URL.Query().Get("version"); raw != "" {
+		version, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+	}
+
+	matchVersion, matched, appErr := ifMatchVersion(r.Context(), h.store, r, int64(id))
+	if appErr != nil {
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	if !matched {
+		preconditionFailed(w)
+		return
+	}
+
+	if matchVersion != 0 {
+		version = matchVersion
+	}
+
+	appErr = deleteVersion(r.Context(), h.store, int64(id), version)
+	if matchVersion != 0 && appErr != nil && appErr.StatusCode == http.StatusConflict {
+		preconditionFailed(w)
+		return
+	}
+
+	if appErr != nil {
 		w.WriteHeader(int(appErr.StatusCode))
 		fmt.Fprint(w, appErr.Message)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// deleteVersion deletes the entry with id, only if it still has version
+// unless that is 0.
+func deleteVersion(ctx context.Context, store storage.Storage, id, version int64) *model.PhoeBookError {
+	if version == 0 {
+		return store.Delete(ctx, id)
+	}
+
+	deleter, ok := store.(storage.VersionedDeleter)
+	if !ok {
+		return storage.Unsupported("entry versions")
+	}
+
+	return deleter.DeleteVersion(ctx, id, version)
+}
+
 // listHandler
 // @Summary      List phonebook entries
-// @Description  Get all phonebook entries
+// @Description  Get all phonebook entries, or with archived=true the archived ones
 // @Tags         phonebook
+// @Param        archived  query     bool  false  "List the archived entries instead"
 // @Produce      json
 // @Success      200  {object}  phonebook.ListResponse
 // @Failure      500  {string}  string  "Internal Server Error"
 // @Router       /list [get]
-func listHandler(w http.ResponseWriter, r *http.Request) {
-	entries, appErr := db.GetList()
+func (h *handlers) listHandler(w http.ResponseWriter, r *http.Request) {
+	entries, appErr := h.store.List(r.Context())
 	if appErr != nil {
 		w.WriteHeader(int(appErr.StatusCode))
 		fmt.Fprint(w, appErr.Message)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	archived, _ := strconv.ParseBool(r.URL.Query().Get("archived"))
+	entries = visible(entries, viewOf(archived))
+
 	jsonResponse, err := json.MarshalIndent(model.ListResponse{Entries: entries}, "", " ")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	writeTagged(w, r, bodyETag(jsonResponse), jsonResponse)
+}
+
+// entriesHandler
+// @Summary      Query phonebook entries
+// @Description  List the entries matching a filter expression, e.g. surname=Smith AND company~Acme, or ranked by a free text search
+// @Tags         phonebook
+// @Param        q       query     string  false  "Filter expression"
+// @Param        search  query     string  false  "Free text search, best matches first"
+// @Param        limit   query     int     false  "Return at most this many entries, and a next_cursor for the rest"
+// @Param        cursor  query     string  false  "The next_cursor of the previous page"
+// @Param        archived  query   bool    false  "Query the archived entries instead"
+// @Produce      json
+// @Success      200  {object}  phonebook.ListResponse
+// @Failure      400  {object}  phonebook.QueryError
+// @Router       /entries [get]
+func (h *handlers) entriesHandler(w http.ResponseWriter, r *http.Request) {
+	var expr filter.Expr
+	if q := r.URL.Query().Get("q"); q != "" {
+		parsed, err := filter.Parse(q)
+		if err != nil {
+			queryErr := model.QueryError{Message: err.Error()}
+			var syntaxErr *filter.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				queryErr = model.QueryError{Message: syntaxErr.Message, Position: syntaxErr.Pos}
+			}
+
+			jsonResponse, _ := json.MarshalIndent(queryErr, "", " ")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, string(jsonResponse))
+			return
+		}
+
+		expr = parsed
+	}
+
+	token := r.URL.Query().Get("cursor")
+	size, err := pageSize(r.URL.Query().Get("limit"), token)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	archived, _ := strconv.ParseBool(r.URL.Query().Get("archived"))
+
+	var results []search.Result
+	term := r.URL.Query().Get("search")
+	if term != "" {
+		var appErr *model.PhoeBookError
+		results, _, appErr = searchEntries(r.Context(), h.store, term, viewOf(archived))
+		if appErr != nil {
+			w.WriteHeader(int(appErr.StatusCode))
+			fmt.Fprint(w, appErr.Message)
+			return
+		}
+	} else {
+		entries, appErr := h.store.List(r.Context())
+		if appErr != nil {
+			w.WriteHeader(int(appErr.StatusCode))
+			fmt.Fprint(w, appErr.Message)
+			return
+		}
+
+		entries = visible(entries, viewOf(archived))
+		results = make([]search.Result, len(entries))
+		for i, entry := range entries {
+			results[i] = search.Result{Entry: entry}
+		}
+	}
+
+	if expr != nil {
+		var matched []search.Result
+		for _, result := range results {
+			if expr.Match(result.Entry) {
+				matched = append(matched, result)
+			}
+		}
+
+		results = matched
+	}
+
+	response := model.ListResponse{}
+	if size > 0 {
+		results, response.NextCursor, err = page(results, size, token, r.URL.Query().Get("q")+"\x00"+term+"\x00"+strconv.FormatBool(archived))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+	}
+
+	response.Entries = search.Entries(results)
+
+	jsonResponse, err := json.MarshalIndent(response, "", " ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
+	writeTagged(w, r, bodyETag(jsonResponse), jsonResponse)
+}
+
+// entryHandler
+// @Summary      Get a phonebook entry
+// @Description  Get one entry by its ID. Its ETag changes with the entry's version
+// @Tags         phonebook
+// @Param        id   path      int   true  "Entry ID"
+// @Produce      json
+// @Success      200  {object}  phonebook.Entry
+// @Success      304  {string}  string  "Not Modified"
+// @Failure      404  {string}  string  "Not Found"
+// @Router       /entries/{id} [get]
+func (h *handlers) entryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	entries, appErr := h.store.List(r.Context())
+	if appErr != nil {
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.ID != id {
+			continue
+		}
+
+		jsonResponse, err := json.MarshalIndent(entry, "", " ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, err.Error())
+			return
+		}
+
+		writeTagged(w, r, entryETag(entry), jsonResponse)
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, "there is no record with given id")
+}
+
+// updateHandler
+// @Summary      Edit a phonebook entry
+// @Description  Replace the fields of an entry, keeping its photo. A non-zero version, or an If-Match ETag, must match the stored one
+// @Tags         phonebook
+// @Accept       json
+// @Param        id        path      int              true   "Entry ID"
+// @Param        If-Match  header    string           false  "ETag of the entry this change was made to"
+// @Param        entry     body      phonebook.Entry  true   "Phonebook Entry"
+// @Success      200    {string}  string  "Updated"
+// @Failure      404    {string}  string  "Not Found"
+// @Failure      409    {string}  string  "Conflict"
+// @Failure      412    {string}  string  "Precondition Failed"
+// @Failure      413    {string}  string  "Request Entity Too Large"
+// @Failure      501    {string}  string  "Not Implemented"
+// @Router       /entries/{id} [put]
+func (h *handlers) updateHandler(w http.ResponseWriter, r *http.Request) {
+	updater, ok := h.store.(storage.Updater)
+	if !ok {
+		appErr := storage.Unsupported("editing entries")
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	body, ok := readBody(w, r, maxBodySize)
+	if !ok {
+		return
+	}
+
+	var entry model.Entry
+
+	err = json.Unmarshal(body, &entry)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	version, matched, appErr := ifMatchVersion(r.Context(), h.store, r, id)
+	if appErr != nil {
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	if !matched {
+		preconditionFailed(w)
+		return
+	}
+
+	entry.ID = id
+	if version != 0 {
+		entry.Version = version
+	}
+
+	if err := checkEntry(entry); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	prepareEntry(&entry)
+
+	if appErr := updater.Update(r.Context(), &entry); appErr != nil {
+		if version != 0 && appErr.StatusCode == http.StatusConflict {
+			preconditionFailed(w)
+			return
+		}
+
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	w.Header().Set("ETag", entryETag(entry))
 	w.WriteHeader(http.StatusOK)
-	fmt.Fprint(w, string(jsonResponse))
 }
 
 // insertHandler
@@ -103,34 +410,44 @@ func listHandler(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        entry  body      phonebook.Entry  true  "Phonebook Entry"
 // @Success      200    {object}  phonebook.InsertResponse
+// @Failure      413    {string}  string  "Request Entity Too Large"
 // @Failure      500    {string}  string  "Internal Server Error"
 // @Router       /insert [post]
-func insertHandler(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		fmt.Fprint(w, err.Error())
+func (h *handlers) insertHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := readBody(w, r, maxBodySize)
+	if !ok {
+		return
 	}
 
 	var entry model.Entry
 
-	err = json.Unmarshal(body, &entry)
+	err := json.Unmarshal(body, &entry)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
+		return
 	}
 
-	id, appErr := db.Insert(&entry)
+	if err := checkEntry(entry); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	prepareEntry(&entry)
+
+	id, appErr := h.store.Insert(r.Context(), &entry)
 	if appErr != nil {
 		w.WriteHeader(int(appErr.StatusCode))
 		fmt.Fprint(w, appErr.Message)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	jsonResponse, err := json.MarshalIndent(model.InsertResponse{ID: id}, "", " ")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -147,11 +464,12 @@ func insertHandler(w http.ResponseWriter, r *http.Request) {
 // @Success      200  {object}  phonebook.Entry
 // @Failure      500  {string}  string  "Internal Server Error"
 // @Router       /search [get]
-func searchHandler(w http.ResponseWriter, r *http.Request) {
-	data, appErr := db.GetList()
+func (h *handlers) searchHandler(w http.ResponseWriter, r *http.Request) {
+	data, appErr := h.store.List(r.Context())
 	if appErr != nil {
 		w.WriteHeader(int(appErr.StatusCode))
 		fmt.Fprint(w, appErr.Message)
+		return
 	}
 
 	telephone := r.URL.Query().Get("phone-number")
@@ -159,13 +477,81 @@ func searchHandler(w http.ResponseWriter, r *http.Request) {
 	if appErr != nil {
 		w.WriteHeader(int(appErr.StatusCode))
 		fmt.Fprint(w, appErr.Message)
+		return
 	}
 
-	w.WriteHeader(http.StatusOK)
 	jsonResponse, err := json.MarshalIndent(entry, "", " ")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(jsonResponse))
+}
+
+// blockedHandler
+// @Summary      Check a number against the blocklist
+// @Description  Tell whether a phone number is blocked, for call screening
+// @Tags         phonebook
+// @Param        number  path      string  true  "Phone number"
+// @Produce      json
+// @Success      200  {object}  phonebook.BlockedResponse
+// @Failure      501  {string}  string  "Not Implemented"
+// @Router       /blocked/{number} [get]
+func (h *handlers) blockedHandler(w http.ResponseWriter, r *http.Request) {
+	blocklist, ok := h.store.(storage.Blocklist)
+	if !ok {
+		appErr := storage.Unsupported("blocklists")
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	number := model.PhoneNumber(r.PathValue("number")).Canonical()
+	numbers, appErr := blocklist.Blocked(r.Context())
+	if appErr != nil {
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	jsonResponse, err := json.MarshalIndent(model.BlockedResponse{Number: number, Blocked: slices.Contains(numbers, string(number))}, "", " ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(jsonResponse))
+}
+
+// lookupHandler
+// @Summary      Look up a phone number
+// @Description  Return the entry owning a number, whether it is blocked and its spam score
+// @Tags         phonebook
+// @Param        number  path      string  true  "Phone number"
+// @Produce      json
+// @Success      200  {object}  phonebook.LookupResponse
+// @Failure      500  {string}  string  "Internal Server Error"
+// @Router       /lookup/{number} [get]
+func (h *handlers) lookupHandler(w http.ResponseWriter, r *http.Request) {
+	result, appErr := h.lookups.lookup(r.Context(), r.PathValue("number"))
+	if appErr != nil {
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	jsonResponse, err := json.MarshalIndent(result, "", " ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, err.Error())
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -173,33 +559,171 @@ func searchHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, string(jsonResponse))
 }
 
-func StartHander() {
+// spamHandler
+// @Summary      Report a number as spam
+// @Description  Record a spam report; reports from different reporters raise the spam score. The reporter is the signed in user, or the client address
+// @Tags         phonebook
+// @Accept       json
+// @Param        report  body      phonebook.SpamReport  true  "Spam report"
+// @Success      200  {string}  string  "Reported"
+// @Failure      413  {string}  string  "Request Entity Too Large"
+// @Failure      501  {string}  string  "Not Implemented"
+// @Router       /spam [post]
+func (h *handlers) spamHandler(w http.ResponseWriter, r *http.Request) {
+	spamReports, ok := h.store.(storage.SpamReports)
+	if !ok {
+		appErr := storage.Unsupported("spam reports")
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	body, ok := readBody(w, r, maxBodySize)
+	if !ok {
+		return
+	}
+
+	var report model.SpamReport
+
+	err := json.Unmarshal(body, &report)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	report.Number = report.Number.Canonical()
+	report.ReportedAt = time.Now().UTC()
+	// The reporter of the body is ignored: anyone could raise a score by
+	// reporting under many names.
+	report.Reporter = middleware.User(r.Context())
+	if report.Reporter == "" {
+		report.Reporter, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+
+	if appErr := spamReports.ReportSpam(r.Context(), report); appErr != nil {
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// photoHandler
+// @Summary      Get the photo of an entry
+// @Description  Serve the image stored for an entry
+// @Tags         phonebook
+// @Param        id   path      int   true  "Entry ID"
+// @Produce      image/jpeg,image/png,image/gif
+// @Success      200  {file}    file  "Photo"
+// @Failure      404  {string}  string  "Not Found"
+// @Router       /photo/{id} [get]
+func (h *handlers) photoHandler(w http.ResponseWriter, r *http.Request) {
+	photos, ok := h.store.(storage.Photos)
+	if !ok {
+		appErr := storage.Unsupported("photos")
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return
+	}
+
+	photo, appErr := photos.Photo(r.Context(), id)
+	if appErr != nil {
+		w.WriteHeader(int(appErr.StatusCode))
+		fmt.Fprint(w, appErr.Message)
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(photo))
+	w.WriteHeader(http.StatusOK)
+	w.Write(photo)
+}
+
+// Handler returns the phone book routes wrapped in the default middleware
+// stack (recovery and logging) followed by extra, ready to be mounted into a
+// larger mux. The web UI at "/" only gets the default stack: it is static,
+// and asks for the API token itself when extra requires one.
+func Handler(store storage.Storage, extra ...middleware.Middleware) http.Handler {
+	h := &handlers{store: store, lookups: newLookupCache(store)}
+
+	mux := http.NewServeMux()
+	mux.Handle("/list", http.HandlerFunc(h.listHandler))
+	mux.Handle("GET /entries", http.HandlerFunc(h.entriesHandler))
+	mux.Handle("POST /entries:batchCreate", http.HandlerFunc(h.batchCreateHandler))
+	mux.Handle("POST /entries:batchDelete", http.HandlerFunc(h.batchDeleteHandler))
+	mux.Handle("GET /entries/{id}", http.HandlerFunc(h.entryHandler))
+	mux.Handle("PUT /entries/{id}", http.HandlerFunc(h.updateHandler))
+	mux.Handle("POST /insert", http.HandlerFunc(h.insertHandler))
+	mux.Handle("DELETE /delete/{id}", http.HandlerFunc(h.deleteHandler))
+	mux.Handle("/search/", http.HandlerFunc(h.searchHandler))
+	mux.Handle("GET /blocked/{number}", http.HandlerFunc(h.blockedHandler))
+	mux.Handle("GET /lookup/{number}", http.HandlerFunc(h.lookupHandler))
+	mux.Handle("POST /spam", http.HandlerFunc(h.spamHandler))
+	mux.Handle("GET /photo/{id}", http.HandlerFunc(h.photoHandler))
+
+	stack := append([]middleware.Middleware{middleware.Recovery, middleware.Logging}, extra...)
+
+	root := http.NewServeMux()
+	root.Handle("GET /{$}", middleware.Chain(web.Handler(), middleware.Recovery, middleware.Logging))
+	root.Handle("GET /ui/", middleware.Chain(web.Handler(), middleware.Recovery, middleware.Logging))
+	root.Handle("/", middleware.Chain(h.lookups.clearOnWrite(mux), stack...))
+
+	return root
+}
+
+func StartHander(store storage.Storage, extra ...middleware.Middleware) {
+	Serve(Handler(store, extra...))
+}
+
+// StartBooksHandler serves several phone books from one server, see
+// BooksHandler.
+func StartBooksHandler(books map[string]Book, extra ...middleware.Middleware) {
+	Serve(BooksHandler(books, extra...))
+}
+
+// Serve mounts root next to the metrics and documentation endpoints and
+// listens on :8001.
+func Serve(root http.Handler) {
+	listener, err := net.Listen("tcp", ":8001")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ServeListener(listener, root)
+}
+
+// ServeListener is Serve on a listener of the caller's, like a socket
+// passed by systemd. The service manager is told once requests are
+// accepted.
+func ServeListener(listener net.Listener, root http.Handler) {
 	mux := http.NewServeMux()
 	server := &http.Server{
-		Addr:         ":8001",
 		Handler:      mux,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  10 * time.Second,
 	}
 
-	mux.Handle("/list", http.HandlerFunc(listHandler))
-	mux.Handle("/insert", http.HandlerFunc(insertHandler))
-	mux.Handle("/delete/{id}", http.HandlerFunc(deleteHandler))
-	mux.Handle("/search/", http.HandlerFunc(searchHandler))
+	mux.Handle("/", root)
 	mux.Handle("/metrics", promhttp.Handler())
 
-	mux.HandleFunc("/debug/pprof/", pprof.Index)
-	mux.HandleFunc("debug/pprof/cmdline", pprof.Cmdline)
-	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
-	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
-	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
-
 	mux.Handle("/swagger/", httpSwagger.WrapHandler)
+	mux.HandleFunc("/openapi.json", docs.OpenAPIHandler)
+	mux.HandleFunc("/docs", docs.SwaggerUIHandler)
 
-	fmt.Println("Ready to serve at", "8001")
+	fmt.Println("Ready to serve at", listener.Addr())
+	systemd.Notify("READY=1")
 
-	err := server.ListenAndServe()
+	err := server.Serve(listener)
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -209,3 +733,24 @@ func StartHander() {
 		http.ListenAndServe(metrics.METRICS_PORT, nil)
 	}()
 }
+
+// readBody reads the body of r, answering the request itself when it is
+// longer than limit or cannot be read.
+func readBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		fmt.Fprintf(w, "the request body is larger than %d bytes", limit)
+		return nil, false
+
+	case err != nil:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, err.Error())
+		return nil, false
+	}
+
+	return body, true
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage/memory"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage/storagemock"
+)
+
+// serve makes a request to the routes of Handler on store and returns the
+// response.
+func serve(store storage.Storage, method, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	Handler(store).ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	return w
+}
+
+func TestInsertHandler(t *testing.T) {
+	store := memory.New()
+
+	w := serve(store, http.MethodPost, "/insert", `{"name":"John","surname":"Smith","phone_number":"+1 (415) 555-0100"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	var response model.InsertResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := store.List(context.Background())
+	if len(entries) != 1 || entries[0].ID != response.ID {
+		t.Fatalf("got entries %+v, want the one with id %d", entries, response.ID)
+	}
+
+	if got := entries[0]; got.PhoneNumber != "+14155550100" || got.Country != "US" {
+		t.Errorf("got %+v, want the number in E.164 with its country", got)
+	}
+}
+
+func TestInsertHandlerRefusesInvalidEntries(t *testing.T) {
+	store := &storagemock.Storage{}
+
+	w := serve(store, http.MethodPost, "/insert", `{"name":"Ali","phone_number":"09121234567","consent":"maybe"}`)
+	if w.Code != http.StatusBadRequest || w.Body.String() != "consent must be yes or no" {
+		t.Errorf("got status %d: %s", w.Code, w.Body)
+	}
+
+	if store.Called("Insert") != 0 {
+		t.Error("the invalid entry was inserted")
+	}
+}
+
+func TestDeleteHandler(t *testing.T) {
+	store := memory.New(storage.Entry{ID: 1, Name: "Ali", PhoneNumber: "+989121234567"})
+
+	// Only DELETE deletes, so a link followed or a GET made by a viewer
+	// cannot.
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		if w := serve(store, method, "/delete/1", ""); w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s: got status %d, want %d", method, w.Code, http.StatusMethodNotAllowed)
+		}
+	}
+
+	if w := serve(store, http.MethodDelete, "/delete/1", ""); w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	if entries, _ := store.List(context.Background()); len(entries) != 0 {
+		t.Errorf("got %d entries after the delete, want none", len(entries))
+	}
+
+	if w := serve(store, http.MethodDelete, "/delete/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("deleting it again: got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestListHandlerBackendFailure(t *testing.T) {
+	store := &storagemock.Storage{
+		ListFunc: func(ctx context.Context) ([]storage.Entry, *storage.Error) {
+			return nil, &storage.Error{Message: "the database is down", StatusCode: http.StatusServiceUnavailable}
+		},
+	}
+
+	w := serve(store, http.MethodGet, "/list", "")
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != "the database is down" {
+		t.Errorf("got status %d: %s", w.Code, w.Body)
+	}
+
+	if store.Called("List") != 1 {
+		t.Errorf("List was called %d times, want 1", store.Called("List"))
+	}
+}
+
+func TestBlockedHandler(t *testing.T) {
+	store := memory.New()
+	if appErr := store.Block(context.Background(), "+989121234567"); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	w := serve(store, http.MethodGet, "/blocked/09121234567", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	var response model.BlockedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Blocked {
+		t.Errorf("got %+v, want the number blocked", response)
+	}
+
+	// Without a blocklist the backend cannot tell.
+	if w := serve(&storagemock.Storage{}, http.MethodGet, "/blocked/09121234567", ""); w.Code != http.StatusNotImplemented {
+		t.Errorf("without a blocklist: got status %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+}
+
+func TestBatchCreateHandlerRefusesInvalidEntries(t *testing.T) {
+	store := memory.New()
+
+	w := serve(store, http.MethodPost, "/entries:batchCreate", `[{"name":"Ali","phone_number":"09121234567"},{"name":"Sara","phone_number":"09351112233","birthday":"1990-13-40"}]`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	var response model.BatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Committed || len(response.Results) != 2 || response.Results[1].Status != http.StatusBadRequest || !strings.Contains(response.Results[1].Error, "invalid date") {
+		t.Errorf("got %+v, want the second entry refused for its birthday", response)
+	}
+
+	if entries, _ := store.List(context.Background()); len(entries) != 0 {
+		t.Errorf("got %d entries, want none: the batch is all or nothing", len(entries))
+	}
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// maxAutomaticRelations is how many other entries of its company an entry
+// is related to automatically, so the colleagues at a big company don't
+// drown its links.
+const maxAutomaticRelations = 10
+
+// linkCommand handles "link add <id> <id> --type T", "link remove <id>
+// <id>" and "link list [<id>]". A link says the second entry is the first
+// one's T, e.g. their spouse or their assistant.
+func linkCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	relationships, ok := store.(storage.Relationships)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("links").Message))
+	}
+
+	usage := i18n.T("usage: link add <id> <id> --type %s, link remove <id> <id> or link list [<id>]", strings.Join(model.LinkTypes, "|"))
+	if len(arguments) < 3 {
+		return &UsageError{Message: usage}
+	}
+
+	flags := flag.NewFlagSet("link "+arguments[2], flag.ContinueOnError)
+	var linkType *string
+	if arguments[2] == "add" {
+		linkType = flags.String("type", "", i18n.T("how the second entry relates to the first, one of %s", strings.Join(model.LinkTypes, ", ")))
+	}
+
+	// The ids may come before the flags, after them or between them.
+	var ids []int64
+	rest := arguments[3:]
+	for {
+		if err := flags.Parse(rest); err != nil {
+			return flagError(err)
+		}
+
+		if flags.NArg() == 0 {
+			break
+		}
+
+		id, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+		if err != nil {
+			return errors.New(i18n.T("invalid id %q", flags.Arg(0)))
+		}
+
+		ids, rest = append(ids, id), flags.Args()[1:]
+	}
+
+	switch {
+	case arguments[2] == "list" && len(ids) <= 1:
+	case (arguments[2] == "add" || arguments[2] == "remove") && len(ids) == 2:
+	default:
+		return &UsageError{Message: usage}
+	}
+
+	entries, appErr := entriesByID(ctx, store)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	for _, id := range ids {
+		if _, ok := entries[id]; !ok && arguments[2] != "remove" {
+			return errors.New(i18n.T("there is no record with id %d", id))
+		}
+	}
+
+	switch arguments[2] {
+	case "add":
+		if !slices.Contains(model.LinkTypes, *linkType) {
+			return usageError("--type must be one of %s", strings.Join(model.LinkTypes, ", "))
+		}
+
+		if ids[0] == ids[1] {
+			return errors.New(i18n.T("an entry cannot be linked to itself"))
+		}
+
+		if appErr := relationships.Link(ctx, model.Link{From: ids[0], To: ids[1], Type: *linkType}); appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		fmt.Println(i18n.T("linked entry %d to entry %d as %s", ids[1], ids[0], i18n.T(*linkType)))
+
+	case "remove":
+		if appErr := relationships.Unlink(ctx, ids[0], ids[1]); appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		fmt.Println(i18n.T("unlinked entries %d and %d", ids[0], ids[1]))
+
+	case "list":
+		if len(ids) == 1 {
+			related, appErr := relations(ctx, store, entries[ids[0]], entries)
+			if appErr != nil {
+				return errors.New(i18n.T(appErr.Message))
+			}
+
+			if len(related) == 0 {
+				fmt.Println(i18n.T("entry %d is not related to any other", ids[0]))
+				return nil
+			}
+
+			output.Relations(os.Stdout, related)
+			return nil
+		}
+
+		links, appErr := relationships.Links(ctx, 0)
+		if appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		if len(links) == 0 {
+			fmt.Println(i18n.T("there are no links yet"))
+			return nil
+		}
+
+		output.Links(os.Stdout, links, entries)
+	}
+
+	return nil
+}
+
+// relations returns the entries related to entry: those it has links with,
+// then up to maxAutomaticRelations other entries of the same company it
+// has no link with, which are related to it automatically. Links with
+// entries no longer in the book are left out.
+func relations(ctx context.Context, store storage.Storage, entry model.Entry, entries map[int64]model.Entry) ([]model.Relation, *model.PhoeBookError) {
+	var related []model.Relation
+	linked := map[int64]bool{entry.ID: true}
+
+	if relationships, ok := store.(storage.Relationships); ok {
+		links, appErr := relationships.Links(ctx, entry.ID)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		for _, link := range links {
+			other, ok := entries[link.To]
+			if link.To == entry.ID {
+				other, ok = entries[link.From]
+			}
+
+			if !ok {
+				continue
+			}
+
+			linked[other.ID] = true
+			related = append(related, model.Relation{Entry: other, Type: link.Type, AssistantOf: link.Type == model.LinkAssistant && link.To == entry.ID})
+		}
+	}
+
+	if entry.Company == "" {
+		return related, nil
+	}
+
+	var colleagues []model.Entry
+	for _, other := range entries {
+		if !linked[other.ID] && other.ArchivedAt == nil && strings.EqualFold(other.Company, entry.Company) {
+			colleagues = append(colleagues, other)
+		}
+	}
+
+	sort.Slice(colleagues, func(i, j int) bool { return colleagues[i].ID < colleagues[j].ID })
+	if len(colleagues) > maxAutomaticRelations {
+		colleagues = colleagues[:maxAutomaticRelations]
+	}
+
+	for _, colleague := range colleagues {
+		related = append(related, model.Relation{Entry: colleague, Type: model.LinkSameCompany, Automatic: true})
+	}
+
+	return related, nil
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/spam"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// lookup gathers everything known about a number: the entry it belongs to,
+// whether it is blocked and how likely it is spam. Blocklist and spam data
+// are left empty for backends that don't support them.
+func lookup(ctx context.Context, store storage.Storage, number string) (*model.LookupResponse, *model.PhoeBookError) {
+	result := &model.LookupResponse{Number: model.PhoneNumber(number).Canonical()}
+
+	entries, appErr := numberCandidates(ctx, store, string(result.Number))
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	for _, entry := range entries {
+		if entry.PhoneNumber.Equal(result.Number) {
+			result.Entry = &entry
+			break
+		}
+	}
+
+	if blocklist, ok := store.(storage.Blocklist); ok {
+		numbers, appErr := blocklist.Blocked(ctx)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		for _, blocked := range numbers {
+			if blocked == string(result.Number) {
+				result.Blocked = true
+			}
+		}
+	}
+
+	if spamReports, ok := store.(storage.SpamReports); ok && result.Number != "" {
+		reports, appErr := spamReports.SpamReports(ctx, string(result.Number))
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		result.SpamReports = len(reports)
+		result.SpamScore = spam.Score(reports)
+	}
+
+	return result, nil
+}
+
+func lookupCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	if len(arguments) != 3 {
+		return usageError("usage: lookup <number>")
+	}
+
+	result, appErr := lookup(ctx, store, arguments[2])
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	output.Lookup(os.Stdout, result)
+
+	return nil
+}
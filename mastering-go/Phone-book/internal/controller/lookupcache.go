@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/metrics"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+const (
+	// lookupCacheSize is how many numbers the lookup cache of a book keeps;
+	// the least recently looked up go first.
+	lookupCacheSize = 10000
+	// lookupCacheTTL is how long a lookup is answered from the cache. It
+	// bounds how long changes the cache cannot see stay unnoticed, like a
+	// number blocked on the command line.
+	lookupCacheTTL = 30 * time.Second
+)
+
+// lookupCache keeps the answers of the lookup endpoint, for call screening
+// asking about the same numbers over and over. It is emptied by every
+// request that may change the book and whenever the backend's fingerprint
+// changes, which catches the changes made by other processes.
+type lookupCache struct {
+	store storage.Storage
+
+	mu sync.Mutex
+	// order has the cached lookups, most recently used first, and byNumber
+	// their elements by the canonical number.
+	order    *list.List
+	byNumber map[model.PhoneNumber]*list.Element
+	// fingerprint is the backend's fingerprint when the lookups were made.
+	fingerprint string
+	// generation counts the times the cache was emptied, so a lookup made
+	// while it was isn't kept.
+	generation uint64
+}
+
+// cachedLookup is a lookup kept in the cache until expires.
+type cachedLookup struct {
+	number  model.PhoneNumber
+	result  *model.LookupResponse
+	expires time.Time
+}
+
+func newLookupCache(store storage.Storage) *lookupCache {
+	return &lookupCache{store: store, order: list.New(), byNumber: make(map[model.PhoneNumber]*list.Element)}
+}
+
+// lookup is the package's lookup, answered from the cache when it can be.
+// The result is shared with other callers and must not be modified.
+func (c *lookupCache) lookup(ctx context.Context, number string) (*model.LookupResponse, *model.PhoeBookError) {
+	key := model.PhoneNumber(number).Canonical()
+
+	if fingerprinter, ok := c.store.(storage.Fingerprinter); ok {
+		fingerprint, appErr := fingerprinter.Fingerprint(ctx)
+		if appErr != nil && appErr.StatusCode != http.StatusNotImplemented {
+			return nil, appErr
+		}
+
+		c.mu.Lock()
+		if fingerprint != c.fingerprint {
+			c.clearLocked()
+			c.fingerprint = fingerprint
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	if element, ok := c.byNumber[key]; ok {
+		cached := element.Value.(*cachedLookup)
+		if time.Now().Before(cached.expires) {
+			c.order.MoveToFront(element)
+			c.mu.Unlock()
+			metrics.LookupCacheRequests.WithLabelValues("hit").Inc()
+			return cached.result, nil
+		}
+
+		c.removeLocked(element)
+	}
+
+	generation := c.generation
+	c.mu.Unlock()
+
+	metrics.LookupCacheRequests.WithLabelValues("miss").Inc()
+	result, appErr := lookup(ctx, c.store, number)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return result, nil
+	}
+
+	if element, ok := c.byNumber[key]; ok {
+		c.removeLocked(element)
+	}
+
+	c.byNumber[key] = c.order.PushFront(&cachedLookup{number: key, result: result, expires: time.Now().Add(lookupCacheTTL)})
+	metrics.LookupCacheEntries.Inc()
+	if c.order.Len() > lookupCacheSize {
+		c.removeLocked(c.order.Back())
+	}
+
+	return result, nil
+}
+
+// clear empties the cache.
+func (c *lookupCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clearLocked()
+}
+
+func (c *lookupCache) clearLocked() {
+	metrics.LookupCacheEntries.Sub(float64(c.order.Len()))
+	c.order.Init()
+	clear(c.byNumber)
+	c.generation++
+}
+
+func (c *lookupCache) removeLocked(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.byNumber, element.Value.(*cachedLookup).number)
+	metrics.LookupCacheEntries.Dec()
+}
+
+// clearOnWrite empties the cache after every request to next that may have
+// changed the book, anything but GET, HEAD and OPTIONS, whether it
+// succeeded or not.
+func (c *lookupCache) clearOnWrite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			c.clear()
+		}
+	})
+}
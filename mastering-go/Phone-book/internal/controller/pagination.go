@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+)
+
+// Page sizes of GET /entries: defaultPageSize when only a cursor is given,
+// at most maxPageSize.
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+// cursor is what the opaque next_cursor tokens of GET /entries hold: the
+// position of the last entry of a page, and which query it belongs to.
+type cursor struct {
+	Query string `json:"q"`
+	Score int    `json:"s,omitempty"`
+	ID    int64  `json:"id"`
+}
+
+// pageSize parses the limit parameter, 0 meaning no paging at all.
+func pageSize(limit, token string) (int, error) {
+	if limit == "" {
+		if token != "" {
+			return defaultPageSize, nil
+		}
+
+		return 0, nil
+	}
+
+	size, err := strconv.Atoi(limit)
+	if err != nil || size < 1 || size > maxPageSize {
+		return 0, fmt.Errorf("limit must be a number from 1 to %d", maxPageSize)
+	}
+
+	return size, nil
+}
+
+// page returns at most size of results, starting after the entry token
+// points at, and the token for the next page, "" on the last one. Results are
+// ordered by score, then ID, so a page picks up right after the last entry of
+// the previous one even when entries were added or removed in between, unlike
+// with offsets. query identifies the search the tokens belong to.
+func page(results []search.Result, size int, token, query string) ([]search.Result, string, error) {
+	key := queryKey(query)
+
+	var after *cursor
+	if token != "" {
+		data, err := base64.RawURLEncoding.DecodeString(token)
+		if err == nil {
+			after = &cursor{}
+			err = json.Unmarshal(data, after)
+		}
+
+		if err != nil {
+			return nil, "", errors.New("invalid cursor")
+		}
+
+		if after.Query != key {
+			return nil, "", errors.New("the cursor belongs to another query")
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+
+		return results[i].Entry.ID < results[j].Entry.ID
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(results), func(i int) bool {
+			result := results[i]
+			return result.Score < after.Score || (result.Score == after.Score && result.Entry.ID > after.ID)
+		})
+	}
+
+	end := min(start+size, len(results))
+	if end == len(results) {
+		return results[start:end], "", nil
+	}
+
+	last := results[end-1]
+	data, _ := json.Marshal(cursor{Query: key, Score: last.Score, ID: last.Entry.ID})
+
+	return results[start:end], base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func queryKey(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+)
+
+// results returns a result for each of ids, all with score.
+func results(score int, ids ...int64) []search.Result {
+	var all []search.Result
+	for _, id := range ids {
+		all = append(all, search.Result{Entry: model.Entry{ID: id}, Score: score})
+	}
+
+	return all
+}
+
+func ids(results []search.Result) []int64 {
+	var all []int64
+	for _, result := range results {
+		all = append(all, result.Entry.ID)
+	}
+
+	return all
+}
+
+func TestPageAcrossChanges(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// then is the result set the second page is read from.
+		then []search.Result
+		want []int64
+	}{
+		{name: "unchanged", then: results(0, 1, 2, 3, 4, 5), want: []int64{3, 4}},
+		// Entries added on the first page don't push its last ones onto
+		// the second.
+		{name: "added before", then: append(results(0, 1, 2, 3, 4, 5), results(5, 9)...), want: []int64{3, 4}},
+		{name: "added after", then: results(0, 1, 2, 3, 4, 5, 6), want: []int64{3, 4}},
+		// Entries removed from the first page don't make the second skip
+		// any.
+		{name: "removed before", then: results(0, 3, 4, 5), want: []int64{3, 4}},
+		{name: "last removed", then: results(0, 1, 3, 4, 5), want: []int64{3, 4}},
+		{name: "removed after", then: results(0, 1, 2, 5), want: []int64{5}},
+		{name: "all removed", then: nil, want: nil},
+	} {
+		first, token, err := page(results(0, 1, 2, 3, 4, 5), 2, "", "ali")
+		if err != nil || !slices.Equal(ids(first), []int64{1, 2}) || token == "" {
+			t.Fatalf("first page: got %v, %q, %v", ids(first), token, err)
+		}
+
+		second, _, err := page(test.then, 2, token, "ali")
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		if got := ids(second); !slices.Equal(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPageOrdersByScore(t *testing.T) {
+	all := append(results(1, 4, 2), results(3, 5, 1)...)
+
+	var got []int64
+	token := ""
+	for {
+		one, next, err := page(all, 1, token, "ali")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, ids(one)...)
+		if next == "" {
+			break
+		}
+
+		token = next
+	}
+
+	if want := []int64{1, 5, 2, 4}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPageInvalidCursor(t *testing.T) {
+	_, token, _ := page(results(0, 1, 2, 3), 1, "", "ali")
+
+	for _, test := range []struct {
+		token, query, message string
+	}{
+		{token: "not a cursor", query: "ali", message: "invalid cursor"},
+		{token: token, query: "sara", message: "the cursor belongs to another query"},
+	} {
+		if _, _, err := page(results(0, 1, 2, 3), 1, test.token, test.query); err == nil || err.Error() != test.message {
+			t.Errorf("%q for %q: got %v, want %q", test.token, test.query, err, test.message)
+		}
+	}
+}
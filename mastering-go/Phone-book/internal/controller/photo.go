@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// photoCommand handles "photo set <id> <file>" and "photo get <id> [file]".
+// Without a file, get writes the image to standard output.
+func photoCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	photos, ok := store.(storage.Photos)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("photos").Message))
+	}
+
+	if len(arguments) < 4 {
+		return usageError("usage: photo set <id> <file> or photo get <id> [file]")
+	}
+
+	id, err := strconv.ParseInt(arguments[3], 10, 64)
+	if err != nil {
+		return errors.New(i18n.T("invalid id %q", arguments[3]))
+	}
+
+	switch {
+	case arguments[2] == "set" && len(arguments) == 5:
+		photo, err := os.ReadFile(arguments[4])
+		if err != nil {
+			return err
+		}
+
+		if appErr := photos.SetPhoto(ctx, id, photo); appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		fmt.Println(i18n.T("photo saved"))
+
+	case arguments[2] == "get" && len(arguments) <= 5:
+		photo, appErr := photos.Photo(ctx, id)
+		if appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		if len(arguments) == 4 {
+			os.Stdout.Write(photo)
+			return nil
+		}
+
+		if err := os.WriteFile(arguments[4], photo, 0644); err != nil {
+			return err
+		}
+
+	default:
+		return usageError("usage: photo set <id> <file> or photo get <id> [file]")
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// privacyCommand handles "privacy export <id>" and "privacy erase <id>",
+// the tools for requests from a person about their own data.
+//
+// The phone book keeps a person's entry, its photo, call log and history,
+// and, for their number, the blocklist and spam reports. export writes all
+// of it as JSON; erase deletes all of it and logs that it did, without the personal
+// data. The number's blocklist entry and spam reports are kept when another
+// entry still has the same number, such as a shared office line. Backends
+// that keep a history, like the event log, have the person's events redacted
+// too, and erase is refused on those that cannot. Backups made with export,
+// dump or by copying the data files have to be handled separately.
+func privacyCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	if len(arguments) != 4 || (arguments[2] != "export" && arguments[2] != "erase") {
+		return usageError("usage: privacy export <id> or privacy erase <id>")
+	}
+
+	id, err := strconv.ParseInt(arguments[3], 10, 64)
+	if err != nil {
+		return errors.New(i18n.T("invalid id %q", arguments[3]))
+	}
+
+	data, shared, appErr := personalData(ctx, store, id)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	if arguments[2] == "export" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(data)
+		return nil
+	}
+
+	if appErr := erasePerson(ctx, store, data, shared); appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	log.Printf("privacy: erased entry %d", id)
+	fmt.Println(i18n.T("erased entry %d", id))
+	if shared {
+		fmt.Println(i18n.T("its number is shared with other entries, its blocklist entry and spam reports were kept"))
+	}
+
+	return nil
+}
+
+// personalData gathers what the phone book keeps about the entry with id.
+// shared tells whether other entries have the same number.
+func personalData(ctx context.Context, store storage.Storage, id int64) (data *model.PersonalData, shared bool, appErr *model.PhoeBookError) {
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return nil, false, appErr
+	}
+
+	for _, entry := range entries {
+		if entry.ID == id {
+			data = &model.PersonalData{Entry: entry, SpamReports: []model.SpamReport{}, Calls: []model.Call{}, Links: []model.Link{}}
+		}
+	}
+
+	if data == nil {
+		return nil, false, &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+	}
+
+	number := data.Entry.PhoneNumber.Canonical()
+	for _, entry := range entries {
+		if entry.ID != id && entry.PhoneNumber.Equal(number) {
+			shared = true
+		}
+	}
+
+	if photos, ok := store.(storage.Photos); ok && data.Entry.Photo != "" {
+		if data.Photo, appErr = photos.Photo(ctx, id); appErr != nil {
+			return nil, false, appErr
+		}
+	}
+
+	if callLog, ok := store.(storage.CallLog); ok {
+		calls, appErr := callLog.Calls(ctx, id)
+		if appErr != nil {
+			return nil, false, appErr
+		}
+
+		data.Calls = append(data.Calls, calls...)
+	}
+
+	if relationships, ok := store.(storage.Relationships); ok {
+		links, appErr := relationships.Links(ctx, id)
+		if appErr != nil {
+			return nil, false, appErr
+		}
+
+		data.Links = append(data.Links, links...)
+	}
+
+	if history, ok := store.(storage.History); ok {
+		events, appErr := history.Events(ctx, id)
+		if appErr != nil && appErr.StatusCode != http.StatusNotImplemented {
+			return nil, false, appErr
+		}
+
+		data.Events = events
+	}
+
+	if blocklist, ok := store.(storage.Blocklist); ok {
+		numbers, appErr := blocklist.Blocked(ctx)
+		if appErr != nil {
+			return nil, false, appErr
+		}
+
+		for _, blocked := range numbers {
+			if blocked == string(number) {
+				data.Blocked = true
+			}
+		}
+	}
+
+	if spamReports, ok := store.(storage.SpamReports); ok {
+		reports, appErr := spamReports.SpamReports(ctx, string(number))
+		if appErr != nil {
+			return nil, false, appErr
+		}
+
+		data.SpamReports = append(data.SpamReports, reports...)
+	}
+
+	return data, shared, nil
+}
+
+// erasePerson deletes the entry of data with its photo, calls and links and,
+// unless the number is shared, what is kept about the number, and then takes
+// all of it out of the history of the book. Nothing is deleted from a book
+// with a history it cannot erase.
+func erasePerson(ctx context.Context, store storage.Storage, data *model.PersonalData, shared bool) *model.PhoeBookError {
+	eraser, ok := store.(storage.HistoryEraser)
+	if history, isHistory := store.(storage.History); !ok && isHistory {
+		// Wrappers are History whatever they wrap, only the events tell.
+		if _, appErr := history.Events(ctx, data.Entry.ID); appErr == nil {
+			return storage.Unsupported("erasing the history")
+		} else if appErr.StatusCode != http.StatusNotImplemented {
+			return appErr
+		}
+	}
+
+	if appErr := store.Delete(ctx, data.Entry.ID); appErr != nil {
+		return appErr
+	}
+
+	if len(data.Calls) > 0 {
+		if appErr := store.(storage.CallLog).EraseCalls(ctx, data.Entry.ID); appErr != nil {
+			return appErr
+		}
+	}
+
+	for _, link := range data.Links {
+		if appErr := store.(storage.Relationships).Unlink(ctx, link.From, link.To); appErr != nil {
+			return appErr
+		}
+	}
+
+	var number string
+	if !shared {
+		number = string(data.Entry.PhoneNumber.Canonical())
+		if appErr := eraseNumber(ctx, store, data, number); appErr != nil {
+			return appErr
+		}
+	}
+
+	if eraser == nil {
+		return nil
+	}
+
+	return eraser.EraseHistory(ctx, data.Entry.ID, number)
+}
+
+// eraseNumber unblocks number and deletes its spam reports.
+func eraseNumber(ctx context.Context, store storage.Storage, data *model.PersonalData, number string) *model.PhoeBookError {
+	if blocklist, ok := store.(storage.Blocklist); ok && data.Blocked {
+		if appErr := blocklist.Unblock(ctx, number); appErr != nil {
+			return appErr
+		}
+	}
+
+	if len(data.SpamReports) == 0 {
+		return nil
+	}
+
+	eraser, ok := store.(storage.SpamEraser)
+	if !ok {
+		return storage.Unsupported("erasing spam reports")
+	}
+
+	return eraser.EraseSpamReports(ctx, number)
+}
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/retention"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// purgeCommand handles "purge [--older-than AGE] [--dry-run] [--force]". It
+// deletes the entries nobody modified for AGE, by default the purge_after of
+// the retention section of the config file. --dry-run lists them instead.
+// Purging more of the book than the destructive threshold needs --force,
+// see checkDestructive.
+func purgeCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	defaultAge := ""
+	if cfg, err := config.Load(); err == nil && cfg.Retention != nil {
+		defaultAge = cfg.Retention.PurgeAfter
+	}
+
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	olderThan := flags.String("older-than", defaultAge, i18n.T("purge entries not modified for this long, e.g. 3y, 18m or 90d"))
+	dryRun := flags.Bool("dry-run", false, i18n.T("only list what would be purged"))
+	force := flags.Bool("force", false, i18n.T("purge even more of the book than the destructive threshold allows"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || *olderThan == "" {
+		return usageError("usage: purge [--older-than AGE] [--dry-run] [--force], or set retention.purge_after in the config file")
+	}
+
+	maxAge, err := retention.ParseAge(*olderThan)
+	if err != nil {
+		return err
+	}
+
+	// What would be purged is worked out first, to refuse before anything is
+	// deleted.
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	preview, appErr := retention.Purge(ctx, store, maxAge, true)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	tooMany := checkDestructive("purge", len(preview.Expired), len(entries), *force)
+	if tooMany != nil && !*dryRun {
+		return tooMany
+	}
+
+	report := preview
+	if !*dryRun {
+		report, appErr = retention.Purge(ctx, store, maxAge, false)
+	}
+
+	if appErr != nil {
+		if report.Purged > 0 {
+			fmt.Println(i18n.T("purged %d entries before the failure", report.Purged))
+		}
+
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	if *dryRun {
+		if len(report.Expired) > 0 {
+			output.Table(os.Stdout, report.Expired, output.Options{})
+		}
+
+		fmt.Println(i18n.T("%d entries not modified for %s would be purged", len(report.Expired), *olderThan))
+		if tooMany != nil {
+			fmt.Println(tooMany)
+		}
+	} else {
+		fmt.Println(i18n.T("purged %d entries not modified for %s", report.Purged, *olderThan))
+	}
+
+	if report.Unknown > 0 {
+		fmt.Println(i18n.T("kept %d entries whose last change is not known", report.Unknown))
+	}
+
+	return nil
+}
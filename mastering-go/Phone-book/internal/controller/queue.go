@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/offline"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// queueCommand handles "queue status", which lists the changes waiting for
+// an unreachable backend and those it refused when they were replayed, and
+// "queue flush", which replays them now rather than with the next command.
+func queueCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	if len(arguments) != 3 || (arguments[2] != "status" && arguments[2] != "flush") {
+		return usageError("usage: queue status|flush")
+	}
+
+	queue, ok := store.(*offline.Store)
+	if !ok {
+		return errors.New(i18n.T("only books on a remote backend like postgres queue changes"))
+	}
+
+	if arguments[2] == "flush" {
+		applied, rejected, appErr := queue.Flush(ctx)
+		if applied > 0 || rejected > 0 || appErr == nil {
+			fmt.Println(i18n.T("replayed %d changes, %d refused by the backend", applied, rejected))
+		}
+
+		if appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+	}
+
+	queued, rejected, err := queue.Pending()
+	if err != nil {
+		return errors.New(i18n.T("cannot read %s: %v", queue.Path(), err))
+	}
+
+	if len(queued) == 0 {
+		fmt.Println(i18n.T("no changes are queued"))
+	} else {
+		fmt.Println(i18n.T("%d changes wait for the backend:", len(queued)))
+	}
+
+	for _, change := range queued {
+		fmt.Printf("%s\t%s\n", change.QueuedAt.Local().Format(time.DateTime), change)
+	}
+
+	if len(rejected) > 0 {
+		fmt.Println(i18n.T("%d changes were refused by the backend when they were replayed, they are kept in %s:", len(rejected), queue.Path()+".rejected"))
+		for _, change := range rejected {
+			fmt.Printf("%s\t%s\t%s\n", change.QueuedAt.Local().Format(time.DateTime), change, change.Error)
+		}
+	}
+
+	return nil
+}
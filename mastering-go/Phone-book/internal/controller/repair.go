@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/csvfile"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+)
+
+// repairCommand handles "repair <file> [output]". It salvages what it can
+// from a damaged CSV data file into a new file, data.repaired.csv for
+// data.csv by default, and reports the lines it had to skip. The damaged
+// file is left alone.
+func repairCommand(arguments []string) error {
+	if len(arguments) != 3 && len(arguments) != 4 {
+		return usageError("usage: repair <file> [output]")
+	}
+
+	path := arguments[2]
+	output := repairedPath(path)
+	if len(arguments) == 4 {
+		output = arguments[3]
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	entries, skipped := csvfile.Repair(file)
+	file.Close()
+
+	for _, line := range skipped {
+		fmt.Println(i18n.T("line %d: %s", line.Line, line.Reason))
+	}
+
+	if err := csvfile.WriteFile(output, entries); err != nil {
+		return errors.New(i18n.T("cannot write %s: %v", output, err))
+	}
+
+	fmt.Println(i18n.T("salvaged %d entries into %s, %d lines needed attention", len(entries), output, len(skipped)))
+
+	return nil
+}
+
+// repairedPath names the repaired copy of path: book.csv becomes
+// book.repaired.csv and book.csv.gz becomes book.repaired.csv.gz.
+func repairedPath(path string) string {
+	for _, ext := range []string{".csv.gz", ".csv"} {
+		if base, ok := strings.CutSuffix(path, ext); ok {
+			return base + ".repaired" + ext
+		}
+	}
+
+	return path + ".repaired"
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/report"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/retention"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// reportCommand handles "report [--since AGE] [--format md|html] [--output
+// file]", which writes a digest of the changes made to the book over the
+// last AGE, a week by default, for the people sharing it.
+func reportCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	since := flags.String("since", "7d", i18n.T("report on this long back, e.g. 7d or 1m"))
+	format := flags.String("format", "md", i18n.T("report format, md or html"))
+	outputPath := flags.String("output", "", i18n.T("write the report to this file instead of the standard output"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || !slices.Contains(report.Formats, *format) {
+		return usageError("usage: report [--since AGE] [--format md|html] [--output file]")
+	}
+
+	period, err := retention.ParseAge(*since)
+	if err != nil {
+		return err
+	}
+
+	digest, appErr := report.Build(ctx, store, period, time.Now())
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputPath != "" {
+		file, err := os.OpenFile(*outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+		}
+
+		defer file.Close()
+		w = file
+	}
+
+	if err := report.Write(w, *format, digest); err != nil {
+		return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+	}
+
+	switch {
+	case *outputPath == "":
+	case digest.History:
+		fmt.Println(i18n.T("wrote the report of %d added, %d edited and %d deleted entries", len(digest.Added), len(digest.Edited), len(digest.Deleted)))
+	default:
+		fmt.Println(i18n.T("wrote the report of %d added and %d edited entries", len(digest.Added), len(digest.Edited)))
+	}
+
+	return nil
+}
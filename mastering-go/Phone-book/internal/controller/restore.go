@@ -0,0 +1,191 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// restoreLayouts are the ways --at can be written, in local time unless
+// the time zone is given.
+var restoreLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", time.DateOnly}
+
+// restoreCommand handles "restore --at TIME [--output file | --in-place]
+// [--backups pattern]": it reconstructs the entries as they were at TIME and
+// writes them to a new data file, by default restored-<TIME>.csv, as JSON
+// when it ends in .json.
+//
+// Books keeping their history, like the events backend, are replayed up to
+// TIME, and --in-place takes the book itself back to then with an undo.
+// Other books are restored from the newest backup written no later than
+// TIME: the files matching pattern, by default the outputs of the backup
+// jobs of the config file.
+func restoreCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	atFlag := flags.String("at", "", i18n.T("the moment to restore the book as it was at, e.g. 2024-01-15T10:00"))
+	outputPath := flags.String("output", "", i18n.T("write the restored book to this new file, by default restored-<time>.csv"))
+	inPlace := flags.Bool("in-place", false, i18n.T("take the book itself back, on books keeping their history"))
+	backups := flags.String("backups", "", i18n.T("backup files to restore from, e.g. '/srv/backups/book-*.csv', by default the outputs of the backup jobs"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	at, ok := parseRestoreTime(*atFlag)
+	if flags.NArg() != 0 || !ok || (*inPlace && (*outputPath != "" || *backups != "")) {
+		return usageError("usage: restore --at TIME [--output file | --in-place] [--backups pattern]")
+	}
+
+	history, hasHistory := store.(storage.History)
+	if hasHistory && *backups == "" {
+		if _, appErr := history.Events(ctx, 0); appErr != nil && appErr.StatusCode == http.StatusNotImplemented {
+			hasHistory = false
+		}
+	}
+
+	if *inPlace && !hasHistory {
+		return errors.New(i18n.T("only books keeping their history, like the events backend, can be restored in place"))
+	}
+
+	var entries []model.Entry
+	if hasHistory && *backups == "" {
+		seq, appErr := eventAt(ctx, history, at)
+		if appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		if *inPlace {
+			events, appErr := history.Revert(ctx, seq, false)
+			if appErr != nil {
+				return errors.New(i18n.T(appErr.Message))
+			}
+
+			fmt.Println(i18n.T("made %d changes to take the book back to how it was at %s", len(events), at.Format(time.DateTime)))
+			return nil
+		}
+
+		if entries, appErr = history.EntriesAt(ctx, seq); appErr != nil {
+			return errors.New(i18n.T(appErr.Message))
+		}
+
+		fmt.Println(i18n.T("replayed the history up to event %d", seq))
+	} else {
+		backup, taken, err := backupAt(*backups, at)
+		if err != nil {
+			return err
+		}
+
+		if entries, _, err = readFile(backup); err != nil {
+			return errors.New(i18n.T("cannot read %s: %v", backup, err))
+		}
+
+		fmt.Println(i18n.T("restoring from the backup %s taken at %s", backup, taken.Format(time.DateTime)))
+	}
+
+	if *outputPath == "" {
+		*outputPath = "restored-" + at.Format("20060102-1504") + ".csv"
+	}
+
+	format := "csv"
+	if strings.EqualFold(filepath.Ext(*outputPath), ".json") {
+		format = "json"
+	}
+
+	file, err := os.OpenFile(*outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+	}
+
+	defer file.Close()
+
+	if err := writeEntries(file, format, entries, nil, nil); err != nil {
+		return errors.New(i18n.T("cannot write %s: %v", *outputPath, err))
+	}
+
+	fmt.Println(i18n.T("restored %d entries as they were at %s to %s", len(entries), at.Format(time.DateTime), *outputPath))
+
+	return nil
+}
+
+func parseRestoreTime(value string) (time.Time, bool) {
+	for _, layout := range restoreLayouts {
+		if at, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return at, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// eventAt returns the last event of history that happened no later than at,
+// 0 when the book had none yet.
+func eventAt(ctx context.Context, history storage.History, at time.Time) (int64, *model.PhoeBookError) {
+	events, appErr := history.Events(ctx, 0)
+	if appErr != nil {
+		return 0, appErr
+	}
+
+	var seq int64
+	for _, event := range events {
+		if event.At.After(at) {
+			break
+		}
+
+		seq = event.Seq
+	}
+
+	return seq, nil
+}
+
+// backupAt returns the newest of the backups matching pattern, or the
+// outputs of the backup jobs, written no later than at, and when it was.
+func backupAt(pattern string, at time.Time) (string, time.Time, error) {
+	var patterns []string
+	if pattern != "" {
+		patterns = append(patterns, pattern)
+	} else if cfg, err := config.Load(); err == nil {
+		for _, job := range cfg.Jobs {
+			if job.Task == "backup" && job.Output != "" && job.Book == "" {
+				patterns = append(patterns, strings.NewReplacer("{date}", "*", "{time}", "*").Replace(job.Output))
+			}
+		}
+	}
+
+	if len(patterns) == 0 {
+		return "", time.Time{}, fmt.Errorf("%s", i18n.T("the book keeps no history and there are no backup jobs, give the backups with --backups"))
+	}
+
+	var newest string
+	var taken time.Time
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() || info.ModTime().After(at) || !info.ModTime().After(taken) {
+				continue
+			}
+
+			newest, taken = match, info.ModTime()
+		}
+	}
+
+	if newest == "" {
+		return "", time.Time{}, fmt.Errorf("%s", i18n.T("there is no backup from before %s", at.Format(time.DateTime)))
+	}
+
+	return newest, taken, nil
+}
@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/template"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/filter"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/output"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// searchEntries ranks the entries matching term. When the backend has an
+// index only the candidates it returns are ranked; if none of them match,
+// the whole book is scanned so misspelled and transliterated terms are
+// still found. It also returns the entries it ranked. Only archived entries
+// are searched when archived is set, and only the others when not.
+func searchEntries(ctx context.Context, store storage.Storage, term string, view archiveView) ([]search.Result, []model.Entry, *model.PhoeBookError) {
+	if idx, ok := store.(storage.Index); ok {
+		candidates, ok, appErr := idx.Candidates(ctx, term)
+		if appErr != nil {
+			return nil, nil, appErr
+		}
+
+		if ok {
+			candidates = visible(candidates, view)
+			if results := search.Search(candidates, term); len(results) > 0 {
+				return results, candidates, nil
+			}
+		}
+	}
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return nil, nil, appErr
+	}
+
+	entries = visible(entries, view)
+
+	return search.Search(entries, term), entries, nil
+}
+
+// numberCandidates returns the entries that can own number, asking the
+// backend's index when it has one instead of listing the whole book.
+func numberCandidates(ctx context.Context, store storage.Storage, number string) ([]model.Entry, *model.PhoeBookError) {
+	if idx, ok := store.(storage.Index); ok {
+		candidates, ok, appErr := idx.Candidates(ctx, number)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		if ok {
+			return candidates, nil
+		}
+	}
+
+	return store.List(ctx)
+}
+
+// maxParallelBooks is how many books a search of several reads at the same
+// time.
+const maxParallelBooks = 4
+
+// searchBooks searches every book of books for term like searchEntries,
+// at most maxParallelBooks at the same time, and merges the results best
+// first, those of the same score in the order of the books' names. Without
+// a term every entry in view is a hit.
+func searchBooks(ctx context.Context, books map[string]storage.Storage, term string, view archiveView) ([]model.SearchHit, *model.PhoeBookError) {
+	names := make([]string, 0, len(books))
+	for name := range books {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	found := make([][]search.Result, len(names))
+	errs := make([]*model.PhoeBookError, len(names))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxParallelBooks)
+	for i, name := range names {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, store storage.Storage) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			if term != "" {
+				found[i], _, errs[i] = searchEntries(ctx, store, term, view)
+				return
+			}
+
+			entries, appErr := store.List(ctx)
+			for _, entry := range visible(entries, view) {
+				found[i] = append(found[i], search.Result{Entry: entry})
+			}
+
+			errs[i] = appErr
+		}(i, books[name])
+	}
+
+	wg.Wait()
+
+	var hits []model.SearchHit
+	for i, name := range names {
+		if errs[i] != nil {
+			return nil, &model.PhoeBookError{Message: i18n.T("book %s: %s", name, i18n.T(errs[i].Message)), StatusCode: errs[i].StatusCode}
+		}
+
+		for _, result := range found[i] {
+			hits = append(hits, model.SearchHit{Book: name, Score: result.Score, Fields: result.Fields, Entry: result.Entry})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+
+	return hits, nil
+}
+
+// searchBooksCommand handles search --books, which searches the books of
+// the config file named in list instead of the one the command runs on and
+// shows the book of every hit.
+func searchBooksCommand(ctx context.Context, list, term string, view archiveView, expr filter.Expr, limit int, tmpl *template.Template) error {
+	books, err := openBooks(ctx, list)
+	for _, store := range books {
+		defer store.Close()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	hits, appErr := searchBooks(ctx, books, term, view)
+	if appErr != nil {
+		return errors.New(appErr.Message)
+	}
+
+	if expr != nil {
+		var matched []model.SearchHit
+		for _, hit := range hits {
+			if expr.Match(hit.Entry) {
+				matched = append(matched, hit)
+			}
+		}
+
+		hits = matched
+	}
+
+	if len(hits) == 0 {
+		if term == "" {
+			fmt.Println(i18n.T("there is no record matching the given fields in any of the books"))
+		} else {
+			fmt.Println(i18n.T("there is no record matching %q in any of the books", term))
+		}
+
+		return nil
+	}
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	if tmpl != nil {
+		entries := make([]model.Entry, len(hits))
+		for i, hit := range hits {
+			entries[i] = hit.Entry
+		}
+
+		return output.Template(os.Stdout, entries, tmpl)
+	}
+
+	output.Hits(os.Stdout, hits, term, output.ColorEnabled(os.Stdout))
+
+	return nil
+}
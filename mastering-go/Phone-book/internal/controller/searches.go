@@ -0,0 +1,268 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/filter"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+)
+
+// searchHistorySize is how many searches the history keeps, the oldest
+// going first.
+const searchHistorySize = 50
+
+// pastSearch is a search in the history: the arguments search was given,
+// flags and all, so it can be run again as it was.
+type pastSearch struct {
+	Args []string  `json:"args"`
+	At   time.Time `json:"at"`
+}
+
+// searchHistoryPath is where the searches made are kept, in the user's cache
+// directory as they are only a convenience.
+func searchHistoryPath() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "phonebook", "searches.history.json")
+}
+
+// savedSearchesPath is where the saved searches are kept, next to the config
+// file as they are set up like it and shouldn't go when the cache is
+// cleared.
+func savedSearchesPath() string {
+	path := config.Path()
+	if path == "" {
+		return ""
+	}
+
+	return filepath.Join(filepath.Dir(path), "searches.json")
+}
+
+func loadSearchHistory() ([]pastSearch, error) {
+	var history []pastSearch
+	if err := readJSON(searchHistoryPath(), &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// recordSearch adds the search run with args to the history. The history is
+// only a convenience, so a search goes ahead when it cannot be written.
+func recordSearch(args []string) {
+	history, _ := loadSearchHistory()
+	if n := len(history); n > 0 && slices.Equal(history[n-1].Args, args) {
+		history = history[:n-1]
+	}
+
+	history = append(history, pastSearch{Args: args, At: time.Now()})
+	if len(history) > searchHistorySize {
+		history = history[len(history)-searchHistorySize:]
+	}
+
+	writeJSON(searchHistoryPath(), history)
+}
+
+func loadSavedSearches() (map[string]string, error) {
+	saved := make(map[string]string)
+	if err := readJSON(savedSearchesPath(), &saved); err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// savedFilter returns the filter expression saved as name.
+func savedFilter(name string) (filter.Expr, error) {
+	saved, err := loadSavedSearches()
+	if err != nil {
+		return nil, errors.New(i18n.T("cannot read the saved searches: %v", err))
+	}
+
+	where, ok := saved[name]
+	if !ok {
+		return nil, errors.New(i18n.T("there is no saved search %q, see search saved", name))
+	}
+
+	return filter.Parse(where)
+}
+
+// savedSearchCommand handles "search save NAME EXPR", "search saved" and
+// "search forget NAME", and reports whether arguments were one of them and
+// why it failed. A search for one of these words needs "search -- save".
+func savedSearchCommand(arguments []string) (bool, error) {
+	if len(arguments) < 3 {
+		return false, nil
+	}
+
+	switch arguments[2] {
+	case "save":
+		if len(arguments) != 5 {
+			return true, usageError("usage: search save NAME EXPR")
+		}
+
+		name, where := arguments[3], arguments[4]
+		if _, err := filter.Parse(where); err != nil {
+			return true, err
+		}
+
+		if err := updateSavedSearches(func(saved map[string]string) { saved[name] = where }); err != nil {
+			return true, err
+		}
+
+		fmt.Println(i18n.T("saved search %q, use it with --saved %s", name, name))
+
+	case "saved":
+		saved, err := loadSavedSearches()
+		if err != nil {
+			return true, errors.New(i18n.T("cannot read the saved searches: %v", err))
+		}
+
+		if len(saved) == 0 {
+			fmt.Println(i18n.T("there are no saved searches, add one with search save NAME EXPR"))
+			return true, nil
+		}
+
+		names := make([]string, 0, len(saved))
+		for name := range saved {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("%s\t%s\n", name, saved[name])
+		}
+
+	case "forget":
+		if len(arguments) != 4 {
+			return true, usageError("usage: search forget NAME")
+		}
+
+		name := arguments[3]
+		found := false
+		err := updateSavedSearches(func(saved map[string]string) {
+			_, found = saved[name]
+			delete(saved, name)
+		})
+
+		if err != nil {
+			return true, err
+		}
+
+		if !found {
+			return true, errors.New(i18n.T("there is no saved search %q, see search saved", name))
+		}
+
+		fmt.Println(i18n.T("forgot the saved search %q", name))
+
+	default:
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// updateSavedSearches applies change to the saved searches and writes them
+// back.
+func updateSavedSearches(change func(saved map[string]string)) error {
+	saved, err := loadSavedSearches()
+	if err != nil {
+		return errors.New(i18n.T("cannot read the saved searches: %v", err))
+	}
+
+	change(saved)
+	if err := writeJSON(savedSearchesPath(), saved); err != nil {
+		return errors.New(i18n.T("cannot write the saved searches: %v", err))
+	}
+
+	return nil
+}
+
+// printSearchHistory prints the searches in the history, the latest first.
+func printSearchHistory() error {
+	history, err := loadSearchHistory()
+	if err != nil {
+		return errors.New(i18n.T("cannot read the search history: %v", err))
+	}
+
+	if len(history) == 0 {
+		fmt.Println(i18n.T("there are no searches yet"))
+		return nil
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		fmt.Printf("%s  search %s\n", history[i].At.Local().Format(time.DateTime), quoteArgs(history[i].Args))
+	}
+
+	return nil
+}
+
+// quoteArgs joins args as they would be typed, quoting those with spaces or
+// quotes in them.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t'\"") {
+			quoted[i] = strconv.Quote(arg)
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// readJSON reads the JSON file at path into v, leaving v as it is when the
+// file doesn't exist yet.
+func readJSON(path string, v any) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON replaces the file at path with v as JSON.
+func writeJSON(path string, v any) error {
+	if path == "" {
+		return errors.New(i18n.T("there is no directory to keep it in"))
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/phone"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/spam"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// spamCommand handles "spam report <number> [reason]" and
+// "spam show <number>".
+func spamCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	spamReports, ok := store.(storage.SpamReports)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("spam reports").Message))
+	}
+
+	if len(arguments) < 4 || arguments[3] == "" {
+		return usageError("usage: spam report <number> [reason] or spam show <number>")
+	}
+
+	number := phone.Canonical(arguments[3])
+
+	switch arguments[2] {
+	case "report":
+		report := model.SpamReport{
+			Number:     model.PhoneNumber(number),
+			Reason:     strings.Join(arguments[4:], " "),
+			Reporter:   os.Getenv("USER"),
+			ReportedAt: time.Now().UTC(),
+		}
+
+		if err := spamReports.ReportSpam(ctx, report); err != nil {
+			return errors.New(i18n.T(err.Message))
+		}
+
+		fmt.Println(i18n.T("%s is reported as spam", number))
+
+	case "show":
+		reports, err := spamReports.SpamReports(ctx, number)
+		if err != nil {
+			return errors.New(i18n.T(err.Message))
+		}
+
+		fmt.Println(i18n.T("spam score: %d", spam.Score(reports)))
+		for _, report := range reports {
+			fmt.Printf("%s\t%s\t%s\n", report.ReportedAt.Format(time.DateTime), report.Reporter, report.Reason)
+		}
+
+	default:
+		return usageError("usage: spam report <number> [reason] or spam show <number>")
+	}
+
+	return nil
+}
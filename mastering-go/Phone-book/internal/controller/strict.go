@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/dedupe"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+type strictKey struct{}
+
+// WithStrict returns a context on which commands refuse to go on with what
+// they would only warn about otherwise, like a number that cannot be
+// normalized, so scripts fail instead of filling the book with dirty data.
+// refused is set when a command did.
+func WithStrict(ctx context.Context, refused *bool) context.Context {
+	return context.WithValue(ctx, strictKey{}, refused)
+}
+
+// strict reports whether ctx comes from WithStrict.
+func strict(ctx context.Context) bool {
+	_, ok := ctx.Value(strictKey{}).(*bool)
+	return ok
+}
+
+// warn tells on the standard error about something odd in what a command
+// was given, and reports whether the command may go on anyway. On a context
+// of WithStrict it is an error instead and the command has to stop without
+// changing anything.
+func warn(ctx context.Context, format string, args ...any) bool {
+	message := i18n.T(format, args...)
+	refused, strict := ctx.Value(strictKey{}).(*bool)
+	if !strict {
+		fmt.Fprintln(os.Stderr, i18n.T("warning: %s", message))
+		return true
+	}
+
+	*refused = true
+	fmt.Fprintln(os.Stderr, i18n.T("error: %s (--strict)", message))
+
+	return false
+}
+
+// warnSimilar warns about an entry about to be inserted that looks like one
+// the book has already.
+func warnSimilar(ctx context.Context, store storage.Storage, entry model.Entry) bool {
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return true
+	}
+
+	best, bestScore := model.Entry{}, 0.0
+	var bestReasons []string
+	for _, other := range entries {
+		if score, reasons := dedupe.Score(entry, other); score > bestScore {
+			best, bestScore, bestReasons = other, score, reasons
+		}
+	}
+
+	if bestScore < dedupe.DefaultMinScore {
+		return true
+	}
+
+	name := strings.TrimSpace(best.Name + " " + best.Surname)
+
+	return warn(ctx, "it looks like %s (id %d), who is already in the book: %s", name, best.ID, strings.Join(bestReasons, ", "))
+}
+
+// warnImport warns about the files of an import: the columns left out of
+// CSV files, the rows skipped for invalid fields, and the numbers that
+// cannot be normalized.
+func warnImport(ctx context.Context, files []*importFile, items []importItem) bool {
+	invalid := make(map[*importFile][]string)
+	for _, item := range items {
+		if !item.entry.PhoneNumber.Valid() {
+			invalid[item.file] = append(invalid[item.file], string(item.entry.PhoneNumber))
+		}
+	}
+
+	ok := true
+	for _, file := range files {
+		if len(file.unknown) > 0 {
+			ok = warn(ctx, "%s: unknown columns %s", file.path, strings.Join(file.unknown, ", ")) && ok
+		}
+
+		if len(file.invalid) > 0 {
+			ok = warn(ctx, "%s: skipped %d entries with invalid fields, the first because %s", file.path, len(file.invalid), file.invalid[0]) && ok
+		}
+
+		if numbers := invalid[file]; len(numbers) > 0 {
+			ok = warn(ctx, "%s: %d of the numbers cannot be normalized, like %q", file.path, len(numbers), numbers[0]) && ok
+		}
+	}
+
+	return ok
+}
@@ -0,0 +1,439 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/client"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// syncState is what sync remembers of the last sync with each peer, by the
+// peer's URL: which entries it paired up and the version each had then.
+// Knowing the versions tells which side changed an entry since, and
+// knowing the pairs tells an entry deleted on one side from one never sent
+// to it.
+type syncState struct {
+	Peers map[string][]syncPair `json:"peers"`
+}
+
+type syncPair struct {
+	Local        int64 `json:"local"`
+	Peer         int64 `json:"peer"`
+	LocalVersion int64 `json:"local_version"`
+	PeerVersion  int64 `json:"peer_version"`
+}
+
+// syncCounts sums up what a sync did, in each direction.
+type syncCounts struct {
+	sent, received, updatedPeer, updatedLocal, deletedPeer, deletedLocal, conflicts int
+}
+
+// SyncCommand handles "sync --peer <url> [--token T] [--prefer
+// newer|local|peer] [--dry-run]", which brings the book and the one served
+// at url to the same entries: what was added, edited or deleted on either
+// side since they last synced is done on the other. An entry edited on both
+// sides is a conflict, settled by --prefer: the later edit by default.
+// Before the first sync, entries with the same number are taken for the
+// same contact. statePath is where what the last sync with every peer paired
+// up is kept; photos, calls and the blocklist are not synced.
+func SyncCommand(ctx context.Context, store storage.Storage, statePath string, arguments []string) error {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	peerURL := flags.String("peer", "", i18n.T("URL of the phone book server to sync with, e.g. http://other-host:8001"))
+	token := flags.String("token", os.Getenv("PHONEBOOK_PEER_TOKEN"), i18n.T("bearer token of the peer, by default $PHONEBOOK_PEER_TOKEN"))
+	prefer := flags.String("prefer", "newer", i18n.T("which side wins an entry edited on both: newer, local or peer"))
+	dryRun := flags.Bool("dry-run", false, i18n.T("only print what would be done"))
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 || *peerURL == "" || (*prefer != "newer" && *prefer != "local" && *prefer != "peer") {
+		return usageError("usage: sync --peer <url> [--token T] [--prefer newer|local|peer] [--dry-run]")
+	}
+
+	updater, ok := store.(storage.Updater)
+	if !ok {
+		return errors.New(i18n.T(storage.Unsupported("editing entries").Message))
+	}
+
+	peer, err := client.New(*peerURL, *token)
+	if err != nil {
+		return errors.New(i18n.T(err.Error()))
+	}
+
+	state, err := readSyncState(statePath)
+	if err != nil {
+		return errors.New(i18n.T("cannot read %s: %v", statePath, err))
+	}
+
+	s := &syncer{store: store, updater: updater, peer: peer, prefer: *prefer, dryRun: *dryRun}
+	pairs, appErr := s.run(ctx, state.Peers[*peerURL])
+
+	// What was done before a failure is remembered all the same, so the
+	// next sync doesn't do it again.
+	var errs []error
+	if !*dryRun {
+		state.Peers[*peerURL] = pairs
+		if err := writeSyncState(statePath, state); err != nil {
+			errs = append(errs, errors.New(i18n.T("cannot save %s: %v", statePath, err)))
+		}
+	}
+
+	if appErr != nil {
+		errs = append(errs, errors.New(i18n.T(appErr.Message)))
+	}
+
+	c := s.counts
+	fmt.Println(i18n.T("sent %d new entries, %d edits and %d deletions; received %d new entries, %d edits and %d deletions; %d conflicts", c.sent, c.updatedPeer, c.deletedPeer, c.received, c.updatedLocal, c.deletedLocal, c.conflicts))
+
+	return errors.Join(errs...)
+}
+
+type syncer struct {
+	store   storage.Storage
+	updater storage.Updater
+	peer    *client.Client
+	prefer  string
+	dryRun  bool
+	counts  syncCounts
+}
+
+// run syncs the book with the peer, pairs being those of the last sync, and
+// returns the pairs to remember for the next.
+func (s *syncer) run(ctx context.Context, pairs []syncPair) ([]syncPair, *model.PhoeBookError) {
+	localEntries, appErr := s.store.List(ctx)
+	if appErr != nil {
+		return pairs, appErr
+	}
+
+	peerEntries, appErr := s.peer.List(ctx)
+	if appErr != nil {
+		return pairs, appErr
+	}
+
+	local := make(map[int64]model.Entry, len(localEntries))
+	for _, entry := range localEntries {
+		local[entry.ID] = entry
+	}
+
+	remote := make(map[int64]model.Entry, len(peerEntries))
+	for _, entry := range peerEntries {
+		remote[entry.ID] = entry
+	}
+
+	var kept []syncPair
+	pairedLocal, pairedPeer := make(map[int64]bool), make(map[int64]bool)
+	for i, pair := range pairs {
+		if ctx.Err() != nil {
+			return append(kept, pairs[i:]...), storage.ContextError(ctx)
+		}
+
+		l, inLocal := local[pair.Local]
+		p, inPeer := remote[pair.Peer]
+		pairedLocal[pair.Local], pairedPeer[pair.Peer] = true, true
+
+		pair, keep, appErr := s.syncPair(ctx, pair, l, inLocal, p, inPeer)
+		if appErr != nil {
+			return append(kept, pairs[i:]...), appErr
+		}
+
+		if keep {
+			kept = append(kept, pair)
+		}
+	}
+
+	// Entries new on either side, or from before the first sync, are paired
+	// up by number.
+	byNumber := make(map[model.PhoneNumber]model.Entry)
+	for _, entry := range peerEntries {
+		if !pairedPeer[entry.ID] {
+			byNumber[entry.PhoneNumber] = entry
+		}
+	}
+
+	for _, l := range localEntries {
+		if pairedLocal[l.ID] {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return kept, storage.ContextError(ctx)
+		}
+
+		var pair syncPair
+		var appErr *model.PhoeBookError
+		if p, ok := byNumber[l.PhoneNumber]; ok {
+			delete(byNumber, l.PhoneNumber)
+			pairedPeer[p.ID] = true
+			pair, appErr = s.settle(ctx, syncPair{Local: l.ID, Peer: p.ID}, l, p)
+		} else {
+			pair, appErr = s.send(ctx, l)
+		}
+
+		if appErr != nil {
+			return kept, appErr
+		}
+
+		kept = append(kept, pair)
+	}
+
+	for _, p := range peerEntries {
+		if pairedPeer[p.ID] {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			return kept, storage.ContextError(ctx)
+		}
+
+		pair, appErr := s.receive(ctx, p)
+		if appErr != nil {
+			return kept, appErr
+		}
+
+		kept = append(kept, pair)
+	}
+
+	return kept, nil
+}
+
+// syncPair syncs the entries of a pair from the last sync, l and p, either
+// of which may since have been deleted. keep is false when both are gone.
+func (s *syncer) syncPair(ctx context.Context, pair syncPair, l model.Entry, inLocal bool, p model.Entry, inPeer bool) (syncPair, bool, *model.PhoeBookError) {
+	switch {
+	case !inLocal && !inPeer:
+		return pair, false, nil
+
+	// An entry deleted on one side is deleted on the other, unless it was
+	// edited there since, which is kept and sent back.
+	case !inLocal && p.Version == pair.PeerVersion:
+		s.report("deleted on the peer: %s", p)
+		s.counts.deletedPeer++
+		if !s.dryRun {
+			if appErr := s.peer.DeleteVersion(ctx, p.ID, p.Version); appErr != nil {
+				return pair, true, appErr
+			}
+		}
+
+		return pair, false, nil
+
+	case !inLocal:
+		s.report("conflict: %s was deleted here and edited on the peer, keeping it", p)
+		s.counts.conflicts++
+		pair, appErr := s.receive(ctx, p)
+		return pair, true, appErr
+
+	case !inPeer && l.Version == pair.LocalVersion:
+		s.report("deleted here: %s", l)
+		s.counts.deletedLocal++
+		if !s.dryRun {
+			appErr := deleteVersion(ctx, s.store, l.ID, l.Version)
+			if appErr != nil && appErr.StatusCode == http.StatusNotImplemented {
+				appErr = s.store.Delete(ctx, l.ID)
+			}
+
+			if appErr != nil {
+				return pair, true, appErr
+			}
+		}
+
+		return pair, false, nil
+
+	case !inPeer:
+		s.report("conflict: %s was deleted on the peer and edited here, keeping it", l)
+		s.counts.conflicts++
+		pair, appErr := s.send(ctx, l)
+		return pair, true, appErr
+	}
+
+	localChanged, peerChanged := l.Version != pair.LocalVersion, p.Version != pair.PeerVersion
+	switch {
+	case localChanged && peerChanged:
+		pair, appErr := s.settle(ctx, pair, l, p)
+		return pair, true, appErr
+
+	case localChanged:
+		pair, appErr := s.updatePeer(ctx, pair, l, p)
+		return pair, true, appErr
+
+	case peerChanged:
+		pair, appErr := s.updateLocal(ctx, pair, l, p)
+		return pair, true, appErr
+	}
+
+	return pair, true, nil
+}
+
+// settle makes the entries l and p of pair alike when both changed, or
+// were paired up by number, taking the one --prefer says.
+func (s *syncer) settle(ctx context.Context, pair syncPair, l, p model.Entry) (syncPair, *model.PhoeBookError) {
+	if sameContent(l, p) {
+		pair.LocalVersion, pair.PeerVersion = l.Version, p.Version
+		return pair, nil
+	}
+
+	s.counts.conflicts++
+
+	keepLocal := s.prefer == "local"
+	if s.prefer == "newer" {
+		keepLocal = p.UpdatedAt == nil || (l.UpdatedAt != nil && !l.UpdatedAt.Before(*p.UpdatedAt))
+	}
+
+	if keepLocal {
+		s.report("conflict: %s differs on the peer, keeping the one here", l)
+		return s.updatePeer(ctx, pair, l, p)
+	}
+
+	s.report("conflict: %s differs on the peer, keeping the one there", p)
+	return s.updateLocal(ctx, pair, l, p)
+}
+
+// updatePeer gives p the fields of l.
+func (s *syncer) updatePeer(ctx context.Context, pair syncPair, l, p model.Entry) (syncPair, *model.PhoeBookError) {
+	pair.LocalVersion, pair.PeerVersion = l.Version, p.Version
+	if sameContent(l, p) {
+		return pair, nil
+	}
+
+	s.report("edited on the peer: %s", l)
+	s.counts.updatedPeer++
+	if s.dryRun {
+		return pair, nil
+	}
+
+	entry := withContent(p, l)
+	if appErr := s.peer.Update(ctx, &entry); appErr != nil {
+		return pair, appErr
+	}
+
+	pair.PeerVersion = entry.Version
+	return pair, nil
+}
+
+// updateLocal gives l the fields of p.
+func (s *syncer) updateLocal(ctx context.Context, pair syncPair, l, p model.Entry) (syncPair, *model.PhoeBookError) {
+	pair.LocalVersion, pair.PeerVersion = l.Version, p.Version
+	if sameContent(l, p) {
+		return pair, nil
+	}
+
+	s.report("edited here: %s", p)
+	s.counts.updatedLocal++
+	if s.dryRun {
+		return pair, nil
+	}
+
+	entry := withContent(l, p)
+	if appErr := s.updater.Update(ctx, &entry); appErr != nil {
+		return pair, appErr
+	}
+
+	pair.LocalVersion = entry.Version
+	return pair, nil
+}
+
+// send inserts l on the peer.
+func (s *syncer) send(ctx context.Context, l model.Entry) (syncPair, *model.PhoeBookError) {
+	s.report("sent to the peer: %s", l)
+	s.counts.sent++
+	if s.dryRun {
+		return syncPair{Local: l.ID, LocalVersion: l.Version}, nil
+	}
+
+	id, appErr := s.peer.Insert(ctx, &l)
+	if appErr != nil {
+		return syncPair{}, appErr
+	}
+
+	// Versions start at 1.
+	return syncPair{Local: l.ID, Peer: id, LocalVersion: l.Version, PeerVersion: 1}, nil
+}
+
+// receive inserts p here.
+func (s *syncer) receive(ctx context.Context, p model.Entry) (syncPair, *model.PhoeBookError) {
+	s.report("received from the peer: %s", p)
+	s.counts.received++
+	if s.dryRun {
+		return syncPair{Peer: p.ID, PeerVersion: p.Version}, nil
+	}
+
+	entry := withContent(model.Entry{}, p)
+	id, appErr := s.store.Insert(ctx, &entry)
+	if appErr != nil {
+		return syncPair{}, appErr
+	}
+
+	return syncPair{Local: id, Peer: p.ID, LocalVersion: 1, PeerVersion: p.Version}, nil
+}
+
+func (s *syncer) report(format string, entry model.Entry) {
+	fmt.Println(i18n.T(format, fmt.Sprintf("%s %s (%s)", entry.Name, entry.Surname, entry.PhoneNumber)))
+}
+
+// withContent returns target with the fields of source a sync copies, all
+// but its ID, version, photo and update time.
+func withContent(target, source model.Entry) model.Entry {
+	source.ID, source.Version, source.Photo, source.UpdatedAt = target.ID, target.Version, target.Photo, target.UpdatedAt
+	return source
+}
+
+// sameContent tells whether a and b have the same fields a sync copies.
+func sameContent(a, b model.Entry) bool {
+	archivedAlike := (a.ArchivedAt == nil) == (b.ArchivedAt == nil) && (a.ArchivedAt == nil || a.ArchivedAt.Unix() == b.ArchivedAt.Unix())
+	a, b = withContent(model.Entry{}, a), withContent(model.Entry{}, b)
+	a.ArchivedAt, b.ArchivedAt = nil, nil
+
+	return archivedAlike && a == b
+}
+
+// readSyncState reads the state at path, an empty one if there is none yet.
+func readSyncState(path string) (*syncState, error) {
+	state := &syncState{Peers: make(map[string][]syncPair)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	if state.Peers == nil {
+		state.Peers = make(map[string][]syncPair)
+	}
+
+	return state, nil
+}
+
+// writeSyncState replaces the state at path through a temporary file, so a
+// crash leaves either the old state or the new one.
+func writeSyncState(path string, state *syncState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
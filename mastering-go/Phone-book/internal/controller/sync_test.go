@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/client"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage/memory"
+)
+
+// book returns a book with Ali under ID 1, at version and with surname,
+// or an empty one if version is 0.
+func book(version int64, surname string) *memory.Storage {
+	if version == 0 {
+		return memory.New()
+	}
+
+	return memory.New(storage.Entry{ID: 1, Name: "Ali", Surname: surname, PhoneNumber: "+989121234567", Version: version})
+}
+
+func TestSyncPair(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// The versions the entry has on each side, 0 when it is deleted
+		// there. It was at version 1 on both at the last sync, surnamed
+		// Ahmadi, and its surname here or there is Karimi and Rezaei once
+		// edited.
+		local, peer int64
+		prefer      string
+		keep        bool
+		counts      syncCounts
+		// The surnames left on each side.
+		wantLocal, wantPeer string
+	}{
+		{name: "deleted on both", keep: false},
+		{name: "deleted here", local: 0, peer: 1, keep: false, counts: syncCounts{deletedPeer: 1}},
+		{name: "deleted here, edited there", local: 0, peer: 2, keep: true, counts: syncCounts{conflicts: 1, received: 1}, wantLocal: "Rezaei", wantPeer: "Rezaei"},
+		{name: "deleted there", local: 1, peer: 0, keep: false, counts: syncCounts{deletedLocal: 1}},
+		{name: "deleted there, edited here", local: 2, peer: 0, keep: true, counts: syncCounts{conflicts: 1, sent: 1}, wantLocal: "Karimi", wantPeer: "Karimi"},
+		{name: "unchanged", local: 1, peer: 1, keep: true, wantLocal: "Ahmadi", wantPeer: "Ahmadi"},
+		{name: "edited here", local: 2, peer: 1, keep: true, counts: syncCounts{updatedPeer: 1}, wantLocal: "Karimi", wantPeer: "Karimi"},
+		{name: "edited there", local: 1, peer: 2, keep: true, counts: syncCounts{updatedLocal: 1}, wantLocal: "Rezaei", wantPeer: "Rezaei"},
+		{name: "edited on both, local wins", local: 2, peer: 2, prefer: "local", keep: true, counts: syncCounts{conflicts: 1, updatedPeer: 1}, wantLocal: "Karimi", wantPeer: "Karimi"},
+		{name: "edited on both, peer wins", local: 2, peer: 2, prefer: "peer", keep: true, counts: syncCounts{conflicts: 1, updatedLocal: 1}, wantLocal: "Rezaei", wantPeer: "Rezaei"},
+	} {
+		ctx := context.Background()
+
+		edited := func(version int64, surname string) string {
+			if version > 1 {
+				return surname
+			}
+
+			return "Ahmadi"
+		}
+
+		local, remote := book(test.local, edited(test.local, "Karimi")), book(test.peer, edited(test.peer, "Rezaei"))
+		server := httptest.NewServer(Handler(remote))
+
+		peer, err := client.New(server.URL, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		l, inLocal := first(t, local)
+		p, inPeer := first(t, remote)
+
+		s := &syncer{store: local, updater: local, peer: peer, prefer: test.prefer}
+		_, keep, appErr := s.syncPair(ctx, syncPair{Local: 1, Peer: 1, LocalVersion: 1, PeerVersion: 1}, l, inLocal, p, inPeer)
+		server.Close()
+
+		if appErr != nil {
+			t.Errorf("%s: %v", test.name, appErr)
+			continue
+		}
+
+		if keep != test.keep {
+			t.Errorf("%s: got keep %v, want %v", test.name, keep, test.keep)
+		}
+
+		if s.counts != test.counts {
+			t.Errorf("%s: got %+v, want %+v", test.name, s.counts, test.counts)
+		}
+
+		if got, _ := first(t, local); got.Surname != test.wantLocal {
+			t.Errorf("%s: got %q here, want %q", test.name, got.Surname, test.wantLocal)
+		}
+
+		if got, _ := first(t, remote); got.Surname != test.wantPeer {
+			t.Errorf("%s: got %q on the peer, want %q", test.name, got.Surname, test.wantPeer)
+		}
+	}
+}
+
+// first returns the first entry of store and whether it has one, the books
+// of these tests having at most one.
+func first(t *testing.T, store storage.Storage) (storage.Entry, bool) {
+	t.Helper()
+
+	entries, appErr := store.List(context.Background())
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if len(entries) == 0 {
+		return storage.Entry{}, false
+	}
+
+	return entries[0], true
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/vcard"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// exportContactCommand handles "export-contact <id> [--out file.vcf]", which
+// writes the entry with id, with its photo and the entries related to it, as
+// a vCard to hand to someone else. Without --out it goes to the standard
+// output.
+func exportContactCommand(ctx context.Context, store storage.Storage, arguments []string) error {
+	usage := i18n.T("usage: export-contact <id> [--out file.vcf]")
+
+	flags := flag.NewFlagSet("export-contact", flag.ContinueOnError)
+	out := flags.String("out", "", i18n.T("write the vCard to this file instead of the standard output"))
+
+	// The id may come before the flags or after them.
+	if err := flags.Parse(arguments[2:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() == 0 {
+		return &UsageError{Message: usage}
+	}
+
+	idArgument := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return flagError(err)
+	}
+
+	if flags.NArg() != 0 {
+		return &UsageError{Message: usage}
+	}
+
+	id, err := strconv.ParseInt(idArgument, 10, 64)
+	if err != nil {
+		return errors.New(i18n.T("invalid id %q", idArgument))
+	}
+
+	entries, appErr := entriesByID(ctx, store)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	entry, ok := entries[id]
+	if !ok {
+		return errors.New(i18n.T("there is no record with given id"))
+	}
+
+	var photo []byte
+	if photos, ok := store.(storage.Photos); ok && entry.Photo != "" {
+		if photo, appErr = photos.Photo(ctx, id); appErr != nil && appErr.StatusCode != http.StatusNotFound {
+			return errors.New(i18n.T(appErr.Message))
+		}
+	}
+
+	related, appErr := relations(ctx, store, entry, entries)
+	if appErr != nil {
+		return errors.New(i18n.T(appErr.Message))
+	}
+
+	var w io.Writer = os.Stdout
+	name := "stdout"
+	if *out != "" {
+		file, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return errors.New(i18n.T("cannot write %s: %v", *out, err))
+		}
+
+		defer file.Close()
+		w, name = file, *out
+	}
+
+	if err := vcard.Write(w, entry, photo, related); err != nil {
+		return errors.New(i18n.T("cannot write %s: %v", name, err))
+	}
+
+	if *out != "" {
+		fmt.Println(i18n.T("exported entry %d to %s", id, *out))
+	}
+
+	return nil
+}
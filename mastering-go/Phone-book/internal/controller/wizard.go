@@ -0,0 +1,231 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// errWizardCanceled is returned by insertWizard when the input ends or the
+// entry isn't confirmed.
+var errWizardCanceled = errors.New("nothing was inserted")
+
+// insertWizard asks for the fields of a new entry one after the other, for
+// "insert" without arguments. entry holds what the flags gave, which is
+// offered as the answer to keep. The phone number and dates are checked as
+// soon as they are typed, and asked for again until they are valid.
+func insertWizard(ctx context.Context, store storage.Storage, w *wizard, entry *model.Entry) error {
+	entry.Name = w.ask(i18n.T("Name"), entry.Name, func(s string) error {
+		if s == "" {
+			return errors.New(i18n.T("the name is required"))
+		}
+
+		return nil
+	})
+
+	entry.Surname = w.ask(i18n.T("Surname (optional)"), entry.Surname, nil)
+
+	entry.PhoneNumber = model.PhoneNumber(w.ask(i18n.T("Phone number"), string(entry.PhoneNumber), func(s string) error {
+		normalized, err := model.ParsePhoneNumber(s, "")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w.out, "  %s %s\n", normalized.Format(), normalized.Country())
+		if owner, ok := numberOwner(ctx, store, normalized); ok {
+			fmt.Fprintln(w.out, "  "+i18n.T("this number is already saved for %s (id %d)", strings.TrimSpace(owner.Name+" "+owner.Surname), owner.ID))
+		}
+
+		return nil
+	}))
+
+	entry.Nickname = w.ask(i18n.T("Nickname (optional)"), entry.Nickname, nil)
+	entry.Company = w.ask(i18n.T("Company (optional)"), entry.Company, nil)
+	entry.Title = w.ask(i18n.T("Job title (optional)"), entry.Title, nil)
+	entry.Birthday = w.ask(i18n.T("Birthday, e.g. 1990-05-17 or 05-17 (optional)"), entry.Birthday, checkDate)
+
+	if w.err != nil {
+		return w.err
+	}
+
+	name := strings.TrimSpace(entry.Name + " " + entry.Surname)
+	confirmed := w.ask(i18n.T("Save %s, %s? [Y/n]", name, entry.PhoneNumber.Format()), "", nil)
+	if w.err != nil {
+		return w.err
+	}
+
+	if !yes(confirmed, true) {
+		return errWizardCanceled
+	}
+
+	return nil
+}
+
+// yes reports whether answer says yes, def being what an empty answer
+// means.
+func yes(answer string, def bool) bool {
+	if answer == "" {
+		return def
+	}
+
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes" || answer == i18n.T("yes")
+}
+
+// wizard asks questions on out and reads the answers from in, a line each.
+// Once the input ends err is set and every question gets its default.
+type wizard struct {
+	in  *bufio.Scanner
+	out io.Writer
+	err error
+}
+
+func newWizard(in io.Reader, out io.Writer) *wizard {
+	return &wizard{in: bufio.NewScanner(in), out: out}
+}
+
+// ask asks for label until check, when not nil, accepts the answer, and
+// returns it. An empty answer takes def.
+func (w *wizard) ask(label, def string, check func(string) error) string {
+	for w.err == nil {
+		if def != "" {
+			fmt.Fprintf(w.out, "%s [%s]: ", label, def)
+		} else {
+			fmt.Fprintf(w.out, "%s: ", label)
+		}
+
+		if !w.in.Scan() {
+			fmt.Fprintln(w.out)
+			w.err = errWizardCanceled
+			break
+		}
+
+		answer := strings.TrimSpace(w.in.Text())
+		if answer == "" {
+			answer = def
+		}
+
+		if check == nil {
+			return answer
+		}
+
+		if err := check(answer); err != nil {
+			fmt.Fprintln(w.out, "  "+i18n.T(err.Error()))
+			continue
+		}
+
+		return answer
+	}
+
+	return def
+}
+
+// checkDate checks a birthday or anniversary typed in the wizard, which may
+// be left empty.
+func checkDate(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	_, _, _, err := model.ParseDate(s)
+	return err
+}
+
+// numberOwner returns the entry number is saved for, if any.
+func numberOwner(ctx context.Context, store storage.Storage, number model.PhoneNumber) (model.Entry, bool) {
+	candidates, appErr := numberCandidates(ctx, store, string(number))
+	if appErr != nil {
+		return model.Entry{}, false
+	}
+
+	for _, entry := range candidates {
+		if entry.PhoneNumber.Equal(number) {
+			return entry, true
+		}
+	}
+
+	return model.Entry{}, false
+}
+
+// similarEntry returns the entry of entries whose name is closest to that of
+// entry, among those with another number, if it is close enough to be the
+// same person: written in another script or with vowels left out, or a
+// typo or so away.
+func similarEntry(entries []model.Entry, entry model.Entry) (model.Entry, bool) {
+	name := strings.ToLower(strings.TrimSpace(entry.Name + " " + entry.Surname))
+	folded := search.Fold(name)
+
+	var closest model.Entry
+	best := -1
+	for _, other := range entries {
+		if other.PhoneNumber.Equal(entry.PhoneNumber) {
+			continue
+		}
+
+		otherName := strings.ToLower(strings.TrimSpace(other.Name + " " + other.Surname))
+		distance := search.Distance(name, otherName)
+		if folded != "" && search.Fold(otherName) == folded {
+			distance = 0
+		}
+
+		if distance > max(1, max(utf8.RuneCountInString(name), utf8.RuneCountInString(otherName))/5) {
+			continue
+		}
+
+		if best < 0 || distance < best {
+			closest, best = other, distance
+		}
+	}
+
+	return closest, best >= 0
+}
+
+// offerMerge asks, when the book has a contact with a name close to that of
+// entry but another number, whether to give that contact the new number
+// instead of adding entry, and does so. It reports whether the contact was
+// updated, which leaves nothing to insert.
+func offerMerge(ctx context.Context, store storage.Storage, w *wizard, entry model.Entry) bool {
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return false
+	}
+
+	similar, ok := similarEntry(entries, entry)
+	if !ok {
+		return false
+	}
+
+	name := strings.TrimSpace(similar.Name + " " + similar.Surname)
+	fmt.Fprintln(w.out, i18n.T("Similar contact exists: %s, %s (id %d).", name, similar.PhoneNumber.Format(), similar.ID))
+
+	updater, ok := store.(storage.Updater)
+	if !ok {
+		return false
+	}
+
+	// Entries hold a single number, so the existing one is replaced.
+	answer := w.ask(i18n.T("Replace its number with %s instead of adding a new entry? [y/N]", entry.PhoneNumber.Format()), "", nil)
+	if !yes(answer, false) {
+		return false
+	}
+
+	similar.PhoneNumber = entry.PhoneNumber
+	prepareEntry(&similar)
+	if appErr := updater.Update(ctx, &similar); appErr != nil {
+		fmt.Fprintln(w.out, i18n.T(appErr.Message))
+		return true
+	}
+
+	fmt.Fprintln(w.out, i18n.T("gave %s the number %s", name, similar.PhoneNumber.Format()))
+
+	return true
+}
@@ -0,0 +1,127 @@
+package csvfile
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// The blocklist lives next to the data file, one number per line.
+func (s *Storage) blocklistPath() string {
+	return s.path + ".blocked"
+}
+
+func (s *Storage) Block(ctx context.Context, number string) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	numbers, err := s.loadBlocked()
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	if slices.Contains(numbers, number) {
+		return nil
+	}
+
+	if err := s.saveBlocked(append(numbers, number)); err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
+
+func (s *Storage) Unblock(ctx context.Context, number string) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	numbers, err := s.loadBlocked()
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	i := slices.Index(numbers, number)
+	if i < 0 {
+		return &model.PhoeBookError{Message: "the number is not blocked", StatusCode: http.StatusNotFound}
+	}
+
+	if err := s.saveBlocked(slices.Delete(numbers, i, i+1)); err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
+
+func (s *Storage) Blocked(ctx context.Context) ([]string, *model.PhoeBookError) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	defer unlock()
+
+	numbers, err := s.loadBlocked()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	slices.Sort(numbers)
+
+	return numbers, nil
+}
+
+func (s *Storage) loadBlocked() ([]string, error) {
+	file, err := os.Open(s.blocklistPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read blocklist: %v", err)
+	}
+
+	defer file.Close()
+
+	var numbers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if number := strings.TrimSpace(scanner.Text()); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read blocklist: %v", err)
+	}
+
+	return numbers, nil
+}
+
+func (s *Storage) saveBlocked(numbers []string) error {
+	var content strings.Builder
+	for _, number := range numbers {
+		content.WriteString(number + "\n")
+	}
+
+	if err := replaceFile(s.blocklistPath(), []byte(content.String())); err != nil {
+		return fmt.Errorf("cannot save blocklist: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package csvfile
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Calls are appended to a CSV file next to the data file, one
+// entry_id,at,duration_seconds,note record per call.
+func (s *Storage) callsPath() string {
+	return s.path + ".calls"
+}
+
+func (s *Storage) LogCall(ctx context.Context, call model.Call) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	file, err := os.OpenFile(s.callsPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save call: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{
+		strconv.FormatInt(call.EntryID, 10),
+		call.At.UTC().Format(time.RFC3339),
+		strconv.FormatInt(int64(call.Duration/time.Second), 10),
+		call.Note,
+	})
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save call: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
+
+func (s *Storage) Calls(ctx context.Context, id int64) ([]model.Call, *model.PhoeBookError) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	defer unlock()
+
+	file, err := os.Open(s.callsPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("cannot read calls: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 4
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("cannot read calls: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	var calls []model.Call
+	for _, record := range records {
+		entryID, _ := strconv.ParseInt(record[0], 10, 64)
+		if id != 0 && entryID != id {
+			continue
+		}
+
+		at, _ := time.Parse(time.RFC3339, record[1])
+		seconds, _ := strconv.ParseInt(record[2], 10, 64)
+		calls = append(calls, model.Call{EntryID: entryID, At: at, Duration: time.Duration(seconds) * time.Second, Note: record[3]})
+	}
+
+	return calls, nil
+}
+
+func (s *Storage) EraseCalls(ctx context.Context, id int64) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	file, err := os.Open(s.callsPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot read calls: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 4
+
+	records, err := reader.ReadAll()
+	file.Close()
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot read calls: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	kept := records[:0]
+	for _, record := range records {
+		if record[0] != strconv.FormatInt(id, 10) {
+			kept = append(kept, record)
+		}
+	}
+
+	// Like the spam reports, the calls are rewritten through a temporary
+	// file so a failure leaves them as they were.
+	tmp := s.callsPath() + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save calls: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	writer := csv.NewWriter(out)
+	writer.WriteAll(kept)
+	if err := errors.Join(writer.Error(), out.Close()); err != nil {
+		os.Remove(tmp)
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save calls: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	if err := os.Rename(tmp, s.callsPath()); err != nil {
+		os.Remove(tmp)
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save calls: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package csvfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The checksum of the data file is kept in <path>.sha256 as
+//
+//	sha256 <hex digest> <size> <modification time in Unix nanoseconds>
+//
+// The size and time tell whether the file was changed by the phone book
+// since: a file that still looks like the one that was saved but hashes
+// differently has been damaged, while one edited by hand or by another
+// program is trusted and gets a new checksum on the next save.
+func (s *Storage) checksumPath() string {
+	return s.path + ".sha256"
+}
+
+// verifyChecksum checks the open data file against its saved checksum, if
+// the file is the one the checksum was saved for. It leaves file at its
+// start.
+func (s *Storage) verifyChecksum(file *os.File) error {
+	data, err := os.ReadFile(s.checksumPath())
+	if err != nil {
+		return nil
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 4 || fields[0] != "sha256" {
+		return nil
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	size, _ := strconv.ParseInt(fields[2], 10, 64)
+	modTime, _ := strconv.ParseInt(fields[3], 10, 64)
+	if size != info.Size() || modTime != info.ModTime().UnixNano() {
+		return nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if got := hex.EncodeToString(hash.Sum(nil)); got != fields[1] {
+		return fmt.Errorf("data file %s is corrupted: its checksum does not match the one saved with it, see the repair command", s.path)
+	}
+
+	return nil
+}
+
+// writeChecksum records the checksum of the data file just saved, whose
+// size and modification time are those of info, taken from the staged file
+// before it was renamed: the file at the path may already be another one.
+// The caller holds the lock of s.
+func (s *Storage) writeChecksum(sum []byte, info os.FileInfo) error {
+	line := fmt.Sprintf("sha256 %s %d %d\n", hex.EncodeToString(sum), info.Size(), info.ModTime().UnixNano())
+
+	return replaceFile(s.checksumPath(), []byte(line))
+}
@@ -0,0 +1,428 @@
+package csvfile
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+func init() {
+	storage.Register("csv", Open)
+}
+
+// Storage keeps the phone book in a CSV file with one entry per line, see
+// toRecord for the field order. Files named *.gz are gzip compressed.
+type Storage struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Open returns a backend for the CSV file at path, creating it if needed.
+func Open(path string) (storage.Storage, error) {
+	if path == "" {
+		return nil, fmt.Errorf("csv storage needs a file path")
+	}
+
+	file, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open data file: %v", err)
+	}
+
+	file.Close()
+
+	return &Storage{path: path}, nil
+}
+
+func (s *Storage) List(ctx context.Context) ([]model.Entry, *model.PhoeBookError) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return entries, nil
+}
+
+func (s *Storage) Insert(ctx context.Context, entry *model.Entry) (int64, *model.PhoeBookError) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return 0, appErr
+	}
+
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return 0, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	id, err := s.allocateIDs(entries, 1)
+	if err != nil {
+		return 0, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	newEntry := *entry
+	newEntry.ID = id
+	newEntry.Version = 1
+	newEntry.UpdatedAt = now()
+	entries = append(entries, newEntry)
+
+	if err := s.save(entries); err != nil {
+		return 0, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return newEntry.ID, nil
+}
+
+// insertWithID adds entry keeping its ID, for the sharded backend that
+// allocates IDs across all of its files.
+func (s *Storage) insertWithID(entry model.Entry) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	if err := s.save(append(entries, entry)); err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
+
+func (s *Storage) Update(ctx context.Context, entry *model.Entry) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	i := indexOf(entries, entry.ID)
+	if i < 0 {
+		return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+	}
+
+	updated, appErr := update(entries[i], *entry)
+	if appErr != nil {
+		return appErr
+	}
+
+	entries[i] = updated
+
+	if err := s.save(entries); err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	entry.Version = updated.Version
+	entry.UpdatedAt = updated.UpdatedAt
+
+	return nil
+}
+
+// update returns stored replaced by entry, keeping the photo and counting
+// the change, or ConflictError if entry expects another version.
+func update(stored, entry model.Entry) (model.Entry, *model.PhoeBookError) {
+	if entry.Version != 0 && entry.Version != stored.Version {
+		return model.Entry{}, storage.ConflictError()
+	}
+
+	entry.Photo = stored.Photo
+	entry.Version = stored.Version + 1
+	entry.UpdatedAt = now()
+
+	return entry, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, id int64) *model.PhoeBookError {
+	return s.DeleteVersion(ctx, id, 0)
+}
+
+// DeleteVersion deletes the entry with id, if it still has version or
+// version is 0.
+func (s *Storage) DeleteVersion(ctx context.Context, id, version int64) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	i := indexOf(entries, id)
+	if i < 0 {
+		return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+	}
+
+	if version != 0 && entries[i].Version != version {
+		return storage.ConflictError()
+	}
+
+	entries = append(entries[:i], entries[i+1:]...)
+	if err := s.save(entries); err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	os.Remove(s.photoPath(id))
+
+	return nil
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+// Fingerprint identifies the data file as it is now by its size and
+// modification time.
+func (s *Storage) Fingerprint(ctx context.Context) (string, *model.PhoeBookError) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return fmt.Sprintf("%d %d", info.Size(), info.ModTime().UnixNano()), nil
+}
+
+func (s *Storage) load() ([]model.Entry, error) {
+	entries, _, err := s.loadOffsets()
+	return entries, err
+}
+
+// loadOffsets loads the entries together with the byte offset of the record
+// of each of them, by ID.
+func (s *Storage) loadOffsets() ([]model.Entry, map[int64]int64, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer file.Close()
+
+	if err := s.verifyChecksum(file); err != nil {
+		return nil, nil, err
+	}
+
+	var data io.Reader = file
+	if s.compressed() {
+		unzipped, err := gzip.NewReader(file)
+		if err == io.EOF {
+			// A new, still empty file.
+			return nil, nil, nil
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot read data file: %v", err)
+		}
+
+		defer unzipped.Close()
+		data = unzipped
+	}
+
+	entries, starts, _, err := parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	offsets := make(map[int64]int64, len(entries))
+	nextID := maxID(entries) + 1
+	for i := range entries {
+		if entries[i].ID == 0 {
+			entries[i].ID = nextID
+			nextID++
+		}
+
+		// Files written before entries had versions start them at 1.
+		if entries[i].Version == 0 {
+			entries[i].Version = 1
+		}
+
+		offsets[entries[i].ID] = starts[i]
+	}
+
+	return entries, offsets, nil
+}
+
+// save writes the entries to a temporary file first so a failed write never
+// leaves a half written data file behind, then records its checksum and
+// brings the search index up to date.
+func (s *Storage) save(entries []model.Entry) error {
+	st, err := s.stage(entries)
+	if err != nil {
+		return err
+	}
+
+	defer st.discard()
+
+	return st.commit()
+}
+
+// staged is a data file written next to the one it replaces, waiting for
+// commit to move it into place. info is that of the staged file, which the
+// rename keeps.
+type staged struct {
+	s       *Storage
+	tmp     string
+	before  os.FileInfo
+	info    os.FileInfo
+	sum     []byte
+	entries []model.Entry
+	offsets map[int64]int64
+}
+
+// stage writes entries to a temporary file. Everything that can reasonably
+// fail when saving fails here, before the data file is touched.
+func (s *Storage) stage(entries []model.Entry) (*staged, error) {
+	before, _ := os.Stat(s.path)
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("cannot save data file: %v", err)
+	}
+
+	fail := func(err error) (*staged, error) {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("cannot save data file: %v", err)
+	}
+
+	if err := tmp.Chmod(0644); err != nil {
+		return fail(err)
+	}
+
+	hash := sha256.New()
+	buffered := bufio.NewWriter(io.MultiWriter(tmp, hash))
+
+	var out io.Writer = buffered
+	var zipped *gzip.Writer
+	if s.compressed() {
+		zipped = gzip.NewWriter(buffered)
+		out = zipped
+	}
+
+	counter := &countingWriter{w: out}
+	writer := csv.NewWriter(counter)
+
+	offsets := make(map[int64]int64, len(entries))
+	for _, entry := range entries {
+		offsets[entry.ID] = counter.n
+
+		// Flushing after each record only moves it into buffered, and keeps
+		// counter.n at the start of the next record.
+		writer.Write(toRecord(entry))
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return fail(err)
+		}
+	}
+
+	if zipped != nil {
+		if err := zipped.Close(); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return fail(err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("cannot save data file: %v", err)
+	}
+
+	info, err := os.Stat(tmp.Name())
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("cannot save data file: %v", err)
+	}
+
+	return &staged{s: s, tmp: tmp.Name(), before: before, info: info, sum: hash.Sum(nil), entries: entries, offsets: offsets}, nil
+}
+
+// commit moves the staged file into place. The caller holds the lock of the
+// Storage, which keeps the checksum and the file it is for together.
+func (st *staged) commit() error {
+	if err := os.Rename(st.tmp, st.s.path); err != nil {
+		return err
+	}
+
+	if err := st.s.writeChecksum(st.sum, st.info); err != nil {
+		return fmt.Errorf("cannot save checksum: %v", err)
+	}
+
+	st.s.updateIndex(st.before, st.entries, st.offsets)
+
+	return nil
+}
+
+// discard removes the staged file if commit has not moved it.
+func (st *staged) discard() {
+	os.Remove(st.tmp)
+}
+
+// compressed reports whether the data file is gzip compressed. Offsets into
+// a compressed file cannot be seeked to, so such books have no search index.
+func (s *Storage) compressed() bool {
+	return strings.HasSuffix(s.path, ".gz")
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func maxID(entries []model.Entry) int64 {
+	var highest int64
+	for _, entry := range entries {
+		if entry.ID > highest {
+			highest = entry.ID
+		}
+	}
+
+	return highest
+}
@@ -0,0 +1,38 @@
+package csvfile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// FuzzRead checks that reading CSV data in any layout returns an error or
+// entries that write back, and never panics.
+func FuzzRead(f *testing.F) {
+	for _, seed := range []string{
+		"1,John,Smith,+14155550100\n",
+		"name,surname,phone_number,company\nJohn,Smith,+14155550100,Acme\n",
+		"Name;Surname;Phone\nJohn;Smith;0912 123 4567\n",
+		"\"unterminated,John\n",
+		"id,name\nabc,John\n",
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries, unknown, err := ReadUnknown(bytes.NewReader(data))
+		if err != nil {
+			if entries != nil || unknown != nil {
+				t.Fatalf("ReadUnknown returned entries or columns with the error %v", err)
+			}
+
+			return
+		}
+
+		var written strings.Builder
+		if err := Write(&written, entries); err != nil {
+			t.Fatalf("cannot write what was read: %v", err)
+		}
+	})
+}
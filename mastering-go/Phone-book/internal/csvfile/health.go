@@ -0,0 +1,100 @@
+package csvfile
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Health checks that the data file can be read, that its directory can
+// still be written, which every save needs, and whether the search index is
+// up to date.
+func (s *Storage) Health(ctx context.Context) []model.HealthCheck {
+	checks := []model.HealthCheck{readableCheck([]*Storage{s}), writableCheck(filepath.Dir(s.path))}
+	if check, ok := indexCheck([]*Storage{s}); ok {
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// Health checks the shards like Storage.Health does a single file.
+func (s *Sharded) Health(ctx context.Context) []model.HealthCheck {
+	shards, err := s.existing()
+	if err != nil {
+		return []model.HealthCheck{{Name: "data files", Status: model.HealthFailing, Detail: err.Error()}}
+	}
+
+	checks := []model.HealthCheck{readableCheck(shards), writableCheck(s.dir)}
+	if check, ok := indexCheck(shards); ok {
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+func readableCheck(files []*Storage) model.HealthCheck {
+	for _, s := range files {
+		file, err := os.Open(s.path)
+		if err != nil {
+			return model.HealthCheck{Name: "data files", Status: model.HealthFailing, Detail: err.Error()}
+		}
+
+		file.Close()
+	}
+
+	return model.HealthCheck{Name: "data files", Status: model.HealthOK}
+}
+
+// writableCheck creates and removes a file in dir, the way a save replaces
+// the data file.
+func writableCheck(dir string) model.HealthCheck {
+	check := model.HealthCheck{Name: "data directory writable", Status: model.HealthOK, Write: true}
+
+	tmp, err := os.CreateTemp(dir, ".health.*.tmp")
+	if err != nil {
+		check.Status, check.Detail = model.HealthFailing, err.Error()
+		return check
+	}
+
+	tmp.Close()
+	os.Remove(tmp.Name())
+
+	return check
+}
+
+// indexCheck reports whether the search indexes of files describe them. ok
+// is false when none of them has an index, as small books don't. An index
+// out of date only makes the next search slower, as it is rebuilt then.
+func indexCheck(files []*Storage) (check model.HealthCheck, ok bool) {
+	check = model.HealthCheck{Name: "search index", Status: model.HealthOK}
+
+	var indexed, stale, entries int
+	for _, s := range files {
+		stats, ok := s.indexStats()
+		if !ok {
+			continue
+		}
+
+		indexed++
+		entries += stats.Entries
+		if stats.Stale {
+			stale++
+		}
+	}
+
+	if indexed == 0 {
+		return check, false
+	}
+
+	check.Detail = fmt.Sprintf("%d entries indexed", entries)
+	if stale > 0 {
+		check.Status = model.HealthDegraded
+		check.Detail = fmt.Sprintf("%d of %d indexes out of date, rebuilt on the next search", stale, indexed)
+	}
+
+	return check, true
+}
@@ -0,0 +1,105 @@
+package csvfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// The ID the next entry gets is kept in <path>.next_id. Taking the highest ID
+// in the file plus one would give the ID of the last entry to the next one
+// once it is deleted, along with the calls and photo still kept under it; the
+// counter only goes up. It never falls behind the file either, so IDs stay
+// unique in a book edited by hand or restored from a backup.
+func (s *Storage) nextIDPath() string {
+	return s.path + ".next_id"
+}
+
+// allocateIDs reserves n IDs for entries added to entries and returns the
+// first. The caller holds the lock of s.
+func (s *Storage) allocateIDs(entries []model.Entry, n int64) (int64, error) {
+	first, err := s.peekID(entries)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeCounter(s.nextIDPath(), first+n); err != nil {
+		return 0, err
+	}
+
+	return first, nil
+}
+
+// peekID returns the ID the next entry added to entries gets, without
+// reserving it.
+func (s *Storage) peekID(entries []model.Entry) (int64, error) {
+	next, _, err := readCounter(s.nextIDPath())
+	if err != nil {
+		return 0, err
+	}
+
+	return max(next, maxID(entries)+1), nil
+}
+
+// readCounter reads the counter file at path. ok is false when there is none
+// yet.
+func readCounter(path string) (next int64, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+
+	if err != nil {
+		return 0, false, err
+	}
+
+	next, err = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("corrupt %s: %v", path, err)
+	}
+
+	return next, true, nil
+}
+
+// writeCounter replaces the counter file at path through a temporary file, so
+// a crash leaves either the old count or the new one.
+func writeCounter(path string, next int64) error {
+	if err := replaceFile(path, []byte(strconv.FormatInt(next, 10)+"\n")); err != nil {
+		return fmt.Errorf("cannot save %s: %v", path, err)
+	}
+
+	return nil
+}
+
+// replaceFile writes data to a temporary file next to path and renames it
+// to path, so readers see the old content or the new one, never a part.
+func replaceFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
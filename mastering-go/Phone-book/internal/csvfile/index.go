@@ -0,0 +1,366 @@
+package csvfile
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+)
+
+// indexMinEntries is the size from which a book gets a search index. Smaller
+// books are scanned, which is fast enough and also finds fuzzy matches.
+const indexMinEntries = 1000
+
+// index is the inverted search index kept in <path>.index. It records the
+// size and modification time of the data file it describes, so an index
+// left behind by an edit made outside the phone book is noticed and rebuilt.
+type index struct {
+	Size    int64
+	ModTime time.Time
+	Entries map[int64]indexedEntry
+	Tokens  map[string][]int64
+}
+
+// indexedEntry is what the index remembers of an entry: where its record
+// starts and the fields it was tokenized from.
+type indexedEntry struct {
+	Offset   int64
+	Name     string
+	Surname  string
+	Nickname string
+	Phone    model.PhoneNumber
+}
+
+func (s *Storage) indexPath() string {
+	return s.path + ".index"
+}
+
+// Candidates returns the entries containing the tokens of term, in file
+// order, reading only their records. ok is false when the book is too small
+// to be indexed or the term too short to narrow the search down.
+func (s *Storage) Candidates(ctx context.Context, term string) ([]model.Entry, bool, *model.PhoeBookError) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return nil, false, appErr
+	}
+
+	defer unlock()
+
+	sets := search.TermTokens(term)
+	if len(sets) == 0 || s.compressed() {
+		return nil, false, nil
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, false, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	idx, ok := s.readIndex(info)
+	if !ok {
+		entries, offsets, err := s.loadOffsets()
+		if err != nil {
+			return nil, false, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+
+		if len(entries) < indexMinEntries {
+			return nil, false, nil
+		}
+
+		idx = buildIndex(entries, offsets)
+		s.writeIndex(idx, info)
+	}
+
+	if len(idx.Entries) < indexMinEntries {
+		return nil, false, nil
+	}
+
+	var ids []int64
+	for _, tokens := range sets {
+		matching := idx.Tokens[tokens[0]]
+		for _, token := range tokens[1:] {
+			matching = intersect(matching, idx.Tokens[token])
+		}
+
+		ids = union(ids, matching)
+	}
+
+	offsets := make([]int64, 0, len(ids))
+	byOffset := make(map[int64]int64, len(ids))
+	for _, id := range ids {
+		offset := idx.Entries[id].Offset
+		offsets = append(offsets, offset)
+		byOffset[offset] = id
+	}
+
+	slices.Sort(offsets)
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, false, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	defer file.Close()
+
+	// Files edited by hand may have another layout than the one save
+	// writes, the offsets point into the file as it is.
+	l := sniff(bufio.NewReaderSize(file, 64*1024))
+
+	entries := make([]model.Entry, 0, len(offsets))
+	for _, offset := range offsets {
+		reader := l.reader(io.NewSectionReader(file, offset, info.Size()-offset))
+
+		record, err := reader.Read()
+		if err != nil {
+			return nil, false, &model.PhoeBookError{Message: "cannot read data file: " + err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+
+		entry, err := l.entry(record)
+		if err != nil {
+			return nil, false, &model.PhoeBookError{Message: "cannot read data file: " + err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+
+		// Records without an id got theirs when the index was built.
+		entry.ID = byOffset[offset]
+		entries = append(entries, entry)
+	}
+
+	return entries, true, nil
+}
+
+// updateIndex brings the index in line with entries after a save. Entries
+// the index already knows unchanged are not tokenized again. before is the
+// data file as it was before the save; an index that didn't describe it is
+// rebuilt from scratch.
+func (s *Storage) updateIndex(before os.FileInfo, entries []model.Entry, offsets map[int64]int64) {
+	if s.compressed() {
+		return
+	}
+
+	after, err := os.Stat(s.path)
+	if err != nil {
+		return
+	}
+
+	var idx *index
+	if before != nil {
+		idx, _ = s.readIndex(before)
+	}
+
+	if idx == nil {
+		if len(entries) < indexMinEntries {
+			os.Remove(s.indexPath())
+			return
+		}
+
+		s.writeIndex(buildIndex(entries, offsets), after)
+		return
+	}
+
+	current := make(map[int64]bool, len(entries))
+	for _, entry := range entries {
+		current[entry.ID] = true
+
+		known, ok := idx.Entries[entry.ID]
+		if ok && known.Name == entry.Name && known.Surname == entry.Surname && known.Nickname == entry.Nickname && known.Phone == entry.PhoneNumber {
+			known.Offset = offsets[entry.ID]
+			idx.Entries[entry.ID] = known
+			continue
+		}
+
+		if ok {
+			idx.remove(entry.ID, known)
+		}
+
+		idx.add(entry, offsets[entry.ID])
+	}
+
+	for id, known := range idx.Entries {
+		if !current[id] {
+			idx.remove(id, known)
+		}
+	}
+
+	s.writeIndex(idx, after)
+}
+
+func buildIndex(entries []model.Entry, offsets map[int64]int64) *index {
+	idx := &index{Entries: make(map[int64]indexedEntry, len(entries)), Tokens: make(map[string][]int64)}
+	for _, entry := range entries {
+		idx.add(entry, offsets[entry.ID])
+	}
+
+	return idx
+}
+
+func (idx *index) add(entry model.Entry, offset int64) {
+	idx.Entries[entry.ID] = indexedEntry{Offset: offset, Name: entry.Name, Surname: entry.Surname, Nickname: entry.Nickname, Phone: entry.PhoneNumber}
+
+	for _, token := range search.Tokens(entry) {
+		ids := idx.Tokens[token]
+		if i, found := slices.BinarySearch(ids, entry.ID); !found {
+			idx.Tokens[token] = slices.Insert(ids, i, entry.ID)
+		}
+	}
+}
+
+func (idx *index) remove(id int64, known indexedEntry) {
+	delete(idx.Entries, id)
+
+	for _, token := range search.Tokens(model.Entry{Name: known.Name, Surname: known.Surname, Nickname: known.Nickname, PhoneNumber: known.Phone}) {
+		ids := idx.Tokens[token]
+		if i, found := slices.BinarySearch(ids, id); found {
+			ids = slices.Delete(ids, i, i+1)
+			if len(ids) == 0 {
+				delete(idx.Tokens, token)
+			} else {
+				idx.Tokens[token] = ids
+			}
+		}
+	}
+}
+
+// readIndex reads the index and reports whether it describes the data file
+// as info shows it.
+func (s *Storage) readIndex(info os.FileInfo) (*index, bool) {
+	idx, err := s.decodeIndex()
+	if err != nil || !idx.describes(info) {
+		return nil, false
+	}
+
+	return idx, true
+}
+
+func (s *Storage) decodeIndex() (*index, error) {
+	file, err := os.Open(s.indexPath())
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	var idx index
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&idx); err != nil {
+		return nil, err
+	}
+
+	return &idx, nil
+}
+
+func (idx *index) describes(info os.FileInfo) bool {
+	return idx.Size == info.Size() && idx.ModTime.Equal(info.ModTime())
+}
+
+// Inspect reports the size of the search index, if the book has one.
+func (s *Storage) Inspect(ctx context.Context) (model.Inspection, *model.PhoeBookError) {
+	var inspection model.Inspection
+	if stats, ok := s.indexStats(); ok {
+		inspection.Indexes = append(inspection.Indexes, stats)
+	}
+
+	return inspection, nil
+}
+
+func (s *Storage) indexStats() (model.IndexStats, bool) {
+	indexInfo, err := os.Stat(s.indexPath())
+	if err != nil {
+		return model.IndexStats{}, false
+	}
+
+	stats := model.IndexStats{Name: s.indexPath(), Bytes: indexInfo.Size(), Stale: true}
+
+	idx, err := s.decodeIndex()
+	if err != nil {
+		return stats, true
+	}
+
+	stats.Entries, stats.Tokens = len(idx.Entries), len(idx.Tokens)
+	if info, err := os.Stat(s.path); err == nil {
+		stats.Stale = !idx.describes(info)
+	}
+
+	return stats, true
+}
+
+// writeIndex saves the index for the data file described by info. The index
+// is only an accelerator, so failing to write it is not an error.
+func (s *Storage) writeIndex(idx *index, info os.FileInfo) {
+	idx.Size, idx.ModTime = info.Size(), info.ModTime()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.indexPath())+".*.tmp")
+	if err != nil {
+		return
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return
+	}
+
+	buffered := bufio.NewWriter(tmp)
+	if err := gob.NewEncoder(buffered).Encode(idx); err != nil {
+		tmp.Close()
+		return
+	}
+
+	if err := buffered.Flush(); err != nil {
+		tmp.Close()
+		return
+	}
+
+	if err := tmp.Close(); err != nil {
+		return
+	}
+
+	os.Rename(tmp.Name(), s.indexPath())
+}
+
+func intersect(a, b []int64) []int64 {
+	var both []int64
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			both = append(both, a[i])
+			i++
+			j++
+		}
+	}
+
+	return both
+}
+
+func union(a, b []int64) []int64 {
+	var either []int64
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			either = append(either, a[i])
+			i++
+		case a[i] > b[j]:
+			either = append(either, b[j])
+			j++
+		default:
+			either = append(either, a[i])
+			i++
+			j++
+		}
+	}
+
+	either = append(either, a[i:]...)
+	return append(either, b[j:]...)
+}
@@ -0,0 +1,156 @@
+package csvfile
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Links are kept in a CSV file next to the data file, one from,to,type
+// record per link, which is rewritten on every change.
+func (s *Storage) linksPath() string {
+	return s.path + ".links"
+}
+
+func (s *Storage) Link(ctx context.Context, link model.Link) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	links, appErr := s.readLinks()
+	if appErr != nil {
+		return appErr
+	}
+
+	links, _ = withoutLink(links, link.From, link.To)
+
+	return s.writeLinks(append(links, link))
+}
+
+func (s *Storage) Unlink(ctx context.Context, a, b int64) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	links, appErr := s.readLinks()
+	if appErr != nil {
+		return appErr
+	}
+
+	links, found := withoutLink(links, a, b)
+	if !found {
+		return storage.NoLinkError()
+	}
+
+	return s.writeLinks(links)
+}
+
+func (s *Storage) Links(ctx context.Context, id int64) ([]model.Link, *model.PhoeBookError) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	defer unlock()
+
+	links, appErr := s.readLinks()
+	if appErr != nil || id == 0 {
+		return links, appErr
+	}
+
+	var of []model.Link
+	for _, link := range links {
+		if link.From == id || link.To == id {
+			of = append(of, link)
+		}
+	}
+
+	return of, nil
+}
+
+// withoutLink returns links without the link between a and b, and whether
+// there was one.
+func withoutLink(links []model.Link, a, b int64) ([]model.Link, bool) {
+	kept := links[:0]
+	for _, link := range links {
+		if link.From == a && link.To == b || link.From == b && link.To == a {
+			continue
+		}
+
+		kept = append(kept, link)
+	}
+
+	return kept, len(kept) < len(links)
+}
+
+func (s *Storage) readLinks() ([]model.Link, *model.PhoeBookError) {
+	file, err := os.Open(s.linksPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("cannot read links: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 3
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("cannot read links: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	links := make([]model.Link, 0, len(records))
+	for _, record := range records {
+		from, _ := strconv.ParseInt(record[0], 10, 64)
+		to, _ := strconv.ParseInt(record[1], 10, 64)
+		links = append(links, model.Link{From: from, To: to, Type: record[2]})
+	}
+
+	return links, nil
+}
+
+// writeLinks replaces the links through a temporary file, like the calls,
+// so a failure leaves them as they were.
+func (s *Storage) writeLinks(links []model.Link) *model.PhoeBookError {
+	tmp := s.linksPath() + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save links: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	writer := csv.NewWriter(out)
+	for _, link := range links {
+		writer.Write([]string{strconv.FormatInt(link.From, 10), strconv.FormatInt(link.To, 10), link.Type})
+	}
+
+	writer.Flush()
+	if err := errors.Join(writer.Error(), out.Close()); err != nil {
+		os.Remove(tmp)
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save links: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	if err := os.Rename(tmp, s.linksPath()); err != nil {
+		os.Remove(tmp)
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save links: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
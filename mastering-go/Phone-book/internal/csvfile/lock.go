@@ -0,0 +1,61 @@
+package csvfile
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"syscall"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// lock takes s.mu and the lock file of the data file, which every process
+// using the book takes around its reads and load-modify-saves, so those of
+// two processes cannot interleave any more than those of two goroutines.
+// It returns what releases both.
+func (s *Storage) lock() (func(), *model.PhoeBookError) {
+	s.mu.Lock()
+
+	file, err := lockFile(s.path + ".lock")
+	if err != nil {
+		s.mu.Unlock()
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("cannot lock data file: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	return func() {
+		unlockFile(file)
+		s.mu.Unlock()
+	}, nil
+}
+
+// lockFile takes an exclusive lock on the file at path, creating it, and
+// waits for other processes to release theirs first. The file is nil when it
+// cannot be created in a directory that is not writable: no other process can
+// change the book there either.
+func lockFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := flock(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// unlockFile releases a lock of lockFile.
+func unlockFile(file *os.File) {
+	if file != nil {
+		// Closing the file releases the lock.
+		file.Close()
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package csvfile
+
+import (
+	"os"
+	"syscall"
+)
+
+func flock(file *os.File) error {
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package csvfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// layout describes how a CSV file is written. The phone book writes plain
+// comma separated records in toRecord order, but files edited by hand or
+// exported from spreadsheets and other programs often start with a byte
+// order mark, use semicolons or tabs, or have a header row naming the
+// columns.
+type layout struct {
+	comma rune
+	// skip is the number of bytes before the first line, for a BOM.
+	skip int64
+	// columns is the field name of each column when the first line is a
+	// header row, nil otherwise, and unknown the names in it of the columns
+	// that hold none of the fields.
+	columns []string
+	unknown []string
+}
+
+// headerNames maps the column names found in header rows, lower cased with
+// spaces, dashes and underscores removed, to the toRecord field they hold.
+var headerNames = map[string]string{
+	"name":             "name",
+	"firstname":        "name",
+	"givenname":        "name",
+	"surname":          "surname",
+	"lastname":         "surname",
+	"familyname":       "surname",
+	"phone":            "phone_number",
+	"phonenumber":      "phone_number",
+	"number":           "phone_number",
+	"telephone":        "phone_number",
+	"tel":              "phone_number",
+	"mobile":           "phone_number",
+	"id":               "id",
+	"country":          "country",
+	"photo":            "photo",
+	"company":          "company",
+	"organization":     "company",
+	"organisation":     "company",
+	"nickname":         "nickname",
+	"nick":             "nickname",
+	"alias":            "nickname",
+	"birthday":         "birthday",
+	"birthdate":        "birthday",
+	"dateofbirth":      "birthday",
+	"anniversary":      "anniversary",
+	"reminddays":       "remind_days",
+	"title":            "title",
+	"jobtitle":         "title",
+	"updatedat":        "updated_at",
+	"modified":         "updated_at",
+	"lastmodified":     "updated_at",
+	"archivedat":       "archived_at",
+	"consent":          "consent",
+	"consentdate":      "consent_date",
+	"channel":          "preferred_channel",
+	"preferredchannel": "preferred_channel",
+	"accessnotes":      "access_notes",
+}
+
+// recordFields is the order of the fields in a record, see toRecord.
+var recordFields = []string{"name", "surname", "phone_number", "id", "country", "photo", "company", "title", "version", "updated_at", "nickname", "birthday", "anniversary", "remind_days", "archived_at", "consent", "consent_date", "preferred_channel", "access_notes"}
+
+// sniff works out the layout of the CSV data r starts with, consuming the
+// byte order mark if there is one. Wrap r in a bufio.Reader large enough to
+// hold the first line.
+func sniff(r *bufio.Reader) layout {
+	l := layout{comma: ','}
+
+	if bom, _ := r.Peek(len(utf8BOM)); bytes.Equal(bom, utf8BOM) {
+		r.Discard(len(utf8BOM))
+		l.skip = int64(len(utf8BOM))
+	}
+
+	start, _ := r.Peek(r.Size())
+	line, _, _ := bytes.Cut(start, []byte("\n"))
+
+	best := 0
+	for _, comma := range []rune{',', ';', '\t'} {
+		if n := countUnquoted(line, byte(comma)); n > best {
+			best, l.comma = n, comma
+		}
+	}
+
+	reader := csv.NewReader(bytes.NewReader(line))
+	reader.Comma = l.comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	if record, err := reader.Read(); err == nil {
+		l.columns = headerColumns(record)
+		for i, column := range l.columns {
+			if name := strings.TrimSpace(record[i]); column == "" && name != "" {
+				l.unknown = append(l.unknown, name)
+			}
+		}
+	}
+
+	return l
+}
+
+// countUnquoted counts the separators in line that are outside quotes.
+func countUnquoted(line []byte, separator byte) int {
+	count, quoted := 0, false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == separator && !quoted:
+			count++
+		}
+	}
+
+	return count
+}
+
+// headerColumns returns the field of each column if record is a header row:
+// one naming the phone number column and at least one of the names.
+func headerColumns(record []string) []string {
+	columns := make([]string, len(record))
+	found := make(map[string]bool)
+	for i, name := range record {
+		key := strings.ToLower(strings.TrimSpace(name))
+		key = strings.NewReplacer(" ", "", "_", "", "-", "").Replace(key)
+		columns[i] = headerNames[key]
+		found[columns[i]] = true
+	}
+
+	if !found["phone_number"] || !(found["name"] || found["surname"]) {
+		return nil
+	}
+
+	return columns
+}
+
+func (l layout) reader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = l.comma
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	return reader
+}
+
+// entry parses a record of a file with this layout.
+func (l layout) entry(record []string) (model.Entry, error) {
+	if l.columns == nil {
+		return fromRecord(record)
+	}
+
+	ordered := make([]string, len(recordFields))
+	for i, value := range record {
+		if i >= len(l.columns) || l.columns[i] == "" {
+			continue
+		}
+
+		for j, field := range recordFields {
+			if field == l.columns[i] {
+				ordered[j] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	return fromRecord(ordered)
+}
+
+// Read parses phone book entries from CSV data in any layout sniff
+// understands. Lines that are entirely empty are skipped; entries keep the
+// IDs the data gives them, if any.
+func Read(r io.Reader) ([]model.Entry, error) {
+	entries, _, _, err := parse(r)
+	return entries, err
+}
+
+// ReadUnknown is Read that also returns the names of the columns of the
+// header row it doesn't know, whose values Read leaves out.
+func ReadUnknown(r io.Reader) ([]model.Entry, []string, error) {
+	entries, _, unknown, err := parse(r)
+	return entries, unknown, err
+}
+
+// Write writes entries in the layout of a data file, which Read and the csv
+// backend read back.
+func Write(w io.Writer, entries []model.Entry) error {
+	writer := csv.NewWriter(w)
+	for _, entry := range entries {
+		writer.Write(toRecord(entry))
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// parse reads every entry of r along with the byte offset its record starts
+// at, and the names of the unknown columns of its header row.
+func parse(r io.Reader) ([]model.Entry, []int64, []string, error) {
+	buffered := bufio.NewReaderSize(r, 64*1024)
+	l := sniff(buffered)
+	reader := l.reader(buffered)
+
+	var entries []model.Entry
+	var starts []int64
+	for first := true; ; first = false {
+		start := l.skip + reader.InputOffset()
+
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("cannot read data file: %v", err)
+		}
+
+		if first && l.columns != nil {
+			continue
+		}
+
+		entry, err := l.entry(record)
+		if err != nil {
+			line, _ := reader.FieldPos(0)
+			return nil, nil, nil, fmt.Errorf("line %d of data file: %v", line, err)
+		}
+
+		entries = append(entries, entry)
+		starts = append(starts, start)
+	}
+
+	return entries, starts, l.unknown, nil
+}
@@ -0,0 +1,85 @@
+package csvfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Photos are kept as one file per entry in a directory next to the data file,
+// and the entry's Photo field holds the file path.
+func (s *Storage) photoPath(id int64) string {
+	return filepath.Join(s.path+".photos", strconv.FormatInt(id, 10))
+}
+
+func (s *Storage) SetPhoto(ctx context.Context, id int64, photo []byte) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	entries, err := s.load()
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	i := indexOf(entries, id)
+	if i < 0 {
+		return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+	}
+
+	path := s.photoPath(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save photo: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	if err := replaceFile(path, photo); err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save photo: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	entries[i].Photo = path
+	if err := s.save(entries); err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
+
+func (s *Storage) Photo(ctx context.Context, id int64) ([]byte, *model.PhoeBookError) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	defer unlock()
+
+	photo, err := os.ReadFile(s.photoPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &model.PhoeBookError{Message: "the entry has no photo", StatusCode: http.StatusNotFound}
+	}
+
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("cannot read photo: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	return photo, nil
+}
+
+func indexOf(entries []model.Entry, id int64) int {
+	for i, entry := range entries {
+		if entry.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
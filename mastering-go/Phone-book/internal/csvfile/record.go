@@ -0,0 +1,139 @@
+package csvfile
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// toRecord lays an entry out as
+// name,surname,phone_number,id,country,photo,company,title,version,updated_at,
+// nickname,birthday,anniversary,remind_days,archived_at,consent,consent_date,
+// preferred_channel,access_notes.
+// New fields are only ever appended so older files stay readable.
+func toRecord(entry model.Entry) []string {
+	return []string{
+		entry.Name,
+		entry.Surname,
+		string(entry.PhoneNumber),
+		strconv.FormatInt(entry.ID, 10),
+		entry.Country,
+		entry.Photo,
+		entry.Company,
+		entry.Title,
+		strconv.FormatInt(entry.Version, 10),
+		formatTime(entry.UpdatedAt),
+		entry.Nickname,
+		entry.Birthday,
+		entry.Anniversary,
+		formatDays(entry.RemindDays),
+		formatTime(entry.ArchivedAt),
+		entry.Consent,
+		entry.ConsentDate,
+		entry.Channel,
+		entry.AccessNotes,
+	}
+}
+
+// fromRecord parses a record written by toRecord. Only the first three
+// fields are required: lines without an id (like the ones in data/data.csv)
+// get one assigned when the file is loaded.
+func fromRecord(record []string) (model.Entry, error) {
+	if len(record) < 3 {
+		return model.Entry{}, fmt.Errorf("has %d fields, expected at least 3", len(record))
+	}
+
+	field := func(i int) string {
+		if i < len(record) {
+			return record[i]
+		}
+
+		return ""
+	}
+
+	entry := model.Entry{
+		Name:        record[0],
+		Surname:     record[1],
+		PhoneNumber: model.PhoneNumber(record[2]),
+		Country:     field(4),
+		Photo:       field(5),
+		Company:     field(6),
+		Title:       field(7),
+		Nickname:    field(10),
+		Birthday:    field(11),
+		Anniversary: field(12),
+		Consent:     field(15),
+		ConsentDate: field(16),
+		Channel:     field(17),
+		AccessNotes: field(18),
+	}
+
+	if id := field(3); id != "" {
+		var err error
+		entry.ID, err = strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return model.Entry{}, fmt.Errorf("invalid id: %v", err)
+		}
+	}
+
+	if version := field(8); version != "" {
+		var err error
+		entry.Version, err = strconv.ParseInt(version, 10, 64)
+		if err != nil {
+			return model.Entry{}, fmt.Errorf("invalid version: %v", err)
+		}
+	}
+
+	if days := field(13); days != "" {
+		var err error
+		entry.RemindDays, err = strconv.Atoi(days)
+		if err != nil {
+			return model.Entry{}, fmt.Errorf("invalid remind_days: %v", err)
+		}
+	}
+
+	if updatedAt := field(9); updatedAt != "" {
+		t, err := time.Parse(time.RFC3339, updatedAt)
+		if err != nil {
+			return model.Entry{}, fmt.Errorf("invalid updated_at: %v", err)
+		}
+
+		entry.UpdatedAt = &t
+	}
+
+	if archivedAt := field(14); archivedAt != "" {
+		t, err := time.Parse(time.RFC3339, archivedAt)
+		if err != nil {
+			return model.Entry{}, fmt.Errorf("invalid archived_at: %v", err)
+		}
+
+		entry.ArchivedAt = &t
+	}
+
+	return entry, nil
+}
+
+func formatDays(days int) string {
+	if days == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(days)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+// now is the time a change is stamped with, to the second like the files
+// keep it.
+func now() *time.Time {
+	t := time.Now().UTC().Truncate(time.Second)
+	return &t
+}
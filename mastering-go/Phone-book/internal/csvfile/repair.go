@@ -0,0 +1,163 @@
+package csvfile
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// maxRecordLines is how many lines a quoted field may span before repair
+// gives up on the quote and treats the lines on their own.
+const maxRecordLines = 10
+
+// Skipped is a line repair could not salvage, or salvaged with a change.
+type Skipped struct {
+	Line   int
+	Reason string
+}
+
+// Repair reads a damaged data file line by line, so a broken record only
+// costs its own line instead of everything after it. It returns the entries
+// it could parse and the lines it skipped or changed, with the reason. The
+// data may be gzip compressed; whatever decompresses is salvaged.
+func Repair(r io.Reader) ([]model.Entry, []Skipped) {
+	var skipped []Skipped
+
+	buffered := bufio.NewReaderSize(r, 64*1024)
+	if magic, _ := buffered.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		unzipped, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, []Skipped{{Line: 1, Reason: fmt.Sprintf("cannot decompress: %v", err)}}
+		}
+
+		buffered = bufio.NewReaderSize(unzipped, 64*1024)
+	}
+
+	l := sniff(buffered)
+
+	var lines []string
+	scanner := bufio.NewScanner(buffered)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		skipped = append(skipped, Skipped{Line: len(lines) + 1, Reason: fmt.Sprintf("the rest of the file cannot be read: %v", err)})
+	}
+
+	var entries []model.Entry
+	seen := make(map[int64]bool)
+	var renumber []int
+
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		if i == 0 && l.columns != nil {
+			continue
+		}
+
+		if strings.TrimSpace(lines[i]) == "" {
+			continue
+		}
+
+		// A quoted field may hold line breaks: join lines until the quotes
+		// balance, but not forever.
+		text, used := lines[i], 1
+		for strings.Count(text, `"`)%2 == 1 && used < maxRecordLines && i+used < len(lines) {
+			text += "\n" + lines[i+used]
+			used++
+		}
+
+		if strings.Count(text, `"`)%2 == 1 {
+			text, used = lines[i], 1
+		}
+
+		entry, err := repairRecord(l, text)
+		if err != nil {
+			skipped = append(skipped, Skipped{Line: lineNumber, Reason: err.Error()})
+			continue
+		}
+
+		i += used - 1
+
+		if entry.ID != 0 && seen[entry.ID] {
+			skipped = append(skipped, Skipped{Line: lineNumber, Reason: fmt.Sprintf("duplicate id %d, given a new one", entry.ID)})
+			entry.ID = 0
+		}
+
+		if entry.ID == 0 {
+			renumber = append(renumber, len(entries))
+		}
+
+		seen[entry.ID] = true
+		entries = append(entries, entry)
+	}
+
+	nextID := maxID(entries) + 1
+	for _, i := range renumber {
+		entries[i].ID = nextID
+		nextID++
+	}
+
+	return entries, skipped
+}
+
+func repairRecord(l layout, text string) (model.Entry, error) {
+	if !utf8.ValidString(text) {
+		return model.Entry{}, errors.New("not valid UTF-8")
+	}
+
+	if strings.ContainsRune(text, 0) {
+		return model.Entry{}, errors.New("contains NUL bytes")
+	}
+
+	reader := l.reader(strings.NewReader(text))
+
+	record, err := reader.Read()
+	if err != nil {
+		// The reader counts lines from the start of text, which only
+		// confuses the report.
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return model.Entry{}, parseErr.Err
+		}
+
+		return model.Entry{}, err
+	}
+
+	if _, err := reader.Read(); err != io.EOF {
+		return model.Entry{}, errors.New("does not hold exactly one record")
+	}
+
+	entry, err := l.entry(record)
+	if err != nil {
+		return model.Entry{}, err
+	}
+
+	if strings.TrimSpace(string(entry.PhoneNumber)) == "" {
+		return model.Entry{}, errors.New("has no phone number")
+	}
+
+	return entry, nil
+}
+
+// WriteFile saves entries to a new data file at path the way the csv backend
+// does, compressed if the name ends in .gz and with a checksum.
+func WriteFile(path string, entries []model.Entry) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	s := &Storage{path: path}
+
+	return s.save(entries)
+}
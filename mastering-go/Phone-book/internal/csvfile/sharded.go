@@ -0,0 +1,492 @@
+package csvfile
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+func init() {
+	storage.Register("csvshards", OpenSharded)
+}
+
+const defaultShards = 16
+
+// maxParallelShards is how many shards a list or search reads at the same
+// time.
+const maxParallelShards = 8
+
+// Sharded keeps a big phone book in a directory of CSV files, so answering a
+// request only parses the files it concerns. Entries are spread by the first
+// letter of their surname (the default) or by ID:
+//
+//	-storage csvshards -dsn book/
+//	-storage csvshards -dsn "book/?shard=id&shards=32"
+//	-storage csvshards -dsn "book/?compress=gzip"
+//
+// With surname sharding an insert touches a single file; with ID sharding
+// deletes and photos do too. Every file is an ordinary CSV data file with its
+// own search index, and IDs are allocated across all of them from next_id.
+// The blocklist and spam reports are kept once for the whole book.
+type Sharded struct {
+	dir    string
+	byID   bool
+	shards int
+	// ext is the file name extension of the shards, .csv or .csv.gz.
+	ext string
+
+	mu   sync.Mutex
+	open map[string]*Storage
+	book *Storage
+	idMu sync.Mutex
+	// txMu lets one transaction at a time lock several shards. Both are
+	// taken with a lock file, next_id.lock and tx.lock, for other processes.
+	txMu sync.Mutex
+}
+
+// OpenSharded returns a sharded backend for the directory in dsn, creating
+// the directory if needed.
+func OpenSharded(dsn string) (storage.Storage, error) {
+	dir, rawQuery, _ := strings.Cut(dsn, "?")
+	if dir == "" {
+		return nil, fmt.Errorf("csvshards storage needs a directory")
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid csvshards options %q: %v", rawQuery, err)
+	}
+
+	s := &Sharded{dir: dir, shards: defaultShards, ext: ".csv", open: make(map[string]*Storage)}
+
+	switch compress := query.Get("compress"); compress {
+	case "":
+	case "gzip":
+		s.ext = ".csv.gz"
+	default:
+		return nil, fmt.Errorf("cannot compress with %q, use gzip", compress)
+	}
+
+	switch mode := query.Get("shard"); mode {
+	case "", "surname":
+	case "id":
+		s.byID = true
+	default:
+		return nil, fmt.Errorf("cannot shard by %q, use surname or id", mode)
+	}
+
+	if shards := query.Get("shards"); shards != "" {
+		s.shards, err = strconv.Atoi(shards)
+		if err != nil || s.shards < 1 {
+			return nil, fmt.Errorf("invalid number of shards %q", shards)
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create data directory: %v", err)
+	}
+
+	s.book = &Storage{path: filepath.Join(dir, "book")}
+
+	return s, nil
+}
+
+// shardName picks the file of an entry. Latin surnames go by their first
+// letter; surnames in other scripts by the first letter of their
+// transliteration, so both spellings of a name end up together.
+func (s *Sharded) shardName(entry model.Entry) string {
+	if s.byID {
+		return fmt.Sprintf("%02d", entry.ID%int64(s.shards))
+	}
+
+	surname := strings.ToLower(strings.TrimSpace(entry.Surname))
+	for _, candidate := range []string{surname, search.Fold(surname)} {
+		if candidate != "" && candidate[0] >= 'a' && candidate[0] <= 'z' {
+			return candidate[:1]
+		}
+	}
+
+	return "_"
+}
+
+func (s *Sharded) shard(name string) *Storage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	shard, ok := s.open[name]
+	if !ok {
+		shard = &Storage{path: filepath.Join(s.dir, name+s.ext)}
+		s.open[name] = shard
+	}
+
+	return shard
+}
+
+// existing returns the shards that have a data file, in name order.
+func (s *Sharded) existing() ([]*Storage, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+s.ext))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+
+	shards := make([]*Storage, len(paths))
+	for i, path := range paths {
+		shards[i] = s.shard(strings.TrimSuffix(filepath.Base(path), s.ext))
+	}
+
+	return shards, nil
+}
+
+// candidateShards returns the shards that can hold id: just one with ID
+// sharding, all of them otherwise.
+func (s *Sharded) candidateShards(id int64) ([]*Storage, error) {
+	if s.byID {
+		return []*Storage{s.shard(s.shardName(model.Entry{ID: id}))}, nil
+	}
+
+	return s.existing()
+}
+
+func (s *Sharded) List(ctx context.Context) ([]model.Entry, *model.PhoeBookError) {
+	shards, err := s.existing()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	entries, appErr := eachShard(shards, func(shard *Storage) ([]model.Entry, *model.PhoeBookError) {
+		return shard.List(ctx)
+	})
+
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	return entries, nil
+}
+
+// eachShard calls read for every shard, at most maxParallelShards of them at
+// the same time, and returns all the entries they read, or the error of the
+// first shard that failed.
+func eachShard(shards []*Storage, read func(shard *Storage) ([]model.Entry, *model.PhoeBookError)) ([]model.Entry, *model.PhoeBookError) {
+	found := make([][]model.Entry, len(shards))
+	errs := make([]*model.PhoeBookError, len(shards))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxParallelShards)
+	for i, shard := range shards {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, shard *Storage) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			found[i], errs[i] = read(shard)
+		}(i, shard)
+	}
+
+	wg.Wait()
+
+	var entries []model.Entry
+	for i := range shards {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+
+		entries = append(entries, found[i]...)
+	}
+
+	return entries, nil
+}
+
+func (s *Sharded) Insert(ctx context.Context, entry *model.Entry) (int64, *model.PhoeBookError) {
+	id, err := s.nextID(ctx)
+	if err != nil {
+		return 0, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	newEntry := *entry
+	newEntry.ID = id
+	newEntry.Version = 1
+	newEntry.UpdatedAt = now()
+	if appErr := s.shard(s.shardName(newEntry)).insertWithID(newEntry); appErr != nil {
+		return 0, appErr
+	}
+
+	return id, nil
+}
+
+func (s *Sharded) Delete(ctx context.Context, id int64) *model.PhoeBookError {
+	return s.DeleteVersion(ctx, id, 0)
+}
+
+func (s *Sharded) DeleteVersion(ctx context.Context, id, version int64) *model.PhoeBookError {
+	shards, err := s.candidateShards(id)
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	for _, shard := range shards {
+		appErr := shard.DeleteVersion(ctx, id, version)
+		if appErr == nil || appErr.StatusCode != http.StatusNotFound {
+			return appErr
+		}
+	}
+
+	return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+}
+
+// Update edits the entry in its shard, moving it (and its photo) to another
+// shard when a new surname belongs elsewhere. A move is a transaction, so
+// the entry is never missing from both shards or in both.
+func (s *Sharded) Update(ctx context.Context, entry *model.Entry) *model.PhoeBookError {
+	shards, err := s.candidateShards(entry.ID)
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	target := s.shard(s.shardName(*entry))
+	if slices.Contains(shards, target) {
+		appErr := target.Update(ctx, entry)
+		if appErr == nil || appErr.StatusCode != http.StatusNotFound {
+			return appErr
+		}
+	}
+
+	return storage.Batch(ctx, s, func(tx storage.Tx) *model.PhoeBookError {
+		return tx.Update(ctx, entry)
+	})
+}
+
+func (s *Sharded) Close() error {
+	return nil
+}
+
+// Inspect reports the search index of every shard that has one.
+func (s *Sharded) Inspect(ctx context.Context) (model.Inspection, *model.PhoeBookError) {
+	shards, err := s.existing()
+	if err != nil {
+		return model.Inspection{}, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	var inspection model.Inspection
+	for _, shard := range shards {
+		if stats, ok := shard.indexStats(); ok {
+			inspection.Indexes = append(inspection.Indexes, stats)
+		}
+	}
+
+	return inspection, nil
+}
+
+// Fingerprint combines the fingerprints of every shard, so it also changes
+// when a shard is added.
+func (s *Sharded) Fingerprint(ctx context.Context) (string, *model.PhoeBookError) {
+	shards, err := s.existing()
+	if err != nil {
+		return "", &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	var fingerprint strings.Builder
+	for _, shard := range shards {
+		shardFingerprint, appErr := shard.Fingerprint(ctx)
+		if appErr != nil {
+			return "", appErr
+		}
+
+		fmt.Fprintf(&fingerprint, "%s %s;", filepath.Base(shard.path), shardFingerprint)
+	}
+
+	return fingerprint.String(), nil
+}
+
+// nextID hands out IDs from the next_id file. The first time, it is seeded
+// from the highest ID in any shard.
+func (s *Sharded) nextID(ctx context.Context) (int64, error) {
+	unlock, err := s.lock(&s.idMu, "next_id.lock")
+	if err != nil {
+		return 0, err
+	}
+
+	defer unlock()
+
+	if err := s.seedNextID(ctx); err != nil {
+		return 0, err
+	}
+
+	path := s.nextIDPath()
+	id, _, err := readCounter(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeCounter(path, id+1); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// seedNextID creates the next_id file if there is none yet. Seeding reads
+// every shard, so transactions do it before they lock any. The caller holds
+// the next_id lock.
+func (s *Sharded) seedNextID(ctx context.Context) error {
+	path := s.nextIDPath()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return err
+	}
+
+	entries, appErr := s.List(ctx)
+	if appErr != nil {
+		return fmt.Errorf("%s", appErr.Message)
+	}
+
+	return writeCounter(path, maxID(entries)+1)
+}
+
+func (s *Sharded) nextIDPath() string {
+	return filepath.Join(s.dir, "next_id")
+}
+
+// lock takes mu and the lock file name in the directory of the book, see
+// Storage.lock, and returns what releases both.
+func (s *Sharded) lock(mu *sync.Mutex, name string) (func(), error) {
+	mu.Lock()
+
+	file, err := lockFile(filepath.Join(s.dir, name))
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("cannot lock %s: %v", name, err)
+	}
+
+	return func() {
+		unlockFile(file)
+		mu.Unlock()
+	}, nil
+}
+
+// Candidates asks the index of every shard, several at the same time.
+// Shards too small to be indexed contribute all of their entries, so the
+// result is still a superset of the literal matches.
+func (s *Sharded) Candidates(ctx context.Context, term string) ([]model.Entry, bool, *model.PhoeBookError) {
+	if len(search.TermTokens(term)) == 0 {
+		return nil, false, nil
+	}
+
+	shards, err := s.existing()
+	if err != nil {
+		return nil, false, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	candidates, appErr := eachShard(shards, func(shard *Storage) ([]model.Entry, *model.PhoeBookError) {
+		entries, ok, appErr := shard.Candidates(ctx, term)
+		if appErr == nil && !ok {
+			entries, appErr = shard.List(ctx)
+		}
+
+		return entries, appErr
+	})
+
+	if appErr != nil {
+		return nil, false, appErr
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].ID < candidates[j].ID })
+
+	return candidates, true, nil
+}
+
+func (s *Sharded) SetPhoto(ctx context.Context, id int64, photo []byte) *model.PhoeBookError {
+	shards, err := s.candidateShards(id)
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	for _, shard := range shards {
+		appErr := shard.SetPhoto(ctx, id, photo)
+		if appErr == nil || appErr.StatusCode != http.StatusNotFound {
+			return appErr
+		}
+	}
+
+	return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+}
+
+func (s *Sharded) Photo(ctx context.Context, id int64) ([]byte, *model.PhoeBookError) {
+	shards, err := s.candidateShards(id)
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	for _, shard := range shards {
+		photo, appErr := shard.Photo(ctx, id)
+		if appErr == nil || appErr.StatusCode != http.StatusNotFound {
+			return photo, appErr
+		}
+	}
+
+	return nil, &model.PhoeBookError{Message: "the entry has no photo", StatusCode: http.StatusNotFound}
+}
+
+func (s *Sharded) Block(ctx context.Context, number string) *model.PhoeBookError {
+	return s.book.Block(ctx, number)
+}
+
+func (s *Sharded) Unblock(ctx context.Context, number string) *model.PhoeBookError {
+	return s.book.Unblock(ctx, number)
+}
+
+func (s *Sharded) Blocked(ctx context.Context) ([]string, *model.PhoeBookError) {
+	return s.book.Blocked(ctx)
+}
+
+func (s *Sharded) ReportSpam(ctx context.Context, report model.SpamReport) *model.PhoeBookError {
+	return s.book.ReportSpam(ctx, report)
+}
+
+func (s *Sharded) SpamReports(ctx context.Context, number string) ([]model.SpamReport, *model.PhoeBookError) {
+	return s.book.SpamReports(ctx, number)
+}
+
+func (s *Sharded) EraseSpamReports(ctx context.Context, number string) *model.PhoeBookError {
+	return s.book.EraseSpamReports(ctx, number)
+}
+
+func (s *Sharded) LogCall(ctx context.Context, call model.Call) *model.PhoeBookError {
+	return s.book.LogCall(ctx, call)
+}
+
+func (s *Sharded) Calls(ctx context.Context, id int64) ([]model.Call, *model.PhoeBookError) {
+	return s.book.Calls(ctx, id)
+}
+
+func (s *Sharded) EraseCalls(ctx context.Context, id int64) *model.PhoeBookError {
+	return s.book.EraseCalls(ctx, id)
+}
+
+func (s *Sharded) Link(ctx context.Context, link model.Link) *model.PhoeBookError {
+	return s.book.Link(ctx, link)
+}
+
+func (s *Sharded) Unlink(ctx context.Context, a, b int64) *model.PhoeBookError {
+	return s.book.Unlink(ctx, a, b)
+}
+
+func (s *Sharded) Links(ctx context.Context, id int64) ([]model.Link, *model.PhoeBookError) {
+	return s.book.Links(ctx, id)
+}
@@ -0,0 +1,56 @@
+package csvfile
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+func TestShardedUpdateMovesEntry(t *testing.T) {
+	ctx := context.Background()
+	opened, err := OpenSharded(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := opened.(*Sharded)
+	id, appErr := s.Insert(ctx, &model.Entry{Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if appErr := s.SetPhoto(ctx, id, []byte("photo")); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	// A new surname belongs to another shard, which the entry and its photo
+	// move to.
+	entry := model.Entry{ID: id, Name: "Ali", Surname: "Karimi", PhoneNumber: "+989121234567", Version: 1}
+	if appErr := s.Update(ctx, &entry); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	entries, appErr := s.List(ctx)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if len(entries) != 1 || entries[0].Surname != "Karimi" || entries[0].Version != 2 {
+		t.Fatalf("got %+v, want the one entry with its new surname", entries)
+	}
+
+	if moved, _ := s.shard("k").List(ctx); len(moved) != 1 {
+		t.Errorf("got %d entries in the shard of the new surname, want 1", len(moved))
+	}
+
+	if photo, appErr := s.Photo(ctx, id); appErr != nil || string(photo) != "photo" {
+		t.Errorf("got photo %q, %v, want it moved along", photo, appErr)
+	}
+
+	// The version it was moved with is stale now.
+	if appErr := s.Update(ctx, &model.Entry{ID: id, Surname: "Ahmadi", Version: 1}); appErr == nil || appErr.StatusCode != http.StatusConflict {
+		t.Errorf("got %v, want a conflict", appErr)
+	}
+}
@@ -0,0 +1,141 @@
+package csvfile
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Spam reports are appended to a CSV file next to the data file, one
+// number,reporter,reported_at,reason record per report.
+func (s *Storage) spamPath() string {
+	return s.path + ".spam"
+}
+
+func (s *Storage) ReportSpam(ctx context.Context, report model.SpamReport) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	file, err := os.OpenFile(s.spamPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save spam report: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{string(report.Number), report.Reporter, report.ReportedAt.Format(time.RFC3339), report.Reason})
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save spam report: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
+
+func (s *Storage) SpamReports(ctx context.Context, number string) ([]model.SpamReport, *model.PhoeBookError) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	defer unlock()
+
+	file, err := os.Open(s.spamPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("cannot read spam reports: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 4
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("cannot read spam reports: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	var reports []model.SpamReport
+	for _, record := range records {
+		if number != "" && record[0] != number {
+			continue
+		}
+
+		reportedAt, _ := time.Parse(time.RFC3339, record[2])
+		reports = append(reports, model.SpamReport{Number: model.PhoneNumber(record[0]), Reporter: record[1], ReportedAt: reportedAt, Reason: record[3]})
+	}
+
+	return reports, nil
+}
+
+func (s *Storage) EraseSpamReports(ctx context.Context, number string) *model.PhoeBookError {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return appErr
+	}
+
+	defer unlock()
+
+	file, err := os.Open(s.spamPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot read spam reports: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 4
+
+	records, err := reader.ReadAll()
+	file.Close()
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot read spam reports: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	kept := records[:0]
+	for _, record := range records {
+		if record[0] != number {
+			kept = append(kept, record)
+		}
+	}
+
+	// The reports are rewritten through a temporary file, so a failure
+	// leaves them as they were.
+	tmp := s.spamPath() + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save spam reports: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	writer := csv.NewWriter(out)
+	writer.WriteAll(kept)
+	if err := errors.Join(writer.Error(), out.Close()); err != nil {
+		os.Remove(tmp)
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save spam reports: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	if err := os.Rename(tmp, s.spamPath()); err != nil {
+		os.Remove(tmp)
+		return &model.PhoeBookError{Message: fmt.Sprintf("cannot save spam reports: %v", err), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
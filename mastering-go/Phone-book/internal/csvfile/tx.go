@@ -0,0 +1,413 @@
+package csvfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// fileTx is a transaction on one data file. It holds the file's lock from
+// begin to Commit or Rollback and stages every change in memory, so Commit
+// rewrites the file once, through the same temporary file and rename as any
+// other save.
+type fileTx struct {
+	s       *Storage
+	unlock  func()
+	entries []model.Entry
+	// deleted are the IDs whose photos go once the deletes are committed.
+	deleted []int64
+	// nextID is the ID the next insert gets, 0 until the first one. IDs of
+	// inserts that are rolled back are not reused.
+	nextID int64
+	done   bool
+}
+
+// Begin starts a transaction. Other requests to the book wait until it ends.
+func (s *Storage) Begin(ctx context.Context) (storage.Tx, *model.PhoeBookError) {
+	tx, err := s.begin()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return tx, nil
+}
+
+func (s *Storage) begin() (*fileTx, error) {
+	unlock, appErr := s.lock()
+	if appErr != nil {
+		return nil, errors.New(appErr.Message)
+	}
+
+	entries, err := s.load()
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	return &fileTx{s: s, unlock: unlock, entries: entries}, nil
+}
+
+func (t *fileTx) Insert(ctx context.Context, entry *model.Entry) (int64, *model.PhoeBookError) {
+	if t.done {
+		return 0, storage.TxDone()
+	}
+
+	if t.nextID == 0 {
+		next, err := t.s.peekID(t.entries)
+		if err != nil {
+			return 0, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+
+		t.nextID = next
+	}
+
+	newEntry := *entry
+	newEntry.ID = t.nextID
+	newEntry.Version = 1
+	newEntry.UpdatedAt = now()
+	t.entries = append(t.entries, newEntry)
+	t.nextID++
+
+	return newEntry.ID, nil
+}
+
+func (t *fileTx) insertWithID(entry model.Entry) {
+	t.entries = append(t.entries, entry)
+}
+
+func (t *fileTx) Update(ctx context.Context, entry *model.Entry) *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	i := indexOf(t.entries, entry.ID)
+	if i < 0 {
+		return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+	}
+
+	updated, appErr := update(t.entries[i], *entry)
+	if appErr != nil {
+		return appErr
+	}
+
+	t.entries[i] = updated
+	entry.Version = updated.Version
+	entry.UpdatedAt = updated.UpdatedAt
+
+	return nil
+}
+
+func (t *fileTx) Delete(ctx context.Context, id int64) *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	if _, ok := t.remove(id); !ok {
+		return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+	}
+
+	t.deleted = append(t.deleted, id)
+
+	return nil
+}
+
+// remove takes the entry with id out of the file without touching its photo.
+func (t *fileTx) remove(id int64) (model.Entry, bool) {
+	i := indexOf(t.entries, id)
+	if i < 0 {
+		return model.Entry{}, false
+	}
+
+	entry := t.entries[i]
+	t.entries = append(t.entries[:i], t.entries[i+1:]...)
+
+	return entry, true
+}
+
+func (t *fileTx) Commit() *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	defer t.release()
+
+	// The counter is saved first: one ahead of the file after a crash only
+	// skips IDs.
+	if t.nextID != 0 {
+		if err := writeCounter(t.s.nextIDPath(), t.nextID); err != nil {
+			return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+	}
+
+	st, err := t.s.stage(t.entries)
+	if err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	defer st.discard()
+
+	if err := t.finish(st); err != nil {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return nil
+}
+
+// finish moves the staged file into place and removes the photos of the
+// deleted entries.
+func (t *fileTx) finish(st *staged) error {
+	if err := st.commit(); err != nil {
+		return err
+	}
+
+	for _, id := range t.deleted {
+		os.Remove(t.s.photoPath(id))
+	}
+
+	return nil
+}
+
+func (t *fileTx) Rollback() *model.PhoeBookError {
+	if t.done {
+		return nil
+	}
+
+	defer t.release()
+
+	// The inserts are gone, but their IDs may have been shown already.
+	if t.nextID != 0 {
+		if err := writeCounter(t.s.nextIDPath(), t.nextID); err != nil {
+			return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+	}
+
+	return nil
+}
+
+func (t *fileTx) release() {
+	t.done = true
+	t.unlock()
+}
+
+// shardedTx is a transaction across the shards of a Sharded book. It begins
+// a fileTx on each shard it touches and commits them together: every shard is
+// staged first, and only when all of them were written are they moved into
+// place. IDs handed out to inserts that are rolled back are not reused.
+type shardedTx struct {
+	s      *Sharded
+	unlock func()
+	shards map[*Storage]*fileTx
+	// moves are the photos to carry along with entries that changed shard.
+	moves []photoMove
+	done  bool
+}
+
+type photoMove struct {
+	from, to string
+}
+
+// Begin starts a transaction. Shards it touches are locked until it ends.
+func (s *Sharded) Begin(ctx context.Context) (storage.Tx, *model.PhoeBookError) {
+	unlock, err := s.lock(&s.idMu, "next_id.lock")
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	err = s.seedNextID(ctx)
+	unlock()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	if unlock, err = s.lock(&s.txMu, "tx.lock"); err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	return &shardedTx{s: s, unlock: unlock, shards: make(map[*Storage]*fileTx)}, nil
+}
+
+// shard returns the transaction of shard, beginning it the first time.
+func (t *shardedTx) shard(shard *Storage) (*fileTx, *model.PhoeBookError) {
+	if tx, ok := t.shards[shard]; ok {
+		return tx, nil
+	}
+
+	tx, err := shard.begin()
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	t.shards[shard] = tx
+
+	return tx, nil
+}
+
+// find returns the transaction of the shard holding id, including shards
+// only created within this transaction.
+func (t *shardedTx) find(id int64) (*fileTx, *model.PhoeBookError) {
+	shards, err := t.s.candidateShards(id)
+	if err != nil {
+		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	for shard := range t.shards {
+		shards = append(shards, shard)
+	}
+
+	for _, shard := range shards {
+		tx, appErr := t.shard(shard)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		if indexOf(tx.entries, id) >= 0 {
+			return tx, nil
+		}
+	}
+
+	return nil, &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+}
+
+func (t *shardedTx) Insert(ctx context.Context, entry *model.Entry) (int64, *model.PhoeBookError) {
+	if t.done {
+		return 0, storage.TxDone()
+	}
+
+	id, err := t.s.nextID(ctx)
+	if err != nil {
+		return 0, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	newEntry := *entry
+	newEntry.ID = id
+	newEntry.Version = 1
+	newEntry.UpdatedAt = now()
+
+	tx, appErr := t.shard(t.s.shard(t.s.shardName(newEntry)))
+	if appErr != nil {
+		return 0, appErr
+	}
+
+	tx.insertWithID(newEntry)
+
+	return id, nil
+}
+
+func (t *shardedTx) Update(ctx context.Context, entry *model.Entry) *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	source, appErr := t.find(entry.ID)
+	if appErr != nil {
+		return appErr
+	}
+
+	targetShard := t.s.shard(t.s.shardName(*entry))
+	if source.s == targetShard {
+		return source.Update(ctx, entry)
+	}
+
+	target, appErr := t.shard(targetShard)
+	if appErr != nil {
+		return appErr
+	}
+
+	moved, appErr := update(source.entries[indexOf(source.entries, entry.ID)], *entry)
+	if appErr != nil {
+		return appErr
+	}
+
+	old, _ := source.remove(entry.ID)
+
+	moved.Photo = ""
+	if old.Photo != "" {
+		moved.Photo = targetShard.photoPath(entry.ID)
+		t.moves = append(t.moves, photoMove{from: source.s.photoPath(entry.ID), to: moved.Photo})
+	}
+
+	target.insertWithID(moved)
+	entry.Version = moved.Version
+	entry.UpdatedAt = moved.UpdatedAt
+
+	return nil
+}
+
+func (t *shardedTx) Delete(ctx context.Context, id int64) *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	tx, appErr := t.find(id)
+	if appErr != nil {
+		return appErr
+	}
+
+	return tx.Delete(ctx, id)
+}
+
+func (t *shardedTx) Commit() *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	defer t.release()
+
+	stagedShards := make(map[*fileTx]*staged, len(t.shards))
+	defer func() {
+		for _, st := range stagedShards {
+			st.discard()
+		}
+	}()
+
+	for _, tx := range t.shards {
+		st, err := tx.s.stage(tx.entries)
+		if err != nil {
+			return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+
+		stagedShards[tx] = st
+	}
+
+	for tx, st := range stagedShards {
+		if err := tx.finish(st); err != nil {
+			return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		}
+	}
+
+	for _, move := range t.moves {
+		if err := os.MkdirAll(filepath.Dir(move.to), 0755); err != nil {
+			return &model.PhoeBookError{Message: fmt.Sprintf("cannot move photo: %v", err), StatusCode: http.StatusInternalServerError}
+		}
+
+		if err := os.Rename(move.from, move.to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return &model.PhoeBookError{Message: fmt.Sprintf("cannot move photo: %v", err), StatusCode: http.StatusInternalServerError}
+		}
+	}
+
+	return nil
+}
+
+func (t *shardedTx) Rollback() *model.PhoeBookError {
+	if !t.done {
+		t.release()
+	}
+
+	return nil
+}
+
+func (t *shardedTx) release() {
+	for _, tx := range t.shards {
+		tx.release()
+	}
+
+	t.done = true
+	t.unlock()
+}
@@ -0,0 +1,152 @@
+package csvfile
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// surnames returns the surnames in store, ordered by ID.
+func surnames(t *testing.T, store storage.Storage) []string {
+	t.Helper()
+
+	entries, appErr := store.List(context.Background())
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	slices.SortFunc(entries, func(a, b model.Entry) int { return int(a.ID - b.ID) })
+
+	var all []string
+	for _, entry := range entries {
+		all = append(all, entry.Surname)
+	}
+
+	return all
+}
+
+func TestTx(t *testing.T) {
+	ctx := context.Background()
+
+	for _, backend := range []struct {
+		name string
+		open func(dir string) (storage.Storage, error)
+	}{
+		{name: "file", open: func(dir string) (storage.Storage, error) { return Open(filepath.Join(dir, "data.csv")) }},
+		{name: "sharded", open: OpenSharded},
+	} {
+		for _, test := range []struct {
+			commit bool
+			want   []string
+		}{
+			{commit: true, want: []string{"Karimi", "Rezaei"}},
+			{commit: false, want: []string{"Ahmadi", "Mohammadi"}},
+		} {
+			store, err := backend.open(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, surname := range []string{"Ahmadi", "Mohammadi"} {
+				if _, appErr := store.Insert(ctx, &model.Entry{Name: "Ali", Surname: surname, PhoneNumber: "+989121234567"}); appErr != nil {
+					t.Fatal(appErr)
+				}
+			}
+
+			tx, appErr := store.(storage.Transactional).Begin(ctx)
+			if appErr != nil {
+				t.Fatal(appErr)
+			}
+
+			// On the sharded book the update moves the entry to another
+			// shard.
+			if appErr := tx.Update(ctx, &model.Entry{ID: 1, Name: "Ali", Surname: "Karimi", PhoneNumber: "+989121234567", Version: 1}); appErr != nil {
+				t.Fatal(appErr)
+			}
+
+			if appErr := tx.Delete(ctx, 2); appErr != nil {
+				t.Fatal(appErr)
+			}
+
+			id, appErr := tx.Insert(ctx, &model.Entry{Name: "Sara", Surname: "Rezaei", PhoneNumber: "+989351112233"})
+			if appErr != nil {
+				t.Fatal(appErr)
+			}
+
+			if test.commit {
+				appErr = tx.Commit()
+			} else {
+				appErr = tx.Rollback()
+			}
+
+			if appErr != nil {
+				t.Fatalf("%s: %v", backend.name, appErr)
+			}
+
+			if got := surnames(t, store); !slices.Equal(got, test.want) {
+				t.Errorf("%s, committed %v: got %v, want %v", backend.name, test.commit, got, test.want)
+			}
+
+			if _, appErr := tx.Insert(ctx, &model.Entry{Name: "Reza"}); appErr == nil {
+				t.Errorf("%s, committed %v: the transaction was used after it ended", backend.name, test.commit)
+			}
+
+			// The transaction no longer holds the lock, and the ID of a
+			// rolled back insert is not handed out again.
+			next, appErr := store.Insert(ctx, &model.Entry{Name: "Reza", Surname: "Hosseini", PhoneNumber: "+989121112233"})
+			if appErr != nil {
+				t.Fatalf("%s: %v", backend.name, appErr)
+			}
+
+			if next <= id {
+				t.Errorf("%s, committed %v: got ID %d after %d", backend.name, test.commit, next, id)
+			}
+		}
+	}
+}
+
+func TestTxDeletesPhotoOnCommit(t *testing.T) {
+	ctx := context.Background()
+
+	for _, commit := range []bool{true, false} {
+		store, err := Open(filepath.Join(t.TempDir(), "data.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		id, appErr := store.Insert(ctx, &model.Entry{Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"})
+		if appErr != nil {
+			t.Fatal(appErr)
+		}
+
+		s := store.(*Storage)
+		if appErr := s.SetPhoto(ctx, id, []byte("photo")); appErr != nil {
+			t.Fatal(appErr)
+		}
+
+		appErr = storage.Batch(ctx, store, func(tx storage.Tx) *storage.Error {
+			if appErr := tx.Delete(ctx, id); appErr != nil {
+				return appErr
+			}
+
+			if !commit {
+				return storage.ConflictError()
+			}
+
+			return nil
+		})
+
+		if commit != (appErr == nil) {
+			t.Fatalf("committed %v: got %v", commit, appErr)
+		}
+
+		// A rolled back delete keeps the photo.
+		if _, err := s.Photo(ctx, id); commit == (err == nil) {
+			t.Errorf("committed %v: got photo error %v", commit, err)
+		}
+	}
+}
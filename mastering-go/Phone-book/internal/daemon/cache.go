@@ -0,0 +1,226 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// indexMinEntries is the size from which searches use the index kept in
+// memory, the same as for the index of the csv backend: smaller books are
+// scanned, which also finds fuzzy matches.
+const indexMinEntries = 1000
+
+// cache keeps the entries of a backend in memory, with a search index, for
+// as long as the backend's fingerprint says they didn't change. Backends
+// without a fingerprint are not cached.
+type cache struct {
+	store storage.Storage
+
+	mu          sync.Mutex
+	current     *snapshot
+	fingerprint string
+	// generation is the number of writes made through the daemon when the
+	// current snapshot was taken. It catches the writes the fingerprint
+	// misses, like two saves of the same size within the resolution of the
+	// file clock.
+	generation uint64
+	writes     uint64
+}
+
+// snapshot is the book at one point in time. Its entries are shared between
+// callers and must not be modified.
+type snapshot struct {
+	entries []storage.Entry
+
+	mu sync.Mutex
+	// tokens maps every search token to the positions in entries of the
+	// entries having it, in increasing order. It is built on the first
+	// search, see index.
+	tokens map[string][]int
+}
+
+// load returns the current snapshot of the book, or nil when the backend
+// cannot be cached.
+func (c *cache) load(ctx context.Context) (*snapshot, *storage.Error) {
+	fingerprinter, ok := c.store.(storage.Fingerprinter)
+	if !ok {
+		return nil, nil
+	}
+
+	// The fingerprint is taken before listing: a change made in between is
+	// in the entries, but makes the next fingerprint differ anyway.
+	fingerprint, appErr := fingerprinter.Fingerprint(ctx)
+	if appErr != nil && appErr.StatusCode == http.StatusNotImplemented {
+		return nil, nil
+	}
+
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	c.mu.Lock()
+	if c.current != nil && c.fingerprint == fingerprint && c.generation == c.writes {
+		current := c.current
+		c.mu.Unlock()
+		return current, nil
+	}
+
+	writes := c.writes
+	c.mu.Unlock()
+
+	entries, appErr := c.store.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	current := &snapshot{entries: entries}
+
+	c.mu.Lock()
+	c.current, c.fingerprint, c.generation = current, fingerprint, writes
+	c.mu.Unlock()
+
+	return current, nil
+}
+
+func (c *cache) list(ctx context.Context) ([]storage.Entry, *storage.Error) {
+	current, appErr := c.load(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if current == nil {
+		return c.store.List(ctx)
+	}
+
+	return current.entries, nil
+}
+
+// candidates answers storage.Index from memory, or asks the backend when it
+// is not cached.
+func (c *cache) candidates(ctx context.Context, term string) ([]storage.Entry, bool, *storage.Error) {
+	current, appErr := c.load(ctx)
+	if appErr != nil {
+		return nil, false, appErr
+	}
+
+	if current == nil {
+		if idx, ok := c.store.(storage.Index); ok {
+			return idx.Candidates(ctx, term)
+		}
+
+		return nil, false, nil
+	}
+
+	sets := search.TermTokens(term)
+	if len(sets) == 0 || len(current.entries) < indexMinEntries {
+		return nil, false, nil
+	}
+
+	index := current.index()
+
+	var positions []int
+	for _, tokens := range sets {
+		matching := index[tokens[0]]
+		for _, token := range tokens[1:] {
+			matching = intersect(matching, index[token])
+		}
+
+		positions = union(positions, matching)
+	}
+
+	entries := make([]storage.Entry, len(positions))
+	for i, position := range positions {
+		entries[i] = current.entries[position]
+	}
+
+	return entries, true, nil
+}
+
+// changed records that the entries were written to.
+func (c *cache) changed() {
+	c.mu.Lock()
+	c.writes++
+	c.mu.Unlock()
+}
+
+// indexStats describes the index in memory, if it was built.
+func (c *cache) indexStats() (model.IndexStats, bool) {
+	c.mu.Lock()
+	current := c.current
+	c.mu.Unlock()
+
+	if current == nil {
+		return model.IndexStats{}, false
+	}
+
+	current.mu.Lock()
+	defer current.mu.Unlock()
+
+	if current.tokens == nil {
+		return model.IndexStats{}, false
+	}
+
+	return model.IndexStats{Name: "daemon memory", Entries: len(current.entries), Tokens: len(current.tokens)}, true
+}
+
+// index returns the tokens of the snapshot, building them the first time.
+func (s *snapshot) index() map[string][]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens == nil {
+		s.tokens = make(map[string][]int)
+		for i, entry := range s.entries {
+			for _, token := range search.Tokens(entry) {
+				s.tokens[token] = append(s.tokens[token], i)
+			}
+		}
+	}
+
+	return s.tokens
+}
+
+func intersect(a, b []int) []int {
+	var both []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			both = append(both, a[i])
+			i++
+			j++
+		}
+	}
+
+	return both
+}
+
+func union(a, b []int) []int {
+	var either []int
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			either = append(either, a[i])
+			i++
+		case a[i] > b[j]:
+			either = append(either, b[j])
+			j++
+		default:
+			either = append(either, a[i])
+			i++
+			j++
+		}
+	}
+
+	either = append(either, a[i:]...)
+	return append(either, b[j:]...)
+}
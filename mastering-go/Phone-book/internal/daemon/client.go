@@ -0,0 +1,217 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/rpc"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+func init() {
+	storage.Register("daemon", Dial)
+}
+
+// Client is the backend of a phone book served by a daemon. It has every
+// optional feature but transactions; the ones the daemon's own backend lacks
+// fail with storage.Unsupported.
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial connects to the daemon listening on the Unix socket at path.
+func Dial(path string) (storage.Storage, error) {
+	if path == "" {
+		return nil, fmt.Errorf("daemon storage needs the path of the daemon's socket")
+	}
+
+	client, err := rpc.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach the phone book daemon: %v", err)
+	}
+
+	return &Client{rpc: client}, nil
+}
+
+// call runs method on the daemon, giving up when ctx is done. The daemon
+// still finishes what it started.
+func (c *Client) call(ctx context.Context, method string, args, reply any) *storage.Error {
+	call := c.rpc.Go("Daemon."+method, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-ctx.Done():
+		return storage.ContextError(ctx)
+	case <-call.Done:
+	}
+
+	if call.Error != nil {
+		return &storage.Error{Message: "the phone book daemon stopped answering: " + call.Error.Error(), StatusCode: http.StatusServiceUnavailable}
+	}
+
+	return nil
+}
+
+func (c *Client) List(ctx context.Context) ([]storage.Entry, *storage.Error) {
+	var reply EntriesReply
+	if appErr := c.call(ctx, "List", 0, &reply); appErr != nil {
+		return nil, appErr
+	}
+
+	return reply.Entries, reply.Err
+}
+
+func (c *Client) Insert(ctx context.Context, entry *storage.Entry) (int64, *storage.Error) {
+	var reply EntryReply
+	if appErr := c.call(ctx, "Insert", &EntryArgs{Entry: *entry}, &reply); appErr != nil {
+		return 0, appErr
+	}
+
+	return reply.Entry.ID, reply.Err
+}
+
+func (c *Client) Update(ctx context.Context, entry *storage.Entry) *storage.Error {
+	var reply EntryReply
+	if appErr := c.call(ctx, "Update", &EntryArgs{Entry: *entry}, &reply); appErr != nil {
+		return appErr
+	}
+
+	if reply.Err != nil {
+		return reply.Err
+	}
+
+	entry.Version, entry.UpdatedAt = reply.Entry.Version, reply.Entry.UpdatedAt
+
+	return nil
+}
+
+func (c *Client) Delete(ctx context.Context, id int64) *storage.Error {
+	return c.errorCall(ctx, "Delete", &IDArgs{ID: id})
+}
+
+func (c *Client) DeleteVersion(ctx context.Context, id, version int64) *storage.Error {
+	return c.errorCall(ctx, "DeleteVersion", &IDArgs{ID: id, Version: version})
+}
+
+func (c *Client) Block(ctx context.Context, number string) *storage.Error {
+	return c.errorCall(ctx, "Block", &NumberArgs{Number: number})
+}
+
+func (c *Client) Unblock(ctx context.Context, number string) *storage.Error {
+	return c.errorCall(ctx, "Unblock", &NumberArgs{Number: number})
+}
+
+func (c *Client) Blocked(ctx context.Context) ([]string, *storage.Error) {
+	var reply NumbersReply
+	if appErr := c.call(ctx, "Blocked", 0, &reply); appErr != nil {
+		return nil, appErr
+	}
+
+	return reply.Numbers, reply.Err
+}
+
+func (c *Client) ReportSpam(ctx context.Context, report storage.SpamReport) *storage.Error {
+	return c.errorCall(ctx, "ReportSpam", &SpamArgs{Report: report})
+}
+
+func (c *Client) SpamReports(ctx context.Context, number string) ([]storage.SpamReport, *storage.Error) {
+	var reply SpamReply
+	if appErr := c.call(ctx, "SpamReports", &NumberArgs{Number: number}, &reply); appErr != nil {
+		return nil, appErr
+	}
+
+	return reply.Reports, reply.Err
+}
+
+func (c *Client) EraseSpamReports(ctx context.Context, number string) *storage.Error {
+	return c.errorCall(ctx, "EraseSpamReports", &NumberArgs{Number: number})
+}
+
+func (c *Client) LogCall(ctx context.Context, call storage.Call) *storage.Error {
+	return c.errorCall(ctx, "LogCall", &CallArgs{Call: call})
+}
+
+func (c *Client) Calls(ctx context.Context, id int64) ([]storage.Call, *storage.Error) {
+	var reply CallsReply
+	if appErr := c.call(ctx, "Calls", &IDArgs{ID: id}, &reply); appErr != nil {
+		return nil, appErr
+	}
+
+	return reply.Calls, reply.Err
+}
+
+func (c *Client) EraseCalls(ctx context.Context, id int64) *storage.Error {
+	return c.errorCall(ctx, "EraseCalls", &IDArgs{ID: id})
+}
+
+func (c *Client) Link(ctx context.Context, link storage.Link) *storage.Error {
+	return c.errorCall(ctx, "Link", &LinkArgs{Link: link})
+}
+
+func (c *Client) Unlink(ctx context.Context, a, b int64) *storage.Error {
+	return c.errorCall(ctx, "Unlink", &LinkArgs{Link: storage.Link{From: a, To: b}})
+}
+
+func (c *Client) Links(ctx context.Context, id int64) ([]storage.Link, *storage.Error) {
+	var reply LinksReply
+	if appErr := c.call(ctx, "Links", &IDArgs{ID: id}, &reply); appErr != nil {
+		return nil, appErr
+	}
+
+	return reply.Links, reply.Err
+}
+
+func (c *Client) SetPhoto(ctx context.Context, id int64, photo []byte) *storage.Error {
+	return c.errorCall(ctx, "SetPhoto", &PhotoArgs{ID: id, Photo: photo})
+}
+
+func (c *Client) Photo(ctx context.Context, id int64) ([]byte, *storage.Error) {
+	var reply PhotoReply
+	if appErr := c.call(ctx, "Photo", &IDArgs{ID: id}, &reply); appErr != nil {
+		return nil, appErr
+	}
+
+	return reply.Photo, reply.Err
+}
+
+func (c *Client) Candidates(ctx context.Context, term string) ([]storage.Entry, bool, *storage.Error) {
+	var reply EntriesReply
+	if appErr := c.call(ctx, "Candidates", &TermArgs{Term: term}, &reply); appErr != nil {
+		return nil, false, appErr
+	}
+
+	return reply.Entries, reply.OK, reply.Err
+}
+
+// Health returns the checks of the daemon's backend, or a failing check of
+// the daemon when it doesn't answer.
+func (c *Client) Health(ctx context.Context) []storage.HealthCheck {
+	var reply HealthReply
+	if appErr := c.call(ctx, "Health", 0, &reply); appErr != nil {
+		return []storage.HealthCheck{{Name: "daemon", Status: model.HealthFailing, Detail: appErr.Message}}
+	}
+
+	return reply.Checks
+}
+
+func (c *Client) Inspect(ctx context.Context) (storage.Inspection, *storage.Error) {
+	var reply InspectReply
+	if appErr := c.call(ctx, "Inspect", 0, &reply); appErr != nil {
+		return storage.Inspection{}, appErr
+	}
+
+	return reply.Inspection, reply.Err
+}
+
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+func (c *Client) errorCall(ctx context.Context, method string, args any) *storage.Error {
+	var reply ErrorReply
+	if appErr := c.call(ctx, method, args, &reply); appErr != nil {
+		return appErr
+	}
+
+	return reply.Err
+}
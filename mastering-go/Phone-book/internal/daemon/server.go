@@ -0,0 +1,432 @@
+// Package daemon keeps a phone book loaded in a long running process that
+// command line invocations talk to over a Unix socket, so they answer in
+// milliseconds instead of reading the data file again every time.
+//
+// The daemon is started with
+//
+//	phonebook -storage csv -dsn book.csv daemon
+//
+// and used through the "daemon" backend, whose data source is the socket:
+//
+//	phonebook -storage daemon -dsn /run/user/1000/phonebook-0123456789ab.sock list
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/diagnostics"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// SocketPath is the socket the daemon of a phone book listens on unless
+// told otherwise. It is derived from the backend and the data source, so
+// every phone book gets a daemon of its own, in $XDG_RUNTIME_DIR or else a
+// directory of the user in the temporary directory.
+func SocketPath(backend, dsn string) string {
+	// Relative file names mean the same file from any directory.
+	path, query, _ := strings.Cut(dsn, "?")
+	if _, err := os.Stat(path); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			dsn = abs
+			if query != "" {
+				dsn += "?" + query
+			}
+		}
+	}
+
+	sum := sha256.Sum256([]byte(backend + "\x00" + dsn))
+	name := "phonebook-" + hex.EncodeToString(sum[:])[:12] + ".sock"
+
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, name)
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("phonebook-%d", os.Getuid()), name)
+}
+
+// Serve loads store and answers the clients connecting to the Unix socket
+// at path until ctx is done. Only the user running the daemon can connect.
+func Serve(ctx context.Context, store storage.Storage, path string) error {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("a daemon is already running on %s", path)
+	}
+
+	// Nobody answers: the socket was left behind by a daemon that didn't
+	// stop cleanly.
+	os.Remove(path)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	svc := &service{ctx: ctx, store: store, cache: &cache{store: store}}
+	current, appErr := svc.cache.load(ctx)
+	if appErr != nil {
+		return fmt.Errorf("%s", appErr.Message)
+	}
+
+	// Indexing takes a while for big books, so the first search shouldn't
+	// have to.
+	if current != nil && len(current.entries) >= indexMinEntries {
+		current.index()
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+
+	defer listener.Close()
+
+	if err := os.Chmod(path, 0600); err != nil {
+		return err
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Daemon", svc); err != nil {
+		return err
+	}
+
+	log.Printf("daemon: serving the phone book on %s", path)
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		go server.ServeConn(conn)
+	}
+}
+
+// The arguments and replies of the daemon's methods. Errors of the backend
+// travel in the replies, the error of a call is left to the connection.
+type (
+	EntryArgs struct {
+		Entry storage.Entry
+	}
+	IDArgs struct {
+		ID      int64
+		Version int64
+	}
+	NumberArgs struct {
+		Number string
+	}
+	SpamArgs struct {
+		Report storage.SpamReport
+	}
+	CallArgs struct {
+		Call storage.Call
+	}
+	LinkArgs struct {
+		Link storage.Link
+	}
+	PhotoArgs struct {
+		ID    int64
+		Photo []byte
+	}
+	TermArgs struct {
+		Term string
+	}
+
+	ErrorReply struct {
+		Err *storage.Error
+	}
+	EntriesReply struct {
+		Entries []storage.Entry
+		// OK is what Candidates returns as ok.
+		OK  bool
+		Err *storage.Error
+	}
+	EntryReply struct {
+		Entry storage.Entry
+		Err   *storage.Error
+	}
+	NumbersReply struct {
+		Numbers []string
+		Err     *storage.Error
+	}
+	SpamReply struct {
+		Reports []storage.SpamReport
+		Err     *storage.Error
+	}
+	CallsReply struct {
+		Calls []storage.Call
+		Err   *storage.Error
+	}
+	LinksReply struct {
+		Links []storage.Link
+		Err   *storage.Error
+	}
+	PhotoReply struct {
+		Photo []byte
+		Err   *storage.Error
+	}
+	HealthReply struct {
+		Checks []storage.HealthCheck
+	}
+	InspectReply struct {
+		Inspection storage.Inspection
+		Err        *storage.Error
+	}
+)
+
+// service exposes a backend over net/rpc. Its methods run on the context of
+// the daemon: a client that gives up doesn't stop a write half way.
+type service struct {
+	ctx   context.Context
+	store storage.Storage
+	cache *cache
+}
+
+func (s *service) List(_ int, reply *EntriesReply) error {
+	reply.Entries, reply.Err = s.cache.list(s.ctx)
+	return nil
+}
+
+func (s *service) Insert(args *EntryArgs, reply *EntryReply) error {
+	defer s.cache.changed()
+
+	reply.Entry.ID, reply.Err = s.store.Insert(s.ctx, &args.Entry)
+	return nil
+}
+
+func (s *service) Update(args *EntryArgs, reply *EntryReply) error {
+	updater, ok := s.store.(storage.Updater)
+	if !ok {
+		reply.Err = storage.Unsupported("updates")
+		return nil
+	}
+
+	defer s.cache.changed()
+
+	reply.Err = updater.Update(s.ctx, &args.Entry)
+	reply.Entry = args.Entry
+	return nil
+}
+
+func (s *service) Delete(args *IDArgs, reply *ErrorReply) error {
+	defer s.cache.changed()
+
+	reply.Err = s.store.Delete(s.ctx, args.ID)
+	return nil
+}
+
+func (s *service) DeleteVersion(args *IDArgs, reply *ErrorReply) error {
+	versioned, ok := s.store.(storage.VersionedDeleter)
+	if !ok {
+		reply.Err = storage.Unsupported("versioned deletes")
+		return nil
+	}
+
+	defer s.cache.changed()
+
+	reply.Err = versioned.DeleteVersion(s.ctx, args.ID, args.Version)
+	return nil
+}
+
+func (s *service) Block(args *NumberArgs, reply *ErrorReply) error {
+	blocklist, ok := s.store.(storage.Blocklist)
+	if !ok {
+		reply.Err = storage.Unsupported("blocking numbers")
+		return nil
+	}
+
+	reply.Err = blocklist.Block(s.ctx, args.Number)
+	return nil
+}
+
+func (s *service) Unblock(args *NumberArgs, reply *ErrorReply) error {
+	blocklist, ok := s.store.(storage.Blocklist)
+	if !ok {
+		reply.Err = storage.Unsupported("blocking numbers")
+		return nil
+	}
+
+	reply.Err = blocklist.Unblock(s.ctx, args.Number)
+	return nil
+}
+
+func (s *service) Blocked(_ int, reply *NumbersReply) error {
+	blocklist, ok := s.store.(storage.Blocklist)
+	if !ok {
+		reply.Err = storage.Unsupported("blocking numbers")
+		return nil
+	}
+
+	reply.Numbers, reply.Err = blocklist.Blocked(s.ctx)
+	return nil
+}
+
+func (s *service) ReportSpam(args *SpamArgs, reply *ErrorReply) error {
+	spamReports, ok := s.store.(storage.SpamReports)
+	if !ok {
+		reply.Err = storage.Unsupported("spam reports")
+		return nil
+	}
+
+	reply.Err = spamReports.ReportSpam(s.ctx, args.Report)
+	return nil
+}
+
+func (s *service) SpamReports(args *NumberArgs, reply *SpamReply) error {
+	spamReports, ok := s.store.(storage.SpamReports)
+	if !ok {
+		reply.Err = storage.Unsupported("spam reports")
+		return nil
+	}
+
+	reply.Reports, reply.Err = spamReports.SpamReports(s.ctx, args.Number)
+	return nil
+}
+
+func (s *service) EraseSpamReports(args *NumberArgs, reply *ErrorReply) error {
+	eraser, ok := s.store.(storage.SpamEraser)
+	if !ok {
+		reply.Err = storage.Unsupported("erasing spam reports")
+		return nil
+	}
+
+	reply.Err = eraser.EraseSpamReports(s.ctx, args.Number)
+	return nil
+}
+
+func (s *service) LogCall(args *CallArgs, reply *ErrorReply) error {
+	callLog, ok := s.store.(storage.CallLog)
+	if !ok {
+		reply.Err = storage.Unsupported("call logs")
+		return nil
+	}
+
+	reply.Err = callLog.LogCall(s.ctx, args.Call)
+	return nil
+}
+
+func (s *service) Calls(args *IDArgs, reply *CallsReply) error {
+	callLog, ok := s.store.(storage.CallLog)
+	if !ok {
+		reply.Err = storage.Unsupported("call logs")
+		return nil
+	}
+
+	reply.Calls, reply.Err = callLog.Calls(s.ctx, args.ID)
+	return nil
+}
+
+func (s *service) EraseCalls(args *IDArgs, reply *ErrorReply) error {
+	callLog, ok := s.store.(storage.CallLog)
+	if !ok {
+		reply.Err = storage.Unsupported("call logs")
+		return nil
+	}
+
+	reply.Err = callLog.EraseCalls(s.ctx, args.ID)
+	return nil
+}
+
+func (s *service) Link(args *LinkArgs, reply *ErrorReply) error {
+	relationships, ok := s.store.(storage.Relationships)
+	if !ok {
+		reply.Err = storage.Unsupported("links")
+		return nil
+	}
+
+	reply.Err = relationships.Link(s.ctx, args.Link)
+	return nil
+}
+
+// Unlink takes the two entries as the From and To of the link.
+func (s *service) Unlink(args *LinkArgs, reply *ErrorReply) error {
+	relationships, ok := s.store.(storage.Relationships)
+	if !ok {
+		reply.Err = storage.Unsupported("links")
+		return nil
+	}
+
+	reply.Err = relationships.Unlink(s.ctx, args.Link.From, args.Link.To)
+	return nil
+}
+
+func (s *service) Links(args *IDArgs, reply *LinksReply) error {
+	relationships, ok := s.store.(storage.Relationships)
+	if !ok {
+		reply.Err = storage.Unsupported("links")
+		return nil
+	}
+
+	reply.Links, reply.Err = relationships.Links(s.ctx, args.ID)
+	return nil
+}
+
+func (s *service) SetPhoto(args *PhotoArgs, reply *ErrorReply) error {
+	photos, ok := s.store.(storage.Photos)
+	if !ok {
+		reply.Err = storage.Unsupported("photos")
+		return nil
+	}
+
+	// The entry's photo reference changes too.
+	defer s.cache.changed()
+
+	reply.Err = photos.SetPhoto(s.ctx, args.ID, args.Photo)
+	return nil
+}
+
+func (s *service) Photo(args *IDArgs, reply *PhotoReply) error {
+	photos, ok := s.store.(storage.Photos)
+	if !ok {
+		reply.Err = storage.Unsupported("photos")
+		return nil
+	}
+
+	reply.Photo, reply.Err = photos.Photo(s.ctx, args.ID)
+	return nil
+}
+
+func (s *service) Candidates(args *TermArgs, reply *EntriesReply) error {
+	reply.Entries, reply.OK, reply.Err = s.cache.candidates(s.ctx, args.Term)
+	return nil
+}
+
+func (s *service) Health(_ int, reply *HealthReply) error {
+	reply.Checks = storage.Health(s.ctx, s.store)
+	return nil
+}
+
+// Inspect adds the index kept in memory and the state of the daemon process
+// to what the backend reports.
+func (s *service) Inspect(_ int, reply *InspectReply) error {
+	if inspector, ok := s.store.(storage.Inspector); ok {
+		if reply.Inspection, reply.Err = inspector.Inspect(s.ctx); reply.Err != nil {
+			return nil
+		}
+	}
+
+	if stats, ok := s.cache.indexStats(); ok {
+		reply.Inspection.Indexes = append(reply.Inspection.Indexes, stats)
+	}
+
+	daemon := diagnostics.Runtime()
+	reply.Inspection.Daemon = &daemon
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+func (r *Repository) Block(ctx context.Context, number string) *model.PhoeBookError {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO blocked_numbers (number) VALUES ($1) ON CONFLICT DO NOTHING", number)
+	if err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *Repository) Unblock(ctx context.Context, number string) *model.PhoeBookError {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM blocked_numbers WHERE number = $1", number)
+	if err != nil {
+		return dbError(err)
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+
+	if affectedRows == 0 {
+		return &model.PhoeBookError{Message: "the number is not blocked", StatusCode: http.StatusNotFound}
+	}
+
+	return nil
+}
+
+func (r *Repository) Blocked(ctx context.Context) ([]string, *model.PhoeBookError) {
+	rows, err := r.db.QueryContext(ctx, "SELECT number FROM blocked_numbers ORDER BY number")
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	defer rows.Close()
+
+	var numbers []string
+	for rows.Next() {
+		var number string
+		if err := rows.Scan(&number); err != nil {
+			return nil, dbError(err)
+		}
+
+		numbers = append(numbers, number)
+	}
+
+	return numbers, nil
+}
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+func (r *Repository) LogCall(ctx context.Context, call model.Call) *model.PhoeBookError {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO calls (entry_id, called_at, duration_seconds, note) VALUES ($1, $2, $3, $4)", call.EntryID, call.At, int64(call.Duration/time.Second), call.Note)
+	if err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *Repository) EraseCalls(ctx context.Context, id int64) *model.PhoeBookError {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM calls WHERE entry_id = $1", id); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *Repository) Calls(ctx context.Context, id int64) ([]model.Call, *model.PhoeBookError) {
+	rows, err := r.db.QueryContext(ctx, "SELECT entry_id, called_at, duration_seconds, note FROM calls WHERE $1 = 0 OR entry_id = $1 ORDER BY id", id)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	defer rows.Close()
+
+	var calls []model.Call
+	for rows.Next() {
+		var call model.Call
+		var seconds int64
+		if err := rows.Scan(&call.EntryID, &call.At, &seconds, &call.Note); err != nil {
+			return nil, dbError(err)
+		}
+
+		call.Duration = time.Duration(seconds) * time.Second
+		calls = append(calls, call)
+	}
+
+	return calls, nil
+}
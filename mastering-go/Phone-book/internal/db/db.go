@@ -2,23 +2,69 @@ package db
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 
 	_ "github.com/lib/pq"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
 )
 
-func ConnectDB() (*sql.DB, error) {
-	connectionStr := "user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"
+const defaultDSN = "user=postgres password=postgres dbname=postgres port=5432 sslmode=disable"
 
-	conn, err := sql.Open("postgres", connectionStr)
+func init() {
+	storage.Register("postgres", Open)
+}
+
+// Open connects to postgres and returns it as a phone book storage backend.
+// An empty dsn falls back to the docker-compose defaults.
+func Open(dsn string) (storage.Storage, error) {
+	conn, err := ConnectDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repository{db: conn}, nil
+}
+
+func ConnectDB(dsn string) (*sql.DB, error) {
+	if dsn == "" {
+		dsn = defaultDSN
+	}
+
+	conn, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("cannot connect to database!!")
 	}
 
 	err = conn.Ping()
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect to database: %v", err)
+		return nil, fmt.Errorf("cannot connect to database: %w", err)
 	}
 
 	return conn, nil
 }
+
+// dbError is the error of a failed query, 503 Service Unavailable when the
+// database could not be reached so callers can tell an outage from a query
+// that failed.
+func dbError(err error) *model.PhoeBookError {
+	if Unreachable(err) {
+		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusServiceUnavailable}
+	}
+
+	return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+}
+
+// Unreachable tells whether err comes from not reaching the database before
+// anything was sent, such as a refused connection or one found broken
+// before the query, rather than from the database refusing a query or a
+// login. A connection breaking off during a query is not: the query may
+// have run, so a change must not be queued to be made again.
+func Unreachable(err error) bool {
+	var opErr *net.OpError
+	return (errors.As(err, &opErr) && opErr.Op == "dial") || errors.Is(err, driver.ErrBadConn)
+}
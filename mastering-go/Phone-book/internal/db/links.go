@@ -0,0 +1,58 @@
+package db
+
+import (
+	"context"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Link drops the link the other way round, if any, in the same statement,
+// so two entries keep at most one link.
+func (r *Repository) Link(ctx context.Context, link model.Link) *model.PhoeBookError {
+	_, err := r.db.ExecContext(ctx, "WITH reversed AS (DELETE FROM links WHERE from_id = $2 AND to_id = $1) INSERT INTO links (from_id, to_id, type) VALUES ($1, $2, $3) ON CONFLICT (from_id, to_id) DO UPDATE SET type = EXCLUDED.type", link.From, link.To, link.Type)
+	if err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *Repository) Unlink(ctx context.Context, a, b int64) *model.PhoeBookError {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM links WHERE (from_id = $1 AND to_id = $2) OR (from_id = $2 AND to_id = $1)", a, b)
+	if err != nil {
+		return dbError(err)
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+
+	if affectedRows == 0 {
+		return storage.NoLinkError()
+	}
+
+	return nil
+}
+
+func (r *Repository) Links(ctx context.Context, id int64) ([]model.Link, *model.PhoeBookError) {
+	rows, err := r.db.QueryContext(ctx, "SELECT from_id, to_id, type FROM links WHERE $1 = 0 OR from_id = $1 OR to_id = $1 ORDER BY from_id, to_id", id)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	defer rows.Close()
+
+	var links []model.Link
+	for rows.Next() {
+		var link model.Link
+		if err := rows.Scan(&link.From, &link.To, &link.Type); err != nil {
+			return nil, dbError(err)
+		}
+
+		links = append(links, link)
+	}
+
+	return links, nil
+}
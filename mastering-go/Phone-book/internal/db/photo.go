@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// photoStored is the Photo reference of entries whose photo is kept in the
+// photos table.
+const photoStored = "stored"
+
+// SetPhoto marks the entry as having a photo and stores it in one
+// transaction, so an entry is never marked without its photo.
+func (r *Repository) SetPhoto(ctx context.Context, id int64, photo []byte) *model.PhoeBookError {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dbError(err)
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE phone_book SET photo = $1 WHERE id = $2", photoStored, id)
+	if err != nil {
+		return dbError(err)
+	}
+
+	affectedRows, err := result.RowsAffected()
+	if err != nil {
+		return dbError(err)
+	}
+
+	if affectedRows == 0 {
+		return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO photos (entry_id, data) VALUES ($1, $2) ON CONFLICT (entry_id) DO UPDATE SET data = EXCLUDED.data", id, photo)
+	if err != nil {
+		return dbError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *Repository) Photo(ctx context.Context, id int64) ([]byte, *model.PhoeBookError) {
+	var photo []byte
+	err := r.db.QueryRowContext(ctx, "SELECT data FROM photos WHERE entry_id = $1", id).Scan(&photo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &model.PhoeBookError{Message: "the entry has no photo", StatusCode: http.StatusNotFound}
+	}
+
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	return photo, nil
+}
@@ -1,32 +1,58 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"net/http"
+	"time"
 
 	_ "github.com/lib/pq"
 	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
 )
 
-func GetList() ([]model.Entry, *model.PhoeBookError) {
-	db, err := ConnectDB()
-	if err != nil {
-		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+// Repository is the postgres implementation of storage.Storage.
+type Repository struct {
+	db *sql.DB
+}
+
+// entryColumns are the phone_book columns read into a model.Entry by
+// scanEntry, in that order.
+const entryColumns = "id, name, surname, nickname, phone_number, country, photo, company, title, birthday, anniversary, remind_days, version, updated_at, archived_at, consent, consent_date, preferred_channel, access_notes"
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(row scanner) (model.Entry, error) {
+	var entry model.Entry
+	var updatedAt, archivedAt sql.NullTime
+	err := row.Scan(&entry.ID, &entry.Name, &entry.Surname, &entry.Nickname, &entry.PhoneNumber, &entry.Country, &entry.Photo, &entry.Company, &entry.Title, &entry.Birthday, &entry.Anniversary, &entry.RemindDays, &entry.Version, &updatedAt, &archivedAt, &entry.Consent, &entry.ConsentDate, &entry.Channel, &entry.AccessNotes)
+	if updatedAt.Valid {
+		entry.UpdatedAt = &updatedAt.Time
 	}
 
-	defer db.Close()
+	if archivedAt.Valid {
+		entry.ArchivedAt = &archivedAt.Time
+	}
+
+	return entry, err
+}
 
-	rows, err := db.Query("SELECT * FROM phone_book")
+func (r *Repository) List(ctx context.Context) ([]model.Entry, *model.PhoeBookError) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+entryColumns+" FROM phone_book ORDER BY id")
 	if err != nil {
-		return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		return nil, dbError(err)
 	}
 
+	defer rows.Close()
+
 	var entries []model.Entry
 	for rows.Next() {
-		var entry model.Entry
-
-		err := rows.Scan(&entry.ID, &entry.Name, &entry.Surname, &entry.PhoneNumber)
+		entry, err := scanEntry(rows)
 		if err != nil {
-			return nil, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+			return nil, dbError(err)
 		}
 
 		entries = append(entries, entry)
@@ -35,51 +61,120 @@ func GetList() ([]model.Entry, *model.PhoeBookError) {
 	return entries, nil
 }
 
-func Insert(entry *model.Entry) (int64, *model.PhoeBookError) {
-	db, err := ConnectDB()
-	if err != nil {
-		return 0, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
-	}
+func (r *Repository) Insert(ctx context.Context, entry *model.Entry) (int64, *model.PhoeBookError) {
+	return insertEntry(ctx, r.db, entry)
+}
+
+func (r *Repository) Update(ctx context.Context, entry *model.Entry) *model.PhoeBookError {
+	return updateEntry(ctx, r.db, entry)
+}
 
-	defer db.Close()
+func (r *Repository) Delete(ctx context.Context, id int64) *model.PhoeBookError {
+	return deleteEntry(ctx, r.db, id, 0)
+}
+
+func (r *Repository) DeleteVersion(ctx context.Context, id, version int64) *model.PhoeBookError {
+	return deleteEntry(ctx, r.db, id, version)
+}
+
+// execer is what insertEntry, updateEntry and deleteEntry need, so they work
+// on the database as well as inside a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
+func insertEntry(ctx context.Context, q execer, entry *model.Entry) (int64, *model.PhoeBookError) {
 	var id int64
-	err = db.QueryRow("INSERT INTO phone_book (name, surname, phone_number) VALUES ($1, $2, $3) RETURNING id", entry.Name, entry.Surname, entry.PhoneNumber).Scan(&id)
+	err := q.QueryRowContext(ctx, "INSERT INTO phone_book (name, surname, phone_number, country, company, title, nickname, birthday, anniversary, remind_days, archived_at, consent, consent_date, preferred_channel, access_notes, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, now()) RETURNING id", entry.Name, entry.Surname, entry.PhoneNumber, entry.Country, entry.Company, entry.Title, entry.Nickname, entry.Birthday, entry.Anniversary, entry.RemindDays, entry.ArchivedAt, entry.Consent, entry.ConsentDate, entry.Channel, entry.AccessNotes).Scan(&id)
 	if err != nil {
-		return 0, &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		return 0, dbError(err)
 	}
 
 	return id, nil
 }
 
-func Delete(id int64) *model.PhoeBookError {
-	db, err := ConnectDB()
+// updateEntry and deleteEntry only touch the row if it still has the
+// expected version, or any version when it is 0.
+func updateEntry(ctx context.Context, q execer, entry *model.Entry) *model.PhoeBookError {
+	var version int64
+	var updatedAt time.Time
+	err := q.QueryRowContext(ctx, "UPDATE phone_book SET name = $1, surname = $2, phone_number = $3, country = $4, company = $5, title = $6, nickname = $7, birthday = $8, anniversary = $9, remind_days = $10, archived_at = $11, consent = $12, consent_date = $13, preferred_channel = $14, access_notes = $15, version = version + 1, updated_at = now() WHERE id = $16 AND ($17 = 0 OR version = $17) RETURNING version, updated_at", entry.Name, entry.Surname, entry.PhoneNumber, entry.Country, entry.Company, entry.Title, entry.Nickname, entry.Birthday, entry.Anniversary, entry.RemindDays, entry.ArchivedAt, entry.Consent, entry.ConsentDate, entry.Channel, entry.AccessNotes, entry.ID, entry.Version).Scan(&version, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return missingOrConflict(ctx, q, entry.ID)
+	}
+
 	if err != nil {
-		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		return dbError(err)
 	}
 
-	defer db.Close()
+	entry.Version = version
+	entry.UpdatedAt = &updatedAt
+
+	return nil
+}
 
-	result, err := db.Exec("DELETE FROM phone_book WHERE id = ?", id)
+func deleteEntry(ctx context.Context, q execer, id, version int64) *model.PhoeBookError {
+	result, err := q.ExecContext(ctx, "DELETE FROM phone_book WHERE id = $1 AND ($2 = 0 OR version = $2)", id, version)
 	if err != nil {
-		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		return dbError(err)
 	}
 
 	affectedRows, err := result.RowsAffected()
 	if err != nil {
-		return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+		return dbError(err)
 	}
 
 	if affectedRows == 0 {
-		return &model.PhoeBookError{Message: "there is no record with given phone number", StatusCode: http.StatusNotFound}
+		return missingOrConflict(ctx, q, id)
 	}
 
 	return nil
 }
 
+// missingOrConflict tells why a statement guarded by a version matched no
+// row: the entry is gone, or it has another version.
+func missingOrConflict(ctx context.Context, q execer, id int64) *model.PhoeBookError {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM phone_book WHERE id = $1)", id).Scan(&exists); err != nil {
+		return dbError(err)
+	}
+
+	if exists {
+		return storage.ConflictError()
+	}
+
+	return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// Health pings the database and checks the phone_book table can be read,
+// which fails before the migrations have run.
+func (r *Repository) Health(ctx context.Context) []model.HealthCheck {
+	check := model.HealthCheck{Name: "database", Status: model.HealthOK}
+
+	err := r.db.PingContext(ctx)
+	if err == nil {
+		var one int
+		err = r.db.QueryRowContext(ctx, "SELECT 1 FROM phone_book LIMIT 1").Scan(&one)
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		check.Status, check.Detail = model.HealthFailing, err.Error()
+	}
+
+	return []model.HealthCheck{check}
+}
+
 func Serach(data []model.Entry, telephone string) (*model.Entry, *model.PhoeBookError) {
 	for _, entry := range data {
-		if entry.PhoneNumber == telephone {
+		if entry.PhoneNumber.Equal(model.PhoneNumber(telephone)) {
 			return &entry, nil
 		}
 	}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+func (r *Repository) ReportSpam(ctx context.Context, report model.SpamReport) *model.PhoeBookError {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO spam_reports (number, reason, reporter, reported_at) VALUES ($1, $2, $3, $4)", report.Number, report.Reason, report.Reporter, report.ReportedAt)
+	if err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (r *Repository) SpamReports(ctx context.Context, number string) ([]model.SpamReport, *model.PhoeBookError) {
+	rows, err := r.db.QueryContext(ctx, "SELECT number, reason, reporter, reported_at FROM spam_reports WHERE $1 = '' OR number = $1 ORDER BY reported_at", number)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	defer rows.Close()
+
+	var reports []model.SpamReport
+	for rows.Next() {
+		var report model.SpamReport
+		if err := rows.Scan(&report.Number, &report.Reason, &report.Reporter, &report.ReportedAt); err != nil {
+			return nil, dbError(err)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (r *Repository) EraseSpamReports(ctx context.Context, number string) *model.PhoeBookError {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM spam_reports WHERE number = $1", number); err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Tx is a database transaction on the phone book table.
+type Tx struct {
+	tx *sql.Tx
+}
+
+// Begin starts a database transaction. It is rolled back if ctx is canceled
+// before Commit.
+func (r *Repository) Begin(ctx context.Context) (storage.Tx, *model.PhoeBookError) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(err)
+	}
+
+	return &Tx{tx: tx}, nil
+}
+
+func (t *Tx) Insert(ctx context.Context, entry *model.Entry) (int64, *model.PhoeBookError) {
+	return insertEntry(ctx, t.tx, entry)
+}
+
+func (t *Tx) Update(ctx context.Context, entry *model.Entry) *model.PhoeBookError {
+	return updateEntry(ctx, t.tx, entry)
+}
+
+func (t *Tx) Delete(ctx context.Context, id int64) *model.PhoeBookError {
+	return deleteEntry(ctx, t.tx, id, 0)
+}
+
+func (t *Tx) Commit() *model.PhoeBookError {
+	err := t.tx.Commit()
+	if errors.Is(err, sql.ErrTxDone) {
+		return storage.TxDone()
+	}
+
+	if err != nil {
+		return dbError(err)
+	}
+
+	return nil
+}
+
+func (t *Tx) Rollback() *model.PhoeBookError {
+	if err := t.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return dbError(err)
+	}
+
+	return nil
+}
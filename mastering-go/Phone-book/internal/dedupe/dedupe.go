@@ -0,0 +1,293 @@
+// Package dedupe finds entries that are probably the same contact entered
+// more than once. It only reports them; nothing is merged or deleted.
+package dedupe
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+)
+
+// Reasons a pair of entries is suspected to be a duplicate.
+const (
+	SamePhone   = "same phone number"
+	SameName    = "same name"
+	SimilarName = "similar name"
+)
+
+// Group is a set of entries suspected to be duplicates of each other. Score
+// runs from 0 to 1 and is that of the weakest pair holding the group
+// together, so a group is never reported surer than its least certain member.
+type Group struct {
+	Score   float64       `json:"score"`
+	Reasons []string      `json:"reasons"`
+	Entries []model.Entry `json:"entries"`
+}
+
+// DefaultMinScore is the score below which two entries are not considered
+// duplicates.
+const DefaultMinScore = 0.8
+
+// maxBlock is the most entries sharing a phone number or a name that are
+// compared with each other.
+const maxBlock = 1000
+
+// Find groups the suspected duplicates among entries whose pairwise score is
+// at least minScore, surest groups first. Entries are only compared with
+// those sharing their phone number, or the folded spelling of their surname
+// and the first letter of their name, which keeps big books from being
+// compared pair by pair.
+func Find(entries []model.Entry, minScore float64) []Group {
+	return FindProgress(entries, minScore, nil)
+}
+
+// FindProgress is Find calling progress, unless it is nil, with the number
+// of entries compared so far after each one.
+func FindProgress(entries []model.Entry, minScore float64, progress func(done int)) []Group {
+	keys := make([]key, len(entries))
+	blocks := make(map[string][]int)
+	// blocksOf are the blocks of each entry.
+	blocksOf := make([][]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = keyOf(entry)
+
+		if keys[i].phone != "" {
+			blocksOf[i] = append(blocksOf[i], "phone:"+keys[i].phone)
+		}
+
+		// The first letter of the name splits up common surnames.
+		if surname := search.Fold(entry.Surname); surname != "" {
+			block := "name:" + surname
+			if name := search.Fold(entry.Name); name != "" {
+				block += " " + name[:1]
+			}
+
+			blocksOf[i] = append(blocksOf[i], block)
+		}
+
+		for _, block := range blocksOf[i] {
+			blocks[block] = append(blocks[block], i)
+		}
+	}
+
+	type pair struct{ a, b int }
+	type link struct {
+		score   float64
+		reasons []string
+	}
+
+	// Every entry is compared with the entries after it in its blocks,
+	// which hold the entries in order.
+	links := make(map[pair]link)
+	for a := range entries {
+		for _, name := range blocksOf[a] {
+			block := blocks[name]
+
+			// A number or name shared by that many entries is a switchboard
+			// or a placeholder rather than a duplicate.
+			if len(block) > maxBlock {
+				continue
+			}
+
+			for _, b := range block {
+				if b <= a {
+					continue
+				}
+
+				p := pair{a, b}
+				if _, done := links[p]; done {
+					continue
+				}
+
+				score, reasons := keys[p.a].score(keys[p.b])
+				if score >= minScore {
+					links[p] = link{score, reasons}
+				}
+			}
+		}
+
+		if progress != nil {
+			progress(a + 1)
+		}
+	}
+
+	// Union-find over the linked pairs.
+	parent := make([]int, len(entries))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+
+		return parent[i]
+	}
+
+	for p := range links {
+		parent[root(p.a)] = root(p.b)
+	}
+
+	type building struct {
+		members []int
+		score   float64
+		reasons map[string]bool
+	}
+
+	groups := make(map[int]*building)
+	for p, l := range links {
+		g, ok := groups[root(p.a)]
+		if !ok {
+			g = &building{score: 1, reasons: make(map[string]bool)}
+			groups[root(p.a)] = g
+		}
+
+		g.score = min(g.score, l.score)
+		for _, reason := range l.reasons {
+			g.reasons[reason] = true
+		}
+	}
+
+	for i := range entries {
+		if g, ok := groups[root(i)]; ok {
+			g.members = append(g.members, i)
+		}
+	}
+
+	result := make([]Group, 0, len(groups))
+	for _, g := range groups {
+		group := Group{Score: g.score}
+		for _, reason := range []string{SamePhone, SameName, SimilarName} {
+			if g.reasons[reason] {
+				group.Reasons = append(group.Reasons, reason)
+			}
+		}
+
+		for _, i := range g.members {
+			group.Entries = append(group.Entries, entries[i])
+		}
+
+		result = append(result, group)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+
+		return result[i].Entries[0].ID < result[j].Entries[0].ID
+	})
+
+	return result
+}
+
+// Score tells how likely a and b are the same contact, from 0 to 1, and why.
+// A shared phone number counts most; names are compared after folding, so
+// transliterations of one name match, and otherwise by edit distance.
+func Score(a, b model.Entry) (float64, []string) {
+	return keyOf(a).score(keyOf(b))
+}
+
+// key is what an entry is compared by, worked out once per entry.
+type key struct {
+	phone  string
+	name   string
+	folded string
+}
+
+func keyOf(entry model.Entry) key {
+	name := strings.ToLower(strings.TrimSpace(entry.Name + " " + entry.Surname))
+
+	return key{phone: string(entry.PhoneNumber.Canonical()), name: name, folded: search.Fold(name)}
+}
+
+func (a key) score(b key) (float64, []string) {
+	var reasons []string
+
+	samePhone := a.phone != "" && a.phone == b.phone
+	if samePhone {
+		reasons = append(reasons, SamePhone)
+	}
+
+	var nameScore float64
+	switch {
+	case a.name == "" || b.name == "":
+	case a.name == b.name:
+		nameScore = 1
+	case a.folded != "" && a.folded == b.folded:
+		nameScore = 0.9
+	default:
+		longest := max(len([]rune(a.name)), len([]rune(b.name)))
+		nameScore = 1 - float64(search.Distance(a.name, b.name))/float64(longest)
+	}
+
+	switch {
+	case nameScore == 1:
+		reasons = append(reasons, SameName)
+	case nameScore >= DefaultMinScore:
+		reasons = append(reasons, SimilarName)
+	}
+
+	switch {
+	case samePhone && nameScore >= DefaultMinScore:
+		return 0.8 + 0.2*nameScore, reasons
+	case samePhone:
+		// Shared numbers are often office lines, the name has to agree a
+		// little too.
+		return 0.6 + 0.3*nameScore, reasons
+	default:
+		return 0.9 * nameScore, reasons
+	}
+}
+
+// Write writes groups as a report in format, "json" or "csv". CSV has one row
+// per entry, numbering the groups so the rows of one group can be told apart
+// in a spreadsheet. Scores are rounded to two decimals in both, as a float
+// like 0.9800000000000001 tells nothing more.
+func Write(w io.Writer, format string, groups []Group) error {
+	if format == "json" {
+		rounded := make([]Group, len(groups))
+		for i, group := range groups {
+			rounded[i] = group
+			rounded[i].Score = math.Round(group.Score*100) / 100
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(rounded)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"group", "score", "reasons", "id", "name", "surname", "phone_number", "company", "title", "nickname"})
+
+	for n, group := range groups {
+		for _, entry := range group.Entries {
+			writer.Write([]string{
+				strconv.Itoa(n + 1),
+				strconv.FormatFloat(group.Score, 'f', 2, 64),
+				strings.Join(group.Reasons, "; "),
+				strconv.FormatInt(entry.ID, 10),
+				entry.Name,
+				entry.Surname,
+				string(entry.PhoneNumber),
+				entry.Company,
+				entry.Title,
+				entry.Nickname,
+			})
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
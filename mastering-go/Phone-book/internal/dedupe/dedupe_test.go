@@ -0,0 +1,131 @@
+package dedupe
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+func TestScore(t *testing.T) {
+	for _, test := range []struct {
+		a, b    model.Entry
+		score   float64
+		reasons []string
+	}{
+		{
+			a:       model.Entry{Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"},
+			b:       model.Entry{Name: "ali", Surname: "ahmadi ", PhoneNumber: "0912 123 4567"},
+			score:   1,
+			reasons: []string{SamePhone, SameName},
+		},
+		// A shared office line alone is not enough.
+		{
+			a:       model.Entry{Name: "Ali", PhoneNumber: "+982112345678"},
+			b:       model.Entry{PhoneNumber: "+982112345678"},
+			score:   0.6,
+			reasons: []string{SamePhone},
+		},
+		{
+			a:       model.Entry{Name: "Morteza", Surname: "Shahrabi"},
+			b:       model.Entry{Name: "مرتضی", Surname: "شهرابی"},
+			score:   0.81,
+			reasons: []string{SimilarName},
+		},
+		{
+			a:     model.Entry{Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"},
+			b:     model.Entry{Name: "Sara", Surname: "Rezaei", PhoneNumber: "+989351112233"},
+			score: 0.9 * (1 - 7.0/11),
+		},
+	} {
+		score, reasons := Score(test.a, test.b)
+		if math.Abs(score-test.score) > 1e-9 || !slices.Equal(reasons, test.reasons) {
+			t.Errorf("Score(%s %s, %s %s) = %v, %v, want %v, %v", test.a.Name, test.a.Surname, test.b.Name, test.b.Surname, score, reasons, test.score, test.reasons)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	entries := []model.Entry{
+		{ID: 1, Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"},
+		{ID: 2, Name: "Reza", Surname: "Karimi", PhoneNumber: "+982112345678"},
+		{ID: 3, Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"},
+		{ID: 4, Name: "Sara", Surname: "Rezaei", PhoneNumber: "+989351112233"},
+		{ID: 5, Name: "علی", Surname: "احمدی", PhoneNumber: "+989121110000"},
+		// Colleagues sharing the office line of entry 2.
+		{ID: 6, Name: "Maryam", Surname: "Hosseini", PhoneNumber: "+982112345678"},
+	}
+
+	for _, test := range []struct {
+		minScore float64
+		want     [][]int64
+		scores   []float64
+	}{
+		{minScore: DefaultMinScore, want: [][]int64{{1, 3, 5}}, scores: []float64{0.81}},
+		{minScore: 0.9, want: [][]int64{{1, 3}}, scores: []float64{1}},
+		{minScore: 0.5, want: [][]int64{{1, 3, 5}, {2, 6}}},
+	} {
+		var done int
+		groups := FindProgress(entries, test.minScore, func(n int) { done = n })
+		if done != len(entries) {
+			t.Errorf("min %v: progress got to %d, want %d", test.minScore, done, len(entries))
+		}
+
+		var got [][]int64
+		for i, group := range groups {
+			var ids []int64
+			for _, entry := range group.Entries {
+				ids = append(ids, entry.ID)
+			}
+
+			got = append(got, ids)
+			if i < len(test.scores) && math.Abs(group.Score-test.scores[i]) > 1e-9 {
+				t.Errorf("min %v: got score %v for %v, want %v", test.minScore, group.Score, ids, test.scores[i])
+			}
+		}
+
+		if !slices.EqualFunc(got, test.want, slices.Equal[[]int64]) {
+			t.Errorf("min %v: got %v, want %v", test.minScore, got, test.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	groups := []Group{{
+		Score:   0.8100000000000001,
+		Reasons: []string{SamePhone, SimilarName},
+		Entries: []model.Entry{
+			{ID: 1, Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"},
+			{ID: 5, Name: "Ali", Surname: "Ahmady", PhoneNumber: "+989121234567", Company: "Acme, Inc."},
+		},
+	}}
+
+	var csv bytes.Buffer
+	if err := Write(&csv, "csv", groups); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "group,score,reasons,id,name,surname,phone_number,company,title,nickname\n" +
+		"1,0.81,same phone number; similar name,1,Ali,Ahmadi,+989121234567,,,\n" +
+		"1,0.81,same phone number; similar name,5,Ali,Ahmady,+989121234567,\"Acme, Inc.\",,\n"
+	if csv.String() != want {
+		t.Errorf("got CSV\n%s\nwant\n%s", &csv, want)
+	}
+
+	var json bytes.Buffer
+	if err := Write(&json, "json", groups); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(json.String(), `"score": 0.81,`) {
+		t.Errorf("got JSON %s, want the score rounded", &json)
+	}
+
+	// Rounding is only for the report.
+	if groups[0].Score != 0.8100000000000001 {
+		t.Errorf("Write changed the score of the group to %v", groups[0].Score)
+	}
+}
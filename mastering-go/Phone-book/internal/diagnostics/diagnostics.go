@@ -0,0 +1,53 @@
+// Package diagnostics gathers what helps troubleshoot a phone book in the
+// field: how the process is doing and what the backend keeps besides the
+// entries.
+package diagnostics
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Runtime describes the current process.
+func Runtime() model.RuntimeStats {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+
+	return model.RuntimeStats{
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  memory.HeapAlloc,
+		HeapSys:    memory.HeapSys,
+		Sys:        memory.Sys,
+		NumGC:      memory.NumGC,
+	}
+}
+
+// Stats reports on store and the current process. The entries are counted
+// first, so the memory they take when listed is included.
+func Stats(ctx context.Context, store storage.Storage) (model.DebugStats, *model.PhoeBookError) {
+	var stats model.DebugStats
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return stats, appErr
+	}
+
+	stats.Entries = len(entries)
+
+	if inspector, ok := store.(storage.Inspector); ok {
+		if stats.Inspection, appErr = inspector.Inspect(ctx); appErr != nil {
+			return stats, appErr
+		}
+	}
+
+	if stats.Indexes == nil {
+		stats.Indexes = []model.IndexStats{}
+	}
+
+	stats.Process = Runtime()
+
+	return stats, nil
+}
@@ -0,0 +1,144 @@
+// Package diff compares two phone books entry by entry.
+package diff
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Key tells how entries of two books are paired up.
+type Key int
+
+const (
+	// ByID pairs the entries with the same ID, for copies of one book.
+	ByID Key = iota
+	// ByPhone pairs the entries with the same phone number, for books that
+	// were filled separately and whose IDs mean nothing to each other.
+	ByPhone
+)
+
+// Result is the difference between book A and book B.
+type Result struct {
+	OnlyInA []model.Entry `json:"only_in_a"`
+	OnlyInB []model.Entry `json:"only_in_b"`
+	Changed []Change      `json:"changed"`
+}
+
+// Change is a pair of entries that differ in some fields.
+type Change struct {
+	// Key is the ID or phone number the entries were paired by.
+	Key    string        `json:"key"`
+	A      model.Entry   `json:"a"`
+	B      model.Entry   `json:"b"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is one field that differs, named as in the JSON of an entry.
+type FieldChange struct {
+	Field string `json:"field"`
+	A     string `json:"a"`
+	B     string `json:"b"`
+}
+
+// Empty reports whether the books hold the same entries.
+func (r Result) Empty() bool {
+	return len(r.OnlyInA) == 0 && len(r.OnlyInB) == 0 && len(r.Changed) == 0
+}
+
+// Compare returns the entries only one of a and b has and the fields that
+// changed between paired entries. Versions, countries and photo paths are
+// bookkeeping of each book and not compared. When several entries of a book
+// share a phone number, ByPhone pairs them in ID order.
+func Compare(a, b []model.Entry, by Key) Result {
+	keyOf := func(entry model.Entry) string {
+		if by == ByPhone {
+			return string(entry.PhoneNumber.Canonical())
+		}
+
+		return strconv.FormatInt(entry.ID, 10)
+	}
+
+	a, b = sortedByID(a), sortedByID(b)
+
+	unpaired := make(map[string][]model.Entry)
+	for _, entry := range b {
+		unpaired[keyOf(entry)] = append(unpaired[keyOf(entry)], entry)
+	}
+
+	result := Result{OnlyInA: []model.Entry{}, OnlyInB: []model.Entry{}, Changed: []Change{}}
+	for _, entryA := range a {
+		key := keyOf(entryA)
+		candidates := unpaired[key]
+		if len(candidates) == 0 {
+			result.OnlyInA = append(result.OnlyInA, entryA)
+			continue
+		}
+
+		entryB := candidates[0]
+		unpaired[key] = candidates[1:]
+
+		if fields := Fields(entryA, entryB, by); len(fields) > 0 {
+			result.Changed = append(result.Changed, Change{Key: key, A: entryA, B: entryB, Fields: fields})
+		}
+	}
+
+	for _, entry := range b {
+		key := keyOf(entry)
+		if rest := unpaired[key]; len(rest) > 0 && rest[0].ID == entry.ID {
+			result.OnlyInB = append(result.OnlyInB, entry)
+			unpaired[key] = rest[1:]
+		}
+	}
+
+	return result
+}
+
+// Fields lists the fields in which a and b differ. Pairing by phone number
+// also compares the IDs.
+func Fields(a, b model.Entry, by Key) []FieldChange {
+	var fields []FieldChange
+	compare := func(field, valueA, valueB string) {
+		if valueA != valueB {
+			fields = append(fields, FieldChange{Field: field, A: valueA, B: valueB})
+		}
+	}
+
+	if by == ByPhone {
+		compare("id", strconv.FormatInt(a.ID, 10), strconv.FormatInt(b.ID, 10))
+	}
+
+	compare("name", a.Name, b.Name)
+	compare("surname", a.Surname, b.Surname)
+	compare("nickname", a.Nickname, b.Nickname)
+	compare("birthday", a.Birthday, b.Birthday)
+	compare("anniversary", a.Anniversary, b.Anniversary)
+	compare("phone_number", string(a.PhoneNumber.Canonical()), string(b.PhoneNumber.Canonical()))
+	compare("company", a.Company, b.Company)
+	compare("title", a.Title, b.Title)
+	compare("archived_at", day(a.ArchivedAt), day(b.ArchivedAt))
+	compare("consent", a.Consent, b.Consent)
+	compare("consent_date", a.ConsentDate, b.ConsentDate)
+	compare("preferred_channel", a.Channel, b.Channel)
+	compare("access_notes", a.AccessNotes, b.AccessNotes)
+
+	return fields
+}
+
+func sortedByID(entries []model.Entry) []model.Entry {
+	sorted := append([]model.Entry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	return sorted
+}
+
+// day is the day of t, or "" when it is nil.
+func day(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.UTC().Format(time.DateOnly)
+}
@@ -0,0 +1,549 @@
+// Package eventlog is the "events" backend: the phone book as an append-only
+// log of the changes made to it, one JSON event per line, and the entries
+// and blocklist are what replaying the log gives. Nothing is overwritten but
+// the events of a person erased on request, see EraseHistory, so the log is
+// the book's history and audit trail, and any moment of it can be looked at
+// or gone back to, see storage.History.
+//
+// The state is kept in memory and brought up to date with the events other
+// processes appended before every request. A snapshot of it is written next
+// to the log every snapshotEvery events, so opening a long log only replays
+// the events after the last snapshot. The snapshot can always be deleted;
+// the log cannot.
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+func init() {
+	storage.Register("events", Open)
+}
+
+// snapshotEvery is how many events are appended between snapshots.
+const snapshotEvery = 1000
+
+// Storage keeps the phone book in the event log at path.
+type Storage struct {
+	path string
+
+	mu    sync.Mutex
+	state *state
+	// offset is how much of the log state has replayed, snapshotSeq the
+	// event the last snapshot was taken after.
+	offset      int64
+	snapshotSeq int64
+}
+
+// state is the book after some events: the entries by ID, the blocked
+// numbers, and the ID the next insert gets, which never goes down.
+type state struct {
+	Seq     int64                 `json:"seq"`
+	Offset  int64                 `json:"offset"`
+	NextID  int64                 `json:"next_id"`
+	Entries map[int64]model.Entry `json:"entries"`
+	Blocked map[string]bool       `json:"blocked"`
+}
+
+func newState() *state {
+	return &state{NextID: 1, Entries: make(map[int64]model.Entry), Blocked: make(map[string]bool)}
+}
+
+// Open returns a backend for the event log at path, creating it if needed.
+func Open(path string) (storage.Storage, error) {
+	if path == "" {
+		return nil, errors.New("events storage needs a file path")
+	}
+
+	file, err := os.OpenFile(path, os.O_RDONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open event log: %v", err)
+	}
+
+	file.Close()
+
+	s := &Storage{path: path}
+	if err := s.catchUp(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Storage) snapshotPath() string {
+	return s.path + ".snapshot"
+}
+
+func internalError(err error) *model.PhoeBookError {
+	return &model.PhoeBookError{Message: err.Error(), StatusCode: http.StatusInternalServerError}
+}
+
+func notFound() *model.PhoeBookError {
+	return &model.PhoeBookError{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+}
+
+func (s *Storage) List(ctx context.Context) ([]model.Entry, *model.PhoeBookError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.catchUp(); err != nil {
+		return nil, internalError(err)
+	}
+
+	return s.state.list(), nil
+}
+
+func (s *Storage) Insert(ctx context.Context, entry *model.Entry) (int64, *model.PhoeBookError) {
+	var id int64
+	appErr := s.record(func(st *state, at time.Time) ([]model.Event, *model.PhoeBookError) {
+		event := st.insert(*entry, at)
+		id = event.ID
+		return []model.Event{event}, nil
+	})
+
+	return id, appErr
+}
+
+func (s *Storage) Update(ctx context.Context, entry *model.Entry) *model.PhoeBookError {
+	return s.record(func(st *state, at time.Time) ([]model.Event, *model.PhoeBookError) {
+		event, appErr := st.update(*entry, at)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		entry.Version = event.Entry.Version
+		entry.UpdatedAt = event.Entry.UpdatedAt
+
+		return []model.Event{event}, nil
+	})
+}
+
+func (s *Storage) Delete(ctx context.Context, id int64) *model.PhoeBookError {
+	return s.DeleteVersion(ctx, id, 0)
+}
+
+// DeleteVersion deletes the entry with id, if it still has version or
+// version is 0.
+func (s *Storage) DeleteVersion(ctx context.Context, id, version int64) *model.PhoeBookError {
+	return s.record(func(st *state, at time.Time) ([]model.Event, *model.PhoeBookError) {
+		event, appErr := st.delete(id, version, at)
+		if appErr != nil {
+			return nil, appErr
+		}
+
+		return []model.Event{event}, nil
+	})
+}
+
+func (s *Storage) Block(ctx context.Context, number string) *model.PhoeBookError {
+	return s.record(func(st *state, at time.Time) ([]model.Event, *model.PhoeBookError) {
+		if st.Blocked[number] {
+			return nil, nil
+		}
+
+		return []model.Event{{At: at, Type: model.EventBlock, Number: number}}, nil
+	})
+}
+
+func (s *Storage) Unblock(ctx context.Context, number string) *model.PhoeBookError {
+	return s.record(func(st *state, at time.Time) ([]model.Event, *model.PhoeBookError) {
+		if !st.Blocked[number] {
+			return nil, &model.PhoeBookError{Message: "the number is not blocked", StatusCode: http.StatusNotFound}
+		}
+
+		return []model.Event{{At: at, Type: model.EventUnblock, Number: number}}, nil
+	})
+}
+
+func (s *Storage) Blocked(ctx context.Context) ([]string, *model.PhoeBookError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.catchUp(); err != nil {
+		return nil, internalError(err)
+	}
+
+	return sortedNumbers(s.state.Blocked), nil
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+// Fingerprint identifies the log as it is now by its size, which grows with
+// every change, and modification time.
+func (s *Storage) Fingerprint(ctx context.Context) (string, *model.PhoeBookError) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", internalError(err)
+	}
+
+	return fmt.Sprintf("%d %d", info.Size(), info.ModTime().UnixNano()), nil
+}
+
+// record appends the events change makes to the book as it is now. The
+// events are applied to a copy of the state, so a failed change leaves
+// nothing behind.
+func (s *Storage) record(change func(st *state, at time.Time) ([]model.Event, *model.PhoeBookError)) *model.PhoeBookError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.catchUp(); err != nil {
+		return internalError(err)
+	}
+
+	events, appErr := change(s.state.copy(), time.Now().UTC().Truncate(time.Second))
+	if appErr != nil {
+		return appErr
+	}
+
+	if err := s.append(events); errors.Is(err, errConflict) {
+		return storage.ConflictError()
+	} else if err != nil {
+		return internalError(err)
+	}
+
+	return nil
+}
+
+// append writes events to the end of the log in a single write, so they
+// are all there or none, and replays them. The caller holds s.mu.
+func (s *Storage) append(events []model.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var lines bytes.Buffer
+	for _, event := range events {
+		// The place of an event in the log is its Seq.
+		event.Seq = 0
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		lines.Write(line)
+		lines.WriteByte('\n')
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot write event log: %v", err)
+	}
+
+	if _, err := file.Write(lines.Bytes()); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot write event log: %v", err)
+	}
+
+	// Appending leaves the file offset at the end of the events.
+	end, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("cannot write event log: %v", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot write event log: %v", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("cannot write event log: %v", err)
+	}
+
+	// Replaying rather than applying the events directly also picks up
+	// those another process appended in between, which may have made some
+	// of these impossible.
+	skipped, err := s.replayNew()
+	if err != nil {
+		return err
+	}
+
+	start := end - int64(lines.Len())
+	for _, at := range skipped {
+		if at >= start && at < end {
+			return errConflict
+		}
+	}
+
+	if s.state.Seq-s.snapshotSeq >= snapshotEvery {
+		// The log has the events, a failed snapshot only makes the next
+		// start slower.
+		if err := s.writeSnapshot(); err == nil {
+			s.snapshotSeq = s.state.Seq
+		}
+	}
+
+	return nil
+}
+
+// errConflict is the error of a change another process made impossible
+// between reading the book and appending the change.
+var errConflict = errors.New("the entry was changed by someone else, reload it and try again")
+
+// catchUp replays the events appended to the log since state was brought up
+// to date, starting from the snapshot the first time. The caller holds s.mu
+// or is Open.
+func (s *Storage) catchUp() error {
+	_, err := s.replayNew()
+	return err
+}
+
+// replayNew does catchUp, and returns the offsets of the events it skipped.
+func (s *Storage) replayNew() ([]int64, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read event log: %v", err)
+	}
+
+	// A log that got shorter was replaced, by a restored backup say.
+	if s.state == nil || info.Size() < s.offset {
+		s.state, s.offset = s.loadSnapshot(info.Size())
+		s.snapshotSeq = s.state.Seq
+	}
+
+	if info.Size() == s.offset {
+		return nil, nil
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read event log: %v", err)
+	}
+
+	defer file.Close()
+
+	var skipped []int64
+	offset, err := replay(file, s.offset, s.state, 0, func(event model.Event, at int64, applied bool) {
+		if !applied {
+			skipped = append(skipped, at)
+		}
+	})
+
+	s.offset = offset
+	s.state.Offset = offset
+
+	return skipped, err
+}
+
+// replay applies the events of the log in file from offset on to st, up to
+// the event until unless it is 0, and returns the offset after the last
+// event, telling seen, unless it is nil, about each event, its offset and
+// whether it could be applied. A last line without its newline is an append
+// still being written and is left for later.
+func replay(file io.ReaderAt, offset int64, st *state, until int64, seen func(event model.Event, at int64, applied bool)) (int64, error) {
+	reader := bufio.NewReaderSize(io.NewSectionReader(file, offset, 1<<62), 64<<10)
+	for until == 0 || st.Seq < until {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return offset, fmt.Errorf("cannot read event log: %v", err)
+		}
+
+		var event model.Event
+		if err := json.Unmarshal(line, &event); err != nil {
+			return offset, fmt.Errorf("the event log is damaged at event %d: %v", st.Seq+1, err)
+		}
+
+		st.Seq++
+		event.Seq = st.Seq
+		applied := st.apply(event)
+		if seen != nil {
+			seen(event, offset, applied)
+		}
+
+		offset += int64(len(line))
+	}
+
+	return offset, nil
+}
+
+// loadSnapshot returns the state of the snapshot and the offset it was taken
+// at, or an empty state when there is no snapshot of a log of size.
+func (s *Storage) loadSnapshot(size int64) (*state, int64) {
+	data, err := os.ReadFile(s.snapshotPath())
+	if err != nil {
+		return newState(), 0
+	}
+
+	st := newState()
+	if err := json.Unmarshal(data, st); err != nil || st.Offset > size || st.Entries == nil || st.Blocked == nil {
+		return newState(), 0
+	}
+
+	return st, st.Offset
+}
+
+// writeSnapshot saves the state through a temporary file.
+func (s *Storage) writeSnapshot() error {
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.snapshotPath())+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.snapshotPath())
+}
+
+// apply makes event on st and reports whether it could. Events that cannot
+// be applied, like an insert under an ID another process took meanwhile, are
+// skipped the same way by everyone replaying the log.
+func (st *state) apply(event model.Event) bool {
+	switch event.Type {
+	case model.EventInsert, model.EventUpdate:
+		if event.Entry == nil || event.ID == 0 {
+			return false
+		}
+
+		if _, exists := st.Entries[event.ID]; exists == (event.Type == model.EventInsert) {
+			return false
+		}
+
+		entry := *event.Entry
+		entry.ID = event.ID
+		st.Entries[event.ID] = entry
+		st.NextID = max(st.NextID, event.ID+1)
+	case model.EventDelete:
+		if _, exists := st.Entries[event.ID]; !exists {
+			return false
+		}
+
+		delete(st.Entries, event.ID)
+	case model.EventBlock:
+		st.Blocked[event.Number] = true
+	case model.EventUnblock:
+		delete(st.Blocked, event.Number)
+	}
+
+	return true
+}
+
+func (st *state) copy() *state {
+	c := &state{Seq: st.Seq, Offset: st.Offset, NextID: st.NextID, Entries: make(map[int64]model.Entry, len(st.Entries)), Blocked: make(map[string]bool, len(st.Blocked))}
+	for id, entry := range st.Entries {
+		c.Entries[id] = entry
+	}
+
+	for number := range st.Blocked {
+		c.Blocked[number] = true
+	}
+
+	return c
+}
+
+// list returns the entries in the order they were first inserted.
+func (st *state) list() []model.Entry {
+	entries := make([]model.Entry, 0, len(st.Entries))
+	for _, entry := range st.Entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	return entries
+}
+
+// insert returns the event inserting entry under a new ID, and applies it.
+func (st *state) insert(entry model.Entry, at time.Time) model.Event {
+	entry.ID = st.NextID
+	entry.Version = 1
+	entry.UpdatedAt = &at
+
+	event := model.Event{At: at, Type: model.EventInsert, ID: entry.ID, Entry: &entry}
+	st.apply(event)
+
+	return event
+}
+
+// update returns the event replacing the entry with entry.ID by entry,
+// keeping its photo, and applies it.
+func (st *state) update(entry model.Entry, at time.Time) (model.Event, *model.PhoeBookError) {
+	stored, ok := st.Entries[entry.ID]
+	if !ok {
+		return model.Event{}, notFound()
+	}
+
+	if entry.Version != 0 && entry.Version != stored.Version {
+		return model.Event{}, storage.ConflictError()
+	}
+
+	entry.Photo = stored.Photo
+	entry.Version = stored.Version + 1
+	entry.UpdatedAt = &at
+
+	event := model.Event{At: at, Type: model.EventUpdate, ID: entry.ID, Entry: &entry}
+	st.apply(event)
+
+	return event, nil
+}
+
+// delete returns the event deleting the entry with id, if it still has
+// version or version is 0, and applies it.
+func (st *state) delete(id, version int64, at time.Time) (model.Event, *model.PhoeBookError) {
+	stored, ok := st.Entries[id]
+	if !ok {
+		return model.Event{}, notFound()
+	}
+
+	if version != 0 && stored.Version != version {
+		return model.Event{}, storage.ConflictError()
+	}
+
+	event := model.Event{At: at, Type: model.EventDelete, ID: id}
+	st.apply(event)
+
+	return event, nil
+}
+
+// openLog opens the log for reading from the start, for the history.
+func (s *Storage) openLog() (*os.File, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.New("cannot read event log: it was removed")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read event log: %v", err)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,298 @@
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Events returns the events of the log that were applied, those about the
+// entry with id unless it is 0.
+func (s *Storage) Events(ctx context.Context, id int64) ([]model.Event, *model.PhoeBookError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.openLog()
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	defer file.Close()
+
+	var events []model.Event
+	_, err = replay(file, 0, newState(), 0, func(event model.Event, at int64, applied bool) {
+		if applied && (id == 0 || event.ID == id) {
+			events = append(events, event)
+		}
+	})
+
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return events, nil
+}
+
+// EntriesAt replays the log up to the event seq.
+func (s *Storage) EntriesAt(ctx context.Context, seq int64) ([]model.Entry, *model.PhoeBookError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	then, appErr := s.stateAt(seq, nil)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return then.list(), nil
+}
+
+// stateAt returns the state right after the event seq, telling seen about
+// every event of the log, those after seq too. The caller holds s.mu.
+func (s *Storage) stateAt(seq int64, seen func(event model.Event, at int64, applied bool)) (*state, *model.PhoeBookError) {
+	if err := s.catchUp(); err != nil {
+		return nil, internalError(err)
+	}
+
+	if seq < 0 || seq > s.state.Seq {
+		return nil, &model.PhoeBookError{Message: fmt.Sprintf("there is no event %d, the last one is %d", seq, s.state.Seq), StatusCode: http.StatusBadRequest}
+	}
+
+	file, err := s.openLog()
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	defer file.Close()
+
+	then := newState()
+	if seq > 0 {
+		if _, err := replay(file, 0, then, seq, nil); err != nil {
+			return nil, internalError(err)
+		}
+	}
+
+	if seen != nil {
+		if _, err := replay(file, 0, newState(), 0, seen); err != nil {
+			return nil, internalError(err)
+		}
+	}
+
+	return then, nil
+}
+
+// Revert records the events taking the book back to how it was after seq:
+// entries inserted since are deleted, deleted ones are inserted again under
+// their ID, and edited ones get their fields back, each as a new version.
+func (s *Storage) Revert(ctx context.Context, seq int64, dryRun bool) ([]model.Event, *model.PhoeBookError) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// An entry inserted again continues from the last version it had, so
+	// nobody can still hold that version of it.
+	versions := make(map[int64]int64)
+	then, appErr := s.stateAt(seq, func(event model.Event, at int64, applied bool) {
+		if applied && event.Entry != nil {
+			versions[event.ID] = max(versions[event.ID], event.Entry.Version)
+		}
+	})
+
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	at := time.Now().UTC().Truncate(time.Second)
+	current := s.state.copy()
+	var events []model.Event
+	add := func(event model.Event) {
+		event.At, event.Reverts = at, &seq
+		current.apply(event)
+		events = append(events, event)
+	}
+
+	for _, entry := range current.list() {
+		if _, ok := then.Entries[entry.ID]; !ok {
+			add(model.Event{Type: model.EventDelete, ID: entry.ID})
+		}
+	}
+
+	for _, old := range then.list() {
+		old := old
+		old.UpdatedAt = &at
+
+		now, ok := current.Entries[old.ID]
+		switch {
+		case !ok:
+			old.Version = versions[old.ID] + 1
+			add(model.Event{Type: model.EventInsert, ID: old.ID, Entry: &old})
+		case !sameContent(now, old):
+			old.Version = now.Version + 1
+			add(model.Event{Type: model.EventUpdate, ID: old.ID, Entry: &old})
+		}
+	}
+
+	for _, number := range sortedNumbers(then.Blocked) {
+		if !current.Blocked[number] {
+			add(model.Event{Type: model.EventBlock, Number: number})
+		}
+	}
+
+	for _, number := range sortedNumbers(current.Blocked) {
+		if !then.Blocked[number] {
+			add(model.Event{Type: model.EventUnblock, Number: number})
+		}
+	}
+
+	if dryRun || len(events) == 0 {
+		return events, nil
+	}
+
+	if err := s.append(events); errors.Is(err, errConflict) {
+		return nil, storage.ConflictError()
+	} else if err != nil {
+		return nil, internalError(err)
+	}
+
+	for i := range events {
+		events[i].Seq = s.state.Seq - int64(len(events)-1-i)
+	}
+
+	return events, nil
+}
+
+// sameContent reports whether a and b only differ in their version and when
+// they were updated.
+func sameContent(a, b model.Entry) bool {
+	a.Version, b.Version = 0, 0
+	a.UpdatedAt, b.UpdatedAt = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedNumbers(numbers map[string]bool) []string {
+	sorted := make([]string, 0, len(numbers))
+	for number := range numbers {
+		sorted = append(sorted, number)
+	}
+
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// EraseHistory overwrites the events that carry the entry with id, its
+// inserts and updates, and those blocking or unblocking number unless it is
+// "", with erased events of the same length. Keeping every line where it was
+// keeps the offsets other processes replayed up to valid. The snapshot,
+// which may still have the entry, is then written again from the state. The
+// entry has to be deleted first.
+func (s *Storage) EraseHistory(ctx context.Context, id int64, number string) *model.PhoeBookError {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.catchUp(); err != nil {
+		return internalError(err)
+	}
+
+	if _, ok := s.state.Entries[id]; ok {
+		return &model.PhoeBookError{Message: "the entry has to be deleted before its history is erased", StatusCode: http.StatusConflict}
+	}
+
+	file, err := os.OpenFile(s.path, os.O_RDWR, 0)
+	if err != nil {
+		return internalError(fmt.Errorf("cannot write event log: %v", err))
+	}
+
+	defer file.Close()
+
+	type line struct {
+		at    int64
+		event model.Event
+	}
+
+	var erase []line
+	_, err = replay(file, 0, newState(), s.state.Seq, func(event model.Event, at int64, applied bool) {
+		switch {
+		case event.ID == id && event.Entry != nil:
+		case number != "" && event.Number == number && (event.Type == model.EventBlock || event.Type == model.EventUnblock):
+		default:
+			return
+		}
+
+		erase = append(erase, line{at, event})
+	})
+
+	if err != nil {
+		return internalError(err)
+	}
+
+	for _, l := range erase {
+		old, err := bufio.NewReader(io.NewSectionReader(file, l.at, 1<<62)).ReadBytes('\n')
+		if err != nil {
+			return internalError(fmt.Errorf("cannot read event log: %v", err))
+		}
+
+		redacted, err := json.Marshal(model.Event{At: l.event.At, Type: model.EventErased, ID: l.event.ID})
+		if err != nil {
+			return internalError(err)
+		}
+
+		// The newline stays where it was.
+		if len(redacted) > len(old)-1 {
+			return internalError(fmt.Errorf("cannot erase event %d: its line is too short", l.event.Seq))
+		}
+
+		redacted = append(redacted, bytes.Repeat([]byte(" "), len(old)-1-len(redacted))...)
+		if _, err := file.WriteAt(redacted, l.at); err != nil {
+			return internalError(fmt.Errorf("cannot write event log: %v", err))
+		}
+	}
+
+	if err := file.Sync(); err != nil {
+		return internalError(fmt.Errorf("cannot write event log: %v", err))
+	}
+
+	if err := s.writeSnapshot(); err != nil {
+		return internalError(fmt.Errorf("cannot write event log snapshot: %v", err))
+	}
+
+	s.snapshotSeq = s.state.Seq
+
+	return nil
+}
+
+// LoadHistory appends events, the history of another log, to this one if it
+// is still empty. Events skipped on replay are not part of a history, so
+// the events get new seqs: a revert is made to point at the new seq of the
+// event it went back to.
+func (s *Storage) LoadHistory(ctx context.Context, events []model.Event) *model.PhoeBookError {
+	return s.record(func(st *state, at time.Time) ([]model.Event, *model.PhoeBookError) {
+		if st.Seq > 0 {
+			return nil, &model.PhoeBookError{Message: "the event log already has a history, one is only loaded into an empty log", StatusCode: http.StatusConflict}
+		}
+
+		loaded := make([]model.Event, len(events))
+		for i, event := range events {
+			if event.Reverts != nil {
+				// The events up to the one reverted to, by the seqs of
+				// the other log, are the first seq of this one.
+				seq := int64(sort.Search(i, func(j int) bool { return events[j].Seq > *event.Reverts }))
+				event.Reverts = &seq
+			}
+
+			loaded[i] = event
+		}
+
+		return loaded, nil
+	})
+}
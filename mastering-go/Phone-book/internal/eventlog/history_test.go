@@ -0,0 +1,192 @@
+package eventlog
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// open opens the event log at path, failing the test if it cannot.
+func open(t *testing.T, path string) *Storage {
+	t.Helper()
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s.(*Storage)
+}
+
+func names(t *testing.T, s *Storage) []string {
+	t.Helper()
+
+	entries, appErr := s.List(context.Background())
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	var all []string
+	for _, entry := range entries {
+		all = append(all, entry.Name)
+	}
+
+	slices.Sort(all)
+
+	return all
+}
+
+func TestEraseHistoryReplays(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.log")
+	s := open(t, path)
+
+	ali := model.Entry{Name: "Ali", Surname: "Ahmadi", PhoneNumber: "+989121234567"}
+	id, appErr := s.Insert(ctx, &ali)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if _, appErr := s.Insert(ctx, &model.Entry{Name: "Sara", Surname: "Rezaei", PhoneNumber: "+989351112233"}); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	ali.ID, ali.Version, ali.Surname = id, 1, "Karimi"
+	for _, change := range []func() *model.PhoeBookError{
+		func() *model.PhoeBookError { return s.Update(ctx, &ali) },
+		func() *model.PhoeBookError { return s.Block(ctx, "+989121234567") },
+		func() *model.PhoeBookError { return s.Unblock(ctx, "+989121234567") },
+		func() *model.PhoeBookError { return s.Delete(ctx, id) },
+	} {
+		if appErr := change(); appErr != nil {
+			t.Fatal(appErr)
+		}
+	}
+
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if appErr := s.EraseHistory(ctx, id, "+989121234567"); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every line stays where it was.
+	if len(after) != len(before) || bytes.Count(after, []byte("\n")) != bytes.Count(before, []byte("\n")) {
+		t.Errorf("got a log of %d bytes, want %d in as many lines", len(after), len(before))
+	}
+
+	for _, erased := range []string{"Ahmadi", "Karimi", "+989121234567"} {
+		if bytes.Contains(after, []byte(erased)) {
+			t.Errorf("%q is still in the log", erased)
+		}
+	}
+
+	// Replaying the log from its snapshot and from the start gives the same
+	// book.
+	for _, fromStart := range []bool{false, true} {
+		if fromStart {
+			if err := os.Remove(s.snapshotPath()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		replayed := open(t, path)
+		if got := names(t, replayed); !slices.Equal(got, []string{"Sara"}) {
+			t.Errorf("from the start %v: got %v, want only Sara", fromStart, got)
+		}
+
+		if blocked, appErr := replayed.Blocked(ctx); appErr != nil || len(blocked) != 0 {
+			t.Errorf("from the start %v: got blocked %v, %v, want none", fromStart, blocked, appErr)
+		}
+
+		// Only that there were events is left, the delete is skipped now
+		// that there is no insert before it.
+		events, appErr := replayed.Events(ctx, id)
+		if appErr != nil {
+			t.Fatal(appErr)
+		}
+
+		for _, event := range events {
+			if event.Type != model.EventErased || event.Entry != nil {
+				t.Errorf("from the start %v: got event %+v, want it erased", fromStart, event)
+			}
+		}
+
+		replayed.Close()
+	}
+}
+
+func TestEraseHistoryNeedsDelete(t *testing.T) {
+	ctx := context.Background()
+	s := open(t, filepath.Join(t.TempDir(), "events.log"))
+
+	id, appErr := s.Insert(ctx, &model.Entry{Name: "Ali", PhoneNumber: "+989121234567"})
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if appErr := s.EraseHistory(ctx, id, ""); appErr == nil {
+		t.Error("the history of an entry still in the book was erased")
+	}
+}
+
+func TestLoadHistory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	from := open(t, filepath.Join(dir, "from.log"))
+
+	ali := model.Entry{Name: "Ali", PhoneNumber: "+989121234567"}
+	id, appErr := from.Insert(ctx, &ali)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	ali.ID, ali.Version, ali.Name = id, 1, "Reza"
+	if appErr := from.Update(ctx, &ali); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if _, appErr := from.Revert(ctx, 1, false); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	events, appErr := from.Events(ctx, 0)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	to := open(t, filepath.Join(dir, "to.log"))
+	if appErr := to.LoadHistory(ctx, events); appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if got := names(t, to); !slices.Equal(got, []string{"Ali"}) {
+		t.Errorf("got %v, want the entry as it was reverted to", got)
+	}
+
+	loaded, appErr := to.Events(ctx, id)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if len(loaded) != len(events) || loaded[len(loaded)-1].Reverts == nil || *loaded[len(loaded)-1].Reverts != 1 {
+		t.Errorf("got %+v, want the history with the revert to the first event", loaded)
+	}
+
+	// A log with a history of its own keeps it.
+	if appErr := to.LoadHistory(ctx, events); appErr == nil {
+		t.Error("a history was loaded into a log that has one")
+	}
+}
@@ -0,0 +1,107 @@
+package eventlog
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// logTx is a transaction on the log. It holds the book's lock from Begin to
+// Commit or Rollback and makes its changes on a copy of the state, so Commit
+// appends all of their events in one write.
+type logTx struct {
+	s      *Storage
+	state  *state
+	events []model.Event
+	done   bool
+}
+
+// Begin starts a transaction. Other requests to the book wait until it ends.
+func (s *Storage) Begin(ctx context.Context) (storage.Tx, *model.PhoeBookError) {
+	s.mu.Lock()
+
+	if err := s.catchUp(); err != nil {
+		s.mu.Unlock()
+		return nil, internalError(err)
+	}
+
+	return &logTx{s: s, state: s.state.copy()}, nil
+}
+
+func (t *logTx) now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func (t *logTx) Insert(ctx context.Context, entry *model.Entry) (int64, *model.PhoeBookError) {
+	if t.done {
+		return 0, storage.TxDone()
+	}
+
+	event := t.state.insert(*entry, t.now())
+	t.events = append(t.events, event)
+
+	return event.ID, nil
+}
+
+func (t *logTx) Update(ctx context.Context, entry *model.Entry) *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	event, appErr := t.state.update(*entry, t.now())
+	if appErr != nil {
+		return appErr
+	}
+
+	entry.Version = event.Entry.Version
+	entry.UpdatedAt = event.Entry.UpdatedAt
+	t.events = append(t.events, event)
+
+	return nil
+}
+
+func (t *logTx) Delete(ctx context.Context, id int64) *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	event, appErr := t.state.delete(id, 0, t.now())
+	if appErr != nil {
+		return appErr
+	}
+
+	t.events = append(t.events, event)
+
+	return nil
+}
+
+func (t *logTx) Commit() *model.PhoeBookError {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	t.done = true
+	defer t.s.mu.Unlock()
+
+	if err := t.s.append(t.events); errors.Is(err, errConflict) {
+		return storage.ConflictError()
+	} else if err != nil {
+		return internalError(err)
+	}
+
+	return nil
+}
+
+func (t *logTx) Rollback() *model.PhoeBookError {
+	if t.done {
+		return nil
+	}
+
+	t.done = true
+	t.s.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,458 @@
+// Package filter parses and evaluates the filter expressions accepted by
+// list --where and GET /entries?q=, for example:
+//
+//	surname=Smith AND NOT company~Acme
+//	(country=IR OR country=GB) AND title!=""
+//
+// A comparison is a field, an operator and a value. "=" and "!=" compare the
+// whole value and "~" matches a prefix, all ignoring case. Phone numbers are
+// compared however they are written. Comparisons are combined with AND, OR,
+// NOT (or "!") and parentheses; AND binds tighter than OR. Values holding
+// spaces or operators are written in double quotes.
+//
+// The grammar, with keywords matched ignoring case:
+//
+//	expr       = and { "OR" and }
+//	and        = unary { "AND" unary }
+//	unary      = ( "NOT" | "!" ) unary | "(" expr ")" | comparison
+//	comparison = field ( "=" | "!=" | "~" ) value
+//	value      = word | `"` { char | `\` char } `"`
+//
+// Expressions longer than MaxLength or nested deeper than MaxDepth are
+// rejected, so a hostile query cannot make the parser recurse without bound.
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Limits on the expressions Parse accepts.
+const (
+	MaxLength = 4096
+	MaxDepth  = 32
+)
+
+// Fields lists the entry fields a filter can compare, by name.
+var Fields = map[string]func(model.Entry) string{
+	"id":       func(e model.Entry) string { return strconv.FormatInt(e.ID, 10) },
+	"name":     func(e model.Entry) string { return e.Name },
+	"surname":  func(e model.Entry) string { return e.Surname },
+	"nickname": func(e model.Entry) string { return e.Nickname },
+	"phone":    func(e model.Entry) string { return string(e.PhoneNumber) },
+	"country":  country,
+	"company":  func(e model.Entry) string { return e.Company },
+	"title":    func(e model.Entry) string { return e.Title },
+	"consent":  func(e model.Entry) string { return e.Consent },
+	"channel":  func(e model.Entry) string { return e.Channel },
+}
+
+// Expr is a parsed filter expression.
+type Expr interface {
+	Match(entry model.Entry) bool
+}
+
+// SyntaxError reports where a filter expression stopped making sense. Pos is
+// the byte offset of the offending token; the message counts columns from 1.
+type SyntaxError struct {
+	Pos     int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("filter: %s at column %d", e.Message, e.Pos+1)
+}
+
+// Parse parses a filter expression.
+func Parse(input string) (Expr, error) {
+	if len(input) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Message: fmt.Sprintf("expression longer than %d bytes", MaxLength)}
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: next.pos, Message: fmt.Sprintf("unexpected %s", next)}
+	}
+
+	return expr, nil
+}
+
+// Apply returns the entries matching expr, in their original order.
+func Apply(expr Expr, entries []model.Entry) []model.Entry {
+	var matched []model.Entry
+	for _, entry := range entries {
+		if expr.Match(entry) {
+			matched = append(matched, entry)
+		}
+	}
+
+	return matched
+}
+
+// Compare returns the comparison of field and value with op, the same as
+// parsing it written out, e.g. Compare("surname", "~", "Sm") for surname~Sm.
+func Compare(field, op, value string) (Expr, error) {
+	name := strings.ToLower(field)
+	if _, ok := Fields[name]; !ok {
+		return nil, fmt.Errorf("filter: unknown field %q, use one of %s", field, fieldNames())
+	}
+
+	switch op {
+	case "=", "!=", "~":
+	default:
+		return nil, fmt.Errorf("filter: unknown operator %q, use =, != or ~", op)
+	}
+
+	return comparison{field: name, op: op, value: value}, nil
+}
+
+// And combines exprs so an entry has to match all of them. It returns nil
+// when exprs is empty.
+func And(exprs ...Expr) Expr {
+	var combined Expr
+	for _, expr := range exprs {
+		if combined == nil {
+			combined = expr
+			continue
+		}
+
+		combined = and{left: combined, right: expr}
+	}
+
+	return combined
+}
+
+type and struct{ left, right Expr }
+
+func (e and) Match(entry model.Entry) bool { return e.left.Match(entry) && e.right.Match(entry) }
+
+type or struct{ left, right Expr }
+
+func (e or) Match(entry model.Entry) bool { return e.left.Match(entry) || e.right.Match(entry) }
+
+type not struct{ expr Expr }
+
+func (e not) Match(entry model.Entry) bool { return !e.expr.Match(entry) }
+
+type comparison struct {
+	field string
+	op    string
+	value string
+}
+
+func (c comparison) Match(entry model.Entry) bool {
+	got := Fields[c.field](entry)
+
+	var matched bool
+	switch {
+	case c.field == "phone" && c.op == "~":
+		matched = phonePrefix(entry.PhoneNumber, c.value)
+	case c.field == "phone":
+		matched = entry.PhoneNumber.Equal(model.PhoneNumber(c.value))
+	case c.op == "~":
+		matched = strings.HasPrefix(strings.ToLower(got), strings.ToLower(c.value))
+	default:
+		matched = strings.EqualFold(got, c.value)
+	}
+
+	if c.op == "!=" {
+		return !matched
+	}
+
+	return matched
+}
+
+func country(entry model.Entry) string {
+	if entry.Country != "" {
+		return entry.Country
+	}
+
+	return entry.PhoneNumber.Country()
+}
+
+// phonePrefix reports whether number starts with the digits of prefix,
+// written either internationally or with the national trunk "0".
+func phonePrefix(number model.PhoneNumber, prefix string) bool {
+	want := digits(prefix)
+	if want == "" {
+		return true
+	}
+
+	for _, candidate := range []string{digits(string(number)), digits(string(number.Canonical())), "0" + number.National()} {
+		if strings.HasPrefix(candidate, want) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+
+		return -1
+	}, s)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(input); {
+		c := input[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+
+		case c == '=' || c == '~':
+			tokens = append(tokens, token{kind: tokenOp, text: string(c), pos: i})
+			i++
+
+		case c == '!':
+			if strings.HasPrefix(input[i:], "!=") {
+				tokens = append(tokens, token{kind: tokenOp, text: "!=", pos: i})
+				i += 2
+			} else {
+				tokens = append(tokens, token{kind: tokenNot, text: "!", pos: i})
+				i++
+			}
+
+		case c == '"':
+			value, size, err := quoted(input[i:])
+			if err != nil {
+				return nil, &SyntaxError{Pos: i, Message: err.Error()}
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: value, pos: i})
+			i += size
+
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n\r()=~!\"", rune(input[i])) {
+				i++
+			}
+
+			word := input[start:i]
+			kind := tokenWord
+			switch strings.ToUpper(word) {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+
+			tokens = append(tokens, token{kind: kind, text: word, pos: start})
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// quoted reads the double quoted string at the start of s and returns its
+// value and how many bytes it took. A backslash escapes the next character.
+func quoted(s string) (string, int, error) {
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+
+			i++
+			value.WriteByte(s[i])
+		case '"':
+			return value.String(), i + 1, nil
+		default:
+			value.WriteByte(s[i])
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+type parser struct {
+	tokens []token
+	next   int
+	depth  int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+
+	return t
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.take()
+
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+
+		left = or{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.take()
+
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = and{left, right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > MaxDepth {
+		return nil, &SyntaxError{Pos: p.peek().pos, Message: fmt.Sprintf("expression nested deeper than %d levels", MaxDepth)}
+	}
+
+	switch t := p.take(); t.kind {
+	case tokenNot:
+		expr, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+
+		return not{expr}, nil
+
+	case tokenLParen:
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+
+		if closing := p.take(); closing.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: closing.pos, Message: fmt.Sprintf("expected \")\" but found %s", closing)}
+		}
+
+		return expr, nil
+
+	case tokenWord:
+		return p.comparison(t)
+
+	default:
+		return nil, &SyntaxError{Pos: t.pos, Message: fmt.Sprintf("expected a field name but found %s", t)}
+	}
+}
+
+func (p *parser) comparison(field token) (Expr, error) {
+	name := strings.ToLower(field.text)
+	if _, ok := Fields[name]; !ok {
+		return nil, &SyntaxError{Pos: field.pos, Message: fmt.Sprintf("unknown field %q, use one of %s", field.text, fieldNames())}
+	}
+
+	op := p.take()
+	if op.kind != tokenOp {
+		return nil, &SyntaxError{Pos: op.pos, Message: fmt.Sprintf("expected =, != or ~ after %q but found %s", field.text, op)}
+	}
+
+	value := p.take()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, &SyntaxError{Pos: value.pos, Message: fmt.Sprintf("expected a value after %q but found %s", op.text, value)}
+	}
+
+	return comparison{field: name, op: op.text, value: value.text}, nil
+}
+
+// fieldNames lists the names of Fields in order, for error messages.
+func fieldNames() string {
+	names := make([]string, 0, len(Fields))
+	for name := range Fields {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
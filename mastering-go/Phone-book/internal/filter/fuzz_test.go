@@ -0,0 +1,41 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// FuzzParse checks that Parse returns an error or an expression that can be
+// matched, and never panics.
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"surname=Smith",
+		`surname=Smith AND company~"Acme Inc"`,
+		"NOT (country=IR OR phone^+98)",
+		`name~"unterminated`,
+		"((((",
+		"consent=yes AND channel=sms",
+		"",
+	} {
+		f.Add(seed)
+	}
+
+	entry := model.Entry{Name: "John", Surname: "Smith", PhoneNumber: "+14155550100", Company: "Acme"}
+	f.Fuzz(func(t *testing.T, input string) {
+		expr, err := Parse(input)
+		if err != nil {
+			if expr != nil {
+				t.Fatalf("Parse(%q) returned an expression with the error %v", input, err)
+			}
+
+			return
+		}
+
+		if expr == nil {
+			t.Fatalf("Parse(%q) returned neither an expression nor an error", input)
+		}
+
+		expr.Match(entry)
+	})
+}
@@ -0,0 +1,143 @@
+// Package generate makes up phone books of realistic looking entries, for
+// benchmarks, demos and trying out new backends.
+package generate
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// locale is the language of the names and the country of the numbers made
+// up.
+type locale struct {
+	names     []string
+	surnames  []string
+	nicknames []string
+	companies []string
+	titles    []string
+	// number makes up a phone number of the country in E.164.
+	number func(r *rand.Rand) string
+}
+
+// locales are the locales there are data for, by name.
+var locales = map[string]locale{
+	"en": {
+		names:     []string{"Aaron", "Abigail", "Adam", "Alice", "Amelia", "Andrew", "Anna", "Benjamin", "Charlotte", "Chloe", "Christopher", "Daniel", "David", "Elizabeth", "Emily", "Emma", "Ethan", "Grace", "Hannah", "Henry", "Isabella", "Jack", "James", "Jessica", "John", "Joseph", "Joshua", "Julia", "Laura", "Liam", "Lily", "Lucas", "Madison", "Mason", "Matthew", "Mia", "Michael", "Natalie", "Noah", "Olivia", "Rachel", "Ryan", "Samuel", "Sarah", "Sophia", "Thomas", "Victoria", "William", "Zoe"},
+		surnames:  []string{"Adams", "Allen", "Anderson", "Baker", "Brown", "Campbell", "Carter", "Clark", "Collins", "Davis", "Edwards", "Evans", "Garcia", "Green", "Hall", "Harris", "Hill", "Jackson", "Johnson", "Jones", "King", "Lee", "Lewis", "Martin", "Miller", "Mitchell", "Moore", "Nelson", "Parker", "Perez", "Phillips", "Roberts", "Robinson", "Scott", "Smith", "Taylor", "Thomas", "Thompson", "Turner", "Walker", "White", "Williams", "Wilson", "Wright", "Young"},
+		nicknames: []string{"Al", "Bec", "Ben", "Chris", "Dan", "Ed", "Jim", "Jo", "Kate", "Liz", "Matt", "Meg", "Nick", "Sam", "Tom", "Will"},
+		companies: []string{"Acme", "Blue Harbor", "Copperfield", "Delta Works", "Evergreen", "Foxglove", "Granite Labs", "Hillside", "Ironwood", "Juniper", "Keystone", "Lakeshore", "Northwind", "Oakridge", "Pinecrest", "Riverside Health", "Summit Bank"},
+		titles:    []string{"Accountant", "Consultant", "Designer", "Engineer", "Manager", "Nurse", "Sales Lead", "Teacher", "Developer", "Director"},
+		// The NANP area and exchange codes start with 2 to 9.
+		number: func(r *rand.Rand) string {
+			return fmt.Sprintf("+1%d%02d%d%02d%04d", 2+r.IntN(8), r.IntN(100), 2+r.IntN(8), r.IntN(100), r.IntN(10000))
+		},
+	},
+	"fa": {
+		names:     []string{"آرش", "آزاده", "امید", "بهار", "بهروز", "پرستو", "پویا", "ترانه", "جواد", "حامد", "حمید", "داریوش", "رضا", "رویا", "زهره", "زهرا", "سارا", "سامان", "سعید", "شیرین", "علی", "فاطمه", "فرهاد", "کاوه", "کیان", "گلناز", "لیلا", "مریم", "محمد", "مرتضی", "مهدی", "مینا", "نازنین", "نیلوفر", "نیما", "هستی", "یاسمن", "یوسف"},
+		surnames:  []string{"احمدی", "اکبری", "امینی", "باقری", "تهرانی", "جعفری", "حسینی", "خسروی", "رحیمی", "رضایی", "زمانی", "سلیمانی", "شاهرابی", "شریفی", "صادقی", "عباسی", "علوی", "فراهانی", "قاسمی", "کریمی", "کاظمی", "محمدی", "مرادی", "موسوی", "نوری", "هاشمی", "یزدانی"},
+		nicknames: []string{"آری", "بهی", "حمید خان", "علی آقا", "فری", "مری", "نیمو"},
+		companies: []string{"آسمان", "البرز", "بهاران", "پارس", "دماوند", "زاگرس", "سپهر", "فردا", "کاوش", "مهرگان", "نوآوران", "هامون"},
+		titles:    []string{"برنامه‌نویس", "حسابدار", "طراح", "مدیر", "مهندس", "مشاور", "پزشک", "معلم"},
+		// Mobile numbers, 0912 123 4567, with the operator prefixes in use.
+		number: func(r *rand.Rand) string {
+			prefixes := []string{"901", "902", "910", "911", "912", "913", "915", "919", "920", "921", "930", "933", "935", "936", "937", "938", "939", "990"}
+			return fmt.Sprintf("+98%s%07d", prefixes[r.IntN(len(prefixes))], r.IntN(10000000))
+		},
+	},
+	"de": {
+		names:     []string{"Anna", "Ben", "Clara", "David", "Elias", "Emma", "Felix", "Finn", "Hannah", "Jonas", "Julia", "Laura", "Lea", "Leon", "Lukas", "Marie", "Max", "Mia", "Noah", "Paul", "Sophie", "Tim", "Moritz", "Lena"},
+		surnames:  []string{"Bauer", "Becker", "Fischer", "Hoffmann", "Klein", "Koch", "Meyer", "Müller", "Neumann", "Richter", "Schäfer", "Schmidt", "Schneider", "Schulz", "Schwarz", "Wagner", "Weber", "Wolf", "Zimmermann"},
+		nicknames: []string{"Basti", "Flo", "Hanni", "Jo", "Lenchen", "Maxi", "Tobi"},
+		companies: []string{"Bergmann AG", "Elbwerk", "Nordlicht GmbH", "Rheintal Bau", "Schwarzwald Logistik", "Spreeblick"},
+		titles:    []string{"Architektin", "Berater", "Entwickler", "Ingenieurin", "Kaufmann", "Lehrerin", "Projektleiter"},
+		// Mobile numbers, 0151 12345678.
+		number: func(r *rand.Rand) string {
+			prefixes := []string{"151", "152", "155", "157", "159", "160", "162", "163", "170", "171", "172", "173", "174", "175", "176", "177", "178", "179"}
+			return fmt.Sprintf("+49%s%08d", prefixes[r.IntN(len(prefixes))], r.IntN(100000000))
+		},
+	},
+}
+
+// LocaleNames returns the names of the locales, sorted.
+func LocaleNames() []string {
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Generator makes up entries. The same seed and locale always give the same
+// entries in the same order.
+type Generator struct {
+	locale locale
+	rand   *rand.Rand
+	// used keeps the numbers given out, so no two entries share one.
+	used map[string]bool
+}
+
+// New returns a Generator of entries of locale, one of LocaleNames.
+func New(locale string, seed uint64) (*Generator, error) {
+	l, ok := locales[locale]
+	if !ok {
+		return nil, fmt.Errorf("unknown locale %q, known ones are %s", locale, strings.Join(LocaleNames(), ", "))
+	}
+
+	return &Generator{locale: l, rand: rand.New(rand.NewPCG(seed, seed)), used: make(map[string]bool)}, nil
+}
+
+// Entry makes up an entry. Every entry has a name, a surname and a phone
+// number; some work somewhere, go by a nickname or have a birthday.
+func (g *Generator) Entry() model.Entry {
+	entry := model.Entry{
+		Name:        g.pick(g.locale.names),
+		Surname:     g.pick(g.locale.surnames),
+		PhoneNumber: model.PhoneNumber(g.number()),
+	}
+
+	if g.rand.IntN(10) < 4 {
+		entry.Company = g.pick(g.locale.companies)
+		if g.rand.IntN(2) == 0 {
+			entry.Title = g.pick(g.locale.titles)
+		}
+	}
+
+	if g.rand.IntN(10) == 0 {
+		entry.Nickname = g.pick(g.locale.nicknames)
+	}
+
+	if g.rand.IntN(10) < 3 {
+		// Day 28 at most, to be valid in every month.
+		day := fmt.Sprintf("%02d-%02d", 1+g.rand.IntN(12), 1+g.rand.IntN(28))
+		if g.rand.IntN(4) != 0 {
+			day = fmt.Sprintf("%d-%s", 1950+g.rand.IntN(56), day)
+		}
+
+		entry.Birthday = day
+	}
+
+	return entry
+}
+
+func (g *Generator) pick(values []string) string {
+	return values[g.rand.IntN(len(values))]
+}
+
+// number makes up a phone number no entry of g has yet. The number plans
+// have room for many millions, so this ends quickly.
+func (g *Generator) number() string {
+	for {
+		number := g.locale.number(g.rand)
+		if !g.used[number] {
+			g.used[number] = true
+			return number
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package i18n
+
+var persian = map[string]string{
+	"Please enter required arguments":            "لطفاً آرگومان‌های لازم را وارد کنید",
+	"Please provide a search term":               "لطفاً عبارت جستجو را وارد کنید",
+	"not enough arguments for insert":            "آرگومان‌های کافی برای درج وارد نشده است",
+	"not enough arguments for delete":            "آرگومان‌های کافی برای حذف وارد نشده است",
+	"invalid id %q":                              "شناسه نامعتبر: %q",
+	"successfully inserted with id = %d":         "با موفقیت با شناسه %d درج شد",
+	"successfully deleted":                       "با موفقیت حذف شد",
+	"not a valid command":                        "دستور نامعتبر است",
+	"there is no record with given phone number": "رکوردی با این شماره تلفن وجود ندارد",
+	"there is no record with given id":           "رکوردی با این شناسه وجود ندارد",
+	"there is no record matching %q":             "رکوردی مطابق با %q وجود ندارد",
+	"ID":                                         "شناسه",
+	"NAME":                                       "نام",
+	"SURNAME":                                    "نام خانوادگی",
+	"PHONE":                                      "تلفن",
+	"COUNTRY":                                    "کشور",
+	"ENTRIES":                                    "تعداد",
+	"entries: %d":                                "تعداد رکوردها: %d",
+	"unknown":                                    "نامشخص",
+	"BLOCKED":                                    "مسدود",
+	"yes":                                        "بله",
+	"usage: block add|remove <number> or block list": "استفاده: block add|remove <شماره> یا block list",
+	"%s is blocked":             "%s مسدود شد",
+	"%s is no longer blocked":   "%s از حالت مسدود خارج شد",
+	"the number is not blocked": "این شماره مسدود نیست",
+	"only list entries from this country (ISO code, e.g. IR)": "فقط رکوردهای این کشور نمایش داده شود (کد ISO، مثلاً IR)",
+	"no":                     "خیر",
+	"SPAM SCORE":             "امتیاز اسپم",
+	"%d reports":             "%d گزارش",
+	"spam score: %d":         "امتیاز اسپم: %d",
+	"%s is reported as spam": "%s به عنوان اسپم گزارش شد",
+	"usage: lookup <number>": "استفاده: lookup <شماره>",
+	"usage: spam report <number> [reason] or spam show <number>": "استفاده: spam report <شماره> [دلیل] یا spam show <شماره>",
+	"usage: photo set <id> <file> or photo get <id> [file]":      "استفاده: photo set <شناسه> <فایل> یا photo get <شناسه> [فایل]",
+	"photo saved":            "عکس ذخیره شد",
+	"the entry has no photo": "این رکورد عکس ندارد",
+	"COMPANY":                "شرکت",
+	"TITLE":                  "سمت",
+	"(none)":                 "(هیچ)",
+	"group the entries by company, title or country":           "گروه‌بندی رکوردها بر اساس شرکت، سمت یا کشور",
+	"cannot group by %q, use company, title or country":        "گروه‌بندی بر اساس %q ممکن نیست، از company، title یا country استفاده کنید",
+	"company the contact works for":                            "شرکتی که مخاطب در آن کار می‌کند",
+	"job title of the contact":                                 "سمت شغلی مخاطب",
+	"SCORE":                                                    "امتیاز",
+	"show at most this many results, best first (0 shows all)": "حداکثر این تعداد نتیجه، از بهترین (۰ یعنی همه)",
+	"No match for %q. Did you mean %s?":                        "موردی برای %q پیدا نشد. منظورتان %s بود؟",
+	" or ":                                                     " یا ",
+	"only list entries matching a filter, e.g. \"surname=Smith AND company~Acme\"": "فقط رکوردهای مطابق با فیلتر را نشان بده، مثلاً \"surname=Smith AND company~Acme\"",
+	"the phone book is read-only":                                    "دفترچه تلفن فقط خواندنی است",
+	"there is no phone book named %q in %s":                          "دفترچه تلفنی با نام %q در %s وجود ندارد",
+	"cannot import %s: %v":                                           "وارد کردن %s ممکن نیست: %v",
+	"imported %d entries from %s":                                    "%d رکورد از %s وارد شد",
+	"skipped %d entries without a phone number":                      "%d رکورد بدون شماره تلفن نادیده گرفته شد",
+	"usage: repair <file> [output]":                                  "نحوه استفاده: repair <file> [output]",
+	"line %d: %s":                                                    "خط %d: %s",
+	"cannot write %s: %v":                                            "نوشتن %s ممکن نیست: %v",
+	"salvaged %d entries into %s, %d lines needed attention":         "%d رکورد در %s بازیابی شد، %d خط نیاز به بررسی داشت",
+	"nothing was imported":                                           "هیچ رکوردی وارد نشد",
+	"the entry was changed by someone else, reload it and try again": "این رکورد را شخص دیگری تغییر داده است، آن را دوباره بارگذاری و دوباره تلاش کنید",
+	"the transaction has already been committed or rolled back":      "تراکنش قبلا ثبت یا لغو شده است",
+	"commit every this many entries instead of all at once (0 imports everything in one go)": "ثبت پس از هر این تعداد رکورد به جای همه با هم (۰ همه را یکجا وارد می‌کند)",
+	"pause between batches, e.g. 200ms":                                       "مکث بین دسته‌ها، مثلا 200ms",
+	"entries %d to %d were not imported":                                      "رکوردهای %d تا %d وارد نشدند",
+	"%d of %d entries processed, %d errors, %s left":                          "%d از %d رکورد پردازش شد، %d خطا، %s باقی مانده",
+	"how often to log progress when not on a terminal (0 turns progress off)": "فاصله ثبت پیشرفت وقتی خروجی ترمینال نیست (۰ پیشرفت را خاموش می‌کند)",
+	"%d errors":                  "%d خطا",
+	"%s left":                    "%s باقی مانده",
+	"the operation timed out":    "زمان عملیات به پایان رسید",
+	"the operation was canceled": "عملیات لغو شد",
+	"how many rows or batches to work on at the same time":                       "تعداد سطرها یا دسته‌هایی که هم‌زمان پردازش می‌شوند",
+	"only report suspected duplicates, without changing anything":                "فقط گزارش رکوردهای احتمالا تکراری، بدون هیچ تغییری",
+	"report format, json or csv":                                                 "قالب گزارش، json یا csv",
+	"lowest similarity, from 0 to 1, to report as a duplicate":                   "کمترین شباهت، از 0 تا 1، برای گزارش به عنوان تکراری",
+	"write the report to this file instead of the standard output":               "نوشتن گزارش در این فایل به جای خروجی استاندارد",
+	"usage: dedupe --report [--format json|csv] [--min-score S] [--output file]": "نحوه استفاده: dedupe --report [--format json|csv] [--min-score S] [--output file]",
+	"found %d groups of suspected duplicates":                                    "%d گروه رکورد احتمالا تکراری پیدا شد",
+	"pair entries by id or by phone number":                                      "جفت کردن رکوردها با شناسه یا شماره تلفن",
+	"output format, text or json":                                                "قالب خروجی، text یا json",
+	"usage: diff [--by id|phone] [--format text|json] <file>":                    "نحوه استفاده: diff [--by id|phone] [--format text|json] <file>",
+	"cannot read %s: %v":                                                         "خواندن %s ممکن نیست: %v",
+	"the phone books hold the same entries":                                      "دفترچه‌های تلفن رکوردهای یکسانی دارند",
+	"only in this phone book:":                                                   "فقط در این دفترچه تلفن:",
+	"only in %s:":                                                                "فقط در %s:",
+	"changed:":                                                                   "تغییر کرده:",
+	"output format, csv or json":                                                 "قالب خروجی، csv یا json",
+	"replace names and phone numbers with made-up ones":                          "جایگزینی نام‌ها و شماره تلفن‌ها با مقادیر ساختگی",
+	"seed for --anonymize, the same seed gives the same made-up values":          "بذر --anonymize، بذر یکسان همان مقادیر ساختگی را می‌دهد",
+	"write to this file instead of the standard output":                          "نوشتن در این فایل به جای خروجی استاندارد",
+	"usage: export [--format csv|json] [--anonymize [--seed S]] [--saved NAME] [--columns LIST] [--output file]": "نحوه استفاده: export [--format csv|json] [--anonymize [--seed S]] [--saved NAME] [--columns LIST] [--output file]",
+	"anonymized with seed %s":                          "ناشناس‌سازی با بذر %s انجام شد",
+	"exported %d entries to %s":                        "%d رکورد در %s ذخیره شد",
+	"usage: privacy export <id> or privacy erase <id>": "نحوه استفاده: privacy export <id> یا privacy erase <id>",
+	"erased entry %d":                                  "رکورد %d پاک شد",
+	"its number is shared with other entries, its blocklist entry and spam reports were kept":                "شماره آن با رکوردهای دیگر مشترک است، فهرست مسدودی و گزارش‌های اسپم آن نگه داشته شد",
+	"purge entries not modified for this long, e.g. 3y, 18m or 90d":                                          "پاک کردن رکوردهایی که این مدت تغییر نکرده‌اند، مثلا 3y، 18m یا 90d",
+	"only list what would be purged":                                                                         "فقط فهرست آنچه پاک می‌شود",
+	"usage: purge [--older-than AGE] [--dry-run] [--force], or set retention.purge_after in the config file": "نحوه استفاده: purge [--older-than AGE] [--dry-run] [--force]، یا retention.purge_after را در فایل تنظیمات مشخص کنید",
+	"purged %d entries before the failure":                                                                   "%d رکورد پیش از خطا پاک شد",
+	"%d entries not modified for %s would be purged":                                                         "%d رکورد که %s تغییر نکرده‌اند پاک خواهند شد",
+	"purged %d entries not modified for %s":                                                                  "%d رکورد که %s تغییر نکرده بودند پاک شد",
+	"kept %d entries whose last change is not known":                                                         "%d رکورد که زمان آخرین تغییرشان معلوم نیست نگه داشته شد",
+	"this process: %s": "این فرایند: %s",
+	"daemon: %s":       "سرویس پس‌زمینه: %s",
+	"no search index":  "بدون نمایه جستجو",
+	"INDEX":            "نمایه",
+	"TOKENS":           "توکن‌ها",
+	"SIZE":             "اندازه",
+	"in memory":        "در حافظه",
+	"out of date":      "قدیمی",
+	"%d goroutines, %s heap in use of %s, %s from the OS, %d GC cycles":                 "%d گوروتین، %s از %s هیپ در حال استفاده، %s از سیستم‌عامل، %d چرخه جمع‌آوری زباله",
+	"usage: debug stats [--format text|json]":                                           "استفاده: debug stats [--format text|json]",
+	"report format, text or json":                                                       "قالب گزارش، text یا json",
+	"only show entries whose %s starts with this":                                       "فقط مدخل‌هایی که %s آن‌ها با این شروع می‌شود نشان داده شوند",
+	"there is no record matching the given fields":                                      "هیچ رکوردی با فیلدهای داده‌شده مطابقت ندارد",
+	"what people call the contact, found by search like the name":                       "نامی که دیگران مخاطب را با آن صدا می‌زنند، مانند نام در جستجو یافت می‌شود",
+	"how long the call took, e.g. 3m or 1h20m":                                          "مدت تماس، مثلاً 3m یا 1h20m",
+	"what the call was about":                                                           "موضوع تماس",
+	"logged a call with entry %d":                                                       "تماس با مدخل %d ثبت شد",
+	"usage: calls list [--contact <id>] [--since WHEN] [--until WHEN]":                  "استفاده: calls list [--contact <شناسه>] [--since زمان] [--until زمان]",
+	"only show the calls with the entry with this id":                                   "فقط تماس‌های مدخل با این شناسه نشان داده شود",
+	"only show calls from this day on, e.g. 2024-05-01, or from this long ago, e.g. 7d": "فقط تماس‌ها از این روز به بعد، مثلاً 2024-05-01، یا از این مدت پیش، مثلاً 7d",
+	"only show calls up to the end of this day, or up to this long ago":                 "فقط تماس‌ها تا پایان این روز، یا تا این مدت پیش",
+	"no calls logged": "هیچ تماسی ثبت نشده است",
+	"invalid time %q, use a day like 2024-05-01 or an age like 7d": "زمان نامعتبر %q، روزی مانند 2024-05-01 یا مدتی مانند 7d بدهید",
+	"LAST CONTACTED": "آخرین تماس",
+	"WHEN":           "زمان",
+	"CONTACT":        "مخاطب",
+	"DURATION":       "مدت",
+	"NOTE":           "یادداشت",
+	"birthday of the contact, e.g. 1990-05-17, or 05-17 without the year":           "تاریخ تولد مخاطب، مثلاً 1990-05-17، یا 05-17 بدون سال",
+	"anniversary of the contact, written like the birthday":                         "سالگرد مخاطب، به همان شکل تاریخ تولد",
+	"remind this many days before the birthday and anniversary (0 uses the config)": "این تعداد روز پیش از تولد و سالگرد یادآوری شود (0 یعنی مقدار پیکربندی)",
+	"remind_days cannot be negative":                                                "remind_days نمی‌تواند منفی باشد",
+	"look this many days ahead instead of each contact's reminder period":           "به جای دوره‌ی یادآوری هر مخاطب، این تعداد روز جلوتر را ببین",
+	"usage: birthdays [--days N]":                                                   "استفاده: birthdays [--days N]",
+	"no birthdays or anniversaries coming up":                                       "تولد یا سالگردی در پیش نیست",
+	"birthday":                          "تولد",
+	"anniversary":                       "سالگرد",
+	"Today is the %s of %s":             "امروز %s %s است",
+	"Tomorrow is the %s of %s":          "فردا %s %s است",
+	"The %s of %s is in %d days, on %s": "%s %s %d روز دیگر، در %s است",
+	"(%d years)":                        "(%d سال)",
+	"DATE":                              "تاریخ",
+	"IN":                                "تا",
+	"OCCASION":                          "مناسبت",
+	"YEARS":                             "سال",
+	"today":                             "امروز",
+	"tomorrow":                          "فردا",
+	"%d days":                           "%d روز",
+	"usage: export-contact <id> [--out file.vcf]":                                               "استفاده: export-contact <شناسه> [--out فایل.vcf]",
+	"write the vCard to this file instead of the standard output":                               "vCard به جای خروجی استاندارد در این فایل نوشته شود",
+	"exported entry %d to %s":                                                                   "رکورد %d در %s ذخیره شد",
+	"only list what would be imported":                                                          "فقط فهرست رکوردهایی که وارد می‌شوند نمایش داده شود",
+	"usage: import [--batch-size N] [--delay D] [--workers N] [--dry-run] <file>...":            "نحوه استفاده: import [--batch-size N] [--delay D] [--workers N] [--dry-run] <file>...",
+	"%d entries from %s would be imported":                                                      "%d رکورد از %s وارد می‌شود",
+	"how many entries to make up":                                                               "تعداد رکوردهای ساختگی",
+	"seed of the made-up entries, the same seed gives the same entries":                         "بذر رکوردهای ساختگی، بذر یکسان رکوردهای یکسان می‌دهد",
+	"language of the names and country of the numbers: %s":                                      "زبان نام‌ها و کشور شماره‌ها: %s",
+	"usage: generate [--n N] [--seed S] [--locale %s]":                                          "نحوه استفاده: generate [--n N] [--seed S] [--locale %s]",
+	"generated with seed %d":                                                                    "با بذر %d ساخته شد",
+	"generated %d entries":                                                                      "%d رکورد ساخته شد",
+	"usage: archive <id>... or archive [--where EXPR] [--older-than AGE] [--dry-run] [--force]": "نحوه استفاده: archive <id>... یا archive [--where EXPR] [--older-than AGE] [--dry-run] [--force]",
+	"usage: unarchive <id>... or unarchive [--where EXPR] [--older-than AGE] [--dry-run]":       "نحوه استفاده: unarchive <id>... یا unarchive [--where EXPR] [--older-than AGE] [--dry-run]",
+	"only the entries matching a filter, e.g. \"company=Acme\"":                                 "فقط رکوردهای منطبق بر فیلتر، مثلا \"company=Acme\"",
+	"only the entries not modified for this long, e.g. 2y or 18m":                               "فقط رکوردهایی که این مدت تغییر نکرده‌اند، مثلا 2y یا 18m",
+	"only list the entries that would change":                                                   "فقط فهرست رکوردهایی که تغییر می‌کنند نمایش داده شود",
+	"there is no record with id %d":                                                             "رکوردی با شناسه %d وجود ندارد",
+	"%d entries would be archived":                                                              "%d رکورد بایگانی می‌شود",
+	"%d entries would be unarchived":                                                            "%d رکورد از بایگانی خارج می‌شود",
+	"nothing was changed":                                                                       "هیچ تغییری داده نشد",
+	"archived %d entries":                                                                       "%d رکورد بایگانی شد",
+	"unarchived %d entries":                                                                     "%d رکورد از بایگانی خارج شد",
+	"search the archived entries instead":                                                       "به جای بقیه، در رکوردهای بایگانی‌شده جستجو شود",
+	"list the archived entries instead":                                                         "به جای بقیه، رکوردهای بایگانی‌شده فهرست شوند",
+	"ARCHIVED":                                                                                  "بایگانی",
+	"Name":                                                                                      "نام",
+	"the name is required":                                                                      "نام لازم است",
+	"Surname (optional)":                                                                        "نام خانوادگی (اختیاری)",
+	"Phone number":                                                                              "شماره تلفن",
+	"this number is already saved for %s (id %d)":                                               "این شماره برای %s (شناسه %d) ذخیره شده است",
+	"Nickname (optional)":                                                                       "نام مستعار (اختیاری)",
+	"Company (optional)":                                                                        "شرکت (اختیاری)",
+	"Job title (optional)":                                                                      "سمت (اختیاری)",
+	"Birthday, e.g. 1990-05-17 or 05-17 (optional)":                                             "تاریخ تولد، مثلا 1990-05-17 یا 05-17 (اختیاری)",
+	"Save %s, %s? [Y/n]":                                                                        "%s، %s ذخیره شود؟ [Y/n]",
+	"nothing was inserted":                                                                      "هیچ رکوردی درج نشد",
+	"insert the entries read from the standard input instead":                                   "رکوردهای خوانده‌شده از ورودی استاندارد درج شوند",
+	"format of the standard input: %s":                                                          "قالب ورودی استاندارد: %s",
+	"insert --stdin takes no arguments":                                                         "insert --stdin آرگومانی نمی‌گیرد",
+	"cannot read the standard input: %v":                                                        "خواندن ورودی استاندارد ممکن نیست: %v",
+	"write each entry through this Go template instead of a table, e.g. '{{.Name}}: {{.PhoneNumber}}'": "هر رکورد به جای جدول با این قالب Go نوشته شود، مثلا '{{.Name}}: {{.PhoneNumber}}'",
+	"write the entry through this Go template instead of a table, e.g. '{{.Name}}: {{.PhoneNumber}}'":  "رکورد به جای جدول با این قالب Go نوشته شود، مثلا '{{.Name}}: {{.PhoneNumber}}'",
+	"--template and --group-by cannot be used together":                                                "--template و --group-by را نمی‌توان با هم به کار برد",
+	"invalid template: %v":                                                                     "قالب نامعتبر: %v",
+	"usage: get <id> [--template T]":                                                           "نحوه استفاده: get <id> [--template T]",
+	"Similar contact exists: %s, %s (id %d).":                                                  "مخاطب مشابهی وجود دارد: %s، %s (شناسه %d).",
+	"Replace its number with %s instead of adding a new entry? [y/N]":                          "به جای افزودن مخاطب جدید، شماره‌ی آن با %s جایگزین شود؟ [y/N]",
+	"gave %s the number %s":                                                                    "شماره‌ی %s به %s تغییر کرد",
+	"search the archived entries too, marking them in the results":                             "جستجو در مخاطبان بایگانی‌شده نیز، با مشخص کردن آن‌ها در نتایج",
+	"--archived and --include-archived cannot be used together":                                "‏--archived و --include-archived را نمی‌توان با هم به کار برد",
+	"usage: dump [--output book.tar.gz], or redirect the standard output to a file":            "استفاده: dump [--output دفترچه.tar.gz]، یا خروجی استاندارد را به یک فایل هدایت کنید",
+	"dumped %d entries, %d photos, %d calls, %d blocked numbers, %d spam reports and %d links": "%d مخاطب، %d عکس، %d تماس، %d شماره‌ی مسدود، %d گزارش هرزنامه و %d پیوند ذخیره شد",
+	"usage: load <book.tar.gz>, - for the standard input":                                      "استفاده: load <دفترچه.tar.gz>، - برای ورودی استاندارد",
+	"the book already has %d entries, load only fills an empty one":                            "دفترچه از قبل %d مخاطب دارد، load فقط دفترچه‌ی خالی را پر می‌کند",
+	"loaded %d entries":    "%d مخاطب بارگذاری شد",
+	"%d %s not loaded: %s": "%d %s بارگذاری نشد: %s",
+	"photos":               "عکس",
+	"calls":                "تماس",
+	"blocked numbers":      "شماره‌ی مسدود",
+	"spam reports":         "گزارش هرزنامه",
+	"loaded %d photos, %d calls, %d blocked numbers, %d spam reports and %d links":                                     "%d عکس، %d تماس، %d شماره‌ی مسدود، %d گزارش هرزنامه و %d پیوند بارگذاری شد",
+	"not a phone book dump: it has no manifest.json":                                                                   "این فایل پشتیبان دفترچه تلفن نیست: manifest.json ندارد",
+	"not a phone book dump: manifest.json is not one of a dump":                                                        "این فایل پشتیبان دفترچه تلفن نیست: manifest.json آن مربوط به پشتیبان نیست",
+	"how long to wait for servers to answer":                                                                           "چه مدت برای پاسخ سرورها صبر شود",
+	"usage: discover [--timeout 2s]":                                                                                   "استفاده: discover [--timeout 2s]",
+	"cannot search the local network: %v":                                                                              "امکان جستجوی شبکه محلی نیست: %v",
+	"no phone book server answered":                                                                                    "هیچ سرور دفترچه تلفنی پاسخ نداد",
+	"URL of the phone book server to sync with, e.g. http://other-host:8001":                                           "نشانی سرور دفترچه تلفنی که با آن همگام‌سازی می‌شود، مثلاً http://other-host:8001",
+	"bearer token of the peer, by default $PHONEBOOK_PEER_TOKEN":                                                       "توکن bearer سرور مقابل، به طور پیش‌فرض $PHONEBOOK_PEER_TOKEN",
+	"which side wins an entry edited on both: newer, local or peer":                                                    "کدام طرف در مدخلی که در هر دو ویرایش شده برنده است: newer، local یا peer",
+	"only print what would be done":                                                                                    "فقط چاپ کن چه کاری انجام می‌شد",
+	"usage: sync --peer <url> [--token T] [--prefer newer|local|peer] [--dry-run]":                                     "استفاده: sync --peer <url> [--token T] [--prefer newer|local|peer] [--dry-run]",
+	"sent %d new entries, %d edits and %d deletions; received %d new entries, %d edits and %d deletions; %d conflicts": "%d مدخل جدید، %d ویرایش و %d حذف فرستاده شد؛ %d مدخل جدید، %d ویرایش و %d حذف دریافت شد؛ %d تعارض",
+	"deleted on the peer: %s":                                                                                          "در سرور مقابل حذف شد: %s",
+	"conflict: %s was deleted here and edited on the peer, keeping it":                                                 "تعارض: %s اینجا حذف و در سرور مقابل ویرایش شده بود، نگه داشته شد",
+	"deleted here: %s": "اینجا حذف شد: %s",
+	"conflict: %s was deleted on the peer and edited here, keeping it": "تعارض: %s در سرور مقابل حذف و اینجا ویرایش شده بود، نگه داشته شد",
+	"conflict: %s differs on the peer, keeping the one here":           "تعارض: %s در سرور مقابل فرق دارد، نسخه اینجا نگه داشته شد",
+	"conflict: %s differs on the peer, keeping the one there":          "تعارض: %s در سرور مقابل فرق دارد، نسخه آنجا نگه داشته شد",
+	"edited on the peer: %s":                                           "در سرور مقابل ویرایش شد: %s",
+	"edited here: %s":                                                  "اینجا ویرایش شد: %s",
+	"sent to the peer: %s":                                             "به سرور مقابل فرستاده شد: %s",
+	"received from the peer: %s":                                       "از سرور مقابل دریافت شد: %s",
+	"usage: queue status|flush":                                        "استفاده: queue status|flush",
+	"only books on a remote backend like postgres queue changes":       "فقط دفترچه‌های روی پشتیبان راه دور مانند postgres تغییرات را در صف می‌گذارند",
+	"replayed %d changes, %d refused by the backend":                   "%d تغییر دوباره اعمال شد، %d تغییر را پشتیبان نپذیرفت",
+	"no changes are queued":                                            "هیچ تغییری در صف نیست",
+	"%d changes wait for the backend:":                                 "%d تغییر در انتظار پشتیبان است:",
+	"%d changes were refused by the backend when they were replayed, they are kept in %s:": "%d تغییر هنگام اعمال دوباره توسط پشتیبان پذیرفته نشد، در %s نگه داشته شده‌اند:",
+	"the phone book cannot be reached, the change is queued and will be made once it can":  "دفترچه تلفن در دسترس نیست، تغییر در صف گذاشته شد و وقتی در دسترس باشد انجام می‌شود",
+	"the phone book cannot be reached, changes are queued but reads have to wait for it":   "دفترچه تلفن در دسترس نیست، تغییرات در صف گذاشته می‌شوند اما خواندن باید منتظر آن بماند",
+	"usage: history [<id>]": "استفاده: history [<id>]",
+	"the event of the history to go back to, 0 for the empty book":                                            "رویدادی از تاریخچه که به آن برگردد، 0 برای دفترچهٔ خالی",
+	"only list what would be undone":                                                                          "فقط فهرست چیزهایی که برگردانده می‌شوند",
+	"usage: undo --to <event> [--dry-run]":                                                                    "استفاده: undo --to <event> [--dry-run]",
+	"the book is already as it was after event %d":                                                            "دفترچه همین حالا همان‌طور است که پس از رویداد %d بود",
+	"%d changes would take the book back to how it was after event %d":                                        "%d تغییر دفترچه را به حالت پس از رویداد %d برمی‌گرداند",
+	"made %d changes to take the book back to how it was after event %d":                                      "%d تغییر داده شد تا دفترچه به حالت پس از رویداد %d برگردد",
+	"the moment to restore the book as it was at, e.g. 2024-01-15T10:00":                                      "لحظه‌ای که دفترچه به حالت آن بازگردانده می‌شود، مثلاً 2024-01-15T10:00",
+	"write the restored book to this new file, by default restored-<time>.csv":                                "دفترچه‌ی بازگردانده‌شده در این فایل جدید نوشته شود، به طور پیش‌فرض restored-<time>.csv",
+	"take the book itself back, on books keeping their history":                                               "خود دفترچه بازگردانده شود، در دفترچه‌هایی که تاریخچه‌شان را نگه می‌دارند",
+	"backup files to restore from, e.g. '/srv/backups/book-*.csv', by default the outputs of the backup jobs": "فایل‌های پشتیبانی که از آن‌ها بازگردانی می‌شود، مثلاً '/srv/backups/book-*.csv'، به طور پیش‌فرض خروجی کارهای پشتیبان‌گیری",
+	"usage: restore --at TIME [--output file | --in-place] [--backups pattern]":                               "استفاده: restore --at TIME [--output file | --in-place] [--backups pattern]",
+	"only books keeping their history, like the events backend, can be restored in place":                     "فقط دفترچه‌هایی که تاریخچه‌شان را نگه می‌دارند، مانند ذخیره‌ساز events، در جا بازگردانده می‌شوند",
+	"made %d changes to take the book back to how it was at %s":                                               "%d تغییر برای بازگرداندن دفترچه به حالتش در %s انجام شد",
+	"replayed the history up to event %d":                                                                     "تاریخچه تا رویداد %d بازپخش شد",
+	"restoring from the backup %s taken at %s":                                                                "بازگردانی از پشتیبان %s گرفته‌شده در %s",
+	"restored %d entries as they were at %s to %s":                                                            "%d مدخل به حالتشان در %s در %s بازگردانده شد",
+	"the book keeps no history and there are no backup jobs, give the backups with --backups":                 "دفترچه تاریخچه‌ای نگه نمی‌دارد و کار پشتیبان‌گیری‌ای وجود ندارد، پشتیبان‌ها را با --backups بدهید",
+	"there is no backup from before %s":                                                                       "هیچ پشتیبانی از پیش از %s وجود ندارد",
+	"whether the contact agreed to be contacted, yes or no":                                                   "آیا مخاطب با تماس گرفتن با او موافقت کرده است، yes یا no",
+	"day the contact answered about consent, e.g. 2024-05-17 (today with --consent)":                          "روزی که مخاطب درباره‌ی رضایت پاسخ داد، مثلاً 2024-05-17 (با --consent امروز)",
+	"how the contact prefers to be contacted: %s":                                                             "راه ترجیحی تماس با مخاطب: %s",
+	"what to know before contacting the contact, e.g. \"mornings only\"":                                      "آنچه پیش از تماس با مخاطب باید دانست، مثلاً \"فقط صبح‌ها\"",
+	"consent must be yes or no":                                                                               "consent باید yes یا no باشد",
+	"consent_date needs a consent":                                                                            "consent_date بدون consent معنا ندارد",
+	"consent_date must be a day like 2024-05-17":                                                              "consent_date باید روزی مانند 2024-05-17 باشد",
+	"preferred_channel must be one of %s":                                                                     "preferred_channel باید یکی از %s باشد",
+	"usage: log call <id> [--duration 3m] [--note \"...\"] [--override]":                                      "استفاده: log call <شناسه> [--duration 3m] [--note \"...\"] [--override]",
+	"log the call even though the contact didn't agree to be contacted":                                       "تماس را ثبت کن حتی اگر مخاطب با تماس موافقت نکرده باشد",
+	"entry %d refused to be contacted on %s, use --override to go ahead anyway":                               "مخاطب %d در %s تماس را نپذیرفت، برای ادامه از --override استفاده کنید",
+	"entry %d refused to be contacted, use --override to go ahead anyway":                                     "مخاطب %d تماس را نپذیرفت، برای ادامه از --override استفاده کنید",
+	"entry %d has not agreed to be contacted, use --override to go ahead anyway":                              "مخاطب %d با تماس موافقت نکرده است، برای ادامه از --override استفاده کنید",
+	"CONSENT": "رضایت",
+	"CHANNEL": "راه تماس",
+	"call":    "تماس",
+	"sms":     "پیامک",
+	"email":   "ایمیل",
+	"post":    "پست",
+	"delete the entries matching a filter, e.g. \"company=Acme\"":        "مخاطبانی را که با فیلتر جور درمی‌آیند حذف کن، مثلاً \"company=Acme\"",
+	"only list the entries that would be deleted":                        "فقط مخاطبانی را که حذف می‌شوند فهرست کن",
+	"delete even more of the book than the destructive threshold allows": "حتی بیش از آستانه‌ی مجاز تغییرات مخرب حذف کن",
+	"usage: delete <id>... or delete --where EXPR [--dry-run] [--force]": "نحوه استفاده: delete <id>... یا delete --where EXPR [--dry-run] [--force]",
+	"%d entries would be deleted":                                        "%d مخاطب حذف می‌شوند",
+	"nothing was deleted":                                                "چیزی حذف نشد",
+	"deleted %d entries":                                                 "%d مخاطب حذف شد",
+	"this would %s %d of the %d entries, more than the %g%% allowed at once; check them with --dry-run and add --force to go ahead": "این کار %[2]d از %[3]d مخاطب را %[1]s می‌کند، بیش از %[4]g%% مجاز در یک بار؛ با --dry-run بررسی کنید و برای ادامه --force را اضافه کنید",
+	"delete":  "حذف",
+	"archive": "بایگانی",
+	"purge":   "پاک‌سازی",
+	"archive even more of the book than the destructive threshold allows": "حتی بیش از آستانه‌ی مجاز تغییرات مخرب بایگانی کن",
+	"purge even more of the book than the destructive threshold allows":   "حتی بیش از آستانه‌ی مجاز تغییرات مخرب پاک‌سازی کن",
+	"only show entries matching this saved search":                        "فقط مدخل‌های منطبق با این جستجوی ذخیره‌شده نمایش داده شود",
+	"run the previous search again":                                       "جستجوی قبلی دوباره اجرا شود",
+	"list the recent searches":                                            "فهرست جستجوهای اخیر",
+	"--last and --recent take no other arguments":                         "--last و --recent آرگومان دیگری نمی‌پذیرند",
+	"cannot read the search history: %v":                                  "تاریخچه جستجو خوانده نشد: %v",
+	"there is no previous search":                                         "جستجوی قبلی وجود ندارد",
+	"only list entries matching this saved search":                        "فقط مدخل‌های منطبق با این جستجوی ذخیره‌شده فهرست شوند",
+	"only export the entries matching this saved search":                  "فقط مدخل‌های منطبق با این جستجوی ذخیره‌شده خروجی گرفته شوند",
+	"cannot read the saved searches: %v":                                  "جستجوهای ذخیره‌شده خوانده نشدند: %v",
+	"there is no saved search %q, see search saved":                       "جستجوی ذخیره‌شده‌ای با نام %q وجود ندارد، search saved را ببینید",
+	"usage: search save NAME EXPR":                                        "نحوه استفاده: search save NAME EXPR",
+	"saved search %q, use it with --saved %s":                             "جستجوی %q ذخیره شد، با --saved %s از آن استفاده کنید",
+	"there are no saved searches, add one with search save NAME EXPR":     "جستجوی ذخیره‌شده‌ای وجود ندارد، با search save NAME EXPR یکی اضافه کنید",
+	"usage: search forget NAME":                                           "نحوه استفاده: search forget NAME",
+	"forgot the saved search %q":                                          "جستجوی ذخیره‌شده %q حذف شد",
+	"cannot write the saved searches: %v":                                 "جستجوهای ذخیره‌شده نوشته نشدند: %v",
+	"there are no searches yet":                                           "هنوز جستجویی انجام نشده است",
+	"there is no directory to keep it in":                                 "پوشه‌ای برای نگهداری آن وجود ندارد",
+	"only show these columns, in this order, e.g. name,phone,company":     "فقط این ستون‌ها به همین ترتیب نمایش داده شوند، مثلاً name,phone,company",
+	"--template and --columns cannot be used together":                    "--template و --columns را نمی‌توان با هم استفاده کرد",
+	"only export these fields, in this order, e.g. name,phone,company":    "فقط این فیلدها به همین ترتیب خروجی گرفته شوند، مثلاً name,phone,company",
+	"--columns needs at least one column, e.g. name,phone":                "--columns دست‌کم یک ستون لازم دارد، مثلاً name,phone",
+	"unknown column %q, use %s":                                           "ستون %q ناشناخته است، از %s استفاده کنید",
+	"NICKNAME":                                                            "نام مستعار",
+	"BIRTHDAY":                                                            "تولد",
+	"ANNIVERSARY":                                                         "سالگرد",
+	"REMIND DAYS":                                                         "روزهای یادآوری",
+	"CONSENT DATE":                                                        "تاریخ رضایت",
+	"ACCESS NOTES":                                                        "یادداشت‌های دسترسی",
+	"PHOTO":                                                               "عکس",
+	"VERSION":                                                             "نسخه",
+	"UPDATED":                                                             "به‌روزرسانی",
+	"usage: link add <id> <id> --type %s, link remove <id> <id> or link list [<id>]": "نحوه استفاده: link add <شناسه> <شناسه> --type %s، link remove <شناسه> <شناسه> یا link list [<شناسه>]",
+	"how the second entry relates to the first, one of %s":                           "نسبت مدخل دوم با مدخل اول، یکی از %s",
+	"--type must be one of %s":                                                       "--type باید یکی از %s باشد",
+	"an entry cannot be linked to itself":                                            "یک مدخل را نمی‌توان به خودش پیوند داد",
+	"linked entry %d to entry %d as %s":                                              "مدخل %d به‌عنوان %[3]s به مدخل %[2]d پیوند داده شد",
+	"unlinked entries %d and %d":                                                     "پیوند مدخل‌های %d و %d حذف شد",
+	"entry %d is not related to any other":                                           "مدخل %d با هیچ مدخل دیگری مرتبط نیست",
+	"there are no links yet":                                                         "هنوز پیوندی وجود ندارد",
+	"the entries are not linked":                                                     "این مدخل‌ها به هم پیوند داده نشده‌اند",
+	"RELATIONSHIP":                                                                   "نسبت",
+	"OF":                                                                             "از",
+	"employer":                                                                       "کارفرما",
+	"%s (automatic)":                                                                 "%s (خودکار)",
+	"spouse":                                                                         "همسر",
+	"assistant":                                                                      "دستیار",
+	"household":                                                                      "هم‌خانه",
+	"same-company":                                                                   "هم‌شرکت",
+	"links":                                                                          "پیوند",
+	"report on this long back, e.g. 7d or 1m":                                        "گزارش این مدت اخیر، مثلاً 7d یا 1m",
+	"report format, md or html":                                                      "قالب گزارش، md یا html",
+	"usage: report [--since AGE] [--format md|html] [--output file]": "استفاده: report [--since AGE] [--format md|html] [--output file]",
+	"wrote the report of %d added, %d edited and %d deleted entries": "گزارش %d مدخل افزوده، %d ویرایش‌شده و %d حذف‌شده نوشته شد",
+	"Phone book report":        "گزارش دفترچه تلفن",
+	"From %s to %s":            "از %s تا %s",
+	"Added (%d)":               "افزوده‌شده (%d)",
+	"Edited (%d)":              "ویرایش‌شده (%d)",
+	"Deleted (%d)":             "حذف‌شده (%d)",
+	"Deleted":                  "حذف‌شده",
+	"No entries were added.":   "هیچ مدخلی افزوده نشد.",
+	"No entries were edited.":  "هیچ مدخلی ویرایش نشد.",
+	"No entries were deleted.": "هیچ مدخلی حذف نشد.",
+	"This book keeps no history, so deletions are not known.":                     "این دفترچه تاریخچه نگه نمی‌دارد، پس حذف‌ها معلوم نیستند.",
+	"Possible duplicates (%d)":                                                    "تکراری‌های احتمالی (%d)",
+	"None of the changes look like a duplicate.":                                  "هیچ‌کدام از تغییرها تکراری به نظر نمی‌رسد.",
+	"%s, score %.2f: %s":                                                          "%s، امتیاز %.2f: %s",
+	"%d more groups of suspected duplicates were already in the book, see dedupe": "%d گروه دیگر از تکراری‌های احتمالی از پیش در دفترچه بودند، dedupe را ببینید",
+	"Upcoming birthdays and anniversaries (%d)":                                   "تولدها و سالگردهای پیش رو (%d)",
+	"No birthdays or anniversaries coming up.":                                    "تولد یا سالگردی در پیش نیست.",
+	"%s: the %s of %s":                                                            "%s: %s %s",
+	"entry %d":                                                                    "مدخل %d",
+	"wrote the report of %d added and %d edited entries":                          "گزارش %d مدخل افزوده و %d ویرایش‌شده نوشته شد",
+	"warning: %s":                                                                 "هشدار: %s",
+	"error: %s (--strict)":                                                        "خطا: %s (--strict)",
+	"%q cannot be normalized as a phone number":                                   "%q را نمی‌توان به شکل استاندارد شماره تلفن درآورد",
+	"it looks like %s (id %d), who is already in the book: %s":                    "شبیه %s (شناسه %d) است که از پیش در دفترچه هست: %s",
+	"%s: unknown columns %s":                                                      "%s: ستون‌های ناشناخته %s",
+	"%s: %d of the numbers cannot be normalized, like %q":                         "%s: %d شماره را نمی‌توان به شکل استاندارد درآورد، مثل %q",
+	"BOOK":                                  "دفترچه",
+	"book %s: %s":                           "دفترچهٔ %s: %s",
+	"book %s: %v":                           "دفترچهٔ %s: %v",
+	"there are no books in the config file": "در فایل تنظیمات دفترچه‌ای نیست",
+	"there is no phone book named %q in the config file":                               "در فایل تنظیمات دفترچه‌ای به نام %q نیست",
+	"there is no record matching the given fields in any of the books":                 "در هیچ یک از دفترچه‌ها رکوردی با این فیلدها نیست",
+	"there is no record matching %q in any of the books":                               "در هیچ یک از دفترچه‌ها رکوردی مطابق %q نیست",
+	"search these books of the config file instead, \"all\" or a comma-separated list": "به جای آن در این دفترچه‌های فایل تنظیمات جستجو شود، \"all\" یا فهرستی جداشده با ویرگول",
+	"usage: %s [flags] <command> [arguments]":                                          "استفاده: %s [پرچم‌ها] <دستور> [آرگومان‌ها]",
+	"commands:": "دستورها:",
+	"Run %s -h for the flags, and %s <command> -h for those of a command.": "برای پرچم‌ها %s -h و برای پرچم‌های هر دستور %s <دستور> -h را اجرا کنید.",
+	"usage: version [--format text|json]":                                  "استفاده: version [--format text|json]",
+	"%s cannot be run here":                                                "%s را اینجا نمی‌توان اجرا کرد",
+	"unknown command %q, run help for the list":                            "دستور %q شناخته نیست، برای فهرست دستورها help را اجرا کنید",
+	"show the entries matching a term or fields, best first":               "نمایش رکوردهای مطابق یک عبارت یا فیلدها، بهترین‌ها اول",
+	"show the entries of the book, all of them or those of a filter":       "نمایش رکوردهای دفترچه، همه یا آن‌هایی که با یک فیلتر می‌خوانند",
+	"show one entry and the entries related to it":                         "نمایش یک رکورد و رکوردهای مرتبط با آن",
+	"count the entries by country":                                         "شمارش رکوردها بر اساس کشور",
+	"add an entry, or those of the standard input":                         "افزودن یک رکورد، یا رکوردهای ورودی استاندارد",
+	"delete entries by id or filter":                                       "حذف رکوردها با شناسه یا فیلتر",
+	"hide entries from lists and searches without deleting them":           "پنهان کردن رکوردها از فهرست‌ها و جستجوها بدون حذف آن‌ها",
+	"bring archived entries back":                                          "بازگرداندن رکوردهای بایگانی‌شده",
+	"relate two entries, or list how entries are related":                  "مرتبط کردن دو رکورد، یا فهرست ارتباط‌های رکوردها",
+	"set or get the photo of an entry":                                     "تنظیم یا دریافت عکس یک رکورد",
+	"manage the blocklist of numbers":                                      "مدیریت فهرست شماره‌های مسدود",
+	"report a number as spam or show its reports":                          "گزارش یک شماره به عنوان هرزنامه یا نمایش گزارش‌های آن",
+	"tell who a number belongs to":                                         "اینکه یک شماره مال کیست",
+	"list the birthdays and anniversaries coming up":                       "فهرست تولدها و سالگردهای پیش رو",
+	"record a call with an entry":                                          "ثبت تماس با یک رکورد",
+	"show the call log":                                                    "نمایش سابقهٔ تماس‌ها",
+	"add the entries of CSV, JSON or vCard files":                          "افزودن رکوردهای فایل‌های CSV، JSON یا vCard",
+	"write the entries as CSV or JSON":                                     "نوشتن رکوردها به صورت CSV یا JSON",
+	"write an entry as a vCard":                                            "نوشتن یک رکورد به صورت vCard",
+	"compare the book with a saved copy":                                   "مقایسهٔ دفترچه با یک نسخهٔ ذخیره‌شده",
+	"find the entries that look like duplicates":                           "یافتن رکوردهایی که تکراری به نظر می‌رسند",
+	"write a digest of the recent changes":                                 "نوشتن خلاصه‌ای از تغییرات اخیر",
+	"add made-up entries, for benchmarks and demos":                        "افزودن رکوردهای ساختگی، برای سنجش و نمایش",
+	"write everything the book keeps as a bundle":                          "نوشتن هر چه دفترچه نگه می‌دارد در یک بسته",
+	"fill an empty book from a bundle of dump":                             "پر کردن یک دفترچهٔ خالی از بستهٔ dump",
+	"export or erase what the book keeps about a person":                   "خروجی گرفتن یا پاک کردن آنچه دفترچه دربارهٔ یک نفر نگه می‌دارد",
+	"delete the entries the retention policy no longer keeps":              "حذف رکوردهایی که سیاست نگهداری دیگر نگه نمی‌دارد",
+	"list the changes made to the book or an entry":                        "فهرست تغییرات دفترچه یا یک رکورد",
+	"take the book back to how it was after a change":                      "بازگرداندن دفترچه به حالت پس از یک تغییر",
+	"bring the book back to how it was at a time":                          "بازگرداندن دفترچه به حالت آن در یک زمان",
+	"show or replay the changes waiting for an unreachable backend":        "نمایش یا اجرای دوبارهٔ تغییرات منتظر یک پشتیبان در دسترس‌نبوده",
+	"salvage what can be read of a damaged CSV file":                       "نجات آنچه از یک فایل CSV آسیب‌دیده خواندنی است",
+	"exchange the changes made since the last sync with another server":    "تبادل تغییرات پس از آخرین همگام‌سازی با سرور دیگر",
+	"keep the book loaded for the commands run after it":                   "بارگذاری‌شده نگه داشتن دفترچه برای دستورهای بعدی",
+	"list the phone book servers on the local network":                     "فهرست سرورهای دفترچه تلفن در شبکهٔ محلی",
+	"show the sizes and memory of the book and the process":                "نمایش اندازه‌ها و حافظهٔ دفترچه و فرایند",
+	"show which build of the phone book this is":                           "اینکه این کدام ساخت دفترچه تلفن است",
+	"list the commands":                                                    "فهرست دستورها",
+	"%s: skipped %d entries with invalid fields, the first because %s":     "%s: %d مورد با فیلدهای نامعتبر کنار گذاشته شد، اولی چون %s",
+	"only %d of the %d entries were deleted":                               "فقط %d مورد از %d مورد حذف شد",
+	"only %d of the %d entries were loaded":                                "فقط %d مورد از %d مورد بارگذاری شد",
+	"only %d of the %d entries were changed":                               "فقط %d مورد از %d مورد تغییر کرد",
+	"dumped the history of %d events":                                      "تاریخچه‌ای با %d رویداد ذخیره شد",
+	"loaded %d entries with their history of %d events":                    "%d مورد همراه با تاریخچه‌ای از %d رویداد بارگذاری شد",
+	"events": "رویداد",
+	"the event log already has a history, one is only loaded into an empty log": "گزارش رویدادها از قبل تاریخچه دارد، تاریخچه فقط در گزارشی خالی بارگذاری می‌شود",
+	"cannot save %s: %v": "ذخیره‌ی %s ممکن نیست: %v",
+}
@@ -0,0 +1,63 @@
+// Package i18n translates the messages the command line prints. Messages are
+// looked up by their English text, so anything missing from a catalog -
+// including errors coming from a storage backend - is printed untranslated.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var catalogs = map[string]map[string]string{
+	"en": {},
+	"fa": persian,
+}
+
+var current = catalogs["en"]
+
+// Detect picks the locale to use: the configured one if set, otherwise the
+// first of LC_ALL, LC_MESSAGES and LANG that is set.
+func Detect(configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+
+	return "en"
+}
+
+// SetLocale switches the catalog used by T. Values like "fa_IR.UTF-8" are
+// reduced to their language, unknown languages fall back to English.
+func SetLocale(locale string) {
+	language := strings.ToLower(locale)
+	if i := strings.IndexAny(language, "_-.@"); i >= 0 {
+		language = language[:i]
+	}
+
+	catalog, ok := catalogs[language]
+	if !ok {
+		catalog = catalogs["en"]
+	}
+
+	current = catalog
+}
+
+// T translates message and, when args are given, formats it like
+// fmt.Sprintf.
+func T(message string, args ...any) string {
+	if translated, ok := current[message]; ok {
+		message = translated
+	}
+
+	if len(args) == 0 {
+		return message
+	}
+
+	return fmt.Sprintf(message, args...)
+}
@@ -0,0 +1,426 @@
+// Package jobs runs the scheduled tasks of a long-lived server, like a
+// nightly backup or a weekly duplicate report, as configured in the config
+// file.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/csvfile"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/dedupe"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/report"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/retention"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/upload"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// task does the work of a job against store.
+type task func(ctx context.Context, store storage.Storage, job config.Job) error
+
+var tasks = map[string]task{
+	"backup":        backup,
+	"dedupe-report": dedupeReport,
+	"purge":         purge,
+	"report":        digestReport,
+}
+
+// Scheduler runs jobs on their schedules and on request.
+type Scheduler struct {
+	jobs []*job
+	// ctx is the context Start was called with, that jobs run on request
+	// also end with.
+	ctx context.Context
+}
+
+type job struct {
+	config   config.Job
+	schedule Schedule
+	run      task
+	store    storage.Storage
+
+	mu        sync.Mutex
+	running   bool
+	next      time.Time
+	lastRun   *time.Time
+	lastError string
+}
+
+// New checks the jobs of cfg and prepares a Scheduler for them. store
+// returns the phone book a job works on given its Book.
+func New(cfg *config.Config, store func(book string) (storage.Storage, error)) (*Scheduler, error) {
+	s := &Scheduler{ctx: context.Background()}
+	names := make(map[string]bool)
+	for _, c := range cfg.Jobs {
+		if c.Name == "" || names[c.Name] {
+			return nil, fmt.Errorf("every job needs a name of its own, got %q", c.Name)
+		}
+
+		names[c.Name] = true
+
+		schedule, err := ParseSchedule(c.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %v", c.Name, err)
+		}
+
+		if schedule.Next(time.Now()).IsZero() {
+			return nil, fmt.Errorf("job %s: schedule %q is never due", c.Name, c.Schedule)
+		}
+
+		run, ok := tasks[c.Task]
+		if !ok {
+			return nil, fmt.Errorf("job %s: unknown task %q, use backup, dedupe-report, purge or report", c.Name, c.Task)
+		}
+
+		if c.Task != "purge" && c.Output == "" && c.Upload == "" {
+			return nil, fmt.Errorf("job %s: %s needs an output file or an upload destination", c.Name, c.Task)
+		}
+
+		if c.Upload != "" {
+			if c.Task == "purge" {
+				return nil, fmt.Errorf("job %s: purge has nothing to upload", c.Name)
+			}
+
+			if _, _, err := upload.Open(c.Upload); err != nil {
+				return nil, fmt.Errorf("job %s: %v", c.Name, err)
+			}
+		}
+
+		if c.Task == "purge" && c.OlderThan == "" && cfg.Retention != nil {
+			c.OlderThan = cfg.Retention.PurgeAfter
+		}
+
+		if c.Task == "purge" && c.OlderThan == "" {
+			return nil, fmt.Errorf("job %s: purge needs older_than or a retention policy", c.Name)
+		}
+
+		if c.OlderThan != "" {
+			if _, err := retention.ParseAge(c.OlderThan); err != nil {
+				return nil, fmt.Errorf("job %s: %v", c.Name, err)
+			}
+		}
+
+		if c.Since != "" {
+			if _, err := retention.ParseAge(c.Since); err != nil {
+				return nil, fmt.Errorf("job %s: %v", c.Name, err)
+			}
+		}
+
+		jobStore, err := store(c.Book)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %v", c.Name, err)
+		}
+
+		s.jobs = append(s.jobs, &job{config: c, schedule: schedule, run: run, store: jobStore})
+	}
+
+	return s, nil
+}
+
+// Start runs every job on its schedule until ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.ctx = ctx
+	for _, j := range s.jobs {
+		go s.loop(j)
+	}
+}
+
+func (s *Scheduler) loop(j *job) {
+	for {
+		j.mu.Lock()
+		j.next = j.schedule.Next(time.Now())
+		wait := time.Until(j.next)
+		j.mu.Unlock()
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		// A run started on request that is still going makes this one
+		// skip rather than pile up.
+		if j.start() {
+			s.execute(j)
+		}
+	}
+}
+
+// start marks j as running, unless it already is.
+func (j *job) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.running {
+		return false
+	}
+
+	j.running = true
+
+	return true
+}
+
+func (s *Scheduler) execute(j *job) {
+	started := time.Now()
+	err := j.run(s.ctx, j.store, j.config)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.running = false
+	j.lastRun = &started
+	j.lastError = ""
+	if err != nil {
+		j.lastError = err.Error()
+		log.Printf("job %s failed: %v", j.config.Name, err)
+		return
+	}
+
+	log.Printf("job %s done in %s", j.config.Name, time.Since(started).Round(time.Millisecond))
+}
+
+// List returns the status of every job, in the order of the config file.
+func (s *Scheduler) List() []model.JobStatus {
+	statuses := make([]model.JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		j.mu.Lock()
+		statuses = append(statuses, model.JobStatus{
+			Name:      j.config.Name,
+			Schedule:  j.config.Schedule,
+			Task:      j.config.Task,
+			Running:   j.running,
+			NextRun:   j.next,
+			LastRun:   j.lastRun,
+			LastError: j.lastError,
+		})
+		j.mu.Unlock()
+	}
+
+	return statuses
+}
+
+// Run starts the job called name now, in the background. It fails with 404
+// for an unknown job and 409 when the job is already running.
+func (s *Scheduler) Run(name string) *model.PhoeBookError {
+	for _, j := range s.jobs {
+		if j.config.Name != name {
+			continue
+		}
+
+		if !j.start() {
+			return &model.PhoeBookError{Message: "the job is already running", StatusCode: http.StatusConflict}
+		}
+
+		go s.execute(j)
+
+		return nil
+	}
+
+	return &model.PhoeBookError{Message: "there is no job with given name", StatusCode: http.StatusNotFound}
+}
+
+func backup(ctx context.Context, store storage.Storage, job config.Job) error {
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return fmt.Errorf("%s", appErr.Message)
+	}
+
+	return writeOutput(ctx, job, func(w io.Writer, path string) error {
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			if entries == nil {
+				entries = []model.Entry{}
+			}
+
+			return json.NewEncoder(w).Encode(entries)
+		}
+
+		return csvfile.Write(w, entries)
+	})
+}
+
+func dedupeReport(ctx context.Context, store storage.Storage, job config.Job) error {
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return fmt.Errorf("%s", appErr.Message)
+	}
+
+	groups := dedupe.Find(entries, dedupe.DefaultMinScore)
+
+	return writeOutput(ctx, job, func(w io.Writer, path string) error {
+		format := "csv"
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			format = "json"
+		}
+
+		return dedupe.Write(w, format, groups)
+	})
+}
+
+func digestReport(ctx context.Context, store storage.Storage, job config.Job) error {
+	since := 7 * 24 * time.Hour
+	if job.Since != "" {
+		var err error
+		if since, err = retention.ParseAge(job.Since); err != nil {
+			return err
+		}
+	}
+
+	digest, appErr := report.Build(ctx, store, since, time.Now())
+	if appErr != nil {
+		return fmt.Errorf("%s", appErr.Message)
+	}
+
+	return writeOutput(ctx, job, func(w io.Writer, path string) error {
+		format := "md"
+		if strings.EqualFold(filepath.Ext(path), ".html") {
+			format = "html"
+		}
+
+		return report.Write(w, format, digest)
+	})
+}
+
+func purge(ctx context.Context, store storage.Storage, job config.Job) error {
+	maxAge, err := retention.ParseAge(job.OlderThan)
+	if err != nil {
+		return err
+	}
+
+	// Like the purge command without --force, a purge of more of the book
+	// than the destructive threshold allows is refused.
+	preview, appErr := retention.Purge(ctx, store, maxAge, true)
+	if appErr != nil {
+		return fmt.Errorf("%s", appErr.Message)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	if cfg.TooDestructive(len(preview.Expired), preview.Total) {
+		return fmt.Errorf("not purging %d of the %d entries, more than the %g%% allowed at once; check them with purge --dry-run", len(preview.Expired), preview.Total, cfg.Threshold())
+	}
+
+	report, appErr := retention.Purge(ctx, store, maxAge, false)
+	if appErr != nil {
+		return fmt.Errorf("%s", appErr.Message)
+	}
+
+	log.Printf("job %s purged %d entries not modified for %s", job.Name, report.Purged, job.OlderThan)
+
+	return nil
+}
+
+// writeOutput writes the output of job to its Output file and uploads it
+// to its Upload destination, after expanding the {date} and {time} of both.
+// write is given the path the output goes to, to pick the format by.
+func writeOutput(ctx context.Context, job config.Job, write func(w io.Writer, path string) error) error {
+	now := time.Now()
+	expand := strings.NewReplacer("{date}", now.Format("2006-01-02"), "{time}", now.Format("20060102-150405")).Replace
+
+	if job.Output != "" {
+		if err := writeFile(expand(job.Output), write); err != nil {
+			return err
+		}
+	}
+
+	if job.Upload == "" {
+		return nil
+	}
+
+	destination := expand(job.Upload)
+
+	// The upload goes through a temporary file: a copy of the Output, or
+	// without one the output itself, in the format the extension of the
+	// destination's path picks.
+	content, err := os.CreateTemp("", "phonebook-upload-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(content.Name())
+	defer content.Close()
+
+	if job.Output != "" {
+		err = copyFile(content, expand(job.Output))
+	} else {
+		path := destination
+		if u, parseErr := url.Parse(destination); parseErr == nil {
+			path = u.Path
+		}
+
+		err = write(content, path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err := upload.Upload(ctx, destination, content); err != nil {
+		return fmt.Errorf("cannot upload to %s: %v", upload.Redacted(destination), err)
+	}
+
+	return nil
+}
+
+// copyFile copies the file at path to w, so the upload is the file written
+// even if another run replaces it meanwhile.
+func copyFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+
+	return err
+}
+
+// writeFile writes the file at path through a temporary one, so a failed
+// run never leaves half a file behind.
+func writeFile(path string, write func(w io.Writer, path string) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp, path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,145 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron schedule: "minute hour day-of-month month day-of-week",
+// each field a "*", a number, a range "1-5", a list "1,15" or a step "*/10",
+// or one of @hourly, @daily, @weekly and @monthly. Times are local.
+type Schedule struct {
+	minute, hour, day, month, weekday []bool
+	// anyDay and anyWeekday are set for "*": when both day fields are
+	// restricted, a time matching either of them is due, as in cron.
+	anyDay, anyWeekday bool
+}
+
+var shorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseSchedule parses a cron schedule.
+func ParseSchedule(spec string) (Schedule, error) {
+	if expanded, ok := shorthands[strings.TrimSpace(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("invalid schedule %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	var s Schedule
+	var err error
+	ranges := []struct {
+		set      *[]bool
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.day, 1, 31},
+		{&s.month, 1, 12},
+		{&s.weekday, 0, 7},
+	}
+
+	for i, r := range ranges {
+		if *r.set, err = parseField(fields[i], r.min, r.max); err != nil {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: %v", spec, err)
+		}
+	}
+
+	// Sunday is 0 or 7.
+	s.weekday[0] = s.weekday[0] || s.weekday[7]
+	s.anyDay, s.anyWeekday = fields[2] == "*", fields[4] == "*"
+
+	return s, nil
+}
+
+func parseField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		expr, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		low, high := min, max
+		if expr != "*" {
+			lowText, highText, isRange := strings.Cut(expr, "-")
+			var err error
+			if low, err = strconv.Atoi(lowText); err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highText); err != nil {
+					return nil, fmt.Errorf("invalid value %q", part)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := low; v <= high; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// Next returns the first time after t the schedule is due, to the minute, or
+// the zero time for schedules that are never due, like April 31st.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Every schedule that parses is due at least once in eight years,
+	// February 29th on a given weekday included.
+	for limit := t.AddDate(8, 0, 0); t.Before(limit); {
+		switch {
+		case !s.month[t.Month()]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !s.hour[t.Hour()]:
+			// Not Truncate: local hours do not start on the hour everywhere,
+			// Tehran is UTC+3:30.
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !s.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	day, weekday := s.day[t.Day()], s.weekday[t.Weekday()]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
@@ -0,0 +1,456 @@
+// Package mdns advertises the phone book server on the local network with
+// multicast DNS service discovery (RFC 6762 and 6763), and finds the servers
+// advertised, so clients at home or in a small office need no address
+// configured. Only IPv4 is used.
+package mdns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Service is the DNS-SD service type of the phone book.
+const Service = "_phonebook._tcp.local."
+
+// ttl is how long answers may be cached, 10 seconds in answers to one-shot
+// queries from ordinary sockets as RFC 6762 asks.
+const (
+	ttl        = 120
+	unicastTTL = 10
+)
+
+// cacheFlush is the top bit of the class of records only this host answers
+// for, telling others to replace what they cached.
+const cacheFlush = 1 << 15
+
+var group = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Instance is an advertised phone book server.
+type Instance struct {
+	// Name is the instance name, like "Phone book on office-pc".
+	Name string
+	// Host is the mDNS host name, like "office-pc.local.".
+	Host  string
+	Addrs []net.IP
+	Port  int
+	// Text holds the key=value pairs of the TXT record, such as
+	// "scheme=https".
+	Text []string
+}
+
+// Advertise answers queries for instance until ctx is done, when it says
+// goodbye so the instance is dropped from caches. Instance names may not
+// contain dots, which are replaced. Host and Addrs are filled in when empty.
+func Advertise(ctx context.Context, instance Instance) error {
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return err
+	}
+
+	instance.Name = strings.ReplaceAll(instance.Name, ".", "-")
+	if instance.Host == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+
+		hostname, _, _ = strings.Cut(hostname, ".")
+		instance.Host = hostname + ".local."
+	}
+
+	if len(instance.Addrs) == 0 {
+		instance.Addrs = localAddrs()
+	}
+
+	r, err := newRecords(instance)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		if goodbye, err := r.message(0, nil, r.all(), nil, 0); err == nil {
+			conn.WriteToUDP(goodbye, group)
+		}
+
+		conn.Close()
+	}()
+
+	// Announced twice, a second apart, as RFC 6762 section 8.3 asks.
+	if announcement, err := r.message(0, nil, r.all(), nil, ttl); err == nil {
+		conn.WriteToUDP(announcement, group)
+		time.AfterFunc(time.Second, func() { conn.WriteToUDP(announcement, group) })
+	}
+
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		var p dnsmessage.Parser
+		header, err := p.Start(buf[:n])
+		if err != nil || header.Response {
+			continue
+		}
+
+		questions, err := p.AllQuestions()
+		if err != nil {
+			continue
+		}
+
+		answers, additionals := r.answer(questions)
+		if len(answers) == 0 {
+			continue
+		}
+
+		// Queries from ordinary sockets, not port 5353, get the answer
+		// directly, with the question and its ID.
+		if from.Port != group.Port {
+			if reply, err := r.message(header.ID, questions, answers, additionals, unicastTTL); err == nil {
+				conn.WriteToUDP(reply, from)
+			}
+
+			continue
+		}
+
+		if reply, err := r.message(0, nil, answers, additionals, ttl); err == nil {
+			conn.WriteToUDP(reply, group)
+		}
+	}
+}
+
+// Browse asks the local network for phone book servers and returns those
+// that answered within wait, sorted by name.
+func Browse(ctx context.Context, wait time.Duration) ([]Instance, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	builder.StartQuestions()
+	builder.Question(dnsmessage.Question{Name: dnsmessage.MustNewName(Service), Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET})
+	query, err := builder.Finish()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.WriteToUDP(query, group); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(wait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	conn.SetReadDeadline(deadline)
+
+	found := newFound()
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		var timeout net.Error
+		if errors.As(err, &timeout) && timeout.Timeout() {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		found.add(buf[:n])
+	}
+
+	return found.instances(), nil
+}
+
+// records are the resource records advertising an instance.
+type records struct {
+	service, instance, host dnsmessage.Name
+	port                    uint16
+	text                    []string
+	addrs                   [][4]byte
+}
+
+func newRecords(instance Instance) (*records, error) {
+	r := &records{port: uint16(instance.Port), text: instance.Text}
+
+	var err error
+	if r.service, err = dnsmessage.NewName(Service); err != nil {
+		return nil, err
+	}
+
+	if r.instance, err = dnsmessage.NewName(instance.Name + "." + Service); err != nil {
+		return nil, err
+	}
+
+	if r.host, err = dnsmessage.NewName(instance.Host); err != nil {
+		return nil, err
+	}
+
+	for _, addr := range instance.Addrs {
+		if ip4 := addr.To4(); ip4 != nil {
+			r.addrs = append(r.addrs, [4]byte(ip4))
+		}
+	}
+
+	if len(r.text) == 0 {
+		// A TXT record is required, an empty one has a single empty
+		// string.
+		r.text = []string{""}
+	}
+
+	return r, nil
+}
+
+// kinds of records.
+const (
+	ptrRecord = iota
+	srvRecord
+	txtRecord
+	aRecord
+)
+
+func (r *records) all() []int {
+	return []int{ptrRecord, srvRecord, txtRecord, aRecord}
+}
+
+// answer returns the records answering questions, and those that go along
+// as additional records, the way RFC 6763 section 12 suggests.
+func (r *records) answer(questions []dnsmessage.Question) (answers, additionals []int) {
+	has := make(map[int]bool)
+	add := func(list *[]int, kind int) {
+		if !has[kind] {
+			has[kind] = true
+			*list = append(*list, kind)
+		}
+	}
+
+	for _, q := range questions {
+		name := strings.ToLower(q.Name.String())
+		any := q.Type == dnsmessage.TypeALL
+		switch {
+		case name == strings.ToLower(r.service.String()) && (q.Type == dnsmessage.TypePTR || any):
+			add(&answers, ptrRecord)
+		case name == strings.ToLower(r.instance.String()) && (q.Type == dnsmessage.TypeSRV || any):
+			add(&answers, srvRecord)
+		case name == strings.ToLower(r.instance.String()) && q.Type == dnsmessage.TypeTXT:
+			add(&answers, txtRecord)
+		case name == strings.ToLower(r.host.String()) && (q.Type == dnsmessage.TypeA || any):
+			add(&answers, aRecord)
+		}
+	}
+
+	for _, kind := range answers {
+		switch kind {
+		case ptrRecord:
+			add(&additionals, srvRecord)
+			add(&additionals, txtRecord)
+			add(&additionals, aRecord)
+		case srvRecord:
+			add(&additionals, txtRecord)
+			add(&additionals, aRecord)
+		}
+	}
+
+	return answers, additionals
+}
+
+// message builds a response with the records, every one cached for
+// seconds, 0 saying goodbye.
+func (r *records) message(id uint16, questions []dnsmessage.Question, answers, additionals []int, seconds uint32) ([]byte, error) {
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, Response: true, Authoritative: true})
+	builder.EnableCompression()
+
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+
+	for _, q := range questions {
+		if err := builder.Question(q); err != nil {
+			return nil, err
+		}
+	}
+
+	// Answers to ordinary sockets are not cache-flushing, RFC 6762 section
+	// 6.7 says.
+	unique := dnsmessage.ClassINET
+	if id == 0 {
+		unique |= cacheFlush
+	}
+
+	write := func(kind int) error {
+		switch kind {
+		case ptrRecord:
+			return builder.PTRResource(dnsmessage.ResourceHeader{Name: r.service, Class: dnsmessage.ClassINET, TTL: seconds}, dnsmessage.PTRResource{PTR: r.instance})
+		case srvRecord:
+			return builder.SRVResource(dnsmessage.ResourceHeader{Name: r.instance, Class: unique, TTL: seconds}, dnsmessage.SRVResource{Port: r.port, Target: r.host})
+		case txtRecord:
+			return builder.TXTResource(dnsmessage.ResourceHeader{Name: r.instance, Class: unique, TTL: seconds}, dnsmessage.TXTResource{TXT: r.text})
+		}
+
+		for _, addr := range r.addrs {
+			if err := builder.AResource(dnsmessage.ResourceHeader{Name: r.host, Class: unique, TTL: seconds}, dnsmessage.AResource{A: addr}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	for _, kind := range answers {
+		if err := write(kind); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := builder.StartAdditionals(); err != nil {
+		return nil, err
+	}
+
+	for _, kind := range additionals {
+		if err := write(kind); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Finish()
+}
+
+// found collects the records of the responses Browse gets.
+type found struct {
+	// names has the instance names as they were sent by their lowercase
+	// form, which the other records are looked up by.
+	names map[string]string
+	srv   map[string]dnsmessage.SRVResource
+	txt   map[string][]string
+	addrs map[string][]net.IP
+}
+
+func newFound() *found {
+	return &found{names: make(map[string]string), srv: make(map[string]dnsmessage.SRVResource), txt: make(map[string][]string), addrs: make(map[string][]net.IP)}
+}
+
+func (f *found) add(response []byte) {
+	var p dnsmessage.Parser
+	header, err := p.Start(response)
+	if err != nil || !header.Response {
+		return
+	}
+
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+
+	answers, err := p.AllAnswers()
+	if err != nil {
+		return
+	}
+
+	p.SkipAllAuthorities()
+	additionals, _ := p.AllAdditionals()
+
+	for _, resource := range append(answers, additionals...) {
+		name := strings.ToLower(resource.Header.Name.String())
+		switch body := resource.Body.(type) {
+		case *dnsmessage.PTRResource:
+			target := body.PTR.String()
+			if name == Service && resource.Header.TTL > 0 && strings.HasSuffix(strings.ToLower(target), "."+Service) {
+				f.names[strings.ToLower(target)] = target
+			}
+		case *dnsmessage.SRVResource:
+			f.srv[name] = *body
+		case *dnsmessage.TXTResource:
+			f.txt[name] = body.TXT
+		case *dnsmessage.AResource:
+			ip := net.IP(body.A[:])
+			for _, known := range f.addrs[name] {
+				if known.Equal(ip) {
+					ip = nil
+				}
+			}
+
+			if ip != nil {
+				f.addrs[name] = append(f.addrs[name], ip)
+			}
+		}
+	}
+}
+
+// instances returns the instances that have all of their records.
+func (f *found) instances() []Instance {
+	var instances []Instance
+	for name, original := range f.names {
+		srv, ok := f.srv[name]
+		if !ok {
+			continue
+		}
+
+		host := strings.ToLower(srv.Target.String())
+		instance := Instance{
+			Name:  original[:len(original)-len(Service)-1],
+			Host:  srv.Target.String(),
+			Addrs: f.addrs[host],
+			Port:  int(srv.Port),
+		}
+
+		for _, text := range f.txt[name] {
+			if text != "" {
+				instance.Text = append(instance.Text, text)
+			}
+		}
+
+		instances = append(instances, instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+
+	return instances
+}
+
+// localAddrs returns the IPv4 addresses of the host's interfaces that are up
+// and multicast capable, the loopback address when there are none.
+func localAddrs() []net.IP {
+	var addrs []net.IP
+
+	interfaces, _ := net.Interfaces()
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		ifaceAddrs, _ := iface.Addrs()
+		for _, addr := range ifaceAddrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				addrs = append(addrs, ipNet.IP.To4())
+			}
+		}
+	}
+
+	if len(addrs) == 0 {
+		addrs = append(addrs, net.IPv4(127, 0, 0, 1).To4())
+	}
+
+	return addrs
+}
@@ -1,21 +1,289 @@
 package model
 
+import "time"
+
 type PhoeBookError struct {
 	Message    string
 	StatusCode int32
 }
 
 type Entry struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Surname     string `json:"surname"`
-	PhoneNumber string `json:"phone_number"`
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Surname string `json:"surname"`
+	// Nickname is what people call the contact, e.g. "Mo" for "Morteza".
+	Nickname    string      `json:"nickname,omitempty"`
+	PhoneNumber PhoneNumber `json:"phone_number"`
+	Country     string      `json:"country"`
+	Company     string      `json:"company,omitempty"`
+	Title       string      `json:"title,omitempty"`
+	// Birthday and Anniversary are days like "1990-05-17", or "05-17" when
+	// the year isn't known. The daemon reminds of them RemindDays before,
+	// or as many days as the config says when RemindDays is 0.
+	Birthday    string `json:"birthday,omitempty"`
+	Anniversary string `json:"anniversary,omitempty"`
+	RemindDays  int    `json:"remind_days,omitempty"`
+	// Photo tells where the backend keeps the contact's photo: a file path,
+	// or "stored" for backends that keep the image data themselves. It is
+	// empty when the contact has no photo.
+	Photo string `json:"photo,omitempty"`
+	// Version counts the changes made to the entry, starting at 1. An update
+	// or delete asking for a Version fails if the entry has changed since.
+	Version int64 `json:"version"`
+	// UpdatedAt is when the entry was inserted or last updated, nil for
+	// entries stored before the backend kept track.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// ArchivedAt is when the entry was archived, nil while it is not.
+	// Archived entries are kept but left out of lists and searches unless
+	// asked for.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	// Consent is whether the contact agreed to be contacted, ConsentYes or
+	// ConsentNo, or empty when they were never asked; ConsentDate is the
+	// day they answered, like "2024-05-17". Calls and reminders are refused
+	// for contacts who refused, or weren't asked when consent is required,
+	// unless overridden; see MayContact.
+	Consent     string `json:"consent,omitempty"`
+	ConsentDate string `json:"consent_date,omitempty"`
+	// Channel is how the contact prefers to be contacted, one of Channels,
+	// and AccessNotes anything else to know before contacting them, like
+	// "mornings only" or "through their assistant".
+	Channel     string `json:"preferred_channel,omitempty"`
+	AccessNotes string `json:"access_notes,omitempty"`
+}
+
+// The answers an Entry's Consent records.
+const (
+	ConsentYes = "yes"
+	ConsentNo  = "no"
+)
+
+// Channels are the ways of contacting someone an Entry's Channel can name.
+var Channels = []string{"call", "sms", "email", "post"}
+
+// MayContact reports whether the contact may be contacted: never when they
+// refused, and only when they agreed if consent is required.
+func (e Entry) MayContact(consentRequired bool) bool {
+	return e.Consent == ConsentYes || e.Consent == "" && !consentRequired
 }
 
 type ListResponse struct {
 	Entries []Entry `json:"entries"`
+	// NextCursor fetches the next page of a paged GET /entries, it is empty
+	// on the last one.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SearchHit is an entry found by a search of several books at once, with
+// the book it is in and its score, higher for better matches, and Fields the
+// fields the term matched.
+type SearchHit struct {
+	Book   string   `json:"book"`
+	Score  int      `json:"score"`
+	Fields []string `json:"fields,omitempty"`
+	Entry  Entry    `json:"entry"`
+}
+
+// SearchResponse answers GET /search of a server hosting several books, the
+// best hits first.
+type SearchResponse struct {
+	Hits []SearchHit `json:"hits"`
 }
 
 type InsertResponse struct {
 	ID int64 `json:"id"`
 }
+
+// BatchResponse answers POST /entries:batchCreate and :batchDelete with one
+// result per item of the request, in the same order. Nothing was changed
+// unless Committed is true.
+type BatchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// BatchResult tells how one item of a batch fared: Status is the HTTP status
+// it would have got on its own, and ID the ID an inserted entry was given.
+type BatchResult struct {
+	ID     int64  `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BlockedResponse struct {
+	Number  PhoneNumber `json:"number"`
+	Blocked bool        `json:"blocked"`
+}
+
+type SpamReport struct {
+	Number     PhoneNumber `json:"number"`
+	Reason     string      `json:"reason"`
+	Reporter   string      `json:"reporter"`
+	ReportedAt time.Time   `json:"reported_at"`
+}
+
+// Call is a call with a contact, recorded in the call log.
+type Call struct {
+	EntryID  int64         `json:"entry_id"`
+	At       time.Time     `json:"at"`
+	Duration time.Duration `json:"duration"`
+	Note     string        `json:"note,omitempty"`
+}
+
+// Link relates two entries: To is From's Type, e.g. their spouse or their
+// assistant.
+type Link struct {
+	From int64  `json:"from"`
+	To   int64  `json:"to"`
+	Type string `json:"type"`
+}
+
+// The relationships a Link's Type can be. Only LinkAssistant has a
+// direction, the others read the same both ways.
+const (
+	LinkSpouse      = "spouse"
+	LinkAssistant   = "assistant"
+	LinkHousehold   = "household"
+	LinkSameCompany = "same-company"
+)
+
+// LinkTypes are the relationships a Link's Type can be.
+var LinkTypes = []string{LinkSpouse, LinkAssistant, LinkHousehold, LinkSameCompany}
+
+// Relation is an entry related to the one shown: Entry is its Type, or the
+// entry whose assistant it is when AssistantOf is set. Automatic relations
+// come from the entries' fields, like their company, rather than a Link.
+type Relation struct {
+	Entry       Entry
+	Type        string
+	AssistantOf bool
+	Automatic   bool
+}
+
+type LookupResponse struct {
+	Number      PhoneNumber `json:"number"`
+	Entry       *Entry      `json:"entry,omitempty"`
+	Blocked     bool        `json:"blocked"`
+	SpamScore   int         `json:"spam_score"`
+	SpamReports int         `json:"spam_reports"`
+}
+
+// QueryError is returned by GET /entries when the filter expression in q
+// cannot be parsed. Position is the byte offset the parser stopped at.
+type QueryError struct {
+	Message  string `json:"message"`
+	Position int    `json:"position"`
+}
+
+// PersonalData is everything the phone book keeps about one person, as
+// written by "privacy export". Photo is base64 encoded in JSON. Events, the
+// history of the entry, are only kept by some backends, like the event log.
+type PersonalData struct {
+	Entry       Entry        `json:"entry"`
+	Photo       []byte       `json:"photo,omitempty"`
+	Blocked     bool         `json:"blocked"`
+	SpamReports []SpamReport `json:"spam_reports"`
+	Calls       []Call       `json:"calls"`
+	Links       []Link       `json:"links"`
+	Events      []Event      `json:"events,omitempty"`
+}
+
+// JobStatus describes a scheduled job of the server for GET /jobs. LastRun
+// and LastError are empty until the job has run.
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	Task      string     `json:"task"`
+	Running   bool       `json:"running"`
+	NextRun   time.Time  `json:"next_run"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// The types of an Event.
+const (
+	EventInsert  = "insert"
+	EventUpdate  = "update"
+	EventDelete  = "delete"
+	EventBlock   = "block"
+	EventUnblock = "unblock"
+	// EventErased is what an event with a person's data was redacted to
+	// when they were erased. It changes nothing.
+	EventErased = "erased"
+)
+
+// Event is a change to a phone book that keeps its history, see
+// storage.History. Seq numbers the events from 1 in the order they happened.
+// Entry is the entry as the change left it, and only ID is set for deletes.
+// Reverts is the event the book was taken back to by an undo that recorded
+// this one.
+type Event struct {
+	Seq     int64     `json:"seq,omitempty"`
+	At      time.Time `json:"at"`
+	Type    string    `json:"type"`
+	ID      int64     `json:"id,omitempty"`
+	Entry   *Entry    `json:"entry,omitempty"`
+	Number  string    `json:"number,omitempty"`
+	Reverts *int64    `json:"reverts,omitempty"`
+}
+
+// The statuses of a HealthCheck.
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthFailing  = "failing"
+)
+
+// HealthCheck is the outcome of checking one dependency of the server, like
+// its database or data files, for GET /readyz. A failing check makes the
+// server not ready, a degraded one is only reported.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Book   string `json:"book,omitempty"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Write is set for checks that only matter to changing the book, which
+	// read-only books skip.
+	Write bool `json:"-"`
+}
+
+// Readiness is the body of GET /readyz: "ready" or "not ready", and the
+// checks that decided it.
+type Readiness struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// RuntimeStats describes how a running process is doing, for `debug stats`.
+type RuntimeStats struct {
+	Goroutines int    `json:"goroutines"`
+	HeapAlloc  uint64 `json:"heap_alloc_bytes"`
+	HeapSys    uint64 `json:"heap_sys_bytes"`
+	Sys        uint64 `json:"sys_bytes"`
+	NumGC      uint32 `json:"gc_cycles"`
+}
+
+// IndexStats describes a search index. Bytes is its size on disk, 0 for
+// indexes kept in memory.
+type IndexStats struct {
+	Name    string `json:"name"`
+	Entries int    `json:"entries"`
+	Tokens  int    `json:"tokens"`
+	Bytes   int64  `json:"bytes"`
+	// Stale is set for indexes that no longer describe their data file and
+	// are rebuilt on the next search.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// Inspection is what a backend keeps besides the entries: its search
+// indexes and, for a daemon, how the daemon process is doing.
+type Inspection struct {
+	Indexes []IndexStats  `json:"indexes"`
+	Daemon  *RuntimeStats `json:"daemon,omitempty"`
+}
+
+// DebugStats is the report of `debug stats`.
+type DebugStats struct {
+	Entries int          `json:"entries"`
+	Process RuntimeStats `json:"process"`
+	Inspection
+}
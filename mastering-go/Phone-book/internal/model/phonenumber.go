@@ -0,0 +1,87 @@
+package model
+
+import "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/phone"
+
+// PhoneNumber is a phone number as the phone book keeps it, in E.164 form
+// like "+989121234567" once it went through ParsePhoneNumber or Canonical.
+// Numbers read from older data files may still be written some other way,
+// so its methods accept any way of writing a number.
+type PhoneNumber string
+
+// ParsePhoneNumber converts a number as a person would type it into E.164.
+// Numbers without an international prefix are taken to belong to region,
+// or to phone.DefaultRegion when region is empty.
+func ParsePhoneNumber(number, region string) (PhoneNumber, error) {
+	e164, err := phone.Normalize(number, region)
+	if err != nil {
+		return "", err
+	}
+
+	return PhoneNumber(e164), nil
+}
+
+// Valid reports whether n can be told apart as a phone number.
+func (n PhoneNumber) Valid() bool {
+	return n.ValidIn("")
+}
+
+// ValidIn is Valid taking numbers without an international prefix to belong
+// to region, or to phone.DefaultRegion when region is empty. The other
+// methods ending in In take a region the same way.
+func (n PhoneNumber) ValidIn(region string) bool {
+	_, err := phone.Normalize(string(n), region)
+	return err == nil
+}
+
+// Canonical returns n in E.164 form, or unchanged when it is not Valid.
+func (n PhoneNumber) Canonical() PhoneNumber {
+	return n.CanonicalIn("")
+}
+
+// CanonicalIn is Canonical in region.
+func (n PhoneNumber) CanonicalIn(region string) PhoneNumber {
+	return PhoneNumber(phone.CanonicalIn(string(n), region))
+}
+
+// Equal reports whether n and other are the same number, however each of
+// them is written.
+func (n PhoneNumber) Equal(other PhoneNumber) bool {
+	return n.EqualIn(other, "")
+}
+
+// EqualIn is Equal in region.
+func (n PhoneNumber) EqualIn(other PhoneNumber, region string) bool {
+	return phone.EqualIn(string(n), string(other), region)
+}
+
+// Country returns the ISO code of the country n belongs to, or "" when it
+// cannot be told.
+func (n PhoneNumber) Country() string {
+	return n.CountryIn("")
+}
+
+// CountryIn is Country in region.
+func (n PhoneNumber) CountryIn(region string) string {
+	return phone.RegionIn(string(n), region)
+}
+
+// National returns the part of n after the country code, or "" when it is
+// not Valid.
+func (n PhoneNumber) National() string {
+	return n.NationalIn("")
+}
+
+// NationalIn is National in region.
+func (n PhoneNumber) NationalIn(region string) string {
+	return phone.NationalIn(string(n), region)
+}
+
+// Format renders n for display the way its country usually writes it, like
+// "+98 912 123 4567".
+func (n PhoneNumber) Format() string {
+	return phone.Format(string(n))
+}
+
+func (n PhoneNumber) String() string {
+	return string(n)
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ValidationError is why an Entry's fields are not valid. Its message is
+// Format with Args, so callers can translate Format before filling it in.
+type ValidationError struct {
+	Format string
+	Args   []any
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf(e.Format, e.Args...)
+}
+
+// ParseDate parses a birthday or anniversary, "1990-05-17" or "05-17". year
+// is 0 when it isn't given.
+func ParseDate(s string) (year int, month time.Month, day int, err error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t.Year(), t.Month(), t.Day(), nil
+	}
+
+	// 2000 is a leap year, so "02-29" parses.
+	t, err := time.Parse(time.DateOnly, "2000-"+s)
+	if err != nil {
+		return 0, 0, 0, &ValidationError{Format: "invalid date %q, use e.g. 1990-05-17 or 05-17", Args: []any{s}}
+	}
+
+	return 0, t.Month(), t.Day(), nil
+}
+
+// Validate checks the fields of e that only take some values: that its
+// birthday and anniversary parse and its reminder days aren't negative, and
+// that its consent is a known answer, with a day given only with one, and
+// its channel one of Channels. It returns a *ValidationError.
+func (e Entry) Validate() error {
+	for _, date := range []string{e.Birthday, e.Anniversary} {
+		if date == "" {
+			continue
+		}
+
+		if _, _, _, err := ParseDate(date); err != nil {
+			return err
+		}
+	}
+
+	if e.RemindDays < 0 {
+		return &ValidationError{Format: "remind_days cannot be negative"}
+	}
+
+	if e.Consent != "" && e.Consent != ConsentYes && e.Consent != ConsentNo {
+		return &ValidationError{Format: "consent must be yes or no"}
+	}
+
+	if e.ConsentDate != "" {
+		if e.Consent == "" {
+			return &ValidationError{Format: "consent_date needs a consent"}
+		}
+
+		if _, err := time.Parse(time.DateOnly, e.ConsentDate); err != nil {
+			return &ValidationError{Format: "consent_date must be a day like 2024-05-17"}
+		}
+	}
+
+	if e.Channel != "" && !slices.Contains(Channels, e.Channel) {
+		return &ValidationError{Format: "preferred_channel must be one of %s", Args: []any{strings.Join(Channels, ", ")}}
+	}
+
+	return nil
+}
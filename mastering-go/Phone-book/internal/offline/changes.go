@@ -0,0 +1,388 @@
+package offline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// The operations a Change can be.
+const (
+	opInsert        = "insert"
+	opUpdate        = "update"
+	opDelete        = "delete"
+	opBlock         = "block"
+	opUnblock       = "unblock"
+	opReportSpam    = "report_spam"
+	opEraseSpam     = "erase_spam_reports"
+	opLogCall       = "log_call"
+	opEraseCalls    = "erase_calls"
+	opSetPhoto      = "set_photo"
+	opLink          = "link"
+	opUnlink        = "unlink"
+	opDeleteVersion = "delete_version"
+)
+
+// Change is a queued change, one line of the queue file.
+type Change struct {
+	Op       string    `json:"op"`
+	QueuedAt time.Time `json:"queued_at"`
+	// ID is the entry the change is about, Version the version it must
+	// still have when not 0.
+	ID      int64               `json:"id,omitempty"`
+	Version int64               `json:"version,omitempty"`
+	Entry   *storage.Entry      `json:"entry,omitempty"`
+	Number  string              `json:"number,omitempty"`
+	Report  *storage.SpamReport `json:"report,omitempty"`
+	Call    *storage.Call       `json:"call,omitempty"`
+	Link    *storage.Link       `json:"link,omitempty"`
+	Photo   []byte              `json:"photo,omitempty"`
+	// Error is why the backend refused the change, for rejected ones.
+	Error string `json:"error,omitempty"`
+}
+
+// String describes c for queue status.
+func (c Change) String() string {
+	switch c.Op {
+	case opInsert:
+		return fmt.Sprintf("insert %s %s, %s", c.Entry.Name, c.Entry.Surname, c.Entry.PhoneNumber)
+	case opUpdate:
+		return fmt.Sprintf("update entry %d to %s %s, %s", c.Entry.ID, c.Entry.Name, c.Entry.Surname, c.Entry.PhoneNumber)
+	case opDelete, opDeleteVersion:
+		return fmt.Sprintf("delete entry %d", c.ID)
+	case opBlock:
+		return "block " + c.Number
+	case opUnblock:
+		return "unblock " + c.Number
+	case opReportSpam:
+		return "report " + string(c.Report.Number) + " as spam"
+	case opEraseSpam:
+		return "erase the spam reports about " + c.Number
+	case opLogCall:
+		return fmt.Sprintf("log a call with entry %d", c.Call.EntryID)
+	case opEraseCalls:
+		return fmt.Sprintf("erase the calls with entry %d", c.ID)
+	case opSetPhoto:
+		return fmt.Sprintf("set the photo of entry %d", c.ID)
+	case opLink:
+		return fmt.Sprintf("link entry %d to entry %d as %s", c.Link.From, c.Link.To, c.Link.Type)
+	case opUnlink:
+		return fmt.Sprintf("unlink entries %d and %d", c.Link.From, c.Link.To)
+	}
+
+	return c.Op
+}
+
+// apply makes c on store, returning the ID of the entry it inserted.
+func (c Change) apply(ctx context.Context, store storage.Storage) (int64, *storage.Error) {
+	switch c.Op {
+	case opInsert:
+		return store.Insert(ctx, c.Entry)
+	case opDelete:
+		return 0, store.Delete(ctx, c.ID)
+	case opUpdate:
+		if updater, ok := store.(storage.Updater); ok {
+			return 0, updater.Update(ctx, c.Entry)
+		}
+
+		return 0, storage.Unsupported("updates")
+	case opDeleteVersion:
+		if deleter, ok := store.(storage.VersionedDeleter); ok {
+			return 0, deleter.DeleteVersion(ctx, c.ID, c.Version)
+		}
+
+		return 0, storage.Unsupported("versioned deletes")
+	case opBlock, opUnblock:
+		blocklist, ok := store.(storage.Blocklist)
+		if !ok {
+			return 0, storage.Unsupported("blocking numbers")
+		}
+
+		if c.Op == opBlock {
+			return 0, blocklist.Block(ctx, c.Number)
+		}
+
+		return 0, blocklist.Unblock(ctx, c.Number)
+	case opReportSpam:
+		if spamReports, ok := store.(storage.SpamReports); ok {
+			return 0, spamReports.ReportSpam(ctx, *c.Report)
+		}
+
+		return 0, storage.Unsupported("spam reports")
+	case opEraseSpam:
+		if eraser, ok := store.(storage.SpamEraser); ok {
+			return 0, eraser.EraseSpamReports(ctx, c.Number)
+		}
+
+		return 0, storage.Unsupported("erasing spam reports")
+	case opLogCall, opEraseCalls:
+		callLog, ok := store.(storage.CallLog)
+		if !ok {
+			return 0, storage.Unsupported("call logs")
+		}
+
+		if c.Op == opLogCall {
+			return 0, callLog.LogCall(ctx, *c.Call)
+		}
+
+		return 0, callLog.EraseCalls(ctx, c.ID)
+	case opSetPhoto:
+		if photos, ok := store.(storage.Photos); ok {
+			return 0, photos.SetPhoto(ctx, c.ID, c.Photo)
+		}
+
+		return 0, storage.Unsupported("photos")
+	case opLink, opUnlink:
+		relationships, ok := store.(storage.Relationships)
+		if !ok {
+			return 0, storage.Unsupported("links")
+		}
+
+		if c.Op == opLink {
+			return 0, relationships.Link(ctx, *c.Link)
+		}
+
+		return 0, relationships.Unlink(ctx, c.Link.From, c.Link.To)
+	}
+
+	return 0, &storage.Error{Message: fmt.Sprintf("unknown queued change %q", c.Op), StatusCode: http.StatusBadRequest}
+}
+
+func (s *Store) List(ctx context.Context) ([]storage.Entry, *storage.Error) {
+	var entries []storage.Entry
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		entries, appErr = store.List(ctx)
+		return appErr
+	})
+
+	return entries, appErr
+}
+
+func (s *Store) Insert(ctx context.Context, entry *storage.Entry) (int64, *storage.Error) {
+	return s.change(ctx, Change{Op: opInsert, Entry: entry})
+}
+
+func (s *Store) Delete(ctx context.Context, id int64) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opDelete, ID: id})
+	return appErr
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.store == nil {
+		return nil
+	}
+
+	return s.store.Close()
+}
+
+func (s *Store) Update(ctx context.Context, entry *storage.Entry) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opUpdate, Entry: entry})
+	return appErr
+}
+
+func (s *Store) DeleteVersion(ctx context.Context, id, version int64) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opDeleteVersion, ID: id, Version: version})
+	return appErr
+}
+
+func (s *Store) Block(ctx context.Context, number string) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opBlock, Number: number})
+	return appErr
+}
+
+func (s *Store) Unblock(ctx context.Context, number string) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opUnblock, Number: number})
+	return appErr
+}
+
+func (s *Store) Blocked(ctx context.Context) ([]string, *storage.Error) {
+	var numbers []string
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		if blocklist, ok := store.(storage.Blocklist); ok {
+			numbers, appErr = blocklist.Blocked(ctx)
+		}
+
+		return appErr
+	})
+
+	return numbers, appErr
+}
+
+func (s *Store) ReportSpam(ctx context.Context, report storage.SpamReport) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opReportSpam, Report: &report})
+	return appErr
+}
+
+func (s *Store) SpamReports(ctx context.Context, number string) ([]storage.SpamReport, *storage.Error) {
+	var reports []storage.SpamReport
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		if spamReports, ok := store.(storage.SpamReports); ok {
+			reports, appErr = spamReports.SpamReports(ctx, number)
+		}
+
+		return appErr
+	})
+
+	return reports, appErr
+}
+
+func (s *Store) EraseSpamReports(ctx context.Context, number string) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opEraseSpam, Number: number})
+	return appErr
+}
+
+func (s *Store) LogCall(ctx context.Context, call storage.Call) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opLogCall, Call: &call})
+	return appErr
+}
+
+func (s *Store) Calls(ctx context.Context, id int64) ([]storage.Call, *storage.Error) {
+	var calls []storage.Call
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		if callLog, ok := store.(storage.CallLog); ok {
+			calls, appErr = callLog.Calls(ctx, id)
+		}
+
+		return appErr
+	})
+
+	return calls, appErr
+}
+
+func (s *Store) EraseCalls(ctx context.Context, id int64) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opEraseCalls, ID: id})
+	return appErr
+}
+
+func (s *Store) Link(ctx context.Context, link storage.Link) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opLink, Link: &link})
+	return appErr
+}
+
+func (s *Store) Unlink(ctx context.Context, a, b int64) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opUnlink, Link: &storage.Link{From: a, To: b}})
+	return appErr
+}
+
+func (s *Store) Links(ctx context.Context, id int64) ([]storage.Link, *storage.Error) {
+	var links []storage.Link
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		if relationships, ok := store.(storage.Relationships); ok {
+			links, appErr = relationships.Links(ctx, id)
+		}
+
+		return appErr
+	})
+
+	return links, appErr
+}
+
+func (s *Store) SetPhoto(ctx context.Context, id int64, photo []byte) *storage.Error {
+	_, appErr := s.change(ctx, Change{Op: opSetPhoto, ID: id, Photo: photo})
+	return appErr
+}
+
+func (s *Store) Photo(ctx context.Context, id int64) ([]byte, *storage.Error) {
+	var photo []byte
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		photos, ok := store.(storage.Photos)
+		if !ok {
+			return storage.Unsupported("photos")
+		}
+
+		photo, appErr = photos.Photo(ctx, id)
+		return appErr
+	})
+
+	return photo, appErr
+}
+
+func (s *Store) Candidates(ctx context.Context, term string) ([]storage.Entry, bool, *storage.Error) {
+	var entries []storage.Entry
+	var found bool
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		if index, ok := store.(storage.Index); ok {
+			entries, found, appErr = index.Candidates(ctx, term)
+		}
+
+		return appErr
+	})
+
+	return entries, found, appErr
+}
+
+// Begin starts a transaction of the backend, which fails while it cannot be
+// reached: a batch of changes is not queued.
+func (s *Store) Begin(ctx context.Context) (storage.Tx, *storage.Error) {
+	var tx storage.Tx
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		transactional, ok := store.(storage.Transactional)
+		if !ok {
+			return storage.Unsupported("transactions")
+		}
+
+		tx, appErr = transactional.Begin(ctx)
+		return appErr
+	})
+
+	return tx, appErr
+}
+
+func (s *Store) Fingerprint(ctx context.Context) (string, *storage.Error) {
+	var fingerprint string
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		fingerprinter, ok := store.(storage.Fingerprinter)
+		if !ok {
+			return storage.Unsupported("fingerprints")
+		}
+
+		fingerprint, appErr = fingerprinter.Fingerprint(ctx)
+		return appErr
+	})
+
+	return fingerprint, appErr
+}
+
+// Health adds the queue to the checks of the backend: degraded while changes
+// wait in it.
+func (s *Store) Health(ctx context.Context) []storage.HealthCheck {
+	var checks []storage.HealthCheck
+	appErr := s.read(ctx, func(store storage.Storage) *storage.Error {
+		checks = storage.Health(ctx, store)
+		return nil
+	})
+
+	if appErr != nil {
+		checks = append(checks, storage.HealthCheck{Name: "storage", Status: model.HealthFailing, Detail: appErr.Message})
+	}
+
+	queued, _, err := s.Pending()
+	switch {
+	case err != nil:
+		checks = append(checks, storage.HealthCheck{Name: "offline queue", Status: model.HealthFailing, Detail: err.Error(), Write: true})
+	case len(queued) > 0:
+		checks = append(checks, storage.HealthCheck{Name: "offline queue", Status: model.HealthDegraded, Detail: fmt.Sprintf("%d changes wait for the backend", len(queued)), Write: true})
+	}
+
+	return checks
+}
+
+func (s *Store) Inspect(ctx context.Context) (storage.Inspection, *storage.Error) {
+	var inspection storage.Inspection
+	appErr := s.read(ctx, func(store storage.Storage) (appErr *storage.Error) {
+		if inspector, ok := store.(storage.Inspector); ok {
+			inspection, appErr = inspector.Inspect(ctx)
+		}
+
+		return appErr
+	})
+
+	return inspection, appErr
+}
@@ -0,0 +1,352 @@
+// Package offline keeps a phone book on a remote backend, like postgres,
+// usable while the backend cannot be reached: the changes made meanwhile are
+// queued in a local file and replayed, in order, once it can be reached
+// again. Reads still need the backend.
+package offline
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// retryInterval is how long a Store that could not connect waits before
+// trying again, so a server doesn't try on every request.
+const retryInterval = 5 * time.Second
+
+// QueuedError is what a change made while the backend cannot be reached
+// returns: it is not an error in the usual sense, the change will be made.
+func QueuedError() *storage.Error {
+	return &storage.Error{Message: "the phone book cannot be reached, the change is queued and will be made once it can", StatusCode: http.StatusAccepted}
+}
+
+// UnreachableError is what reads return while the backend cannot be reached.
+func UnreachableError() *storage.Error {
+	return &storage.Error{Message: "the phone book cannot be reached, changes are queued but reads have to wait for it", StatusCode: http.StatusServiceUnavailable}
+}
+
+// Store is a storage.Storage queueing the changes its backend cannot take.
+// Like storage.ReadOnly it has all the optional features: the ones the
+// backend lacks read as empty and fail with storage.Unsupported when they
+// would change something. Transactions are not queued.
+//
+// Changes already queued are replayed before any other operation once the
+// backend answers again, so later changes and reads come after them.
+type Store struct {
+	open        func() (storage.Storage, error)
+	unreachable func(error) bool
+	path        string
+
+	mu          sync.Mutex
+	store       storage.Storage
+	lastAttempt time.Time
+}
+
+// Open opens a backend with open, queueing changes in the file at path while
+// it cannot be reached. unreachable tells the errors of open that mean the
+// backend is down from those that mean it is misconfigured, which Open
+// returns.
+func Open(open func() (storage.Storage, error), unreachable func(error) bool, path string) (*Store, error) {
+	s := &Store{open: open, unreachable: unreachable, path: path, lastAttempt: time.Now()}
+
+	store, err := open()
+	if err != nil && !unreachable(err) {
+		return nil, err
+	}
+
+	s.store = store
+
+	return s, nil
+}
+
+// Path returns the file the changes are queued in. Changes the backend
+// refused when they were replayed are kept next to it, in Path() +
+// ".rejected".
+func (s *Store) Path() string {
+	return s.path
+}
+
+// outage tells whether appErr means the backend could not be reached, rather
+// than refusing the operation or ctx giving up on it.
+func outage(ctx context.Context, appErr *storage.Error) bool {
+	return appErr != nil && appErr.StatusCode == http.StatusServiceUnavailable && ctx.Err() == nil
+}
+
+// backend returns the backend, connecting to it if the last attempt was long
+// enough ago, and replays the queued changes. It fails with UnreachableError,
+// or the error of the replay, while it cannot be reached. The caller holds
+// s.mu.
+func (s *Store) backend(ctx context.Context) (storage.Storage, *storage.Error) {
+	if s.store == nil {
+		if time.Since(s.lastAttempt) < retryInterval {
+			return nil, UnreachableError()
+		}
+
+		s.lastAttempt = time.Now()
+		store, err := s.open()
+		if err != nil {
+			return nil, UnreachableError()
+		}
+
+		s.store = store
+	}
+
+	// Nothing else is done before the queue is replayed, so changes are
+	// made in the order they were asked for.
+	if _, _, appErr := s.flush(ctx, s.store); appErr != nil {
+		return nil, appErr
+	}
+
+	return s.store, nil
+}
+
+// read runs fn against the backend.
+func (s *Store) read(ctx context.Context, fn func(storage.Storage) *storage.Error) *storage.Error {
+	s.mu.Lock()
+	store, appErr := s.backend(ctx)
+	s.mu.Unlock()
+
+	if appErr != nil {
+		return appErr
+	}
+
+	return fn(store)
+}
+
+// change makes c, or queues it when the backend cannot be reached, and
+// returns the ID of the entry it inserted.
+func (s *Store) change(ctx context.Context, c Change) (int64, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	store, appErr := s.backend(ctx)
+	if appErr == nil {
+		var id int64
+		id, appErr = c.apply(ctx, store)
+		if !outage(ctx, appErr) {
+			return id, appErr
+		}
+	} else if !outage(ctx, appErr) {
+		return 0, appErr
+	}
+
+	c.QueuedAt = time.Now().UTC().Truncate(time.Second)
+	if err := appendChanges(s.path, []Change{c}); err != nil {
+		return 0, &storage.Error{Message: fmt.Sprintf("the phone book cannot be reached, and the change cannot be queued: %v", err), StatusCode: http.StatusServiceUnavailable}
+	}
+
+	return 0, QueuedError()
+}
+
+// Pending returns the changes waiting to be replayed, in order, and those
+// the backend refused.
+func (s *Store) Pending() (queued, rejected []Change, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// An interrupted replay left its changes in the flushing file, they are
+	// replayed before the queue.
+	for _, path := range []string{s.path + ".flushing", s.path} {
+		changes, err := readChanges(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		queued = append(queued, changes...)
+	}
+
+	rejected, err = readChanges(s.path + ".rejected")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return queued, rejected, nil
+}
+
+// Flush replays the queued changes now. It returns how many the backend
+// took and how many it refused, which are moved to Path() + ".rejected", or
+// UnreachableError when it still cannot be reached.
+func (s *Store) Flush(ctx context.Context) (applied, rejected int, appErr *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Asked for, connecting is tried right away.
+	if s.store == nil {
+		s.lastAttempt = time.Now()
+		store, err := s.open()
+		if err != nil {
+			return 0, 0, UnreachableError()
+		}
+
+		s.store = store
+	}
+
+	return s.flush(ctx, s.store)
+}
+
+// flush replays the queued changes against store. They are moved to the
+// flushing file first, so changes queued meanwhile by other processes are
+// not lost, and the file is rewritten after every change, so a crash doesn't
+// make one twice. When the backend goes away again, the changes left are put
+// back in front of the queue. The caller holds s.mu.
+func (s *Store) flush(ctx context.Context, store storage.Storage) (applied, rejected int, appErr *storage.Error) {
+	flushing := s.path + ".flushing"
+	for {
+		if _, err := os.Stat(flushing); os.IsNotExist(err) {
+			if _, err := os.Stat(s.path); os.IsNotExist(err) {
+				return applied, rejected, nil
+			}
+
+			if err := os.Rename(s.path, flushing); err != nil {
+				return applied, rejected, queueError(err)
+			}
+		}
+
+		changes, err := readChanges(flushing)
+		if err != nil {
+			return applied, rejected, queueError(err)
+		}
+
+		for len(changes) > 0 {
+			_, appErr := changes[0].apply(ctx, store)
+			if outage(ctx, appErr) || ctx.Err() != nil {
+				if err := requeue(s.path, changes); err != nil {
+					return applied, rejected, queueError(err)
+				}
+
+				os.Remove(flushing)
+				if appErr == nil {
+					appErr = storage.ContextError(ctx)
+				}
+
+				return applied, rejected, appErr
+			}
+
+			if appErr != nil {
+				changes[0].Error = appErr.Message
+				if err := appendChanges(s.path+".rejected", changes[:1]); err != nil {
+					return applied, rejected, queueError(err)
+				}
+
+				rejected++
+			} else {
+				applied++
+			}
+
+			changes = changes[1:]
+			if err := writeChanges(flushing, changes); err != nil {
+				return applied, rejected, queueError(err)
+			}
+		}
+
+		os.Remove(flushing)
+	}
+}
+
+func queueError(err error) *storage.Error {
+	return &storage.Error{Message: fmt.Sprintf("cannot replay the queued changes: %v", err), StatusCode: http.StatusInternalServerError}
+}
+
+// readChanges reads the changes in the file at path, none when there is no
+// such file.
+func readChanges(path string) ([]Change, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var c Change
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+
+		changes = append(changes, c)
+	}
+
+	return changes, scanner.Err()
+}
+
+func encodeChanges(changes []Change) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, c := range changes {
+		line, err := json.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(append(line, '\n'))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// appendChanges adds changes to the file at path, one JSON object per line.
+func appendChanges(path string, changes []Change) error {
+	data, err := encodeChanges(changes)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// writeChanges replaces the file at path with changes, through a temporary
+// file so a crash leaves the old changes or the new ones.
+func writeChanges(path string, changes []Change) error {
+	data, err := encodeChanges(changes)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// requeue puts changes back in front of those queued at path meanwhile.
+func requeue(path string, changes []Change) error {
+	queued, err := readChanges(path)
+	if err != nil {
+		return err
+	}
+
+	return writeChanges(path, append(changes, queued...))
+}
@@ -0,0 +1,206 @@
+package offline
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage/memory"
+)
+
+// flaky is a backend that cannot be reached once up operations have been
+// made, never if up is negative.
+type flaky struct {
+	*memory.Storage
+	up int
+}
+
+func (f *flaky) reachable() *storage.Error {
+	if f.up == 0 {
+		return &storage.Error{Message: "the database is down", StatusCode: http.StatusServiceUnavailable}
+	}
+
+	if f.up > 0 {
+		f.up--
+	}
+
+	return nil
+}
+
+func (f *flaky) List(ctx context.Context) ([]storage.Entry, *storage.Error) {
+	if appErr := f.reachable(); appErr != nil {
+		return nil, appErr
+	}
+
+	return f.Storage.List(ctx)
+}
+
+func (f *flaky) Insert(ctx context.Context, entry *storage.Entry) (int64, *storage.Error) {
+	if appErr := f.reachable(); appErr != nil {
+		return 0, appErr
+	}
+
+	return f.Storage.Insert(ctx, entry)
+}
+
+func (f *flaky) Delete(ctx context.Context, id int64) *storage.Error {
+	if appErr := f.reachable(); appErr != nil {
+		return appErr
+	}
+
+	return f.Storage.Delete(ctx, id)
+}
+
+// openFlaky opens a Store on backend, queueing in a new directory.
+func openFlaky(t *testing.T, backend *flaky) *Store {
+	t.Helper()
+
+	s, err := Open(func() (storage.Storage, error) { return backend, nil }, func(error) bool { return false }, filepath.Join(t.TempDir(), "queue.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func insert(t *testing.T, s *Store, name string) *storage.Error {
+	t.Helper()
+
+	_, appErr := s.Insert(context.Background(), &storage.Entry{Name: name, PhoneNumber: "+989121234567"})
+	return appErr
+}
+
+func TestQueuedWhileDown(t *testing.T) {
+	ctx := context.Background()
+	backend := &flaky{Storage: memory.New(storage.Entry{ID: 1, Name: "Ali"}), up: 0}
+	s := openFlaky(t, backend)
+
+	if appErr := insert(t, s, "Sara"); appErr == nil || appErr.StatusCode != http.StatusAccepted {
+		t.Fatalf("got %v, want the insert queued", appErr)
+	}
+
+	if appErr := s.Delete(ctx, 1); appErr == nil || appErr.StatusCode != http.StatusAccepted {
+		t.Fatalf("got %v, want the delete queued", appErr)
+	}
+
+	// Reads wait for the backend.
+	if _, appErr := s.List(ctx); appErr == nil || appErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got %v, want the list to fail", appErr)
+	}
+
+	if queued, _, err := s.Pending(); err != nil || len(queued) != 2 || queued[0].Op != opInsert || queued[1].Op != opDelete {
+		t.Fatalf("got %+v, %v, want the insert and the delete queued in order", queued, err)
+	}
+
+	// Once it is back, the queue is replayed before the read.
+	backend.up = -1
+	entries, appErr := s.List(ctx)
+	if appErr != nil {
+		t.Fatal(appErr)
+	}
+
+	if len(entries) != 1 || entries[0].Name != "Sara" {
+		t.Errorf("got %+v, want only the queued insert", entries)
+	}
+
+	if queued, rejected, err := s.Pending(); err != nil || len(queued) != 0 || len(rejected) != 0 {
+		t.Errorf("got %d queued and %d rejected, %v, want none", len(queued), len(rejected), err)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		// up is how many operations the backend takes during the flush.
+		up                int
+		applied, rejected int
+		unreachable       bool
+		wantQueued        []string
+	}{
+		{name: "up", up: -1, applied: 2, rejected: 1},
+		// Changes the backend could not be reached for are put back in
+		// front of the queue, in order.
+		{name: "down again", up: 1, applied: 1, unreachable: true, wantQueued: []string{opDelete, opInsert}},
+		{name: "still down", up: 0, unreachable: true, wantQueued: []string{opInsert, opDelete, opInsert}},
+	} {
+		ctx := context.Background()
+		backend := &flaky{Storage: memory.New(), up: 0}
+		s := openFlaky(t, backend)
+
+		// The delete of an entry that isn't there is refused.
+		insert(t, s, "Ali")
+		s.Delete(ctx, 42)
+		insert(t, s, "Sara")
+
+		backend.up = test.up
+		applied, rejected, appErr := s.Flush(ctx)
+		if applied != test.applied || rejected != test.rejected || (appErr != nil) != test.unreachable {
+			t.Errorf("%s: got %d applied, %d rejected, %v, want %d, %d, unreachable %v", test.name, applied, rejected, appErr, test.applied, test.rejected, test.unreachable)
+		}
+
+		queued, refused, err := s.Pending()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ops []string
+		for _, c := range queued {
+			ops = append(ops, c.Op)
+		}
+
+		if !slices.Equal(ops, test.wantQueued) {
+			t.Errorf("%s: got %v queued, want %v", test.name, ops, test.wantQueued)
+		}
+
+		if len(refused) != test.rejected || (len(refused) > 0 && (refused[0].ID != 42 || refused[0].Error == "")) {
+			t.Errorf("%s: got rejected %+v, want the delete with why", test.name, refused)
+		}
+	}
+}
+
+func TestOpenUnreachable(t *testing.T) {
+	ctx := context.Background()
+	errDown := errors.New("connection refused")
+	backend := &flaky{Storage: memory.New(), up: -1}
+	down := true
+	open := func() (storage.Storage, error) {
+		if down {
+			return nil, errDown
+		}
+
+		return backend, nil
+	}
+
+	s, err := Open(open, func(err error) bool { return errors.Is(err, errDown) }, filepath.Join(t.TempDir(), "queue.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if appErr := insert(t, s, "Ali"); appErr == nil || appErr.StatusCode != http.StatusAccepted {
+		t.Fatalf("got %v, want the insert queued", appErr)
+	}
+
+	if _, appErr := s.List(ctx); appErr == nil || appErr.Message != UnreachableError().Message {
+		t.Errorf("got %v, want the backend unreachable", appErr)
+	}
+
+	// Flush doesn't wait for the retry interval.
+	down = false
+	if applied, _, appErr := s.Flush(ctx); appErr != nil || applied != 1 {
+		t.Errorf("got %d applied, %v, want the insert", applied, appErr)
+	}
+
+	if entries, _ := backend.List(ctx); len(entries) != 1 {
+		t.Errorf("got %d entries, want the one queued", len(entries))
+	}
+
+	// Other errors mean the backend is misconfigured.
+	misconfigured := errors.New("no such database")
+	if _, err := Open(func() (storage.Storage, error) { return nil, misconfigured }, func(err error) bool { return errors.Is(err, errDown) }, filepath.Join(t.TempDir(), "queue.jsonl")); err != misconfigured {
+		t.Errorf("got %v, want %v", err, misconfigured)
+	}
+}
@@ -0,0 +1,188 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// ParseColumns parses a --columns list like "name,phone,company" into the
+// fields it names, in its order, for Options.Columns, CSV and JSON.
+func ParseColumns(list string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.ToLower(strings.TrimSpace(field)); field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, errors.New(i18n.T("--columns needs at least one column, e.g. name,phone"))
+	}
+
+	if _, err := selectColumns(fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+// selectColumns returns the entry columns of fields.
+func selectColumns(fields []string) ([]column, error) {
+	selected := make([]column, len(fields))
+	for i, field := range fields {
+		found := false
+		for _, col := range entryColumns {
+			if col.field == field {
+				selected[i], found = col, true
+				break
+			}
+		}
+
+		if !found {
+			names := make([]string, len(entryColumns))
+			for j, col := range entryColumns {
+				names[j] = col.field
+			}
+
+			return nil, errors.New(i18n.T("unknown column %q, use %s", field, strings.Join(names, ", ")))
+		}
+	}
+
+	return selected, nil
+}
+
+func (col column) name() string {
+	if col.key != "" {
+		return col.key
+	}
+
+	return col.field
+}
+
+func (col column) dataOf(entry model.Entry) any {
+	if col.data != nil {
+		return col.data(entry)
+	}
+
+	return col.value(entry)
+}
+
+// CSV writes the fields of entries, columns of ParseColumns, as CSV after a
+// header row naming them the way import recognizes. It calls progress,
+// unless it is nil, with the number of entries written so far after each.
+func CSV(w io.Writer, entries []model.Entry, fields []string, progress func(done int)) error {
+	cols, err := selectColumns(fields)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = col.name()
+	}
+
+	writer.Write(header)
+	for n, entry := range entries {
+		record := make([]string, len(cols))
+		for i, col := range cols {
+			record[i] = csvValue(col.dataOf(entry))
+		}
+
+		writer.Write(record)
+		if progress != nil {
+			progress(n + 1)
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// csvValue is value the way data files write it: missing times and zero
+// days empty, other times in RFC 3339.
+func csvValue(value any) string {
+	switch value := value.(type) {
+	case string:
+		return value
+	case model.PhoneNumber:
+		return string(value)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case int:
+		if value == 0 {
+			return ""
+		}
+
+		return strconv.Itoa(value)
+	case *time.Time:
+		if value == nil {
+			return ""
+		}
+
+		return value.UTC().Format(time.RFC3339)
+	}
+
+	return fmt.Sprint(value)
+}
+
+// JSON writes the fields of entries, columns of ParseColumns, as a JSON
+// array of objects with the keys of the entries' own JSON in the order of
+// fields. It calls progress like CSV.
+func JSON(w io.Writer, entries []model.Entry, fields []string, progress func(done int)) error {
+	cols, err := selectColumns(fields)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	out.WriteString("[")
+	for i, entry := range entries {
+		if i > 0 {
+			out.WriteString(",")
+		}
+
+		out.WriteString("\n  {")
+		for j, col := range cols {
+			if j > 0 {
+				out.WriteString(",")
+			}
+
+			key, _ := json.Marshal(col.name())
+			value, err := json.Marshal(col.dataOf(entry))
+			if err != nil {
+				return err
+			}
+
+			out.WriteString("\n    ")
+			out.Write(key)
+			out.WriteString(": ")
+			out.Write(value)
+		}
+
+		out.WriteString("\n  }")
+		if progress != nil {
+			progress(i + 1)
+		}
+	}
+
+	if len(entries) > 0 {
+		out.WriteString("\n")
+	}
+
+	out.WriteString("]\n")
+	_, err = w.Write(out.Bytes())
+
+	return err
+}
@@ -0,0 +1,121 @@
+package output
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	highlightStart = "\x1b[1;4m"
+	highlightEnd   = "\x1b[0m"
+)
+
+// ColorEnabled reports whether ANSI highlighting should be written to f: only
+// when it is a terminal and NO_COLOR is not set.
+func ColorEnabled(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	return IsTerminal(f)
+}
+
+// highlight marks the part of cell matching term. When the term doesn't
+// appear literally (a fuzzy or transliterated match) the whole cell is
+// marked, since it still is the reason the entry matched.
+func highlight(cell, term string) string {
+	if cell == "" {
+		return cell
+	}
+
+	if start, end, ok := findFold(cell, term); ok {
+		return cell[:start] + highlightStart + cell[start:end] + highlightEnd + cell[end:]
+	}
+
+	return highlightStart + cell + highlightEnd
+}
+
+// highlightDigits marks the digits of a formatted number that match the
+// digits of term, skipping the spaces and punctuation between groups.
+func highlightDigits(cell, term string) string {
+	var termDigits strings.Builder
+	for _, r := range term {
+		if r >= '0' && r <= '9' {
+			termDigits.WriteRune(r)
+		}
+	}
+
+	want := strings.TrimPrefix(termDigits.String(), "0")
+	if want == "" {
+		return highlightStart + cell + highlightEnd
+	}
+
+	var cellDigits []byte
+	var positions []int
+	for i := 0; i < len(cell); i++ {
+		if cell[i] >= '0' && cell[i] <= '9' {
+			cellDigits = append(cellDigits, cell[i])
+			positions = append(positions, i)
+		}
+	}
+
+	at := strings.Index(string(cellDigits), want)
+	if at < 0 {
+		return highlightStart + cell + highlightEnd
+	}
+
+	start, end := positions[at], positions[at+len(want)-1]+1
+
+	return cell[:start] + highlightStart + cell[start:end] + highlightEnd + cell[end:]
+}
+
+// findFold finds term in s ignoring case and returns its byte offsets in s.
+func findFold(s, term string) (int, int, bool) {
+	if term == "" {
+		return 0, 0, false
+	}
+
+	for start := 0; start < len(s); {
+		for end := start; end <= len(s); {
+			if strings.EqualFold(s[start:end], term) {
+				return start, end, true
+			}
+
+			if end == len(s) {
+				break
+			}
+
+			_, size := utf8.DecodeRuneInString(s[end:])
+			end += size
+		}
+
+		_, size := utf8.DecodeRuneInString(s[start:])
+		start += size
+	}
+
+	return 0, 0, false
+}
+
+// visibleWidth is the number of characters of s shown on screen, leaving out
+// ANSI escape sequences.
+func visibleWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			end := strings.IndexByte(s[i:], 'm')
+			if end < 0 {
+				break
+			}
+
+			i += end + 1
+			continue
+		}
+
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		width++
+	}
+
+	return width
+}
@@ -0,0 +1,534 @@
+// Package output renders phone book entries for the command line.
+package output
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/reminders"
+)
+
+// Options controls what Table shows besides the entries themselves.
+type Options struct {
+	// Blocked adds a column marking the entries whose number is in the set.
+	Blocked map[string]bool
+	// Scores adds a column with the search score of each entry, by ID.
+	Scores map[int64]int
+	// Matches holds, by entry ID, the fields ("name", "surname", "nickname",
+	// "phone") a search for Term matched. They are highlighted when Color is
+	// set.
+	Matches map[int64][]string
+	Term    string
+	Color   bool
+	// LastContacted adds a column with the day of the last call logged with
+	// each entry, by ID, when any entry has one.
+	LastContacted map[int64]time.Time
+	// Columns, when set, are the only columns shown, in that order: fields
+	// of ParseColumns.
+	Columns []string
+}
+
+type column struct {
+	field  string
+	header string
+	value  func(model.Entry) string
+	// optional columns are only shown when at least one entry has a value.
+	optional bool
+	// hidden columns are only shown when asked for with Options.Columns.
+	hidden bool
+	// key is the column's name in CSV headers and JSON objects, field when
+	// empty, and data its value there, value when nil.
+	key  string
+	data func(model.Entry) any
+}
+
+// entryColumns are the columns of the fields of an entry, those tables show
+// by default first and in their order, then those only shown when selected.
+var entryColumns = []column{
+	{field: "id", header: "ID", value: func(e model.Entry) string { return strconv.FormatInt(e.ID, 10) }, data: func(e model.Entry) any { return e.ID }},
+	{field: "name", header: "NAME", value: displayName, data: func(e model.Entry) any { return e.Name }},
+	{field: "surname", header: "SURNAME", value: func(e model.Entry) string { return e.Surname }},
+	{field: "phone", header: "PHONE", value: func(e model.Entry) string { return e.PhoneNumber.Format() }, key: "phone_number", data: func(e model.Entry) any { return e.PhoneNumber }},
+	{field: "country", header: "COUNTRY", value: Country},
+	{field: "company", header: "COMPANY", value: func(e model.Entry) string { return e.Company }, optional: true},
+	{field: "title", header: "TITLE", value: func(e model.Entry) string { return e.Title }, optional: true},
+	{field: "consent", header: "CONSENT", optional: true, value: func(e model.Entry) string {
+		if e.Consent == "" {
+			return ""
+		}
+
+		return i18n.T(e.Consent)
+	}, data: func(e model.Entry) any { return e.Consent }},
+	{field: "channel", header: "CHANNEL", value: func(e model.Entry) string { return i18n.T(e.Channel) }, optional: true, key: "preferred_channel", data: func(e model.Entry) any { return e.Channel }},
+	{field: "archived", header: "ARCHIVED", optional: true, value: func(e model.Entry) string {
+		if e.ArchivedAt == nil {
+			return ""
+		}
+
+		return e.ArchivedAt.Local().Format(time.DateOnly)
+	}, key: "archived_at", data: func(e model.Entry) any { return e.ArchivedAt }},
+	{field: "nickname", header: "NICKNAME", value: func(e model.Entry) string { return e.Nickname }, hidden: true},
+	{field: "birthday", header: "BIRTHDAY", value: func(e model.Entry) string { return e.Birthday }, hidden: true},
+	{field: "anniversary", header: "ANNIVERSARY", value: func(e model.Entry) string { return e.Anniversary }, hidden: true},
+	{field: "remind_days", header: "REMIND DAYS", value: func(e model.Entry) string {
+		if e.RemindDays == 0 {
+			return ""
+		}
+
+		return strconv.Itoa(e.RemindDays)
+	}, data: func(e model.Entry) any { return e.RemindDays }, hidden: true},
+	{field: "consent_date", header: "CONSENT DATE", value: func(e model.Entry) string { return e.ConsentDate }, hidden: true},
+	{field: "access_notes", header: "ACCESS NOTES", value: func(e model.Entry) string { return e.AccessNotes }, hidden: true},
+	{field: "photo", header: "PHOTO", value: func(e model.Entry) string { return e.Photo }, hidden: true},
+	{field: "version", header: "VERSION", value: func(e model.Entry) string { return strconv.FormatInt(e.Version, 10) }, data: func(e model.Entry) any { return e.Version }, hidden: true},
+	{field: "updated_at", header: "UPDATED", value: func(e model.Entry) string {
+		if e.UpdatedAt == nil {
+			return ""
+		}
+
+		return e.UpdatedAt.Local().Format(time.DateTime)
+	}, data: func(e model.Entry) any { return e.UpdatedAt }, hidden: true},
+}
+
+func columns(options Options) []column {
+	var cols []column
+	for _, col := range entryColumns {
+		if !col.hidden {
+			cols = append(cols, col)
+		}
+	}
+
+	if options.LastContacted != nil {
+		cols = append(cols, column{field: "last_contacted", header: "LAST CONTACTED", optional: true, value: func(e model.Entry) string {
+			if at, ok := options.LastContacted[e.ID]; ok {
+				return at.Local().Format(time.DateOnly)
+			}
+
+			return ""
+		}})
+	}
+
+	if options.Scores != nil {
+		cols = append(cols, column{field: "score", header: "SCORE", value: func(e model.Entry) string { return strconv.Itoa(options.Scores[e.ID]) }})
+	}
+
+	if options.Blocked != nil {
+		cols = append(cols, column{field: "blocked", header: "BLOCKED", value: func(e model.Entry) string {
+			if options.Blocked[string(e.PhoneNumber.Canonical())] {
+				return i18n.T("yes")
+			}
+
+			return ""
+		}})
+	}
+
+	return cols
+}
+
+// displayName is the name of an entry followed by its nickname in
+// parentheses, "Morteza (Mo)".
+func displayName(entry model.Entry) string {
+	if entry.Nickname == "" {
+		return entry.Name
+	}
+
+	return entry.Name + " (" + entry.Nickname + ")"
+}
+
+// matched reports whether the search matched the column of field, the name
+// column showing the nickname as well.
+func matched(fields []string, field string) bool {
+	return slices.Contains(fields, field) || field == "name" && slices.Contains(fields, "nickname")
+}
+
+// Table writes entries as an aligned table, showing phone numbers in the
+// format of their country.
+func Table(w io.Writer, entries []model.Entry, options Options) error {
+	var shown []column
+	for _, col := range columns(options) {
+		if !col.optional || anyValue(entries, col) {
+			shown = append(shown, col)
+		}
+	}
+
+	if options.Columns != nil {
+		var err error
+		if shown, err = selectColumns(options.Columns); err != nil {
+			return err
+		}
+	}
+
+	header := make([]string, len(shown))
+	for i, col := range shown {
+		header[i] = i18n.T(col.header)
+	}
+
+	rows := [][]string{header}
+	for _, entry := range entries {
+		row := make([]string, len(shown))
+		for i, col := range shown {
+			row[i] = col.value(entry)
+			if options.Color && matched(options.Matches[entry.ID], col.field) {
+				if col.field == "phone" {
+					row[i] = highlightDigits(row[i], options.Term)
+				} else {
+					row[i] = highlight(row[i], options.Term)
+				}
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return writeAligned(w, rows)
+}
+
+// writeAligned writes rows with their columns padded to a common width. It
+// does what text/tabwriter does, except that ANSI escapes take no room.
+func writeAligned(w io.Writer, rows [][]string) error {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+
+			widths[i] = max(widths[i], visibleWidth(cell))
+		}
+	}
+
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			line.WriteString(cell)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-visibleWidth(cell)+2))
+			}
+		}
+
+		if _, err := fmt.Fprintln(w, line.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Groups writes one table per distinct value of key, sorted by that value,
+// with entries that have no value last.
+func Groups(w io.Writer, entries []model.Entry, key func(model.Entry) string, options Options) error {
+	groups := make(map[string][]model.Entry)
+	for _, entry := range entries {
+		groups[key(entry)] = append(groups[key(entry)], entry)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == "" || names[j] == "" {
+			return names[j] == ""
+		}
+
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		label := name
+		if label == "" {
+			label = i18n.T("(none)")
+		}
+
+		fmt.Fprintf(w, "%s (%d)\n", label, len(groups[name]))
+		if err := Table(w, groups[name], options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Calls writes the call log as a table, naming the contact of each call
+// from entries. Calls with entries no longer in the book show only the ID.
+func Calls(w io.Writer, calls []model.Call, entries map[int64]model.Entry) error {
+	rows := [][]string{{i18n.T("WHEN"), i18n.T("ID"), i18n.T("CONTACT"), i18n.T("DURATION"), i18n.T("NOTE")}}
+	for _, call := range calls {
+		contact := ""
+		if entry, ok := entries[call.EntryID]; ok {
+			contact = strings.TrimSpace(displayName(entry) + " " + entry.Surname)
+		}
+
+		duration := ""
+		if call.Duration > 0 {
+			duration = callDuration(call.Duration)
+		}
+
+		rows = append(rows, []string{call.At.Local().Format("2006-01-02 15:04"), strconv.FormatInt(call.EntryID, 10), contact, duration, call.Note})
+	}
+
+	return writeAligned(w, rows)
+}
+
+// Relations writes the entries related to an entry as a table, saying how.
+func Relations(w io.Writer, related []model.Relation) error {
+	rows := [][]string{{i18n.T("ID"), i18n.T("CONTACT"), i18n.T("RELATIONSHIP")}}
+	for _, r := range related {
+		relationship := i18n.T(r.Type)
+		switch {
+		case r.AssistantOf:
+			relationship = i18n.T("employer")
+		case r.Automatic:
+			relationship = i18n.T("%s (automatic)", relationship)
+		}
+
+		contact := strings.TrimSpace(displayName(r.Entry) + " " + r.Entry.Surname)
+		rows = append(rows, []string{strconv.FormatInt(r.Entry.ID, 10), contact, relationship})
+	}
+
+	return writeAligned(w, rows)
+}
+
+// Hits writes the hits of a search of several books as a table, the book
+// of each first, highlighting what matched term when color is set.
+func Hits(w io.Writer, hits []model.SearchHit, term string, color bool) error {
+	companies := false
+	for _, hit := range hits {
+		companies = companies || hit.Entry.Company != ""
+	}
+
+	// Without a term every entry is a hit alike, and SCORE is left out.
+	header := []string{i18n.T("BOOK"), i18n.T("ID"), i18n.T("CONTACT"), i18n.T("PHONE")}
+	if companies {
+		header = append(header, i18n.T("COMPANY"))
+	}
+
+	if term != "" {
+		header = append(header, i18n.T("SCORE"))
+	}
+
+	rows := [][]string{header}
+	for _, hit := range hits {
+		contact := strings.TrimSpace(displayName(hit.Entry) + " " + hit.Entry.Surname)
+		phone := hit.Entry.PhoneNumber.Format()
+		if color && (matched(hit.Fields, "name") || matched(hit.Fields, "surname")) {
+			contact = highlight(contact, term)
+		}
+
+		if color && matched(hit.Fields, "phone") {
+			phone = highlightDigits(phone, term)
+		}
+
+		row := []string{hit.Book, strconv.FormatInt(hit.Entry.ID, 10), contact, phone}
+		if companies {
+			row = append(row, hit.Entry.Company)
+		}
+
+		if term != "" {
+			row = append(row, strconv.Itoa(hit.Score))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return writeAligned(w, rows)
+}
+
+// Links writes links as a table, naming the entries from entries. Entries no
+// longer in the book show only their ID.
+func Links(w io.Writer, links []model.Link, entries map[int64]model.Entry) error {
+	contact := func(id int64) string {
+		entry, ok := entries[id]
+		if !ok {
+			return strconv.FormatInt(id, 10)
+		}
+
+		return strings.TrimSpace(fmt.Sprintf("%d %s %s", id, displayName(entry), entry.Surname))
+	}
+
+	rows := [][]string{{i18n.T("CONTACT"), i18n.T("RELATIONSHIP"), i18n.T("OF")}}
+	for _, link := range links {
+		rows = append(rows, []string{contact(link.To), i18n.T(link.Type), contact(link.From)})
+	}
+
+	return writeAligned(w, rows)
+}
+
+// Reminders writes upcoming birthdays and anniversaries as a table.
+func Reminders(w io.Writer, upcoming []reminders.Reminder) error {
+	rows := [][]string{{i18n.T("DATE"), i18n.T("IN"), i18n.T("ID"), i18n.T("CONTACT"), i18n.T("OCCASION"), i18n.T("YEARS")}}
+	for _, r := range upcoming {
+		in := i18n.T("today")
+		switch {
+		case r.Days == 1:
+			in = i18n.T("tomorrow")
+		case r.Days > 1:
+			in = i18n.T("%d days", r.Days)
+		}
+
+		years := ""
+		if r.Years > 0 {
+			years = strconv.Itoa(r.Years)
+		}
+
+		contact := strings.TrimSpace(displayName(r.Entry) + " " + r.Entry.Surname)
+		rows = append(rows, []string{r.Date, in, strconv.FormatInt(r.Entry.ID, 10), contact, i18n.T(r.Occasion), years})
+	}
+
+	return writeAligned(w, rows)
+}
+
+// callDuration writes d without zero seconds and minutes, "3m" rather than
+// "3m0s".
+func callDuration(d time.Duration) string {
+	s := d.String()
+	if trimmed, ok := strings.CutSuffix(s, "m0s"); ok {
+		s = trimmed + "m"
+	}
+
+	if trimmed, ok := strings.CutSuffix(s, "h0m"); ok {
+		s = trimmed + "h"
+	}
+
+	return s
+}
+
+// Country returns the stored country of entry, deriving it from the number
+// for entries saved before countries were recorded.
+func Country(entry model.Entry) string {
+	if entry.Country != "" {
+		return entry.Country
+	}
+
+	return entry.PhoneNumber.Country()
+}
+
+func anyValue(entries []model.Entry, col column) bool {
+	for _, entry := range entries {
+		if col.value(entry) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stats writes the number of entries followed by how many of them belong to
+// each country, most common first.
+func Stats(w io.Writer, total int, countries map[string]int) error {
+	fmt.Fprintln(w, i18n.T("entries: %d", total))
+
+	names := make([]string, 0, len(countries))
+	for name := range countries {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if countries[names[i]] != countries[names[j]] {
+			return countries[names[i]] > countries[names[j]]
+		}
+
+		return names[i] < names[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\n", i18n.T("COUNTRY"), i18n.T("ENTRIES"))
+	for _, name := range names {
+		label := name
+		if label == "" {
+			label = i18n.T("unknown")
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\n", label, countries[name])
+	}
+
+	return tw.Flush()
+}
+
+// Debug writes the report of `debug stats`.
+func Debug(w io.Writer, stats model.DebugStats) error {
+	fmt.Fprintln(w, i18n.T("entries: %d", stats.Entries))
+	fmt.Fprintln(w, i18n.T("this process: %s", runtimeLine(stats.Process)))
+	if stats.Daemon != nil {
+		fmt.Fprintln(w, i18n.T("daemon: %s", runtimeLine(*stats.Daemon)))
+	}
+
+	if len(stats.Indexes) == 0 {
+		fmt.Fprintln(w, i18n.T("no search index"))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", i18n.T("INDEX"), i18n.T("ENTRIES"), i18n.T("TOKENS"), i18n.T("SIZE"))
+	for _, index := range stats.Indexes {
+		size := i18n.T("in memory")
+		if index.Bytes > 0 {
+			size = byteSize(uint64(index.Bytes))
+		}
+
+		state := ""
+		if index.Stale {
+			state = i18n.T("out of date")
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", index.Name, index.Entries, index.Tokens, size, state)
+	}
+
+	return tw.Flush()
+}
+
+func runtimeLine(stats model.RuntimeStats) string {
+	return i18n.T("%d goroutines, %s heap in use of %s, %s from the OS, %d GC cycles",
+		stats.Goroutines, byteSize(stats.HeapAlloc), byteSize(stats.HeapSys), byteSize(stats.Sys), stats.NumGC)
+}
+
+// byteSize formats n bytes in the largest binary unit that keeps it at least 1.
+func byteSize(n uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	size, unit := float64(n), 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+
+	if unit == 0 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
+
+// Lookup writes what is known about a single number.
+func Lookup(w io.Writer, result *model.LookupResponse) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "%s\t%s\n", i18n.T("PHONE"), result.Number.Format())
+	if result.Entry != nil {
+		fmt.Fprintf(tw, "%s\t%s %s\n", i18n.T("NAME"), result.Entry.Name, result.Entry.Surname)
+	} else {
+		fmt.Fprintf(tw, "%s\t%s\n", i18n.T("NAME"), i18n.T("unknown"))
+	}
+
+	blocked := i18n.T("no")
+	if result.Blocked {
+		blocked = i18n.T("yes")
+	}
+
+	fmt.Fprintf(tw, "%s\t%s\n", i18n.T("BLOCKED"), blocked)
+	fmt.Fprintf(tw, "%s\t%d (%s)\n", i18n.T("SPAM SCORE"), result.SpamScore, i18n.T("%d reports", result.SpamReports))
+
+	return tw.Flush()
+}
@@ -0,0 +1,123 @@
+package output
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+)
+
+const (
+	progressBarWidth = 30
+	// progressRedraw is how often the bar on a terminal is drawn at most.
+	progressRedraw = 100 * time.Millisecond
+)
+
+// Progress reports how far a long running command got. On a terminal it
+// draws a bar that is redrawn in place,
+//
+//	[===========>                  ] 4500/12000  37%  1520/s  3 errors  5s left
+//
+// and elsewhere, like when output goes to a log file, it prints the same
+// counts as a line every interval. An interval of 0 turns both off.
+type Progress struct {
+	f        *os.File
+	total    int
+	interval time.Duration
+	terminal bool
+	started  time.Time
+	drawn    time.Time
+	done     int
+	failed   int
+	// shown is the done count last drawn, -1 before the first time.
+	shown int
+}
+
+// NewProgress starts reporting on f the progress towards total items.
+func NewProgress(f *os.File, total int, interval time.Duration) *Progress {
+	now := time.Now()
+	return &Progress{f: f, total: total, interval: interval, terminal: IsTerminal(f), started: now, drawn: now, shown: -1}
+}
+
+// Update records that done items were processed, failed of them with errors.
+func (p *Progress) Update(done, failed int) {
+	p.done, p.failed = done, failed
+	if p.interval <= 0 {
+		return
+	}
+
+	every := p.interval
+	if p.terminal {
+		every = progressRedraw
+	}
+
+	if time.Since(p.drawn) < every {
+		return
+	}
+
+	p.drawn = time.Now()
+	p.draw()
+}
+
+// Finish shows the final counts, if anything was shown before, and ends the
+// bar's line.
+func (p *Progress) Finish() {
+	if p.interval <= 0 || p.shown < 0 {
+		return
+	}
+
+	if p.shown != p.done {
+		p.draw()
+	}
+
+	if p.terminal {
+		fmt.Fprintln(p.f)
+	}
+}
+
+func (p *Progress) draw() {
+	p.shown = p.done
+	elapsed := time.Since(p.started)
+
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.done) / elapsed.Seconds()
+	}
+
+	left := i18n.T("unknown")
+	if rate > 0 {
+		left = (time.Duration(float64(p.total-p.done)/rate) * time.Second).Round(time.Second).String()
+	}
+
+	if !p.terminal {
+		fmt.Fprintln(p.f, i18n.T("%d of %d entries processed, %d errors, %s left", p.done, p.total, p.failed, left))
+		return
+	}
+
+	percent := 100
+	if p.total > 0 {
+		percent = p.done * 100 / p.total
+	}
+
+	filled := percent * progressBarWidth / 100
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+
+	// \r goes back to the start of the line and \x1b[K clears what a longer
+	// earlier line left behind.
+	fmt.Fprintf(p.f, "\r[%s] %d/%d %3d%%  %.0f/s  %s  %s\x1b[K", bar, p.done, p.total, percent, rate, i18n.T("%d errors", p.failed), i18n.T("%s left", left))
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
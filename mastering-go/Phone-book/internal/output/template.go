@@ -0,0 +1,54 @@
+package output
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// templateFuncs are the functions templates can call besides the built-in
+// ones: {{phone .PhoneNumber}} formats a number like tables show it and
+// {{country .}} is the country an entry's number belongs to.
+var templateFuncs = template.FuncMap{
+	"phone":   model.PhoneNumber.Format,
+	"country": Country,
+}
+
+// ParseTemplate parses text, a text/template executed with a model.Entry
+// such as "{{.Name}} {{.Surname}}: {{.PhoneNumber}}". It is tried on an
+// empty entry, so a misspelled field fails here rather than halfway through
+// the output.
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("entry").Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tmpl.Execute(io.Discard, model.Entry{}); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// Template writes every entry through tmpl, each on a line of its own
+// unless the template ends the line itself.
+func Template(w io.Writer, entries []model.Entry, tmpl *template.Template) error {
+	bw := bufio.NewWriter(w)
+	for _, entry := range entries {
+		var line strings.Builder
+		if err := tmpl.Execute(&line, entry); err != nil {
+			return err
+		}
+
+		bw.WriteString(line.String())
+		if !strings.HasSuffix(line.String(), "\n") {
+			bw.WriteString("\n")
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,273 @@
+// Package phone converts phone numbers between the canonical E.164 form the
+// phone book stores ("+989121234567") and the way people write and read them.
+// The numbers of entries are model.PhoneNumber values, whose methods wrap
+// these functions.
+package phone
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultRegion is the ISO country code assumed for numbers written without
+// an international prefix, like "0912 123 4567".
+var DefaultRegion = "IR"
+
+type country struct {
+	region string
+	code   string
+	// groups splits the national number for display, the last group takes
+	// whatever is left.
+	groups func(national string) []int
+	// keepTrunk is set for countries that keep the leading 0 of national
+	// numbers after the country code.
+	keepTrunk bool
+}
+
+var countries = []country{
+	{region: "US", code: "1", groups: fixed(3, 3, 4)},
+	{region: "RU", code: "7", groups: fixed(3, 3, 2, 2)},
+	{region: "FR", code: "33", groups: fixed(1, 2, 2, 2, 2)},
+	{region: "ES", code: "34", groups: fixed(3, 3, 3)},
+	{region: "IT", code: "39", groups: fixed(3, 3, 4), keepTrunk: true},
+	{region: "GB", code: "44", groups: britain},
+	{region: "DE", code: "49", groups: fixed(3, 8)},
+	{region: "AU", code: "61", groups: fixed(1, 4, 4)},
+	{region: "JP", code: "81", groups: fixed(2, 4, 4)},
+	{region: "CN", code: "86", groups: fixed(3, 4, 4)},
+	{region: "TR", code: "90", groups: fixed(3, 3, 2, 2)},
+	{region: "IN", code: "91", groups: fixed(5, 5)},
+	{region: "IR", code: "98", groups: iran},
+	{region: "AE", code: "971", groups: fixed(2, 3, 4)},
+}
+
+// Normalize converts a number as a person would type it into E.164. Numbers
+// without an international prefix are taken to belong to region, or to
+// DefaultRegion when region is empty.
+func Normalize(number, region string) (string, error) {
+	var digits strings.Builder
+	for i, r := range strings.TrimSpace(number) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0:
+			digits.WriteRune(r)
+		case strings.ContainsRune(" -().", r):
+		default:
+			return "", fmt.Errorf("invalid character %q in phone number %q", r, number)
+		}
+	}
+
+	cleaned := digits.String()
+	switch {
+	case strings.HasPrefix(cleaned, "+"):
+	case strings.HasPrefix(cleaned, "00"):
+		cleaned = "+" + cleaned[2:]
+	default:
+		if region == "" {
+			region = DefaultRegion
+		}
+
+		c, ok := byRegion(region)
+		if !ok {
+			return "", fmt.Errorf("cannot normalize %q: unknown region %q", number, region)
+		}
+
+		if !c.keepTrunk {
+			cleaned = strings.TrimPrefix(cleaned, "0")
+		}
+
+		if c.code == "1" && len(cleaned) == 11 {
+			cleaned = strings.TrimPrefix(cleaned, "1")
+		}
+
+		cleaned = "+" + c.code + cleaned
+	}
+
+	if length := len(cleaned) - 1; length < 8 || length > 15 {
+		return "", fmt.Errorf("%q is not a valid phone number", number)
+	}
+
+	return cleaned, nil
+}
+
+// Canonical returns number in E.164 form, or unchanged when it cannot be
+// normalized.
+func Canonical(number string) string {
+	return CanonicalIn(number, "")
+}
+
+// CanonicalIn is Canonical taking numbers without an international prefix
+// to belong to region, as Normalize does.
+func CanonicalIn(number, region string) string {
+	normalized, err := Normalize(number, region)
+	if err != nil {
+		return number
+	}
+
+	return normalized
+}
+
+// Format renders a number for display, grouping the national part the way its
+// country usually writes it: "+1 (415) 555-0100", "+98 21 1234 5678".
+// Numbers that cannot be normalized are returned unchanged.
+func Format(number string) string {
+	e164, err := Normalize(number, "")
+	if err != nil {
+		return number
+	}
+
+	c, national, ok := split(e164)
+	if !ok {
+		return e164
+	}
+
+	parts := group(national, c.groups(national))
+	if c.code == "1" && len(parts) == 3 {
+		return fmt.Sprintf("+1 (%s) %s-%s", parts[0], parts[1], parts[2])
+	}
+
+	return "+" + c.code + " " + strings.Join(parts, " ")
+}
+
+func split(e164 string) (country, string, bool) {
+	digits := strings.TrimPrefix(e164, "+")
+	for length := 3; length >= 1; length-- {
+		if len(digits) <= length {
+			continue
+		}
+
+		for _, c := range countries {
+			if c.code == digits[:length] {
+				return c, digits[length:], true
+			}
+		}
+	}
+
+	return country{}, "", false
+}
+
+func byRegion(region string) (country, bool) {
+	for _, c := range countries {
+		if strings.EqualFold(c.region, region) {
+			return c, true
+		}
+	}
+
+	return country{}, false
+}
+
+func group(national string, sizes []int) []string {
+	var parts []string
+	for i, size := range sizes {
+		if national == "" {
+			break
+		}
+
+		if i == len(sizes)-1 || size >= len(national) {
+			parts = append(parts, national)
+			national = ""
+			break
+		}
+
+		parts = append(parts, national[:size])
+		national = national[size:]
+	}
+
+	return parts
+}
+
+func fixed(sizes ...int) func(string) []int {
+	return func(string) []int {
+		return sizes
+	}
+}
+
+// iran groups mobile numbers (9xx) as 3-3-4 and landlines, which all have a
+// two digit area code, as 2-4-4.
+func iran(national string) []int {
+	if strings.HasPrefix(national, "9") {
+		return []int{3, 3, 4}
+	}
+
+	return []int{2, 4, 4}
+}
+
+// britain groups mobiles (7xxx) as 4-6 and London numbers as 2-4-4.
+func britain(national string) []int {
+	if strings.HasPrefix(national, "2") {
+		return []int{2, 4, 4}
+	}
+
+	return []int{4, 6}
+}
+
+// Equal reports whether a and b are the same number, however each of them is
+// written.
+func Equal(a, b string) bool {
+	return EqualIn(a, b, "")
+}
+
+// EqualIn is Equal taking numbers without an international prefix to belong
+// to region.
+func EqualIn(a, b, region string) bool {
+	if a == b {
+		return true
+	}
+
+	normalizedA, err := Normalize(a, region)
+	if err != nil {
+		return false
+	}
+
+	normalizedB, err := Normalize(b, region)
+	if err != nil {
+		return false
+	}
+
+	return normalizedA == normalizedB
+}
+
+// National returns the national significant number, the part after the
+// country code, or "" when the number cannot be parsed.
+func National(number string) string {
+	return NationalIn(number, "")
+}
+
+// NationalIn is National taking numbers without an international prefix to
+// belong to region.
+func NationalIn(number, region string) string {
+	e164, err := Normalize(number, region)
+	if err != nil {
+		return ""
+	}
+
+	_, national, ok := split(e164)
+	if !ok {
+		return ""
+	}
+
+	return national
+}
+
+// Region returns the ISO country code the number belongs to, or "" when it
+// cannot be told. Numbers sharing the +1 prefix are all reported as US.
+func Region(number string) string {
+	return RegionIn(number, "")
+}
+
+// RegionIn is Region taking numbers without an international prefix to
+// belong to region.
+func RegionIn(number, region string) string {
+	e164, err := Normalize(number, region)
+	if err != nil {
+		return ""
+	}
+
+	c, _, ok := split(e164)
+	if !ok {
+		return ""
+	}
+
+	return c.region
+}
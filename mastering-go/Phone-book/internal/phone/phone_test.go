@@ -0,0 +1,66 @@
+package phone
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		number, region string
+		want           string
+		invalid        bool
+	}{
+		{number: "09121234567", want: "+989121234567"},
+		{number: "0912 123 4567", want: "+989121234567"},
+		{number: "+98 912 123 4567", want: "+989121234567"},
+		{number: "00989121234567", want: "+989121234567"},
+		{number: "(415) 555-0100", region: "US", want: "+14155550100"},
+		{number: "1 415 555 0100", region: "us", want: "+14155550100"},
+		{number: "020 7946 0018", region: "GB", want: "+442079460018"},
+		// Italy keeps the leading 0 after the country code.
+		{number: "06 1234 5678", region: "IT", want: "+390612345678"},
+		{number: "  +1.415.555.0100  ", want: "+14155550100"},
+		{number: "0912-123-4567x", invalid: true},
+		{number: "12+34567890", invalid: true},
+		{number: "0912 123 4567", region: "XX", invalid: true},
+		{number: "12", invalid: true},
+		{number: "+1234567890123456", invalid: true},
+		{number: "", invalid: true},
+	} {
+		got, err := Normalize(test.number, test.region)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("Normalize(%q, %q) = %q, want an error", test.number, test.region, got)
+			}
+
+			continue
+		}
+
+		if err != nil || got != test.want {
+			t.Errorf("Normalize(%q, %q) = %q, %v, want %q", test.number, test.region, got, err, test.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, test := range []struct {
+		number, want string
+	}{
+		{"+989121234567", "+98 912 123 4567"},
+		{"+982112345678", "+98 21 1234 5678"},
+		{"+14155550100", "+1 (415) 555-0100"},
+		{"+442079460018", "+44 20 7946 0018"},
+		{"+447911123456", "+44 7911 123456"},
+		{"+33123456789", "+33 1 23 45 67 89"},
+		{"+971501234567", "+971 50 123 4567"},
+		// Numbers without an international prefix are in DefaultRegion.
+		{"0912 123 4567", "+98 912 123 4567"},
+		// A country code missing from the table is left ungrouped.
+		{"+2348012345678", "+2348012345678"},
+		// What cannot be normalized is shown as it was written.
+		{"12", "12"},
+		{"call me", "call me"},
+	} {
+		if got := Format(test.number); got != test.want {
+			t.Errorf("Format(%q) = %q, want %q", test.number, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package reminders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+)
+
+// sendTimeout bounds every channel, so one that hangs doesn't hold up the
+// others or the next check.
+const sendTimeout = 30 * time.Second
+
+// Send sends r through every channel of cfg, returning the errors of the
+// channels that failed and how many succeeded.
+func Send(ctx context.Context, cfg *config.Reminders, r Reminder) (sent int, err error) {
+	type channel struct {
+		name string
+		send func(context.Context, Reminder) error
+	}
+
+	var channels []channel
+	if cfg.Command != "" {
+		channels = append(channels, channel{"command", func(ctx context.Context, r Reminder) error { return runCommand(ctx, cfg.Command, r) }})
+	}
+
+	if cfg.Email != nil {
+		channels = append(channels, channel{"email", func(ctx context.Context, r Reminder) error { return sendEmail(cfg.Email, r) }})
+	}
+
+	if cfg.Webhook != "" {
+		channels = append(channels, channel{"webhook", func(ctx context.Context, r Reminder) error { return postWebhook(ctx, cfg.Webhook, r) }})
+	}
+
+	var errs []error
+	for _, ch := range channels {
+		ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+		if err := ch.send(ctx, r); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", ch.name, err))
+		} else {
+			sent++
+		}
+
+		cancel()
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+// runCommand runs command with the reminder in its environment rather than
+// in the command line, so names never need quoting.
+func runCommand(ctx context.Context, command string, r Reminder) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(),
+		"PHONEBOOK_MESSAGE="+r.Message,
+		"PHONEBOOK_NAME="+strings.TrimSpace(r.Entry.Name+" "+r.Entry.Surname),
+		"PHONEBOOK_OCCASION="+r.Occasion,
+		"PHONEBOOK_DATE="+r.Date,
+		"PHONEBOOK_DAYS="+strconv.Itoa(r.Days),
+	)
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+
+	return nil
+}
+
+func sendEmail(cfg *config.Email, r Reminder) error {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("email needs a host, from and to")
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		host, _, err := net.SplitHostPort(cfg.Host)
+		if err != nil {
+			return err
+		}
+
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerSafe(r.Message)))
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", r.Message)
+
+	return smtp.SendMail(cfg.Host, auth, cfg.From, cfg.To, msg.Bytes())
+}
+
+// headerSafe keeps a name with a line break from adding mail headers.
+func headerSafe(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+func postWebhook(ctx context.Context, url string, r Reminder) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+
+	return nil
+}
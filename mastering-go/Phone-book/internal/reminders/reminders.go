@@ -0,0 +1,134 @@
+// Package reminders finds the birthdays and anniversaries coming up in a
+// phone book and sends reminders of them through the channels of the
+// config file: a command, email or a webhook.
+package reminders
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// DefaultDays is how many days ahead of an occasion its reminder is sent
+// unless the config or the entry says otherwise.
+const DefaultDays = 7
+
+// Occasions of a reminder.
+const (
+	Birthday    = "birthday"
+	Anniversary = "anniversary"
+)
+
+// Reminder is an occasion of a contact coming up in Days days, on Date.
+// Years is the age turned or the years celebrated, 0 when the year of the
+// original date isn't known.
+type Reminder struct {
+	Entry    model.Entry `json:"entry"`
+	Occasion string      `json:"occasion"`
+	Date     string      `json:"date"`
+	Days     int         `json:"days"`
+	Years    int         `json:"years,omitempty"`
+	Message  string      `json:"message"`
+}
+
+// key identifies the reminder of one occurrence of an occasion, so it is
+// sent once.
+func (r Reminder) key() string {
+	return fmt.Sprintf("%d/%s/%s", r.Entry.ID, r.Occasion, r.Date)
+}
+
+// Upcoming returns the birthdays and anniversaries of entries within the
+// reminder period of each entry from today on, the soonest first: the
+// entry's RemindDays, or days when it has none. A limit above 0 looks that
+// many days ahead for every entry instead. Dates that don't parse, and
+// archived entries, are skipped.
+func Upcoming(entries []model.Entry, now time.Time, days, limit int) []Reminder {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var upcoming []Reminder
+	for _, entry := range entries {
+		if entry.ArchivedAt != nil {
+			continue
+		}
+
+		ahead := days
+		if entry.RemindDays > 0 {
+			ahead = entry.RemindDays
+		}
+
+		if limit > 0 {
+			ahead = limit
+		}
+
+		for _, occasion := range []struct{ name, date string }{{Birthday, entry.Birthday}, {Anniversary, entry.Anniversary}} {
+			if occasion.date == "" {
+				continue
+			}
+
+			year, month, day, err := model.ParseDate(occasion.date)
+			if err != nil {
+				continue
+			}
+
+			next := occurrence(today.Year(), month, day, today.Location())
+			if next.Before(today) {
+				next = occurrence(today.Year()+1, month, day, today.Location())
+			}
+
+			// Days are counted in calendar days, so a change to or from
+			// daylight saving time doesn't make one short.
+			in := int(next.Sub(today).Round(24*time.Hour) / (24 * time.Hour))
+			if in > ahead {
+				continue
+			}
+
+			reminder := Reminder{Entry: entry, Occasion: occasion.name, Date: next.Format(time.DateOnly), Days: in}
+			if year > 0 {
+				reminder.Years = next.Year() - year
+			}
+
+			reminder.Message = message(reminder)
+			upcoming = append(upcoming, reminder)
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool { return upcoming[i].Days < upcoming[j].Days })
+
+	return upcoming
+}
+
+// occurrence is month and day in year, with February 29 moved to the 28th in
+// years that don't have it.
+func occurrence(year int, month time.Month, day int, location *time.Location) time.Time {
+	t := time.Date(year, month, day, 0, 0, 0, 0, location)
+	if t.Month() != month {
+		t = time.Date(year, month, day-1, 0, 0, 0, 0, location)
+	}
+
+	return t
+}
+
+func message(r Reminder) string {
+	name := strings.TrimSpace(r.Entry.Name + " " + r.Entry.Surname)
+	occasion := i18n.T(r.Occasion)
+
+	var text string
+	switch r.Days {
+	case 0:
+		text = i18n.T("Today is the %s of %s", occasion, name)
+	case 1:
+		text = i18n.T("Tomorrow is the %s of %s", occasion, name)
+	default:
+		text = i18n.T("The %s of %s is in %d days, on %s", occasion, name, r.Days, r.Date)
+	}
+
+	if r.Years > 0 {
+		text += " " + i18n.T("(%d years)", r.Years)
+	}
+
+	return text
+}
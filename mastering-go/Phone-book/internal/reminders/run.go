@@ -0,0 +1,150 @@
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// checkInterval is how often Run looks for reminders to send. It only has
+// to notice that a day has started.
+const checkInterval = time.Hour
+
+// Run sends the reminders of store as they come due, checking right away
+// and then every hour until ctx is done. The reminders sent are recorded
+// in the JSON file at statePath, so a restarted daemon doesn't send them
+// again; with no path they are only remembered while Run runs. A reminder
+// whose every channel failed is tried again on the next check. Contacts who
+// may not be contacted, see model.Entry.MayContact, are not reminded of
+// unless cfg overrides their consent.
+func Run(ctx context.Context, store storage.Storage, cfg *config.Reminders, consentRequired bool, statePath string) {
+	days := cfg.DaysBefore
+	if days <= 0 {
+		days = DefaultDays
+	}
+
+	sent := loadState(statePath)
+	// withheld has the reminders left out for lack of consent, so each is
+	// only logged once.
+	withheld := make(map[string]bool)
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		entries, appErr := store.List(ctx)
+		if appErr != nil {
+			log.Printf("reminders: %s", appErr.Message)
+		}
+
+		changed := false
+		for _, reminder := range Upcoming(entries, time.Now(), days, 0) {
+			if sent[reminder.key()] {
+				continue
+			}
+
+			if !reminder.Entry.MayContact(consentRequired) && !cfg.OverrideConsent {
+				if !withheld[reminder.key()] {
+					log.Printf("reminders: entry %d did not agree to be contacted, not reminding of its %s", reminder.Entry.ID, reminder.Occasion)
+					withheld[reminder.key()] = true
+				}
+
+				continue
+			}
+
+			n, err := Send(ctx, cfg, reminder)
+			if err != nil {
+				log.Printf("reminders: failed to send the %s reminder of entry %d: %v", reminder.Occasion, reminder.Entry.ID, err)
+			}
+
+			if n > 0 {
+				sent[reminder.key()], changed = true, true
+			}
+		}
+
+		if changed {
+			if err := saveState(statePath, sent); err != nil {
+				log.Printf("reminders: cannot record the reminders sent: %v", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// state holds the keys of the reminders sent. A key ends in the day of its
+// occasion, so past ones can be dropped.
+type state map[string]bool
+
+func loadState(path string) state {
+	sent := make(state)
+	if path == "" {
+		return sent
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return sent
+	}
+
+	if err != nil {
+		log.Printf("reminders: cannot read the reminders sent: %v", err)
+		return sent
+	}
+
+	var keys []string
+	if err := json.Unmarshal(data, &keys); err != nil {
+		log.Printf("reminders: cannot read the reminders sent: %v", err)
+		return sent
+	}
+
+	for _, key := range keys {
+		sent[key] = true
+	}
+
+	return sent
+}
+
+// saveState writes the keys of sent, leaving out the occasions already past.
+func saveState(path string, sent state) error {
+	if path == "" {
+		return nil
+	}
+
+	today := time.Now().Format(time.DateOnly)
+
+	keys := []string{}
+	for key := range sent {
+		if day := key[len(key)-len(time.DateOnly):]; day >= today {
+			keys = append(keys, key)
+		}
+	}
+
+	data, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,305 @@
+// Package report writes a digest of what happened to a phone book over a
+// period, the entries added, edited and deleted, the duplicates among them
+// and the birthdays coming up, in Markdown or HTML for mailing to the people
+// sharing the book.
+package report
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/dedupe"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/i18n"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/reminders"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Formats are the formats Write can write.
+var Formats = []string{"md", "html"}
+
+// Digest is what happened to a phone book from Since to Until. Without a
+// History the changes come from the entries' UpdatedAt: deletions are not
+// known then, and an entry counts as added while it is at its first version.
+type Digest struct {
+	Since   time.Time
+	Until   time.Time
+	History bool
+	Added   []model.Entry
+	Edited  []model.Entry
+	Deleted []model.Entry
+	// Duplicates are the suspected duplicates involving an entry added or
+	// edited in the period, and OtherDuplicates counts the others.
+	Duplicates      []dedupe.Group
+	OtherDuplicates int
+	// Upcoming are the birthdays and anniversaries within as many days of
+	// Until as the period lasts.
+	Upcoming []reminders.Reminder
+}
+
+// Build puts together the digest of store for the period of length since
+// that ends now.
+func Build(ctx context.Context, store storage.Storage, since time.Duration, now time.Time) (*Digest, *model.PhoeBookError) {
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	digest := &Digest{Since: now.Add(-since), Until: now}
+
+	var events []model.Event
+	if history, ok := store.(storage.History); ok {
+		// Wrappers are History whatever they wrap; those wrapping a backend
+		// without one answer 501.
+		events, appErr = history.Events(ctx, 0)
+		if appErr != nil && appErr.StatusCode != http.StatusNotImplemented {
+			return nil, appErr
+		}
+
+		digest.History = appErr == nil
+	}
+
+	if digest.History {
+		digest.changes(events, entries)
+	} else {
+		for _, entry := range entries {
+			if entry.UpdatedAt == nil || entry.UpdatedAt.Before(digest.Since) || entry.UpdatedAt.After(now) {
+				continue
+			}
+
+			if entry.Version <= 1 {
+				digest.Added = append(digest.Added, entry)
+			} else {
+				digest.Edited = append(digest.Edited, entry)
+			}
+		}
+	}
+
+	changed := make(map[int64]bool)
+	for _, entry := range append(digest.Added, digest.Edited...) {
+		changed[entry.ID] = true
+	}
+
+	for _, group := range dedupe.Find(entries, dedupe.DefaultMinScore) {
+		involved := false
+		for _, entry := range group.Entries {
+			involved = involved || changed[entry.ID]
+		}
+
+		if involved {
+			digest.Duplicates = append(digest.Duplicates, group)
+		} else {
+			digest.OtherDuplicates++
+		}
+	}
+
+	days := int(since / (24 * time.Hour))
+	if days < 1 {
+		days = 1
+	}
+
+	digest.Upcoming = reminders.Upcoming(entries, now, days, days)
+
+	return digest, nil
+}
+
+// changes sorts the entries the events of the period changed into added,
+// edited and deleted by their net effect: an entry added and then edited
+// was added, and one added and then deleted is left out.
+func (d *Digest) changes(events []model.Event, entries []model.Entry) {
+	current := make(map[int64]model.Entry, len(entries))
+	for _, entry := range entries {
+		current[entry.ID] = entry
+	}
+
+	type change struct{ first, last string }
+	changes := make(map[int64]*change)
+	known := make(map[int64]model.Entry)
+	var order []int64
+
+	for _, event := range events {
+		if event.Type != model.EventInsert && event.Type != model.EventUpdate && event.Type != model.EventDelete {
+			continue
+		}
+
+		id := event.ID
+		if event.Entry != nil {
+			id = event.Entry.ID
+			known[id] = *event.Entry
+		}
+
+		if event.At.Before(d.Since) || event.At.After(d.Until) {
+			continue
+		}
+
+		c, ok := changes[id]
+		if !ok {
+			c = &change{first: event.Type}
+			changes[id] = c
+			order = append(order, id)
+		}
+
+		c.last = event.Type
+	}
+
+	for _, id := range order {
+		c := changes[id]
+		entry, exists := current[id]
+		switch {
+		case c.last == model.EventDelete && c.first != model.EventInsert:
+			deleted, ok := known[id]
+			if !ok {
+				deleted = model.Entry{ID: id}
+			}
+
+			d.Deleted = append(d.Deleted, deleted)
+		case !exists:
+		case c.first == model.EventInsert:
+			d.Added = append(d.Added, entry)
+		default:
+			d.Edited = append(d.Edited, entry)
+		}
+	}
+}
+
+// section is a part of a digest: a heading and its items, or what to say
+// when it has none.
+type section struct {
+	heading string
+	items   []string
+	empty   string
+}
+
+func (d *Digest) sections() []section {
+	entryItems := func(entries []model.Entry) []string {
+		sorted := append([]model.Entry(nil), entries...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+		items := make([]string, len(sorted))
+		for i, entry := range sorted {
+			items[i] = describe(entry)
+		}
+
+		return items
+	}
+
+	sections := []section{
+		{heading: i18n.T("Added (%d)", len(d.Added)), items: entryItems(d.Added), empty: i18n.T("No entries were added.")},
+		{heading: i18n.T("Edited (%d)", len(d.Edited)), items: entryItems(d.Edited), empty: i18n.T("No entries were edited.")},
+	}
+
+	deleted := section{heading: i18n.T("Deleted (%d)", len(d.Deleted)), items: entryItems(d.Deleted), empty: i18n.T("No entries were deleted.")}
+	if !d.History {
+		deleted = section{heading: i18n.T("Deleted"), empty: i18n.T("This book keeps no history, so deletions are not known.")}
+	}
+
+	duplicates := section{heading: i18n.T("Possible duplicates (%d)", len(d.Duplicates)), empty: i18n.T("None of the changes look like a duplicate.")}
+	for _, group := range d.Duplicates {
+		names := make([]string, len(group.Entries))
+		for i, entry := range group.Entries {
+			names[i] = fmt.Sprintf("%s (#%d)", name(entry), entry.ID)
+		}
+
+		duplicates.items = append(duplicates.items, i18n.T("%s, score %.2f: %s", strings.Join(names, " / "), group.Score, strings.Join(group.Reasons, ", ")))
+	}
+
+	if d.OtherDuplicates > 0 {
+		duplicates.items = append(duplicates.items, i18n.T("%d more groups of suspected duplicates were already in the book, see dedupe", d.OtherDuplicates))
+	}
+
+	upcoming := section{heading: i18n.T("Upcoming birthdays and anniversaries (%d)", len(d.Upcoming)), empty: i18n.T("No birthdays or anniversaries coming up.")}
+	for _, r := range d.Upcoming {
+		item := i18n.T("%s: the %s of %s", r.Date, i18n.T(r.Occasion), name(r.Entry))
+		if r.Years > 0 {
+			item += " " + i18n.T("(%d years)", r.Years)
+		}
+
+		upcoming.items = append(upcoming.items, item)
+	}
+
+	return append(sections, deleted, duplicates, upcoming)
+}
+
+// describe is an entry in a list of changes: its name, number, company and
+// ID.
+func describe(entry model.Entry) string {
+	parts := []string{name(entry)}
+	if entry.PhoneNumber != "" {
+		parts = append(parts, string(entry.PhoneNumber))
+	}
+
+	if entry.Company != "" {
+		parts = append(parts, entry.Company)
+	}
+
+	return strings.Join(parts, ", ") + " (#" + strconv.FormatInt(entry.ID, 10) + ")"
+}
+
+func name(entry model.Entry) string {
+	n := strings.TrimSpace(entry.Name + " " + entry.Surname)
+	if n == "" {
+		return i18n.T("entry %d", entry.ID)
+	}
+
+	return n
+}
+
+// Write writes digest in format, "md" for Markdown or "html" for an HTML
+// page that mail clients show as it is.
+func Write(w io.Writer, format string, digest *Digest) error {
+	title := i18n.T("Phone book report")
+	period := i18n.T("From %s to %s", digest.Since.Local().Format(time.DateTime), digest.Until.Local().Format(time.DateTime))
+
+	var b strings.Builder
+	switch format {
+	case "md":
+		fmt.Fprintf(&b, "# %s\n\n%s\n", title, period)
+		for _, s := range digest.sections() {
+			fmt.Fprintf(&b, "\n## %s\n\n", s.heading)
+			if len(s.items) == 0 {
+				fmt.Fprintf(&b, "%s\n", markdown(s.empty))
+			}
+
+			for _, item := range s.items {
+				fmt.Fprintf(&b, "- %s\n", markdown(item))
+			}
+		}
+
+	case "html":
+		fmt.Fprintf(&b, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n", html.EscapeString(title))
+		fmt.Fprintf(&b, "<h1>%s</h1>\n<p>%s</p>\n", html.EscapeString(title), html.EscapeString(period))
+		for _, s := range digest.sections() {
+			fmt.Fprintf(&b, "<h2>%s</h2>\n", html.EscapeString(s.heading))
+			if len(s.items) == 0 {
+				fmt.Fprintf(&b, "<p>%s</p>\n", html.EscapeString(s.empty))
+				continue
+			}
+
+			b.WriteString("<ul>\n")
+			for _, item := range s.items {
+				fmt.Fprintf(&b, "<li>%s</li>\n", html.EscapeString(item))
+			}
+
+			b.WriteString("</ul>\n")
+		}
+
+		b.WriteString("</body>\n</html>\n")
+
+	default:
+		return fmt.Errorf("unknown report format %q, use md or html", format)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+// markdown escapes the characters of s Markdown would take for formatting.
+var markdown = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`).Replace
@@ -0,0 +1,180 @@
+// Package retention purges entries that have not been modified, or have
+// been archived, for longer than the phone book is configured to keep them.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// ParseAge parses an age like "3y", "18m", "2w", "90d" or any Go duration
+// such as "36h". A year counts as 365 days and a month as 30.
+func ParseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "m": 30 * 24 * time.Hour, "y": 365 * 24 * time.Hour}
+
+	s = strings.TrimSpace(s)
+	for suffix, unit := range units {
+		if number, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.Atoi(number)
+			if err != nil || n <= 0 || n > math.MaxInt64/int(unit) {
+				break
+			}
+
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid age %q, use e.g. 3y, 18m, 2w or 90d", s)
+	}
+
+	return age, nil
+}
+
+// Report is the outcome of a purge.
+type Report struct {
+	// Total counts the entries of the book when the purge started.
+	Total int
+	// Expired are the entries not modified since the cutoff, or archived
+	// before it, which a dry run only lists.
+	Expired []model.Entry
+	// Unknown counts the entries kept because when they last changed is
+	// not known, see model.Entry.UpdatedAt.
+	Unknown int
+	// Purged counts the expired entries that were deleted.
+	Purged int
+}
+
+// Purge deletes the entries of store not modified for maxAge, or with dryRun
+// only reports them. Entries changed while the purge runs are left alone on
+// backends that can delete by version.
+func Purge(ctx context.Context, store storage.Storage, maxAge time.Duration, dryRun bool) (Report, *model.PhoeBookError) {
+	return purge(ctx, store, maxAge, false, dryRun)
+}
+
+// PurgeArchived is Purge for the entries archived for maxAge, whatever
+// changed in them since.
+func PurgeArchived(ctx context.Context, store storage.Storage, maxAge time.Duration, dryRun bool) (Report, *model.PhoeBookError) {
+	return purge(ctx, store, maxAge, true, dryRun)
+}
+
+func purge(ctx context.Context, store storage.Storage, maxAge time.Duration, archived, dryRun bool) (Report, *model.PhoeBookError) {
+	var report Report
+
+	entries, appErr := store.List(ctx)
+	if appErr != nil {
+		return report, appErr
+	}
+
+	report.Total = len(entries)
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		since := entry.UpdatedAt
+		if archived {
+			since = entry.ArchivedAt
+		}
+
+		switch {
+		case since == nil && !archived:
+			report.Unknown++
+		case since != nil && since.Before(cutoff):
+			report.Expired = append(report.Expired, entry)
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	versioned, _ := store.(storage.VersionedDeleter)
+	for _, entry := range report.Expired {
+		if ctx.Err() != nil {
+			return report, storage.ContextError(ctx)
+		}
+
+		appErr = storage.Unsupported("versioned deletes")
+		if versioned != nil {
+			appErr = versioned.DeleteVersion(ctx, entry.ID, entry.Version)
+		}
+
+		if appErr != nil && appErr.StatusCode == http.StatusNotImplemented {
+			appErr = store.Delete(ctx, entry.ID)
+		}
+
+		// Entries deleted or changed in the meantime no longer need purging.
+		if appErr != nil && appErr.StatusCode != http.StatusNotFound && appErr.StatusCode != http.StatusConflict {
+			return report, appErr
+		}
+
+		if appErr == nil {
+			report.Purged++
+		}
+	}
+
+	return report, nil
+}
+
+// Policy is what Run purges: the entries not modified for MaxAge and those
+// archived for ArchivedMaxAge, none when it is 0. TooMany tells whether
+// purging expired of the total entries at once is more than allowed, like
+// config.Config.TooDestructive: Run then skips the purge and logs it, so a
+// wrong clock or a mistyped age doesn't empty the book.
+type Policy struct {
+	MaxAge         time.Duration
+	ArchivedMaxAge time.Duration
+	TooMany        func(expired, total int) bool
+}
+
+// Run purges store by policy right away and then every interval until ctx
+// is done, logging what it did, for servers enforcing a retention policy on
+// their own.
+func Run(ctx context.Context, store storage.Storage, policy Policy, interval time.Duration) {
+	for {
+		if policy.MaxAge > 0 {
+			run(ctx, store, policy, Purge, policy.MaxAge, "not modified")
+		}
+
+		if policy.ArchivedMaxAge > 0 {
+			run(ctx, store, policy, PurgeArchived, policy.ArchivedMaxAge, "archived")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// run runs one purge of Run, which is checked against policy.TooMany with a
+// dry run first. what is which entries it purges for the log.
+func run(ctx context.Context, store storage.Storage, policy Policy, fn func(context.Context, storage.Storage, time.Duration, bool) (Report, *model.PhoeBookError), maxAge time.Duration, what string) {
+	days := int(maxAge.Hours() / 24)
+
+	report, appErr := fn(ctx, store, maxAge, true)
+	if appErr == nil && policy.TooMany != nil && policy.TooMany(len(report.Expired), report.Total) {
+		log.Printf("retention: not purging %d of the %d entries %s for %d days, more than allowed at once; check them with purge --dry-run", len(report.Expired), report.Total, what, days)
+		return
+	}
+
+	if appErr == nil {
+		report, appErr = fn(ctx, store, maxAge, false)
+	}
+
+	switch {
+	case appErr != nil && ctx.Err() == nil:
+		log.Printf("retention: purge failed: %s", appErr.Message)
+	case report.Purged > 0:
+		log.Printf("retention: purged %d entries %s for %d days", report.Purged, what, days)
+	}
+}
@@ -0,0 +1,17 @@
+package search
+
+func init() {
+	RegisterTransliteration(persian)
+}
+
+// persian covers the Persian alphabet and the Arabic letters that commonly
+// show up in Persian names.
+var persian = map[rune]string{
+	'ا': "", 'آ': "", 'أ': "", 'إ': "", 'ء': "", 'ئ': "", 'ؤ': "", 'ع': "",
+	'ب': "b", 'پ': "p", 'ت': "t", 'ث': "s", 'ج': "j", 'چ': "ch", 'ح': "h",
+	'خ': "kh", 'د': "d", 'ذ': "z", 'ر': "r", 'ز': "z", 'ژ': "zh", 'س': "s",
+	'ش': "sh", 'ص': "s", 'ض': "z", 'ط': "t", 'ظ': "z", 'غ': "gh", 'ف': "f",
+	'ق': "gh", 'ک': "k", 'ك': "k", 'گ': "g", 'ل': "l", 'م': "m", 'ن': "n",
+	'و': "", 'ه': "h", 'ة': "h", 'ی': "", 'ي': "", 'ى': "",
+	'\u200c': "",
+}
@@ -0,0 +1,347 @@
+// Package search finds phone book entries matching a free text term.
+//
+// Results are ranked: an exact match beats a transliterated one, then a
+// prefix, a substring and finally a fuzzy (misspelled) match. Names are
+// compared through a Latin consonant skeleton, so "Morteza" and "مرتضی" match
+// each other. Scripts other than Latin are mapped with transliteration
+// tables, see RegisterTransliteration.
+package search
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Scores of the different kinds of match. A fuzzy match loses a few points
+// for every edit beyond the first.
+const (
+	ScoreExact           = 100
+	ScoreTransliterated  = 90
+	ScorePrefix          = 75
+	ScoreSubstring       = 50
+	ScoreFuzzy           = 25
+	fuzzyPenaltyPerEdit  = 5
+	maxFuzzyEditDistance = 3
+)
+
+// Result is an entry matching a search together with how well it matched.
+// Fields names the entry fields ("name", "surname", "nickname", "phone") the
+// best match was found in.
+type Result struct {
+	Entry  model.Entry
+	Score  int
+	Fields []string
+}
+
+var (
+	tableMu sync.RWMutex
+	table   = make(map[rune]string)
+)
+
+// RegisterTransliteration adds the Latin spelling of the runes of a script.
+// Runes mapped to "" are dropped, which suits letters that only carry vowels.
+// Later registrations override earlier ones for the same rune.
+func RegisterTransliteration(letters map[rune]string) {
+	tableMu.Lock()
+	defer tableMu.Unlock()
+
+	for r, latin := range letters {
+		table[r] = latin
+	}
+}
+
+// Search returns the entries matching term, best matches first. Entries that
+// match equally well keep their original order.
+func Search(entries []model.Entry, term string) []Result {
+	return SearchIn(entries, term, "")
+}
+
+// SearchIn is Search comparing numbers without an international prefix as
+// numbers of region, or of phone.DefaultRegion when region is empty.
+func SearchIn(entries []model.Entry, term, region string) []Result {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return nil
+	}
+
+	key := Fold(term)
+
+	var results []Result
+	for _, entry := range entries {
+		if score, fields := score(entry, term, key, region); score > 0 {
+			results = append(results, Result{Entry: entry, Score: score, Fields: fields})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
+
+// Entries returns the entries of results.
+func Entries(results []Result) []model.Entry {
+	entries := make([]model.Entry, len(results))
+	for i, result := range results {
+		entries[i] = result.Entry
+	}
+
+	return entries
+}
+
+func score(entry model.Entry, term, key, region string) (int, []string) {
+	best, bestFields := phoneScore(entry.PhoneNumber, term, region), []string{"phone"}
+
+	lowerTerm := strings.ToLower(term)
+	candidates := []struct {
+		value  string
+		fields []string
+	}{
+		{entry.Name, []string{"name"}},
+		{entry.Surname, []string{"surname"}},
+		{entry.Name + " " + entry.Surname, []string{"name", "surname"}},
+		{entry.Nickname, []string{"nickname"}},
+		{entry.Nickname + " " + entry.Surname, []string{"nickname", "surname"}},
+	}
+
+	for _, candidate := range candidates {
+		field := strings.ToLower(strings.TrimSpace(candidate.value))
+		if field == "" {
+			continue
+		}
+
+		var fieldScore int
+		switch {
+		case field == lowerTerm:
+			fieldScore = ScoreExact
+		case key != "" && (!isLatin(field) || !isLatin(term)) && Fold(field) == key:
+			fieldScore = ScoreTransliterated
+		case strings.HasPrefix(field, lowerTerm):
+			fieldScore = ScorePrefix
+		case strings.Contains(field, lowerTerm):
+			fieldScore = ScoreSubstring
+		default:
+			fieldScore = fuzzyScore(field, lowerTerm)
+		}
+
+		if fieldScore > best {
+			best, bestFields = fieldScore, candidate.fields
+		}
+	}
+
+	return best, bestFields
+}
+
+func phoneScore(number model.PhoneNumber, term, region string) int {
+	if number.EqualIn(model.PhoneNumber(term), region) {
+		return ScoreExact
+	}
+
+	termDigits := digits(term)
+	if len(termDigits) < 3 || len(termDigits) < len(strings.TrimSpace(term))/2 {
+		return 0
+	}
+
+	// People type numbers with the country code or with the national trunk
+	// prefix, so compare against both forms.
+	candidates := []string{digits(string(number)), digits(string(number.CanonicalIn(region)))}
+	if national := number.NationalIn(region); national != "" {
+		candidates = append(candidates, national, "0"+national)
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, termDigits) {
+			return ScorePrefix
+		}
+	}
+
+	for _, candidate := range candidates {
+		if strings.Contains(candidate, termDigits) {
+			return ScoreSubstring
+		}
+	}
+
+	return 0
+}
+
+func fuzzyScore(field, term string) int {
+	allowed := min(maxFuzzyEditDistance, max(1, len([]rune(term))/3))
+
+	distance := Distance(field, term)
+	if distance > allowed {
+		return 0
+	}
+
+	return ScoreFuzzy - fuzzyPenaltyPerEdit*(distance-1)
+}
+
+// isLatin reports whether s is written in Latin script only. Folding two
+// Latin spellings onto their skeleton is too lossy to count as a
+// transliteration ("Doe" and "Doey" share one), so it is only used across
+// scripts.
+func isLatin(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
+
+// Distance is the Levenshtein edit distance between a and b, counted in
+// runes.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+// Fold reduces a name to the Latin consonant skeleton used for matching:
+// other scripts are transliterated, vowels are dropped and doubled letters
+// collapsed.
+func Fold(name string) string {
+	tableMu.RLock()
+	defer tableMu.RUnlock()
+
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(name)) {
+		if folded := foldWord(word); folded != "" {
+			words = append(words, folded)
+		}
+	}
+
+	return strings.Join(words, " ")
+}
+
+func foldWord(word string) string {
+	var latin strings.Builder
+	for i, r := range word {
+		if i == 0 {
+			if initial, ok := initials[r]; ok {
+				latin.WriteString(initial)
+				continue
+			}
+		}
+
+		if mapped, ok := table[r]; ok {
+			latin.WriteString(mapped)
+			continue
+		}
+
+		if unicode.IsLetter(r) {
+			latin.WriteRune(r)
+		}
+	}
+
+	spelled := latinSpellings.Replace(latin.String())
+
+	var skeleton []rune
+	for i, r := range spelled {
+		if strings.ContainsRune("aeiou", r) || (r == 'y' && i > 0) {
+			continue
+		}
+
+		if len(skeleton) > 0 && skeleton[len(skeleton)-1] == r {
+			continue
+		}
+
+		skeleton = append(skeleton, r)
+	}
+
+	return string(skeleton)
+}
+
+// latinSpellings unifies Latin letters that transliterations use
+// interchangeably.
+var latinSpellings = strings.NewReplacer("ch", "ch", "ck", "k", "c", "k", "q", "gh", "w", "v", "x", "ks")
+
+// initials are letters that read as consonants at the start of a word, but
+// only carry a vowel anywhere else.
+var initials = map[rune]string{
+	'و': "v",
+	'ی': "y",
+	'ي': "y",
+}
+
+// maxSuggestions is how many surnames Suggest offers at most.
+const maxSuggestions = 3
+
+// Suggest returns the existing surnames closest to term by edit distance,
+// closest first, for a "did you mean" hint after a search found nothing.
+// Surnames that need more edits than half the term are left out.
+func Suggest(entries []model.Entry, term string) []string {
+	term = strings.ToLower(strings.TrimSpace(term))
+	if term == "" {
+		return nil
+	}
+
+	allowed := max(len([]rune(term))/2, 1)
+
+	type suggestion struct {
+		surname  string
+		distance int
+	}
+
+	var suggestions []suggestion
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		surname := strings.TrimSpace(entry.Surname)
+		key := strings.ToLower(surname)
+		if surname == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		if distance := Distance(key, term); distance <= allowed {
+			suggestions = append(suggestions, suggestion{surname: surname, distance: distance})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].distance < suggestions[j].distance
+	})
+
+	surnames := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(suggestions) && i < maxSuggestions; i++ {
+		surnames = append(surnames, suggestions[i].surname)
+	}
+
+	return surnames
+}
@@ -0,0 +1,144 @@
+package search
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+var entries = []model.Entry{
+	{ID: 1, Name: "Morteza", Surname: "Shahrabi", PhoneNumber: "+989121234567"},
+	{ID: 2, Name: "مرتضی", Surname: "کریمی", PhoneNumber: "+989351112233"},
+	{ID: 3, Name: "Mortezaali", Surname: "Ahmadi", PhoneNumber: "+14155550100"},
+	{ID: 4, Name: "Ali", Surname: "Amorteza", Nickname: "Mori", PhoneNumber: "+442079460018"},
+	{ID: 5, Name: "Mortaza", Surname: "Rezaei", PhoneNumber: "+982112345678"},
+}
+
+func TestSearch(t *testing.T) {
+	for _, test := range []struct {
+		term   string
+		ids    []int64
+		scores []int
+		fields []string
+	}{
+		// Exact, transliterated, prefix, substring and fuzzy, in that
+		// order.
+		{term: "morteza", ids: []int64{1, 2, 3, 4, 5}, scores: []int{ScoreExact, ScoreTransliterated, ScorePrefix, ScoreSubstring, ScoreFuzzy}, fields: []string{"name"}},
+		{term: "مرتضی", ids: []int64{2, 1, 4, 5}, scores: []int{ScoreExact, ScoreTransliterated, ScoreTransliterated, ScoreTransliterated}},
+		{term: "Morteza Shahrabi", ids: []int64{1}, scores: []int{ScoreExact}, fields: []string{"name", "surname"}},
+		{term: "mort", ids: []int64{1, 3, 5, 4}, scores: []int{ScorePrefix, ScorePrefix, ScorePrefix, ScoreSubstring}},
+		{term: "mori", ids: []int64{4}, scores: []int{ScoreExact}, fields: []string{"nickname"}},
+		{term: "shahrab", ids: []int64{1}, scores: []int{ScorePrefix}, fields: []string{"surname"}},
+		// Numbers match in E.164, nationally and by a part.
+		{term: "09121234567", ids: []int64{1}, scores: []int{ScoreExact}, fields: []string{"phone"}},
+		{term: "0912", ids: []int64{1}, scores: []int{ScorePrefix}, fields: []string{"phone"}},
+		{term: "5550100", ids: []int64{3}, scores: []int{ScoreSubstring}, fields: []string{"phone"}},
+		{term: "xyzzy"},
+		{term: "  "},
+	} {
+		results := Search(entries, test.term)
+
+		var ids []int64
+		var scores []int
+		for _, result := range results {
+			ids = append(ids, result.Entry.ID)
+			scores = append(scores, result.Score)
+		}
+
+		if !slices.Equal(ids, test.ids) || !slices.Equal(scores, test.scores) {
+			t.Errorf("%q: got %v scored %v, want %v scored %v", test.term, ids, scores, test.ids, test.scores)
+			continue
+		}
+
+		if len(results) > 0 && test.fields != nil && !slices.Equal(results[0].Fields, test.fields) {
+			t.Errorf("%q: got the best match in %v, want %v", test.term, results[0].Fields, test.fields)
+		}
+	}
+}
+
+func TestFold(t *testing.T) {
+	for _, test := range []struct {
+		name, want string
+	}{
+		{"Morteza", "mrtz"},
+		{"مرتضی", "mrtz"},
+		{"Mohammad", "mhmd"},
+		{"محمد", "mhmd"},
+		{"Vahid", "vhd"},
+		{"وحید", "vhd"},
+		{"Yasaman", "ysmn"},
+		{"یاسمن", "ysmn"},
+		{"Ghasemi", "ghsm"},
+		{"Qasemi", "ghsm"},
+		{"  Ali   Rezaei ", "l rz"},
+		{"", ""},
+	} {
+		if got := Fold(test.name); got != test.want {
+			t.Errorf("Fold(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"ali", "", 3},
+		{"kitten", "sitting", 3},
+		{"morteza", "mortaza", 1},
+		// Counted in runes, not bytes.
+		{"علی", "علي", 1},
+	} {
+		if got := Distance(test.a, test.b); got != test.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	for _, test := range []struct {
+		term string
+		want []string
+	}{
+		{term: "Shahraby", want: []string{"Shahrabi"}},
+		{term: "rezai", want: []string{"Rezaei"}},
+		{term: "amadi", want: []string{"Ahmadi"}},
+		{term: "zzzzzzzz", want: []string{}},
+	} {
+		if got := Suggest(entries, test.term); !slices.Equal(got, test.want) {
+			t.Errorf("Suggest(%q) = %v, want %v", test.term, got, test.want)
+		}
+	}
+}
+
+// TestTokens checks that an entry a term matches literally has every token
+// of one of the term's sets, which the indexes rely on.
+func TestTokens(t *testing.T) {
+	for _, term := range []string{"morteza", "Shahrab", "ezaei", "mori", "0912123", "4155550", "9121234567"} {
+		sets := TermTokens(term)
+		if sets == nil {
+			t.Errorf("%q: got no tokens", term)
+			continue
+		}
+
+		for _, result := range Search(entries, term) {
+			if result.Score == ScoreTransliterated || result.Score <= ScoreFuzzy {
+				continue
+			}
+
+			tokens := Tokens(result.Entry)
+			if !slices.ContainsFunc(sets, func(set []string) bool {
+				return !slices.ContainsFunc(set, func(token string) bool { return !slices.Contains(tokens, token) })
+			}) {
+				t.Errorf("%q: entry %d has none of the token sets %v", term, result.Entry.ID, sets)
+			}
+		}
+	}
+
+	if sets := TermTokens("al"); sets != nil {
+		t.Errorf("got %v for a term too short to narrow the search down", sets)
+	}
+}
@@ -0,0 +1,78 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Tokens returns the index tokens of an entry: the trigrams of its lower
+// cased names and nickname and of its number in every form Search compares it in. An
+// entry matching a term literally contains every token of TermTokens(term).
+func Tokens(entry model.Entry) []string {
+	return TokensIn(entry, "")
+}
+
+// TokensIn is Tokens for SearchIn in region.
+func TokensIn(entry model.Entry, region string) []string {
+	seen := make(map[string]bool)
+	var tokens []string
+
+	add := func(s string) {
+		for _, token := range trigrams(s) {
+			if !seen[token] {
+				seen[token] = true
+				tokens = append(tokens, token)
+			}
+		}
+	}
+
+	add(strings.ToLower(entry.Name))
+	add(strings.ToLower(entry.Surname))
+	add(strings.ToLower(entry.Nickname))
+
+	add(digits(string(entry.PhoneNumber)))
+	add(digits(string(entry.PhoneNumber.CanonicalIn(region))))
+	if national := entry.PhoneNumber.NationalIn(region); national != "" {
+		add("0" + national)
+	}
+
+	return tokens
+}
+
+// TermTokens returns the token sets an entry may contain to match term
+// literally: it has to contain every token of at least one of them. Terms
+// made mostly of digits can match a number as well as a name, so they get a
+// set for each. TermTokens returns nil when the term is too short to narrow
+// the search down. Fuzzy and transliterated matches don't share tokens with
+// the term, so they are only found by scanning every entry.
+func TermTokens(term string) [][]string {
+	term = strings.TrimSpace(term)
+
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(term)) {
+		words = append(words, trigrams(word)...)
+	}
+
+	if len(words) == 0 {
+		return nil
+	}
+
+	sets := [][]string{words}
+	if termDigits := digits(term); len(termDigits) >= 3 && len(termDigits) >= len(term)/2 {
+		sets = append(sets, trigrams(termDigits))
+	}
+
+	return sets
+}
+
+func trigrams(s string) []string {
+	runes := []rune(s)
+
+	var grams []string
+	for i := 0; i+3 <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+3]))
+	}
+
+	return grams
+}
@@ -0,0 +1,20 @@
+// Package spam turns spam reports into a score call screening can act on.
+package spam
+
+import (
+	"math"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Score rates how likely a number is spam from 0 to 100. Every distinct
+// reporter halves the remaining doubt, so one report gives 50, two give 75
+// and repeated reports from the same person don't inflate the score.
+func Score(reports []model.SpamReport) int {
+	reporters := make(map[string]bool)
+	for _, report := range reports {
+		reporters[report.Reporter] = true
+	}
+
+	return int(math.Round(100 * (1 - math.Pow(0.5, float64(len(reporters))))))
+}
@@ -0,0 +1,125 @@
+// Package systemd implements the parts of the systemd service protocol a
+// server needs to run as a unit: socket activation, readiness notification
+// and logging to the journal. Without systemd they do nothing.
+package systemd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// listenFDsStart is the first file descriptor systemd passes sockets on.
+const listenFDsStart = 3
+
+// Listeners returns the sockets systemd passed to the process through
+// socket activation, in the order of the socket unit, or nil when it was
+// started without any.
+func Listeners() ([]net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return nil, nil
+	}
+
+	// The sockets are meant for this process, not for the ones it starts.
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, count)
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("socket %d passed by systemd: %v", fd, err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	return listeners, nil
+}
+
+// Notify sends state, e.g. "READY=1" once the server accepts requests, to
+// the service manager. It does nothing when the unit doesn't listen for
+// notifications, that is unless it has Type=notify.
+func Notify(state string) error {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return nil
+	}
+
+	// A leading @ names an abstract socket, which net handles the same way.
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// Priorities of journal messages, see sd-daemon(3).
+const (
+	priorityError = "<3>"
+	priorityInfo  = "<6>"
+)
+
+// errorMarkers are the words that make a log message an error in the
+// journal rather than information.
+var errorMarkers = [][]byte{[]byte("panic"), []byte("failed")}
+
+// Journal returns a writer for the standard logger that marks every line
+// with the priority the journal reads from it: errors for panics and
+// failures, information for everything else. Lines of a message spanning
+// several, like a stack trace, all get the priority of the message.
+func Journal(w io.Writer) io.Writer {
+	return &journal{w: w}
+}
+
+type journal struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write is given one message at a time by the log package.
+func (j *journal) Write(message []byte) (int, error) {
+	priority := []byte(priorityInfo)
+	for _, marker := range errorMarkers {
+		if bytes.Contains(message, marker) {
+			priority = []byte(priorityError)
+			break
+		}
+	}
+
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(message, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		out.Write(priority)
+		out.Write(line)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, err := j.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(message), nil
+}
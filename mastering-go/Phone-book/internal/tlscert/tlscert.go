@@ -0,0 +1,195 @@
+// Package tlscert provides the certificates the server serves HTTPS with:
+// one from files, picked up again when they are renewed, one from Let's
+// Encrypt, or a self-signed one for development.
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// selfSignedValidity is how long a generated certificate is valid. It is
+// generated again once it has less than a week left.
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// nextProtos offers HTTP/2 to clients that speak it.
+var nextProtos = []string{"h2", "http/1.1"}
+
+// Files returns a TLS config serving the certificate and key in the PEM
+// files certFile and keyFile. The files are read again when they change, so
+// a renewed certificate is served without a restart.
+func Files(certFile, keyFile string) (*tls.Config, error) {
+	f := &files{certFile: certFile, keyFile: keyFile}
+	if _, err := f.certificate(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{MinVersion: tls.VersionTLS12, NextProtos: nextProtos, GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return f.certificate()
+	}}, nil
+}
+
+type files struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// certificate returns the certificate of the files, loading it again when
+// either has been modified since.
+func (f *files) certificate() (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var latest time.Time
+	for _, path := range []string{f.certFile, f.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			if f.cert != nil {
+				return f.cert, nil
+			}
+
+			return nil, fmt.Errorf("cannot read TLS certificate: %v", err)
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	if f.cert != nil && latest.Equal(f.modTime) {
+		return f.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		// Halfway through a renewal the old certificate is still good.
+		if f.cert != nil {
+			return f.cert, nil
+		}
+
+		return nil, fmt.Errorf("cannot load TLS certificate: %v", err)
+	}
+
+	f.cert, f.modTime = &cert, latest
+	return f.cert, nil
+}
+
+// SelfSigned returns a TLS config serving a self-signed certificate for
+// localhost, its loopback addresses and the host's name. It is kept in dir,
+// so browsers asked to trust it once don't ask again on every start, and
+// generated when there is none yet or it is about to expire.
+func SelfSigned(dir string) (*tls.Config, error) {
+	certFile, keyFile := filepath.Join(dir, "self-signed.crt"), filepath.Join(dir, "self-signed.key")
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && time.Until(leaf.NotAfter) > 7*24*time.Hour {
+			return &tls.Config{MinVersion: tls.VersionTLS12, NextProtos: nextProtos, Certificates: []tls.Certificate{cert}}, nil
+		}
+	}
+
+	certPEM, keyPEM, err := generate()
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate a self-signed certificate: %v", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	// A certificate that cannot be kept is still served, it is only
+	// generated again next time.
+	if err := os.MkdirAll(dir, 0700); err == nil {
+		if err := os.WriteFile(keyFile, keyPEM, 0600); err == nil {
+			os.WriteFile(certFile, certPEM, 0644)
+		}
+	}
+
+	return &tls.Config{MinVersion: tls.VersionTLS12, NextProtos: nextProtos, Certificates: []tls.Certificate{cert}}, nil
+}
+
+// generate makes a self-signed certificate and its key, PEM encoded.
+func generate() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Phone book development"}, CommonName: "localhost"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	if hostname, err := os.Hostname(); err == nil && hostname != "localhost" {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM, nil
+}
+
+// ACME returns a TLS config getting certificates for hosts from Let's
+// Encrypt, and renewing them, as they are asked for. Certificates and the
+// account key are kept in cacheDir; email, when given, is where Let's Encrypt
+// writes about expiring certificates and account problems. Requests for any
+// other host are refused, so nobody can make the server ask for certificates
+// of names pointed at it.
+//
+// Let's Encrypt checks control of a host over TLS on port 443, or over plain
+// HTTP on port 80 through the handler challenge returns, which passes every
+// other request on to fallback.
+func ACME(hosts []string, cacheDir, email string) (config *tls.Config, challenge func(fallback http.Handler) http.Handler) {
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Email:      email,
+	}
+
+	config = manager.TLSConfig()
+	config.MinVersion = tls.VersionTLS12
+
+	return config, manager.HTTPHandler
+}
@@ -0,0 +1,422 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Storage wraps store so every operation is a span, named after the
+// operation and carrying the backend name. Like storage.ReadOnly it has all
+// the optional features: the ones store lacks read as empty and fail with
+// storage.Unsupported when they would change something.
+func Storage(store storage.Storage, backend string) storage.Storage {
+	return &traced{store: store, backend: backend}
+}
+
+type traced struct {
+	store   storage.Storage
+	backend string
+}
+
+func (t *traced) start(ctx context.Context, operation string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	attributes = append(attributes, attribute.String("phonebook.storage", t.backend))
+	return tracer.Start(ctx, "storage."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// end ends span, marking it failed for appErr.
+func end(span trace.Span, appErr *storage.Error) {
+	if appErr != nil {
+		span.SetStatus(codes.Error, appErr.Message)
+		span.SetAttributes(attribute.Int("phonebook.status_code", int(appErr.StatusCode)))
+	}
+
+	span.End()
+}
+
+func id(id int64) attribute.KeyValue {
+	return attribute.Int64("phonebook.entry.id", id)
+}
+
+func count(n int) attribute.KeyValue {
+	return attribute.Int("phonebook.entries", n)
+}
+
+func (t *traced) List(ctx context.Context) ([]storage.Entry, *storage.Error) {
+	ctx, span := t.start(ctx, "List")
+	entries, appErr := t.store.List(ctx)
+	span.SetAttributes(count(len(entries)))
+	end(span, appErr)
+
+	return entries, appErr
+}
+
+func (t *traced) Insert(ctx context.Context, entry *storage.Entry) (int64, *storage.Error) {
+	ctx, span := t.start(ctx, "Insert")
+	newID, appErr := t.store.Insert(ctx, entry)
+	span.SetAttributes(id(newID))
+	end(span, appErr)
+
+	return newID, appErr
+}
+
+func (t *traced) Delete(ctx context.Context, entryID int64) *storage.Error {
+	ctx, span := t.start(ctx, "Delete", id(entryID))
+	appErr := t.store.Delete(ctx, entryID)
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Close() error {
+	return t.store.Close()
+}
+
+func (t *traced) Update(ctx context.Context, entry *storage.Entry) *storage.Error {
+	ctx, span := t.start(ctx, "Update", id(entry.ID))
+
+	appErr := storage.Unsupported("updates")
+	if updater, ok := t.store.(storage.Updater); ok {
+		appErr = updater.Update(ctx, entry)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) DeleteVersion(ctx context.Context, entryID, version int64) *storage.Error {
+	ctx, span := t.start(ctx, "DeleteVersion", id(entryID), attribute.Int64("phonebook.entry.version", version))
+
+	appErr := storage.Unsupported("versioned deletes")
+	if deleter, ok := t.store.(storage.VersionedDeleter); ok {
+		appErr = deleter.DeleteVersion(ctx, entryID, version)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Block(ctx context.Context, number string) *storage.Error {
+	ctx, span := t.start(ctx, "Block")
+
+	appErr := storage.Unsupported("blocking numbers")
+	if blocklist, ok := t.store.(storage.Blocklist); ok {
+		appErr = blocklist.Block(ctx, number)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Unblock(ctx context.Context, number string) *storage.Error {
+	ctx, span := t.start(ctx, "Unblock")
+
+	appErr := storage.Unsupported("blocking numbers")
+	if blocklist, ok := t.store.(storage.Blocklist); ok {
+		appErr = blocklist.Unblock(ctx, number)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Blocked(ctx context.Context) ([]string, *storage.Error) {
+	ctx, span := t.start(ctx, "Blocked")
+
+	var numbers []string
+	var appErr *storage.Error
+	if blocklist, ok := t.store.(storage.Blocklist); ok {
+		numbers, appErr = blocklist.Blocked(ctx)
+	}
+
+	end(span, appErr)
+
+	return numbers, appErr
+}
+
+func (t *traced) ReportSpam(ctx context.Context, report storage.SpamReport) *storage.Error {
+	ctx, span := t.start(ctx, "ReportSpam")
+
+	appErr := storage.Unsupported("spam reports")
+	if spamReports, ok := t.store.(storage.SpamReports); ok {
+		appErr = spamReports.ReportSpam(ctx, report)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) SpamReports(ctx context.Context, number string) ([]storage.SpamReport, *storage.Error) {
+	ctx, span := t.start(ctx, "SpamReports")
+
+	var reports []storage.SpamReport
+	var appErr *storage.Error
+	if spamReports, ok := t.store.(storage.SpamReports); ok {
+		reports, appErr = spamReports.SpamReports(ctx, number)
+	}
+
+	end(span, appErr)
+
+	return reports, appErr
+}
+
+// EraseSpamReports has nothing to erase on backends without spam reports.
+func (t *traced) EraseSpamReports(ctx context.Context, number string) *storage.Error {
+	ctx, span := t.start(ctx, "EraseSpamReports")
+
+	var appErr *storage.Error
+	if eraser, ok := t.store.(storage.SpamEraser); ok {
+		appErr = eraser.EraseSpamReports(ctx, number)
+	} else if _, ok := t.store.(storage.SpamReports); ok {
+		appErr = storage.Unsupported("erasing spam reports")
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) LogCall(ctx context.Context, call storage.Call) *storage.Error {
+	ctx, span := t.start(ctx, "LogCall", id(call.EntryID))
+
+	appErr := storage.Unsupported("call logs")
+	if callLog, ok := t.store.(storage.CallLog); ok {
+		appErr = callLog.LogCall(ctx, call)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Calls(ctx context.Context, entryID int64) ([]storage.Call, *storage.Error) {
+	ctx, span := t.start(ctx, "Calls", id(entryID))
+
+	var calls []storage.Call
+	var appErr *storage.Error
+	if callLog, ok := t.store.(storage.CallLog); ok {
+		calls, appErr = callLog.Calls(ctx, entryID)
+	}
+
+	span.SetAttributes(attribute.Int("phonebook.calls", len(calls)))
+	end(span, appErr)
+
+	return calls, appErr
+}
+
+func (t *traced) EraseCalls(ctx context.Context, entryID int64) *storage.Error {
+	ctx, span := t.start(ctx, "EraseCalls", id(entryID))
+
+	appErr := storage.Unsupported("call logs")
+	if callLog, ok := t.store.(storage.CallLog); ok {
+		appErr = callLog.EraseCalls(ctx, entryID)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Link(ctx context.Context, link storage.Link) *storage.Error {
+	ctx, span := t.start(ctx, "Link", id(link.From), attribute.Int64("phonebook.link.to", link.To), attribute.String("phonebook.link.type", link.Type))
+
+	appErr := storage.Unsupported("links")
+	if relationships, ok := t.store.(storage.Relationships); ok {
+		appErr = relationships.Link(ctx, link)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Unlink(ctx context.Context, a, b int64) *storage.Error {
+	ctx, span := t.start(ctx, "Unlink", id(a), attribute.Int64("phonebook.link.to", b))
+
+	appErr := storage.Unsupported("links")
+	if relationships, ok := t.store.(storage.Relationships); ok {
+		appErr = relationships.Unlink(ctx, a, b)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Links(ctx context.Context, entryID int64) ([]storage.Link, *storage.Error) {
+	ctx, span := t.start(ctx, "Links", id(entryID))
+
+	var links []storage.Link
+	var appErr *storage.Error
+	if relationships, ok := t.store.(storage.Relationships); ok {
+		links, appErr = relationships.Links(ctx, entryID)
+	}
+
+	span.SetAttributes(attribute.Int("phonebook.links", len(links)))
+	end(span, appErr)
+
+	return links, appErr
+}
+
+func (t *traced) SetPhoto(ctx context.Context, entryID int64, photo []byte) *storage.Error {
+	ctx, span := t.start(ctx, "SetPhoto", id(entryID))
+
+	appErr := storage.Unsupported("photos")
+	if photos, ok := t.store.(storage.Photos); ok {
+		appErr = photos.SetPhoto(ctx, entryID, photo)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+func (t *traced) Photo(ctx context.Context, entryID int64) ([]byte, *storage.Error) {
+	ctx, span := t.start(ctx, "Photo", id(entryID))
+
+	var photo []byte
+	appErr := storage.Unsupported("photos")
+	if photos, ok := t.store.(storage.Photos); ok {
+		photo, appErr = photos.Photo(ctx, entryID)
+	}
+
+	end(span, appErr)
+
+	return photo, appErr
+}
+
+func (t *traced) Candidates(ctx context.Context, term string) ([]storage.Entry, bool, *storage.Error) {
+	ctx, span := t.start(ctx, "Candidates")
+
+	var entries []storage.Entry
+	var ok bool
+	var appErr *storage.Error
+	if idx, isIndex := t.store.(storage.Index); isIndex {
+		entries, ok, appErr = idx.Candidates(ctx, term)
+	}
+
+	span.SetAttributes(count(len(entries)), attribute.Bool("phonebook.index.used", ok))
+	end(span, appErr)
+
+	return entries, ok, appErr
+}
+
+func (t *traced) Begin(ctx context.Context) (storage.Tx, *storage.Error) {
+	ctx, span := t.start(ctx, "Begin")
+
+	var tx storage.Tx
+	appErr := storage.Unsupported("transactions")
+	if transactional, ok := t.store.(storage.Transactional); ok {
+		tx, appErr = transactional.Begin(ctx)
+	}
+
+	end(span, appErr)
+
+	return tx, appErr
+}
+
+func (t *traced) Fingerprint(ctx context.Context) (string, *storage.Error) {
+	if fingerprinter, ok := t.store.(storage.Fingerprinter); ok {
+		return fingerprinter.Fingerprint(ctx)
+	}
+
+	return "", storage.Unsupported("fingerprints")
+}
+
+func (t *traced) Health(ctx context.Context) []storage.HealthCheck {
+	ctx, span := t.start(ctx, "Health")
+	defer span.End()
+
+	return storage.Health(ctx, t.store)
+}
+
+func (t *traced) Inspect(ctx context.Context) (storage.Inspection, *storage.Error) {
+	if inspector, ok := t.store.(storage.Inspector); ok {
+		return inspector.Inspect(ctx)
+	}
+
+	return storage.Inspection{}, nil
+}
+
+func (t *traced) Events(ctx context.Context, entryID int64) ([]storage.Event, *storage.Error) {
+	ctx, span := t.start(ctx, "Events", id(entryID))
+
+	var events []storage.Event
+	appErr := storage.Unsupported("history")
+	if history, ok := t.store.(storage.History); ok {
+		events, appErr = history.Events(ctx, entryID)
+	}
+
+	span.SetAttributes(attribute.Int("phonebook.events", len(events)))
+	end(span, appErr)
+
+	return events, appErr
+}
+
+func (t *traced) EntriesAt(ctx context.Context, seq int64) ([]storage.Entry, *storage.Error) {
+	ctx, span := t.start(ctx, "EntriesAt", attribute.Int64("phonebook.event.seq", seq))
+
+	var entries []storage.Entry
+	appErr := storage.Unsupported("history")
+	if history, ok := t.store.(storage.History); ok {
+		entries, appErr = history.EntriesAt(ctx, seq)
+	}
+
+	span.SetAttributes(count(len(entries)))
+	end(span, appErr)
+
+	return entries, appErr
+}
+
+func (t *traced) Revert(ctx context.Context, seq int64, dryRun bool) ([]storage.Event, *storage.Error) {
+	ctx, span := t.start(ctx, "Revert", attribute.Int64("phonebook.event.seq", seq), attribute.Bool("phonebook.dry_run", dryRun))
+
+	var events []storage.Event
+	appErr := storage.Unsupported("history")
+	if history, ok := t.store.(storage.History); ok {
+		events, appErr = history.Revert(ctx, seq, dryRun)
+	}
+
+	span.SetAttributes(attribute.Int("phonebook.events", len(events)))
+	end(span, appErr)
+
+	return events, appErr
+}
+
+func (t *traced) LoadHistory(ctx context.Context, events []storage.Event) *storage.Error {
+	ctx, span := t.start(ctx, "LoadHistory", attribute.Int("phonebook.events", len(events)))
+
+	appErr := storage.Unsupported("loading a history")
+	if loader, ok := t.store.(storage.HistoryLoader); ok {
+		appErr = loader.LoadHistory(ctx, events)
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
+
+// EraseHistory has nothing to erase on backends without a history.
+func (t *traced) EraseHistory(ctx context.Context, entryID int64, number string) *storage.Error {
+	ctx, span := t.start(ctx, "EraseHistory", id(entryID))
+
+	var appErr *storage.Error
+	if eraser, ok := t.store.(storage.HistoryEraser); ok {
+		appErr = eraser.EraseHistory(ctx, entryID, number)
+	} else if _, ok := t.store.(storage.History); ok {
+		appErr = storage.Unsupported("erasing the history")
+	}
+
+	end(span, appErr)
+
+	return appErr
+}
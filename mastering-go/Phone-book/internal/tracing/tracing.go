@@ -0,0 +1,81 @@
+// Package tracing exports OpenTelemetry traces of the phone book, so a
+// slow request can be followed down to the storage operations it made.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/config"
+)
+
+// instrumentation names the spans of the phone book.
+const instrumentation = "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book"
+
+var tracer = otel.Tracer(instrumentation)
+
+// enabled is whether Setup started exporting traces.
+var enabled bool
+
+// Enabled reports whether traces are exported, so storage is only wrapped
+// in spans that go somewhere.
+func Enabled() bool {
+	return enabled
+}
+
+// Setup starts exporting traces over OTLP/HTTP when cfg is set or the
+// standard OTEL_EXPORTER_OTLP_ENDPOINT variables are. It returns the
+// function that flushes the traces left on shutdown, or nil when tracing is
+// off.
+func Setup(ctx context.Context, cfg *config.Tracing) (func(context.Context) error, error) {
+	if cfg == nil && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return nil, nil
+	}
+
+	if cfg == nil {
+		cfg = &config.Tracing{}
+	}
+
+	var options []otlptracehttp.Option
+	if cfg.Endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(cfg.Endpoint))
+	}
+
+	if cfg.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := cfg.ServiceName
+	if serviceName == "" {
+		serviceName = "phonebook"
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+
+	enabled = true
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,235 @@
+package upload
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("s3", openS3)
+}
+
+// s3Uploader puts objects into a bucket of Amazon S3 or of a compatible
+// service like MinIO, Ceph or Cloudflare R2, signing requests with AWS
+// Signature Version 4.
+type s3Uploader struct {
+	bucket   string
+	region   string
+	endpoint *url.URL
+	// pathStyle puts the bucket in the path of endpoint rather than in its
+	// host name, which compatible services without wildcard DNS need.
+	pathStyle bool
+
+	accessKey, secretKey, sessionToken string
+	client                             *http.Client
+}
+
+// openS3 reads s3://bucket/key?region=R&endpoint=URL. The credentials come
+// from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, the
+// region, unless given, from AWS_REGION or else us-east-1. With an endpoint
+// the bucket is addressed in the path.
+func openS3(destination *url.URL) (Uploader, error) {
+	if destination.Host == "" {
+		return nil, errors.New("no bucket, use s3://bucket/key")
+	}
+
+	s := &s3Uploader{
+		bucket:       destination.Host,
+		region:       destination.Query().Get("region"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 10 * time.Minute},
+	}
+
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+
+	if s.region == "" {
+		s.region = os.Getenv("AWS_REGION")
+	}
+
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+
+	endpoint := destination.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = "https://s3." + s.region + ".amazonaws.com"
+	} else {
+		s.pathStyle = true
+	}
+
+	var err error
+	if s.endpoint, err = url.Parse(endpoint); err != nil || s.endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+
+	return s, nil
+}
+
+func (s *s3Uploader) Upload(ctx context.Context, path string, content io.ReadSeeker) error {
+	key := strings.TrimPrefix(path, "/")
+	if key == "" {
+		return errors.New("no object key, use s3://bucket/key")
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, content)
+	if err != nil {
+		return err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	target := *s.endpoint
+	if s.pathStyle {
+		target.Path = strings.TrimSuffix(target.Path, "/") + "/" + s.bucket + "/" + key
+	} else {
+		target.Host = s.bucket + "." + target.Host
+		target.Path = "/" + key
+	}
+
+	// Keys are sent encoded the way they are signed.
+	target.RawPath = awsEscape(target.Path, false)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), io.NopCloser(content))
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = size
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(hash.Sum(nil)))
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+
+	s.sign(req, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: %s", resp.Status, s3Message(body))
+	}
+
+	return nil
+}
+
+// sign adds the X-Amz-Date and Authorization headers of Signature Version 4
+// to req, signing all of its headers. req has its X-Amz-Content-Sha256.
+func (s *s3Uploader) sign(req *http.Request, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(names, ";")
+	canonical := strings.Join([]string{
+		req.Method,
+		awsEscape(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{day, s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.accessKey+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(name, true)+"="+awsEscape(value, true))
+		}
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// awsEscape percent-encodes every byte of s but the unreserved characters of
+// RFC 3986 and, unless slashes is true, "/", the way signatures want it.
+func awsEscape(s string, slashes bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !slashes:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// s3Message returns the message of an S3 error response, or the response
+// itself when it has none.
+func s3Message(body []byte) string {
+	text := string(body)
+	if start := strings.Index(text, "<Message>"); start >= 0 {
+		if end := strings.Index(text[start:], "</Message>"); end >= 0 {
+			return text[start+len("<Message>") : start+end]
+		}
+	}
+
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,471 @@
+package upload
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func init() {
+	Register("sftp", openSFTP)
+}
+
+// sftpUploader writes files over SFTP, version 3 of the protocol as OpenSSH
+// speaks it.
+type sftpUploader struct {
+	addr        string
+	agentSocket string
+	config      *ssh.ClientConfig
+}
+
+// openSFTP reads sftp://user@host:port/path?key=FILE&known_hosts=FILE. The
+// user signs in with the keys of the ssh agent, with key, or else with the
+// unencrypted keys of ~/.ssh, and only with a password when the URL has
+// one. The host key must be in known_hosts, ~/.ssh/known_hosts by default,
+// as after connecting once with ssh. Paths starting with /~/ are relative
+// to the user's home directory.
+func openSFTP(destination *url.URL) (Uploader, error) {
+	if destination.Hostname() == "" {
+		return nil, errors.New("no host, use sftp://user@host/path")
+	}
+
+	home, _ := os.UserHomeDir()
+
+	name := destination.User.Username()
+	if name == "" {
+		current, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+
+		name = current.Username
+	}
+
+	knownHostsFile := destination.Query().Get("known_hosts")
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	hostKeys, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check the host key: %v", err)
+	}
+
+	var methods []ssh.AuthMethod
+	keyFiles := []string{destination.Query().Get("key")}
+	if keyFiles[0] == "" {
+		keyFiles = nil
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			keyFiles = append(keyFiles, filepath.Join(home, ".ssh", name))
+		}
+	}
+
+	var signers []ssh.Signer
+	for _, file := range keyFiles {
+		pem, err := os.ReadFile(file)
+		if errors.Is(err, os.ErrNotExist) && destination.Query().Get("key") == "" {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			if destination.Query().Get("key") != "" {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+
+			// Keys with a passphrase are left to the agent.
+			continue
+		}
+
+		signers = append(signers, signer)
+	}
+
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+
+	if password, ok := destination.User.Password(); ok {
+		methods = append(methods, ssh.Password(password))
+	}
+
+	agentSocket := os.Getenv("SSH_AUTH_SOCK")
+	if len(methods) == 0 && agentSocket == "" {
+		return nil, errors.New("no ssh agent, key or password to sign in with")
+	}
+
+	port := destination.Port()
+	if port == "" {
+		port = "22"
+	}
+
+	return &sftpUploader{
+		addr:        net.JoinHostPort(destination.Hostname(), port),
+		agentSocket: agentSocket,
+		config: &ssh.ClientConfig{
+			User:            name,
+			Auth:            methods,
+			HostKeyCallback: hostKeys,
+		},
+	}, nil
+}
+
+func (s *sftpUploader) Upload(ctx context.Context, name string, content io.ReadSeeker) error {
+	name = strings.TrimPrefix(name, "/~/")
+	if name == "" || strings.HasSuffix(name, "/") {
+		return errors.New("no file name, use sftp://user@host/path/file")
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	// Closing the connection interrupts the transfer when ctx is done.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	config := *s.config
+	if s.agentSocket != "" {
+		if agentConn, err := net.Dial("unix", s.agentSocket); err == nil {
+			defer agentConn.Close()
+			config.Auth = append([]ssh.AuthMethod{ssh.PublicKeysCallback(agent.NewClient(agentConn).Signers)}, config.Auth...)
+		}
+	}
+
+	sshConn, channels, requests, err := ssh.NewClientConn(conn, s.addr, &config)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	client := ssh.NewClient(sshConn, channels, requests)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+
+	defer session.Close()
+
+	in, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	out, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		return err
+	}
+
+	c := &sftpConn{w: in, r: out}
+	if err := c.init(); err != nil {
+		return err
+	}
+
+	err = c.put(name, content)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return err
+}
+
+// The SFTP packets and flags put uses, from draft-ietf-secsh-filexfer-02.
+const (
+	fxpInit     = 1
+	fxpVersion  = 2
+	fxpOpen     = 3
+	fxpClose    = 4
+	fxpWrite    = 6
+	fxpRemove   = 13
+	fxpMkdir    = 14
+	fxpRename   = 18
+	fxpStatus   = 101
+	fxpHandle   = 102
+	fxpExtended = 200
+
+	fxfWrite = 0x02
+	fxfCreat = 0x08
+	fxfTrunc = 0x10
+
+	fxOK = 0
+
+	// posixRename is the OpenSSH extension renaming over an existing file,
+	// which plain SSH_FXP_RENAME refuses to.
+	posixRename = "posix-rename@openssh.com"
+
+	// sftpChunk is how much a write packet carries, what every server
+	// accepts.
+	sftpChunk = 32 << 10
+)
+
+// sftpConn speaks SFTP over the streams of an ssh session, one request at a
+// time.
+type sftpConn struct {
+	w          io.Writer
+	r          io.Reader
+	id         uint32
+	extensions map[string]bool
+}
+
+func (c *sftpConn) init() error {
+	if err := c.send(fxpInit, uint32(3)); err != nil {
+		return err
+	}
+
+	kind, data, err := c.receive()
+	if err != nil {
+		return err
+	}
+
+	if kind != fxpVersion || len(data) < 4 {
+		return errors.New("the server does not speak sftp")
+	}
+
+	// The version is followed by the names and data of extensions.
+	c.extensions = make(map[string]bool)
+	for data = data[4:]; ; {
+		var name string
+		var ok bool
+		if name, data, ok = readString(data); !ok {
+			break
+		}
+
+		if _, data, ok = readString(data); !ok {
+			break
+		}
+
+		c.extensions[name] = true
+	}
+
+	return nil
+}
+
+// put writes content to a temporary file next to name and renames it, so
+// the file at name is never half written.
+func (c *sftpConn) put(name string, content io.Reader) error {
+	c.mkdirAll(path.Dir(name))
+
+	tmp := name + ".part"
+	handle, err := c.open(tmp)
+	if err != nil {
+		return fmt.Errorf("cannot create %s: %v", tmp, err)
+	}
+
+	var offset uint64
+	buf := make([]byte, sftpChunk)
+	for {
+		n, readErr := io.ReadFull(content, buf)
+		if n > 0 {
+			if err := c.request(fxpWrite, handle, offset, buf[:n]); err != nil {
+				c.request(fxpClose, handle)
+				c.request(fxpRemove, tmp)
+				return fmt.Errorf("cannot write %s: %v", tmp, err)
+			}
+
+			offset += uint64(n)
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if readErr != nil {
+			c.request(fxpClose, handle)
+			c.request(fxpRemove, tmp)
+			return readErr
+		}
+	}
+
+	if err := c.request(fxpClose, handle); err != nil {
+		c.request(fxpRemove, tmp)
+		return fmt.Errorf("cannot write %s: %v", tmp, err)
+	}
+
+	if c.extensions[posixRename] {
+		err = c.request(fxpExtended, posixRename, tmp, name)
+	} else {
+		c.request(fxpRemove, name)
+		err = c.request(fxpRename, tmp, name)
+	}
+
+	if err != nil {
+		c.request(fxpRemove, tmp)
+		return fmt.Errorf("cannot rename %s to %s: %v", tmp, name, err)
+	}
+
+	return nil
+}
+
+// mkdirAll creates dir and its parents, ignoring failures: most of them
+// exist, and a missing one makes the open fail anyway.
+func (c *sftpConn) mkdirAll(dir string) {
+	if dir == "." || dir == "/" || dir == "" {
+		return
+	}
+
+	c.mkdirAll(path.Dir(dir))
+	c.request(fxpMkdir, dir, uint32(0))
+}
+
+func (c *sftpConn) open(name string) (string, error) {
+	id, err := c.sendRequest(fxpOpen, name, uint32(fxfWrite|fxfCreat|fxfTrunc), uint32(0))
+	if err != nil {
+		return "", err
+	}
+
+	kind, data, err := c.reply(id)
+	if err != nil {
+		return "", err
+	}
+
+	if kind == fxpHandle {
+		if handle, _, ok := readString(data); ok {
+			return handle, nil
+		}
+	}
+
+	if kind == fxpStatus {
+		return "", statusError(data)
+	}
+
+	return "", errors.New("unexpected sftp reply")
+}
+
+// request sends a request answered with a status, and returns the error of
+// the status unless it is OK.
+func (c *sftpConn) request(kind byte, fields ...any) error {
+	id, err := c.sendRequest(kind, fields...)
+	if err != nil {
+		return err
+	}
+
+	replyKind, data, err := c.reply(id)
+	if err != nil {
+		return err
+	}
+
+	if replyKind != fxpStatus {
+		return errors.New("unexpected sftp reply")
+	}
+
+	return statusError(data)
+}
+
+func (c *sftpConn) sendRequest(kind byte, fields ...any) (uint32, error) {
+	c.id++
+	return c.id, c.send(kind, append([]any{c.id}, fields...)...)
+}
+
+// reply reads the reply to the request id, returning the rest of it after
+// the id.
+func (c *sftpConn) reply(id uint32) (byte, []byte, error) {
+	kind, data, err := c.receive()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(data) < 4 || binary.BigEndian.Uint32(data) != id {
+		return 0, nil, errors.New("unexpected sftp reply")
+	}
+
+	return kind, data[4:], nil
+}
+
+// send writes a packet of fields: uint32s, uint64s, and strings or []byte
+// encoded with their length.
+func (c *sftpConn) send(kind byte, fields ...any) error {
+	packet := []byte{0, 0, 0, 0, kind}
+	for _, field := range fields {
+		switch v := field.(type) {
+		case uint32:
+			packet = binary.BigEndian.AppendUint32(packet, v)
+		case uint64:
+			packet = binary.BigEndian.AppendUint64(packet, v)
+		case string:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		case []byte:
+			packet = binary.BigEndian.AppendUint32(packet, uint32(len(v)))
+			packet = append(packet, v...)
+		}
+	}
+
+	binary.BigEndian.PutUint32(packet, uint32(len(packet)-4))
+	_, err := c.w.Write(packet)
+
+	return err
+}
+
+func (c *sftpConn) receive() (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:4])
+	if length < 1 || length > 1<<20 {
+		return 0, nil, errors.New("damaged sftp reply")
+	}
+
+	data := make([]byte, length-1)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return 0, nil, err
+	}
+
+	return header[4], data, nil
+}
+
+// statusError returns the error of a status reply, nil when it is OK.
+func statusError(data []byte) error {
+	if len(data) < 4 {
+		return errors.New("damaged sftp reply")
+	}
+
+	code := binary.BigEndian.Uint32(data)
+	if code == fxOK {
+		return nil
+	}
+
+	if message, _, ok := readString(data[4:]); ok && message != "" {
+		return errors.New(message)
+	}
+
+	return fmt.Errorf("sftp error %d", code)
+}
+
+func readString(data []byte) (string, []byte, bool) {
+	if len(data) < 4 {
+		return "", data, false
+	}
+
+	n := binary.BigEndian.Uint32(data)
+	if uint64(n) > uint64(len(data)-4) {
+		return "", data, false
+	}
+
+	return string(data[4 : 4+n]), data[4+n:], true
+}
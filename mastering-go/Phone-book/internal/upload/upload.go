@@ -0,0 +1,106 @@
+// Package upload copies files to remote destinations given as URLs, like
+// s3://bucket/book.json or sftp://backup@host/srv/book.json, for offsite
+// copies of the phone book. Destinations are handled by the Uploader
+// registered for their scheme; s3 and sftp are built in.
+package upload
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"sync"
+)
+
+// Uploader writes files to one remote destination.
+type Uploader interface {
+	// Upload writes content to the file at path, replacing the file there
+	// if any. content may be read more than once, from its start.
+	Upload(ctx context.Context, path string, content io.ReadSeeker) error
+}
+
+// Factory returns the Uploader for destination, which has its scheme. It
+// checks the destination and the credentials it needs but doesn't connect.
+type Factory func(destination *url.URL) (Uploader, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes destinations with scheme uploaded with factory. It panics
+// if called twice with the same scheme or with a nil factory.
+func Register(scheme string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("upload: Register factory is nil")
+	}
+
+	if _, dup := factories[scheme]; dup {
+		panic("upload: Register called twice for scheme " + scheme)
+	}
+
+	factories[scheme] = factory
+}
+
+// Open returns the Uploader for destination and the path of the file there.
+func Open(destination string) (Uploader, string, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, "", err
+	}
+
+	factoriesMu.RLock()
+	factory, ok := factories[u.Scheme]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, "", fmt.Errorf("cannot upload to %q, use one of %v", u.Redacted(), Schemes())
+	}
+
+	uploader, err := factory(u)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %v", u.Redacted(), err)
+	}
+
+	return uploader, u.Path, nil
+}
+
+// Upload writes content to destination.
+func Upload(ctx context.Context, destination string, content io.ReadSeeker) error {
+	uploader, path, err := Open(destination)
+	if err != nil {
+		return err
+	}
+
+	return uploader.Upload(ctx, path, content)
+}
+
+// Schemes returns the sorted schemes that have an Uploader.
+func Schemes() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	schemes := make([]string, 0, len(factories))
+	for scheme := range factories {
+		schemes = append(schemes, scheme)
+	}
+
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// Redacted returns destination with its password, if any, replaced, for
+// logs and error messages.
+func Redacted(destination string) string {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return destination
+	}
+
+	return u.Redacted()
+}
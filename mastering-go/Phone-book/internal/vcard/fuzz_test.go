@@ -0,0 +1,42 @@
+package vcard
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// FuzzRead checks that Read returns an error or entries, and never panics,
+// and that the entries it reads can be written as vCards again.
+func FuzzRead(f *testing.F) {
+	for _, seed := range []string{
+		"BEGIN:VCARD\nVERSION:3.0\nN:Smith;John;;;\nTEL;TYPE=CELL:+1 415 555 0100\nEND:VCARD\n",
+		"BEGIN:VCARD\r\nVERSION:2.1\r\nN;ENCODING=QUOTED-PRINTABLE:=4A=6F=\r\n hn\r\nBDAY:1604-05-01\r\nX-APPLE-OMIT-YEAR:1604\r\nEND:VCARD\r\n",
+		"BEGIN:VCARD\nitem1.TEL;type=pref:0912\nitem1.X-ABLabel:work\nEND:VCARD",
+		"END:VCARD\nBEGIN:VCARD",
+		"",
+	} {
+		f.Add([]byte(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries, err := Read(bytes.NewReader(data))
+		if err != nil {
+			if entries != nil {
+				t.Fatalf("Read returned entries with the error %v", err)
+			}
+
+			return
+		}
+
+		if len(entries) == 0 {
+			t.Fatal("Read returned neither entries nor an error")
+		}
+
+		for _, entry := range entries {
+			if err := Write(io.Discard, entry, nil, nil); err != nil {
+				t.Fatalf("cannot write %+v: %v", entry, err)
+			}
+		}
+	})
+}
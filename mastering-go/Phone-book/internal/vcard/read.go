@@ -0,0 +1,289 @@
+package vcard
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// appleNoYear is the year Contacts.app writes birthdays whose year isn't
+// known with, marked by X-APPLE-OMIT-YEAR.
+const appleNoYear = "1604"
+
+// property is a content line: "item1.TEL;type=CELL:+1 555 0100" has the
+// group item1, the name TEL, the parameter type=CELL and the value.
+type property struct {
+	group, name string
+	params      map[string][]string
+	value       string
+}
+
+// Read reads the vCards of r, versions 2.1 to 4.0 as phones and Contacts.app
+// export them. An entry keeps one number, the first mobile one if any.
+// Contacts without a name or a number are returned too, for the caller to
+// report.
+func Read(r io.Reader) ([]model.Entry, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []model.Entry
+	var card []property
+	inCard := false
+	for _, line := range lines {
+		p, ok := parse(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VCARD"):
+			inCard, card = true, nil
+		case p.name == "END" && strings.EqualFold(p.value, "VCARD") && inCard:
+			entries = append(entries, toEntry(card))
+			inCard = false
+		case inCard:
+			card = append(card, p)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("no BEGIN:VCARD, not a vCard file")
+	}
+
+	return entries, nil
+}
+
+// unfold returns the content lines of r, with continuation lines, starting
+// with a space or tab, joined to the line before.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		// vCard 2.1 quoted-printable values go on after a soft line break.
+		if len(lines) > 0 && strings.HasSuffix(lines[len(lines)-1], "=") && strings.Contains(strings.ToUpper(lines[len(lines)-1]), "QUOTED-PRINTABLE") {
+			lines[len(lines)-1] = strings.TrimSuffix(lines[len(lines)-1], "=") + line
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func parse(line string) (property, bool) {
+	// The value starts at the first colon outside a quoted parameter.
+	quoted, at := false, -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			at = i
+			break
+		}
+	}
+
+	if at < 0 {
+		return property{}, false
+	}
+
+	p := property{value: line[at+1:], params: make(map[string][]string)}
+	parts := strings.Split(line[:at], ";")
+	p.name = strings.ToUpper(parts[0])
+	if group, name, ok := strings.Cut(p.name, "."); ok {
+		p.group, p.name = group, name
+	}
+
+	for _, param := range parts[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			// vCard 2.1 gives types bare: TEL;CELL:…
+			key, value = "TYPE", param
+		}
+
+		key = strings.ToUpper(key)
+		for _, v := range strings.Split(value, ",") {
+			p.params[key] = append(p.params[key], strings.Trim(v, `"`))
+		}
+	}
+
+	if hasParam(p, "ENCODING", "QUOTED-PRINTABLE") {
+		p.value = quotedPrintable(p.value)
+	}
+
+	return p, true
+}
+
+func hasParam(p property, key, value string) bool {
+	for _, v := range p.params[key] {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toEntry(card []property) model.Entry {
+	var entry model.Entry
+	var fullName, firstNumber, mobileNumber string
+
+	// Contacts.app labels properties with an X-ABLabel of the same group.
+	labels := make(map[string]string)
+	for _, p := range card {
+		if p.name == "X-ABLABEL" && p.group != "" {
+			labels[p.group] = p.value
+		}
+	}
+
+	for _, p := range card {
+		switch p.name {
+		case "N":
+			fields := splitValue(p.value)
+			entry.Surname = field(fields, 0)
+			entry.Name = strings.TrimSpace(field(fields, 1) + " " + field(fields, 2))
+		case "FN":
+			fullName = unescape(p.value)
+		case "NICKNAME":
+			entry.Nickname = field(splitValue(p.value), 0)
+		case "ORG":
+			entry.Company = field(splitValue(p.value), 0)
+		case "TITLE":
+			entry.Title = unescape(p.value)
+		case "TEL":
+			number := strings.TrimSpace(strings.TrimPrefix(unescape(p.value), "tel:"))
+			if number == "" {
+				continue
+			}
+
+			label := labels[p.group]
+			if mobileNumber == "" && (hasParam(p, "TYPE", "CELL") || strings.EqualFold(label, "_$!<Mobile>!$_") || strings.EqualFold(label, "iPhone")) {
+				mobileNumber = number
+			}
+
+			if firstNumber == "" {
+				firstNumber = number
+			}
+		case "BDAY":
+			entry.Birthday = day(p)
+		case "ANNIVERSARY":
+			entry.Anniversary = day(p)
+		case "X-ABDATE":
+			if strings.EqualFold(labels[p.group], "_$!<Anniversary>!$_") && entry.Anniversary == "" {
+				entry.Anniversary = day(p)
+			}
+		case "X-ANNIVERSARY":
+			if entry.Anniversary == "" {
+				entry.Anniversary = day(p)
+			}
+		}
+	}
+
+	entry.PhoneNumber = model.PhoneNumber(mobileNumber)
+	if entry.PhoneNumber == "" {
+		entry.PhoneNumber = model.PhoneNumber(firstNumber)
+	}
+
+	// Only the formatted name may be given, "Morteza Shahrabi".
+	if entry.Name == "" && entry.Surname == "" {
+		if i := strings.LastIndexByte(fullName, ' '); i > 0 {
+			entry.Name, entry.Surname = fullName[:i], fullName[i+1:]
+		} else {
+			entry.Name = fullName
+		}
+	}
+
+	// Companies are contacts of their own, with no person's name.
+	if entry.Name == "" && entry.Surname == "" {
+		entry.Name = entry.Company
+	}
+
+	return entry
+}
+
+// day returns the date of p as a day the phone book keeps, "1990-05-17" or
+// "05-17", or "" when it is none. vCards write days as 1990-05-17, 19900517,
+// --0517 or --05-17, possibly with a time after them.
+func day(p property) string {
+	value, _, _ := strings.Cut(strings.TrimSpace(p.value), "T")
+
+	noYear := strings.HasPrefix(value, "--")
+	value = strings.ReplaceAll(strings.TrimPrefix(value, "--"), "-", "")
+	switch {
+	case noYear && len(value) == 4:
+		value = value[:2] + "-" + value[2:]
+	case len(value) == 8:
+		if value[:4] == appleNoYear || hasParam(p, "X-APPLE-OMIT-YEAR", value[:4]) {
+			value = value[4:6] + "-" + value[6:]
+		} else {
+			value = value[:4] + "-" + value[4:6] + "-" + value[6:]
+		}
+	default:
+		return ""
+	}
+
+	if _, _, _, err := model.ParseDate(value); err != nil {
+		return ""
+	}
+
+	return value
+}
+
+// splitValue splits a structured value at the semicolons that are not
+// escaped, unescaping each field.
+func splitValue(value string) []string {
+	var fields []string
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value):
+			b.WriteByte('\\')
+			b.WriteByte(value[i+1])
+			i++
+		case value[i] == ';':
+			fields = append(fields, unescape(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+
+	return append(fields, unescape(b.String()))
+}
+
+func field(fields []string, i int) string {
+	if i < len(fields) {
+		return strings.TrimSpace(fields[i])
+	}
+
+	return ""
+}
+
+// unescape undoes escape.
+func unescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// quotedPrintable decodes the =XX escapes of a vCard 2.1 value, leaving it
+// as it is when it has none that are valid.
+func quotedPrintable(s string) string {
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(s)))
+	if err != nil {
+		return s
+	}
+
+	return string(decoded)
+}
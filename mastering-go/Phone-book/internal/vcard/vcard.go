@@ -0,0 +1,139 @@
+// Package vcard writes phone book entries as vCard 4.0 (RFC 6350), the
+// format phones and mail programs exchange contacts in, and reads them back
+// from the vCards of phones and Contacts.app.
+package vcard
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// maxLine is the length in bytes lines are folded at, without the line
+// break.
+const maxLine = 75
+
+// relatedTypes are the RELATED types of RFC 6350 the links are written as.
+var relatedTypes = map[string]string{
+	model.LinkSpouse:      "spouse",
+	model.LinkAssistant:   "agent",
+	model.LinkHousehold:   "co-resident",
+	model.LinkSameCompany: "co-worker",
+}
+
+// Write writes entry as a vCard, with photo, when not nil, embedded in it,
+// and the entries it is related to as RELATED properties naming them.
+func Write(w io.Writer, entry model.Entry, photo []byte, related []model.Relation) error {
+	bw := bufio.NewWriter(w)
+
+	line := func(name, value string) {
+		bw.WriteString(fold(name + ":" + value))
+	}
+
+	line("BEGIN", "VCARD")
+	line("VERSION", "4.0")
+	line("FN", escape(strings.TrimSpace(entry.Name+" "+entry.Surname)))
+	line("N", escape(entry.Surname)+";"+escape(entry.Name)+";;;")
+
+	if entry.Nickname != "" {
+		line("NICKNAME", escape(entry.Nickname))
+	}
+
+	if entry.PhoneNumber != "" {
+		line("TEL;VALUE=uri;TYPE=cell", "tel:"+string(entry.PhoneNumber))
+	}
+
+	if entry.Company != "" {
+		line("ORG", escape(entry.Company))
+	}
+
+	if entry.Title != "" {
+		line("TITLE", escape(entry.Title))
+	}
+
+	if date, ok := date(entry.Birthday); ok {
+		line("BDAY", date)
+	}
+
+	if date, ok := date(entry.Anniversary); ok {
+		line("ANNIVERSARY", date)
+	}
+
+	for _, r := range related {
+		kind := relatedTypes[r.Type]
+		if r.AssistantOf {
+			kind = "co-worker"
+		}
+
+		if kind == "" {
+			kind = "contact"
+		}
+
+		line("RELATED;TYPE="+kind+";VALUE=text", escape(strings.TrimSpace(r.Entry.Name+" "+r.Entry.Surname)))
+	}
+
+	if len(photo) > 0 {
+		line("PHOTO", "data:"+http.DetectContentType(photo)+";base64,"+base64.StdEncoding.EncodeToString(photo))
+	}
+
+	if entry.UpdatedAt != nil {
+		line("REV", entry.UpdatedAt.UTC().Format("20060102T150405Z"))
+	}
+
+	line("END", "VCARD")
+
+	return bw.Flush()
+}
+
+// date writes a birthday or anniversary as a vCard date, "--0517" when the
+// year isn't known.
+func date(s string) (string, bool) {
+	if s == "" {
+		return "", false
+	}
+
+	year, _, _, err := model.ParseDate(s)
+	if err != nil {
+		return "", false
+	}
+
+	value := strings.ReplaceAll(s, "-", "")
+	if year == 0 {
+		value = "--" + value
+	}
+
+	return value, true
+}
+
+// escape escapes the characters that separate values in a property.
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// fold breaks a content line into lines of at most maxLine bytes, each
+// continuation starting with a space, without splitting a UTF-8 character.
+func fold(s string) string {
+	var b strings.Builder
+	limit := maxLine
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxLine - 1
+	}
+
+	b.WriteString(s)
+	b.WriteString("\r\n")
+
+	return b.String()
+}
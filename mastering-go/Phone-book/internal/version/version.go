@@ -0,0 +1,75 @@
+// Package version tells which build of the phone book is running. Release
+// builds set its variables with the linker:
+//
+//	go build -ldflags "-X $PKG.Version=v1.4.0 -X $PKG.Commit=$(git rev-parse --short HEAD) -X $PKG.Date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+//
+// where PKG is the import path of this package. Builds without a Commit fall
+// back on the one the go command recorded in the binary when built from a
+// git checkout.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+var (
+	// Version is the release, "dev" for builds that are not one.
+	Version = "dev"
+	// Commit is the git commit the binary was built from.
+	Commit = ""
+	// Date is when the binary was built, in RFC 3339.
+	Date = ""
+)
+
+// Info is the build of the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build of the running binary.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, Date: Date, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+					if len(info.Commit) > 12 {
+						info.Commit = info.Commit[:12]
+					}
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true" && Commit == ""
+			}
+		}
+	}
+
+	return info
+}
+
+// String is the build on one line, e.g. "v1.4.0 (commit 81446d4, built
+// 2026-10-14T09:30:00Z, go1.22.5)".
+func (i Info) String() string {
+	s := i.Version + " ("
+	if i.Commit != "" {
+		commit := i.Commit
+		if i.Modified {
+			commit += "+modified"
+		}
+
+		s += fmt.Sprintf("commit %s, ", commit)
+	}
+
+	if i.Date != "" {
+		s += fmt.Sprintf("built %s, ", i.Date)
+	}
+
+	return s + i.GoVersion + ")"
+}
@@ -6,6 +6,27 @@ import (
 
 var METRICS_PORT = ":1234"
 
+// LookupCacheRequests counts the lookups answered from the lookup cache,
+// result "hit", and those the backend had to answer, result "miss". The hit
+// rate is the share of hits.
+var LookupCacheRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "phone_book",
+		Name:      "lookup_cache_requests_total",
+		Help:      "Lookups by whether the lookup cache answered them (hit) or the backend (miss).",
+	},
+	[]string{"result"},
+)
+
+// LookupCacheEntries is the number of lookups kept in the lookup caches.
+var LookupCacheEntries = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Namespace: "phone_book",
+		Name:      "lookup_cache_entries",
+		Help:      "Lookups kept in the lookup caches.",
+	},
+)
+
 func RegisterMetrics() []prometheus.Collector {
 	counterMetric := prometheus.NewCounter(
 		prometheus.CounterOpts{
@@ -37,5 +58,5 @@ func RegisterMetrics() []prometheus.Collector {
 
 	counterMetric.Add(5)
 
-	return []prometheus.Collector{summaryMetric, histogramMetric, gaugeMetric, counterMetric}
+	return []prometheus.Collector{summaryMetric, histogramMetric, gaugeMetric, counterMetric, LookupCacheRequests, LookupCacheEntries}
 }
@@ -0,0 +1,358 @@
+// Package middleware contains the HTTP middleware the phone book server is
+// built from. Embedders can use the same type to add their own layers when
+// mounting the phone book handlers into a larger mux.
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Middleware wraps a handler with extra behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with the given middleware. The first middleware is the
+// outermost one, so it sees the request first and the response last.
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+
+	return h
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Log levels of Logging: every request, the ones that failed, or only the
+// ones that failed on the server's side.
+const (
+	LogInfo  = "info"
+	LogWarn  = "warn"
+	LogError = "error"
+)
+
+// minLogStatus is the lowest status code Logging logs, set by SetLogLevel.
+var minLogStatus atomic.Int32
+
+// SetLogLevel changes which requests Logging logs from now on, "info" (or
+// "") for all of them, "warn" for those answered with a 4xx or 5xx status
+// and "error" for those answered with a 5xx status.
+func SetLogLevel(level string) error {
+	switch level {
+	case "", LogInfo:
+		minLogStatus.Store(0)
+	case LogWarn:
+		minLogStatus.Store(http.StatusBadRequest)
+	case LogError:
+		minLogStatus.Store(http.StatusInternalServerError)
+	default:
+		return fmt.Errorf("unknown log level %q, want %s, %s or %s", level, LogInfo, LogWarn, LogError)
+	}
+
+	return nil
+}
+
+// Logging logs every request with its status code and duration, or the
+// failed ones only at a higher log level.
+func Logging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, r)
+
+		if int32(recorder.status) >= minLogStatus.Load() {
+			log.Println("Serving:", r.Method, r.URL.Path, "from", r.Host, "status", recorder.status, "in", time.Since(start))
+		}
+	})
+}
+
+// Recovery turns a panicking handler into a 500 response instead of a
+// dropped connection.
+func Recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Printf("panic while serving %s: %v\n%s", r.URL.Path, err, debug.Stack())
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, "internal server error")
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type userKey struct{}
+
+// WithUser returns ctx telling the handlers who made the request, for
+// middleware that signs users in.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// User returns the user of WithUser, "" when nobody signed in.
+func User(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// Auth rejects requests that don't carry "Authorization: Bearer <token>"
+// with one of the given tokens.
+func Auth(tokens ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || !ValidToken(tokens, token) {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, "missing or invalid token")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidToken reports whether token is one of tokens, comparing it with every
+// one of them in constant time so the time taken doesn't tell how much of a
+// token a guess got right.
+func ValidToken(tokens []string, token string) bool {
+	valid := 0
+	for _, t := range tokens {
+		valid |= subtle.ConstantTimeCompare([]byte(t), []byte(token))
+	}
+
+	return valid == 1
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit allows every client IP perSecond requests per second on average
+// with bursts of up to burst requests.
+func RateLimit(perSecond float64, burst int) Middleware {
+	return NewLimiter(perSecond, burst).Middleware
+}
+
+// Limiter is the state of a rate limit whose rate can change while it is
+// in use, e.g. when the server reloads its config.
+type Limiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     int
+	buckets   map[string]*bucket
+	// swept is when the buckets were last swept of idle clients.
+	swept time.Time
+}
+
+// limiterSweep is how often a Limiter forgets the clients that stopped
+// sending requests.
+const limiterSweep = time.Minute
+
+// NewLimiter returns a limiter allowing every client IP perSecond requests
+// per second with bursts of up to burst requests. A rate of 0 allows
+// everything.
+func NewLimiter(perSecond float64, burst int) *Limiter {
+	return &Limiter{perSecond: perSecond, burst: burst, buckets: make(map[string]*bucket), swept: time.Now()}
+}
+
+// SetRate changes the rate of l. Clients start over with a full burst.
+func (l *Limiter) SetRate(perSecond float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.perSecond, l.burst = perSecond, burst
+	l.buckets = make(map[string]*bucket)
+}
+
+func (l *Limiter) allow(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.perSecond <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	if now.Sub(l.swept) >= limiterSweep {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[client] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.perSecond
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// sweep drops the buckets of clients idle long enough for them to be full
+// again, which a new bucket is as well, so the map only holds the clients
+// sending requests lately.
+func (l *Limiter) sweep(now time.Time) {
+	full := time.Duration(float64(l.burst) / l.perSecond * float64(time.Second))
+	for client, b := range l.buckets {
+		if now.Sub(b.last) >= full {
+			delete(l.buckets, client)
+		}
+	}
+
+	l.swept = now
+}
+
+// Middleware rejects the requests of clients over the rate of l with 429.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+
+		if !l.allow(client) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, "too many requests")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Defaults of CORSOptions: the methods and headers the API uses, and
+// preflight answers cached by browsers for ten minutes.
+var (
+	corsMethods        = []string{"GET", "POST", "PUT", "DELETE"}
+	corsHeaders        = []string{"Authorization", "Content-Type", "If-Match", "If-None-Match"}
+	corsExposedHeaders = []string{"ETag"}
+	corsMaxAge         = 10 * time.Minute
+)
+
+// CORSOptions configures CORSWith. Origins are like "https://app.example.com",
+// "*" for any. The other fields default to what the API needs when empty.
+// Credentials lets browsers send cookies, as a front end signed in through
+// OIDC needs.
+type CORSOptions struct {
+	Origins        []string
+	Methods        []string
+	Headers        []string
+	ExposedHeaders []string
+	Credentials    bool
+	MaxAge         time.Duration
+}
+
+// CORS allows the given origins ("*" for any) to call the API from a browser
+// and answers preflight requests directly.
+func CORS(origins ...string) Middleware {
+	return CORSWith(CORSOptions{Origins: origins})
+}
+
+// CORSWith is CORS with the methods, headers and caching of options.
+// Preflight requests are answered directly, with the allowed methods and
+// headers only when the origin and the method asked for are allowed;
+// otherwise the browser blocks the request itself.
+func CORSWith(options CORSOptions) Middleware {
+	methods := options.Methods
+	if len(methods) == 0 {
+		methods = corsMethods
+	}
+
+	headers := options.Headers
+	if len(headers) == 0 {
+		headers = corsHeaders
+	}
+
+	exposed := options.ExposedHeaders
+	if len(exposed) == 0 {
+		exposed = corsExposedHeaders
+	}
+
+	maxAge := options.MaxAge
+	if maxAge == 0 {
+		maxAge = corsMaxAge
+	}
+
+	allowMethods := strings.Join(append([]string{http.MethodOptions}, methods...), ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	exposeHeaders := strings.Join(exposed, ", ")
+	maxAgeSeconds := strconv.Itoa(int(maxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+
+			origin := r.Header.Get("Origin")
+			allowed := origin != "" && allowedOrigin(options.Origins, origin)
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+				if options.Credentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+			}
+
+			if !preflight {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			if allowed && slices.ContainsFunc(methods, func(method string) bool {
+				return strings.EqualFold(method, r.Header.Get("Access-Control-Request-Method"))
+			}) {
+				w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+				w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+				w.Header().Set("Access-Control-Max-Age", maxAgeSeconds)
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func allowedOrigin(origins []string, origin string) bool {
+	for _, allowed := range origins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,9 @@
+CREATE TABLE calls (
+    id bigint PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    entry_id bigint NOT NULL,
+    called_at timestamptz NOT NULL DEFAULT now(),
+    duration_seconds bigint NOT NULL DEFAULT 0,
+    note varchar(1000) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX calls_entry_id_idx ON calls (entry_id);
@@ -0,0 +1,3 @@
+ALTER TABLE phone_book ADD COLUMN birthday varchar(10) NOT NULL DEFAULT '';
+ALTER TABLE phone_book ADD COLUMN anniversary varchar(10) NOT NULL DEFAULT '';
+ALTER TABLE phone_book ADD COLUMN remind_days integer NOT NULL DEFAULT 0;
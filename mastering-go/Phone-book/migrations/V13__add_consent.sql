@@ -0,0 +1,4 @@
+ALTER TABLE phone_book ADD COLUMN consent varchar(3) NOT NULL DEFAULT '';
+ALTER TABLE phone_book ADD COLUMN consent_date varchar(10) NOT NULL DEFAULT '';
+ALTER TABLE phone_book ADD COLUMN preferred_channel varchar(16) NOT NULL DEFAULT '';
+ALTER TABLE phone_book ADD COLUMN access_notes text NOT NULL DEFAULT '';
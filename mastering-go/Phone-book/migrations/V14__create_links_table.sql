@@ -0,0 +1,8 @@
+CREATE TABLE links (
+    from_id bigint NOT NULL,
+    to_id bigint NOT NULL,
+    type varchar(20) NOT NULL,
+    PRIMARY KEY (from_id, to_id)
+);
+
+CREATE INDEX links_to_id_idx ON links (to_id);
@@ -0,0 +1 @@
+ALTER TABLE phone_book ALTER COLUMN phone_number TYPE varchar(16);
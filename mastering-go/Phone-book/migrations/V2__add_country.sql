@@ -0,0 +1 @@
+ALTER TABLE phone_book ADD COLUMN country varchar(2) NOT NULL DEFAULT '';
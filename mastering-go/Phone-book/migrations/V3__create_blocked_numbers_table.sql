@@ -0,0 +1,4 @@
+CREATE TABLE blocked_numbers (
+    number varchar(16) PRIMARY KEY,
+    blocked_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,9 @@
+CREATE TABLE spam_reports (
+    id bigint PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    number varchar(16) NOT NULL,
+    reason varchar(255) NOT NULL DEFAULT '',
+    reporter varchar(100) NOT NULL DEFAULT '',
+    reported_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX spam_reports_number_idx ON spam_reports (number);
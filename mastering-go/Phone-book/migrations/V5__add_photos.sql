@@ -0,0 +1,6 @@
+ALTER TABLE phone_book ADD COLUMN photo varchar(255) NOT NULL DEFAULT '';
+
+CREATE TABLE photos (
+    entry_id bigint PRIMARY KEY REFERENCES phone_book (id) ON DELETE CASCADE,
+    data bytea NOT NULL
+);
@@ -0,0 +1,2 @@
+ALTER TABLE phone_book ADD COLUMN company varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE phone_book ADD COLUMN title varchar(255) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE phone_book ADD COLUMN version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1 @@
+ALTER TABLE phone_book ADD COLUMN updated_at timestamptz;
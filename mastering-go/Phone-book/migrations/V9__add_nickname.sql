@@ -0,0 +1 @@
+ALTER TABLE phone_book ADD COLUMN nickname varchar(255) NOT NULL DEFAULT '';
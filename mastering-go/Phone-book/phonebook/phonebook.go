@@ -0,0 +1,239 @@
+// Package phonebook opens a phone book for use inside another program,
+// without the server or the command line around it:
+//
+//	book, err := phonebook.Open("contacts.csv")
+//	...
+//	defer book.Close()
+//	id, appErr := book.Insert(ctx, &phonebook.Entry{Name: "Ali", PhoneNumber: "0912 123 4567"})
+//
+// A Book keeps all of its settings itself, so a process can open as many as
+// it likes, each with its own backend and default region.
+package phonebook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	_ "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/csvfile"
+	_ "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/db"
+	_ "github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/eventlog"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/filter"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/search"
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// The types of a phone book, re-exported so programs outside this module can
+// name them.
+type (
+	Entry       = model.Entry
+	Error       = model.PhoeBookError
+	PhoneNumber = model.PhoneNumber
+)
+
+// Options are the settings of a Book besides its data source.
+type Options struct {
+	// Backend is the storage backend, one of storage.Backends(). When it
+	// is empty Open tells it from the data source: postgres for postgres://
+	// URLs, events for .events files, csvshards for directories, and csv
+	// otherwise.
+	Backend string
+	// Region is the ISO country code of numbers written without an
+	// international prefix, IR when it is empty.
+	Region string
+	// ReadOnly refuses every change to the book.
+	ReadOnly bool
+}
+
+// Book is an open phone book.
+type Book struct {
+	store  storage.Storage
+	region string
+}
+
+// Open opens the phone book at dsn, a data file, a directory of shards or a
+// database URL, with the default Options.
+func Open(dsn string) (*Book, error) {
+	return OpenWith(dsn, Options{})
+}
+
+// OpenWith opens the phone book at dsn with options.
+func OpenWith(dsn string, options Options) (*Book, error) {
+	if dsn == "" {
+		return nil, errors.New("the phone book needs a data source")
+	}
+
+	backend := options.Backend
+	if backend == "" {
+		backend = backendOf(dsn)
+	}
+
+	store, err := storage.Open(backend, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.ReadOnly {
+		store = storage.ReadOnly(store)
+	}
+
+	return New(store, options), nil
+}
+
+// New returns a Book on an open backend, for backends from other modules.
+// The Backend and ReadOnly of options are ignored.
+func New(store storage.Storage, options Options) *Book {
+	region := options.Region
+	if region == "" {
+		region = "IR"
+	}
+
+	return &Book{store: store, region: region}
+}
+
+// backendOf tells which backend serves dsn.
+func backendOf(dsn string) string {
+	path, _, _ := strings.Cut(dsn, "?")
+	switch {
+	case strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://"):
+		return "postgres"
+	case strings.HasSuffix(path, ".events"):
+		return "events"
+	case strings.HasSuffix(path, "/") || path != dsn:
+		return "csvshards"
+	}
+
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return "csvshards"
+	}
+
+	return "csv"
+}
+
+// Storage returns the backend of the book, for the optional interfaces of
+// package storage, like storage.Blocklist or storage.History.
+func (b *Book) Storage() storage.Storage {
+	return b.store
+}
+
+// Close closes the backend of the book.
+func (b *Book) Close() error {
+	return b.store.Close()
+}
+
+// ParseNumber converts a number as a person would type it into E.164, taking
+// numbers without an international prefix to be from the book's region.
+func (b *Book) ParseNumber(number string) (PhoneNumber, error) {
+	return model.ParsePhoneNumber(number, b.region)
+}
+
+// List returns the entries of the book that are not archived.
+func (b *Book) List(ctx context.Context) ([]Entry, *Error) {
+	entries, appErr := b.store.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	active := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ArchivedAt == nil {
+			active = append(active, entry)
+		}
+	}
+
+	return active, nil
+}
+
+// Get returns the entry with id.
+func (b *Book) Get(ctx context.Context, id int64) (*Entry, *Error) {
+	entries, appErr := b.store.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	for _, entry := range entries {
+		if entry.ID == id {
+			return &entry, nil
+		}
+	}
+
+	return nil, &Error{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+}
+
+// Filter returns the entries that are not archived matching the filter
+// expression, like "surname=Smith AND company~Acme".
+func (b *Book) Filter(ctx context.Context, expression string) ([]Entry, *Error) {
+	expr, err := filter.Parse(expression)
+	if err != nil {
+		return nil, &Error{Message: err.Error(), StatusCode: http.StatusBadRequest}
+	}
+
+	entries, appErr := b.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return filter.Apply(expr, entries), nil
+}
+
+// Search returns the entries that are not archived matching term, however
+// loosely, best matches first. Numbers without an international prefix are
+// compared as numbers of the book's region.
+func (b *Book) Search(ctx context.Context, term string) ([]Entry, *Error) {
+	entries, appErr := b.List(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return search.Entries(search.SearchIn(entries, term, b.region)), nil
+}
+
+// Insert adds entry to the book and returns its ID. Its number is stored in
+// E.164 when it parses, and its country is the number's.
+func (b *Book) Insert(ctx context.Context, entry *Entry) (int64, *Error) {
+	if appErr := b.prepare(entry); appErr != nil {
+		return 0, appErr
+	}
+
+	return b.store.Insert(ctx, entry)
+}
+
+// Update replaces the fields of the entry with entry.ID like Insert stores
+// them, if it still has entry.Version unless that is 0, and sets
+// entry.Version to its new version.
+func (b *Book) Update(ctx context.Context, entry *Entry) *Error {
+	updater, ok := b.store.(storage.Updater)
+	if !ok {
+		return storage.Unsupported("editing entries")
+	}
+
+	if appErr := b.prepare(entry); appErr != nil {
+		return appErr
+	}
+
+	return updater.Update(ctx, entry)
+}
+
+// Delete deletes the entry with id.
+func (b *Book) Delete(ctx context.Context, id int64) *Error {
+	return b.store.Delete(ctx, id)
+}
+
+// prepare checks the fields of entry that only take some values, see
+// Entry.Validate, and brings its number into its stored form.
+func (b *Book) prepare(entry *Entry) *Error {
+	if err := entry.Validate(); err != nil {
+		return &Error{Message: err.Error(), StatusCode: http.StatusBadRequest}
+	}
+
+	if number, err := b.ParseNumber(string(entry.PhoneNumber)); err == nil {
+		entry.PhoneNumber = number
+	}
+
+	entry.Country = entry.PhoneNumber.CountryIn(b.region)
+
+	return nil
+}
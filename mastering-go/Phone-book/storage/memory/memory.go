@@ -0,0 +1,469 @@
+// Package memory is a phone book backend keeping everything in memory, for
+// tests and for programs that only need a phone book while they run. It is
+// registered as "memory"; every Open returns a new, empty book and the data
+// source is ignored.
+//
+//	store := memory.New(storage.Entry{Name: "Ali", PhoneNumber: "+989121234567"})
+package memory
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+func init() {
+	storage.Register("memory", Open)
+}
+
+// Storage is a phone book in memory. It supports every optional interface
+// of package storage a file backend does: edits, versions, the blocklist,
+// spam reports, photos, the call log, links and transactions.
+type Storage struct {
+	mu      sync.Mutex
+	entries []storage.Entry
+	nextID  int64
+	// changes counts the changes made to the entries, for Fingerprint.
+	changes int64
+	blocked []string
+	reports []storage.SpamReport
+	photos  map[int64][]byte
+	calls   []storage.Call
+	links   []storage.Link
+}
+
+// Open returns a new, empty book.
+func Open(dsn string) (storage.Storage, error) {
+	return New(), nil
+}
+
+// New returns a book holding entries. They keep their IDs, the others get
+// theirs counting on from the highest, and a Version of 1 unless they have
+// one.
+func New(entries ...storage.Entry) *Storage {
+	s := &Storage{nextID: 1, photos: make(map[int64][]byte)}
+	for _, entry := range entries {
+		s.nextID = max(s.nextID, entry.ID+1)
+	}
+
+	for _, entry := range entries {
+		if entry.ID == 0 {
+			entry.ID = s.nextID
+			s.nextID++
+		}
+
+		if entry.Version == 0 {
+			entry.Version = 1
+		}
+
+		s.entries = append(s.entries, entry)
+	}
+
+	return s
+}
+
+func (s *Storage) List(ctx context.Context) ([]storage.Entry, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.entries), nil
+}
+
+func (s *Storage) Insert(ctx context.Context, entry *storage.Entry) (int64, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, s.newEntry(*entry))
+	s.changes++
+
+	return s.entries[len(s.entries)-1].ID, nil
+}
+
+// newEntry gives entry the next ID and its first version. The caller holds
+// s.mu.
+func (s *Storage) newEntry(entry storage.Entry) storage.Entry {
+	entry.ID = s.nextID
+	entry.Version = 1
+	entry.UpdatedAt = now()
+	s.nextID++
+
+	return entry
+}
+
+func (s *Storage) Update(ctx context.Context, entry *storage.Entry) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated, appErr := update(s.entries, *entry)
+	if appErr != nil {
+		return appErr
+	}
+
+	s.changes++
+	entry.Version = updated.Version
+	entry.UpdatedAt = updated.UpdatedAt
+
+	return nil
+}
+
+// update replaces the entry of entries with entry.ID by entry, keeping its
+// photo and counting the change, or fails with ConflictError if entry
+// expects another version.
+func update(entries []storage.Entry, entry storage.Entry) (storage.Entry, *storage.Error) {
+	i := indexOf(entries, entry.ID)
+	if i < 0 {
+		return storage.Entry{}, notFound()
+	}
+
+	if entry.Version != 0 && entry.Version != entries[i].Version {
+		return storage.Entry{}, storage.ConflictError()
+	}
+
+	entry.Photo = entries[i].Photo
+	entry.Version = entries[i].Version + 1
+	entry.UpdatedAt = now()
+	entries[i] = entry
+
+	return entry, nil
+}
+
+func (s *Storage) Delete(ctx context.Context, id int64) *storage.Error {
+	return s.DeleteVersion(ctx, id, 0)
+}
+
+// DeleteVersion deletes the entry with id, if it still has version or
+// version is 0.
+func (s *Storage) DeleteVersion(ctx context.Context, id, version int64) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, appErr := remove(s.entries, id, version)
+	if appErr != nil {
+		return appErr
+	}
+
+	s.entries = entries
+	s.changes++
+	delete(s.photos, id)
+
+	return nil
+}
+
+// remove returns entries without the one with id, or fails if it has
+// another version than version, unless that is 0.
+func remove(entries []storage.Entry, id, version int64) ([]storage.Entry, *storage.Error) {
+	i := indexOf(entries, id)
+	if i < 0 {
+		return nil, notFound()
+	}
+
+	if version != 0 && entries[i].Version != version {
+		return nil, storage.ConflictError()
+	}
+
+	return slices.Delete(entries, i, i+1), nil
+}
+
+func (s *Storage) Close() error {
+	return nil
+}
+
+// Fingerprint counts the changes made to the entries.
+func (s *Storage) Fingerprint(ctx context.Context) (string, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return strconv.FormatInt(s.changes, 10), nil
+}
+
+func (s *Storage) Block(ctx context.Context, number string) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !slices.Contains(s.blocked, number) {
+		s.blocked = append(s.blocked, number)
+	}
+
+	return nil
+}
+
+func (s *Storage) Unblock(ctx context.Context, number string) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.Index(s.blocked, number)
+	if i < 0 {
+		return &storage.Error{Message: "the number is not blocked", StatusCode: http.StatusNotFound}
+	}
+
+	s.blocked = slices.Delete(s.blocked, i, i+1)
+
+	return nil
+}
+
+func (s *Storage) Blocked(ctx context.Context) ([]string, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.blocked), nil
+}
+
+func (s *Storage) ReportSpam(ctx context.Context, report storage.SpamReport) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reports = append(s.reports, report)
+
+	return nil
+}
+
+func (s *Storage) SpamReports(ctx context.Context, number string) ([]storage.SpamReport, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var reports []storage.SpamReport
+	for _, report := range s.reports {
+		if number == "" || string(report.Number) == number {
+			reports = append(reports, report)
+		}
+	}
+
+	return reports, nil
+}
+
+func (s *Storage) EraseSpamReports(ctx context.Context, number string) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reports = slices.DeleteFunc(s.reports, func(report storage.SpamReport) bool {
+		return string(report.Number) == number
+	})
+
+	return nil
+}
+
+func (s *Storage) SetPhoto(ctx context.Context, id int64, photo []byte) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := indexOf(s.entries, id)
+	if i < 0 {
+		return notFound()
+	}
+
+	s.photos[id] = slices.Clone(photo)
+	s.entries[i].Photo = "stored"
+	s.changes++
+
+	return nil
+}
+
+func (s *Storage) Photo(ctx context.Context, id int64) ([]byte, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	photo, ok := s.photos[id]
+	if !ok {
+		return nil, &storage.Error{Message: "the entry has no photo", StatusCode: http.StatusNotFound}
+	}
+
+	return slices.Clone(photo), nil
+}
+
+func (s *Storage) LogCall(ctx context.Context, call storage.Call) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = append(s.calls, call)
+
+	return nil
+}
+
+func (s *Storage) Calls(ctx context.Context, id int64) ([]storage.Call, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []storage.Call
+	for _, call := range s.calls {
+		if id == 0 || call.EntryID == id {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls, nil
+}
+
+func (s *Storage) EraseCalls(ctx context.Context, id int64) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls = slices.DeleteFunc(s.calls, func(call storage.Call) bool {
+		return call.EntryID == id
+	})
+
+	return nil
+}
+
+func (s *Storage) Link(ctx context.Context, link storage.Link) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.links = append(slices.DeleteFunc(s.links, linked(link.From, link.To)), link)
+
+	return nil
+}
+
+func (s *Storage) Unlink(ctx context.Context, a, b int64) *storage.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := len(s.links)
+	if s.links = slices.DeleteFunc(s.links, linked(a, b)); len(s.links) == n {
+		return storage.NoLinkError()
+	}
+
+	return nil
+}
+
+func (s *Storage) Links(ctx context.Context, id int64) ([]storage.Link, *storage.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var links []storage.Link
+	for _, link := range s.links {
+		if id == 0 || link.From == id || link.To == id {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// linked matches the link between a and b, whichever way it goes.
+func linked(a, b int64) func(storage.Link) bool {
+	return func(link storage.Link) bool {
+		return link.From == a && link.To == b || link.From == b && link.To == a
+	}
+}
+
+// Begin starts a transaction. Other requests to the book wait until it ends.
+func (s *Storage) Begin(ctx context.Context) (storage.Tx, *storage.Error) {
+	s.mu.Lock()
+
+	return &memoryTx{s: s, entries: slices.Clone(s.entries), nextID: s.nextID}, nil
+}
+
+// memoryTx is a transaction on the book. It holds the book's lock from
+// Begin to Commit or Rollback and makes its changes on a copy of the
+// entries, which Commit puts in their place.
+type memoryTx struct {
+	s       *Storage
+	entries []storage.Entry
+	deleted []int64
+	// nextID is the ID the next insert gets. IDs of inserts that are rolled
+	// back are not reused.
+	nextID int64
+	done   bool
+}
+
+func (t *memoryTx) Insert(ctx context.Context, entry *storage.Entry) (int64, *storage.Error) {
+	if t.done {
+		return 0, storage.TxDone()
+	}
+
+	newEntry := *entry
+	newEntry.ID = t.nextID
+	newEntry.Version = 1
+	newEntry.UpdatedAt = now()
+	t.entries = append(t.entries, newEntry)
+	t.nextID++
+
+	return newEntry.ID, nil
+}
+
+func (t *memoryTx) Update(ctx context.Context, entry *storage.Entry) *storage.Error {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	updated, appErr := update(t.entries, *entry)
+	if appErr != nil {
+		return appErr
+	}
+
+	entry.Version = updated.Version
+	entry.UpdatedAt = updated.UpdatedAt
+
+	return nil
+}
+
+func (t *memoryTx) Delete(ctx context.Context, id int64) *storage.Error {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	entries, appErr := remove(t.entries, id, 0)
+	if appErr != nil {
+		return appErr
+	}
+
+	t.entries = entries
+	t.deleted = append(t.deleted, id)
+
+	return nil
+}
+
+func (t *memoryTx) Commit() *storage.Error {
+	if t.done {
+		return storage.TxDone()
+	}
+
+	t.done = true
+	defer t.s.mu.Unlock()
+
+	t.s.entries = t.entries
+	t.s.changes++
+	for _, id := range t.deleted {
+		delete(t.s.photos, id)
+	}
+
+	t.s.nextID = t.nextID
+
+	return nil
+}
+
+func (t *memoryTx) Rollback() *storage.Error {
+	if t.done {
+		return nil
+	}
+
+	t.done = true
+	t.s.nextID = t.nextID
+	t.s.mu.Unlock()
+
+	return nil
+}
+
+func indexOf(entries []storage.Entry, id int64) int {
+	for i, entry := range entries {
+		if entry.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func notFound() *storage.Error {
+	return &storage.Error{Message: "there is no record with given id", StatusCode: http.StatusNotFound}
+}
+
+func now() *time.Time {
+	t := time.Now().UTC().Truncate(time.Second)
+	return &t
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+)
+
+// ReadOnlyError is the error every mutating operation of a ReadOnly backend
+// returns.
+func ReadOnlyError() *Error {
+	return &Error{Message: "the phone book is read-only", StatusCode: http.StatusForbidden}
+}
+
+// ReadOnly wraps store so every operation that would change it fails with
+// ReadOnlyError before reaching the backend, e.g. to serve a reference copy
+// or data on a read-only file system. Reads pass through; optional features
+// the backend lacks read as empty rather than unsupported.
+func ReadOnly(store Storage) Storage {
+	return &readOnly{store}
+}
+
+type readOnly struct {
+	Storage
+}
+
+func (r *readOnly) Insert(ctx context.Context, entry *Entry) (int64, *Error) {
+	return 0, ReadOnlyError()
+}
+
+func (r *readOnly) Delete(ctx context.Context, id int64) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Update(ctx context.Context, entry *Entry) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) DeleteVersion(ctx context.Context, id, version int64) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Begin(ctx context.Context) (Tx, *Error) {
+	return nil, ReadOnlyError()
+}
+
+func (r *readOnly) Block(ctx context.Context, number string) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Unblock(ctx context.Context, number string) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Blocked(ctx context.Context) ([]string, *Error) {
+	if blocklist, ok := r.Storage.(Blocklist); ok {
+		return blocklist.Blocked(ctx)
+	}
+
+	return nil, nil
+}
+
+func (r *readOnly) ReportSpam(ctx context.Context, report SpamReport) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) SpamReports(ctx context.Context, number string) ([]SpamReport, *Error) {
+	if spamReports, ok := r.Storage.(SpamReports); ok {
+		return spamReports.SpamReports(ctx, number)
+	}
+
+	return nil, nil
+}
+
+func (r *readOnly) EraseSpamReports(ctx context.Context, number string) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) LogCall(ctx context.Context, call Call) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Calls(ctx context.Context, id int64) ([]Call, *Error) {
+	if callLog, ok := r.Storage.(CallLog); ok {
+		return callLog.Calls(ctx, id)
+	}
+
+	return nil, nil
+}
+
+func (r *readOnly) EraseCalls(ctx context.Context, id int64) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Link(ctx context.Context, link Link) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Unlink(ctx context.Context, a, b int64) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Links(ctx context.Context, id int64) ([]Link, *Error) {
+	if relationships, ok := r.Storage.(Relationships); ok {
+		return relationships.Links(ctx, id)
+	}
+
+	return nil, nil
+}
+
+func (r *readOnly) SetPhoto(ctx context.Context, id int64, photo []byte) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) Photo(ctx context.Context, id int64) ([]byte, *Error) {
+	if photos, ok := r.Storage.(Photos); ok {
+		return photos.Photo(ctx, id)
+	}
+
+	return nil, Unsupported("photos")
+}
+
+func (r *readOnly) Candidates(ctx context.Context, term string) ([]Entry, bool, *Error) {
+	if index, ok := r.Storage.(Index); ok {
+		return index.Candidates(ctx, term)
+	}
+
+	return nil, false, nil
+}
+
+func (r *readOnly) Fingerprint(ctx context.Context) (string, *Error) {
+	if fingerprinter, ok := r.Storage.(Fingerprinter); ok {
+		return fingerprinter.Fingerprint(ctx)
+	}
+
+	return "", Unsupported("fingerprints")
+}
+
+// Health leaves out the checks that only matter to writes, like whether the
+// data files can be written.
+func (r *readOnly) Health(ctx context.Context) []HealthCheck {
+	var checks []HealthCheck
+	for _, check := range Health(ctx, r.Storage) {
+		if !check.Write {
+			checks = append(checks, check)
+		}
+	}
+
+	return checks
+}
+
+func (r *readOnly) Inspect(ctx context.Context) (Inspection, *Error) {
+	if inspector, ok := r.Storage.(Inspector); ok {
+		return inspector.Inspect(ctx)
+	}
+
+	return Inspection{}, nil
+}
+
+func (r *readOnly) Events(ctx context.Context, id int64) ([]Event, *Error) {
+	if history, ok := r.Storage.(History); ok {
+		return history.Events(ctx, id)
+	}
+
+	return nil, Unsupported("history")
+}
+
+func (r *readOnly) EntriesAt(ctx context.Context, seq int64) ([]Entry, *Error) {
+	if history, ok := r.Storage.(History); ok {
+		return history.EntriesAt(ctx, seq)
+	}
+
+	return nil, Unsupported("history")
+}
+
+func (r *readOnly) Revert(ctx context.Context, seq int64, dryRun bool) ([]Event, *Error) {
+	return nil, ReadOnlyError()
+}
+
+func (r *readOnly) EraseHistory(ctx context.Context, id int64, number string) *Error {
+	return ReadOnlyError()
+}
+
+func (r *readOnly) LoadHistory(ctx context.Context, events []Event) *Error {
+	return ReadOnlyError()
+}
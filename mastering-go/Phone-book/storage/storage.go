@@ -0,0 +1,349 @@
+// Package storage defines the interface every phone book backend implements
+// and a registry that lets backends plug themselves in at compile time.
+//
+// A backend registers itself from an init function, the same way database/sql
+// drivers do, so enabling it is just a blank import:
+//
+//	import _ "example.com/phonebook-redis"
+//
+//	store, err := storage.Open("redis", "localhost:6379")
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/internal/model"
+)
+
+// Entry and Error are re-exported so backends living in other modules can
+// implement Storage without importing the internal model package.
+type (
+	Entry       = model.Entry
+	Error       = model.PhoeBookError
+	SpamReport  = model.SpamReport
+	Call        = model.Call
+	HealthCheck = model.HealthCheck
+	Inspection  = model.Inspection
+	Event       = model.Event
+	Link        = model.Link
+)
+
+// Storage is implemented by every phone book backend.
+type Storage interface {
+	List(ctx context.Context) ([]Entry, *Error)
+	Insert(ctx context.Context, entry *Entry) (int64, *Error)
+	Delete(ctx context.Context, id int64) *Error
+	Close() error
+}
+
+// Updater is implemented by backends whose entries can be edited in place.
+// Update replaces every field of the entry with entry.ID except its photo,
+// and sets entry.Version to the entry's new version. If entry.Version is not
+// zero, the stored entry must still have that version or Update fails with
+// ConflictError.
+type Updater interface {
+	Update(ctx context.Context, entry *Entry) *Error
+}
+
+// VersionedDeleter is implemented by backends that can delete an entry only
+// if it still has the given version, failing with ConflictError otherwise.
+type VersionedDeleter interface {
+	DeleteVersion(ctx context.Context, id, version int64) *Error
+}
+
+// ConflictError is the error an update or delete gets when the entry was
+// changed by someone else after the version it expected.
+func ConflictError() *Error {
+	return &Error{Message: "the entry was changed by someone else, reload it and try again", StatusCode: http.StatusConflict}
+}
+
+// Blocklist is implemented by backends that can keep a list of blocked
+// numbers next to the phone book. Numbers are passed in E.164 form.
+type Blocklist interface {
+	Block(ctx context.Context, number string) *Error
+	Unblock(ctx context.Context, number string) *Error
+	Blocked(ctx context.Context) ([]string, *Error)
+}
+
+// SpamReports is implemented by backends that can record numbers reported
+// as spam. Every report is kept, so reports from several users of a shared
+// server add up. SpamReports returns the reports about number, or every
+// report when number is "".
+type SpamReports interface {
+	ReportSpam(ctx context.Context, report SpamReport) *Error
+	SpamReports(ctx context.Context, number string) ([]SpamReport, *Error)
+}
+
+// SpamEraser is implemented by SpamReports backends that can delete every
+// report about a number, to erase a person's data on request.
+type SpamEraser interface {
+	EraseSpamReports(ctx context.Context, number string) *Error
+}
+
+// Photos is implemented by backends that can keep a photo per entry. Setting
+// a photo also updates the entry's Photo reference.
+type Photos interface {
+	SetPhoto(ctx context.Context, id int64, photo []byte) *Error
+	Photo(ctx context.Context, id int64) ([]byte, *Error)
+}
+
+// CallLog is implemented by backends that can keep a log of the calls made
+// with contacts. Calls returns the calls with the entry with id in the order
+// they were logged, or every call when id is 0. EraseCalls deletes the calls
+// with the entry with id.
+type CallLog interface {
+	LogCall(ctx context.Context, call Call) *Error
+	Calls(ctx context.Context, id int64) ([]Call, *Error)
+	EraseCalls(ctx context.Context, id int64) *Error
+}
+
+// Relationships is implemented by backends that can link entries to each
+// other. Two entries have at most one link: Link replaces the one they had,
+// whichever way it went. Unlink deletes the link between the entries with
+// ids a and b, either way, and Links returns the links of the entry with id,
+// either way, or every link when id is 0.
+type Relationships interface {
+	Link(ctx context.Context, link Link) *Error
+	Unlink(ctx context.Context, a, b int64) *Error
+	Links(ctx context.Context, id int64) ([]Link, *Error)
+}
+
+// NoLinkError is the error Unlink returns when the entries have no link.
+func NoLinkError() *Error {
+	return &Error{Message: "the entries are not linked", StatusCode: http.StatusNotFound}
+}
+
+// Index is implemented by backends that keep a full text index for big
+// books. Candidates returns the entries containing term literally, a superset
+// of what search would rank for it apart from fuzzy and transliterated
+// matches. ok is false when the index cannot help and List has to be scanned.
+type Index interface {
+	Candidates(ctx context.Context, term string) (entries []Entry, ok bool, err *Error)
+}
+
+// Fingerprinter is implemented by backends that can tell cheaply whether
+// their entries changed, e.g. from the size and modification time of their
+// files, so a copy of the entries kept in memory can be reused until then.
+// The fingerprint changes with every change, made through this process or
+// any other.
+type Fingerprinter interface {
+	Fingerprint(ctx context.Context) (string, *Error)
+}
+
+// HealthChecker is implemented by backends that can check what they depend
+// on, such as a database connection or data files that can still be
+// written, more cheaply than by listing the book. See Health.
+type HealthChecker interface {
+	Health(ctx context.Context) []HealthCheck
+}
+
+// Health checks that store can serve requests, with its own checks if it is
+// a HealthChecker and by listing it otherwise.
+func Health(ctx context.Context, store Storage) []HealthCheck {
+	if checker, ok := store.(HealthChecker); ok {
+		return checker.Health(ctx)
+	}
+
+	if _, appErr := store.List(ctx); appErr != nil {
+		return []HealthCheck{{Name: "storage", Status: model.HealthFailing, Detail: appErr.Message}}
+	}
+
+	return []HealthCheck{{Name: "storage", Status: model.HealthOK}}
+}
+
+// Inspector is implemented by backends that can describe what they keep
+// besides the entries, like their search indexes, to troubleshoot them.
+type Inspector interface {
+	Inspect(ctx context.Context) (Inspection, *Error)
+}
+
+// History is implemented by backends that record every change to the book
+// as an Event, like the event log. Events returns the events about the entry
+// with id, or every event when id is 0, oldest first. EntriesAt returns the
+// entries as they were right after the event seq, 0 being the empty book
+// before the first one. Revert takes the entries and the blocklist back to
+// how they were after seq by recording the events that undo the later ones,
+// and returns them; with dryRun it only returns them.
+type History interface {
+	Events(ctx context.Context, id int64) ([]Event, *Error)
+	EntriesAt(ctx context.Context, seq int64) ([]Entry, *Error)
+	Revert(ctx context.Context, seq int64, dryRun bool) ([]Event, *Error)
+}
+
+// HistoryEraser is implemented by History backends that can take a deleted
+// entry out of their history, to erase a person's data on request.
+// EraseHistory redacts the events with the data of the entry with id and,
+// unless number is "", those blocking or unblocking number.
+type HistoryEraser interface {
+	EraseHistory(ctx context.Context, id int64, number string) *Error
+}
+
+// HistoryLoader is implemented by History backends that can be given a
+// history, to restore a dump. LoadHistory fails unless the book has no
+// history yet, and then records events with their times, so the entries and
+// the blocklist become what they were after the last one.
+type HistoryLoader interface {
+	LoadHistory(ctx context.Context, events []Event) *Error
+}
+
+// Transactional is implemented by backends that can apply a batch of
+// changes atomically. See Batch for the usual way to use it.
+type Transactional interface {
+	Begin(ctx context.Context) (Tx, *Error)
+}
+
+// Tx is a batch of inserts, updates and deletes that Commit applies all
+// together, or that Rollback drops. Changes are not visible to other users of
+// the backend before Commit, and a Tx must always end with one of the two:
+// backends may hold locks until then. Update checks versions like Updater
+// does. Rollback after Commit does nothing, so it can be deferred.
+type Tx interface {
+	Insert(ctx context.Context, entry *Entry) (int64, *Error)
+	Update(ctx context.Context, entry *Entry) *Error
+	Delete(ctx context.Context, id int64) *Error
+	Commit() *Error
+	Rollback() *Error
+}
+
+// TxDone is the error a Tx returns when it is used after Commit or Rollback.
+func TxDone() *Error {
+	return &Error{Message: "the transaction has already been committed or rolled back", StatusCode: http.StatusInternalServerError}
+}
+
+// Batch runs fn in a transaction of store. The transaction is committed when
+// fn succeeds and rolled back when it fails or panics, so either all of its
+// changes are made or none.
+func Batch(ctx context.Context, store Storage, fn func(tx Tx) *Error) *Error {
+	transactional, ok := store.(Transactional)
+	if !ok {
+		return Unsupported("transactions")
+	}
+
+	tx, err := transactional.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Writer is what BatchOrEach makes its changes through: a Tx, or the backend
+// itself.
+type Writer interface {
+	Insert(ctx context.Context, entry *Entry) (int64, *Error)
+	Update(ctx context.Context, entry *Entry) *Error
+	Delete(ctx context.Context, id int64) *Error
+}
+
+// BatchOrEach runs fn like Batch on backends with transactions, and on the
+// others, whose Batch fails as unsupported before running anything, with
+// store itself, whose Update then fails if it isn't an Updater. atomic tells
+// which of the two it did: when it is false and fn fails, the changes fn made
+// before failing stay.
+func BatchOrEach(ctx context.Context, store Storage, fn func(w Writer) *Error) (atomic bool, err *Error) {
+	started := false
+	err = Batch(ctx, store, func(tx Tx) *Error {
+		started = true
+		return fn(tx)
+	})
+
+	if err == nil || started || err.StatusCode != http.StatusNotImplemented {
+		return true, err
+	}
+
+	return false, fn(direct{store})
+}
+
+// direct is a Writer that makes its changes straight away.
+type direct struct {
+	Storage
+}
+
+func (d direct) Update(ctx context.Context, entry *Entry) *Error {
+	updater, ok := d.Storage.(Updater)
+	if !ok {
+		return Unsupported("editing entries")
+	}
+
+	return updater.Update(ctx, entry)
+}
+
+// ContextError is the error of an operation given up because ctx was
+// canceled, e.g. by Ctrl-C, or ran past its deadline.
+func ContextError(ctx context.Context) *Error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &Error{Message: "the operation timed out", StatusCode: http.StatusGatewayTimeout}
+	}
+
+	return &Error{Message: "the operation was canceled", StatusCode: http.StatusServiceUnavailable}
+}
+
+// Unsupported is the error returned when the selected backend doesn't
+// implement an optional feature.
+func Unsupported(feature string) *Error {
+	return &Error{Message: fmt.Sprintf("the storage backend does not support %s", feature), StatusCode: http.StatusNotImplemented}
+}
+
+// Factory opens a backend from a backend specific data source name.
+type Factory func(dsn string) (Storage, error)
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+// Register makes a backend available under the given name. It panics if
+// called twice with the same name or with a nil factory.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+
+	if _, dup := factories[name]; dup {
+		panic("storage: Register called twice for backend " + name)
+	}
+
+	factories[name] = factory
+}
+
+// Open opens the backend registered under name.
+func Open(name, dsn string) (Storage, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown storage backend %q (forgotten import?)", name)
+	}
+
+	return factory(dsn)
+}
+
+// Backends returns the sorted names of the registered backends.
+func Backends() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,177 @@
+// Command gen writes the mocks of package storagemock, one per interface of
+// package storage, from the interfaces in storage.go:
+//
+//	go run ./internal/gen ../storage.go mocks.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"strings"
+)
+
+const header = `// Code generated by go run ./internal/gen; DO NOT EDIT.
+
+package storagemock
+
+import (
+	"context"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+`
+
+// param is a parameter or result of a method, with its type as it is
+// written in package storagemock.
+type param struct {
+	name string
+	typ  string
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatal("usage: gen <storage.go> <output>")
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, os.Args[1], nil, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	out.WriteString(header)
+
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range decl.Specs {
+			spec := spec.(*ast.TypeSpec)
+			iface, ok := spec.Type.(*ast.InterfaceType)
+			if ok && spec.Name.IsExported() {
+				writeMock(&out, spec.Name.Name, iface)
+			}
+		}
+	}
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatalf("formatting the mocks: %v\n%s", err, out.Bytes())
+	}
+
+	if err := os.WriteFile(os.Args[2], source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeMock writes the mock of the interface name: a struct with a Func
+// field per method, and the methods recording their calls and calling it.
+func writeMock(out *bytes.Buffer, name string, iface *ast.InterfaceType) {
+	type method struct {
+		name            string
+		params, results []param
+	}
+
+	var methods []method
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			log.Fatalf("%s embeds %s, mocks of embedded interfaces are not supported", name, types.ExprString(field.Type))
+		}
+
+		methods = append(methods, method{
+			name:    field.Names[0].Name,
+			params:  params(fn.Params, "arg"),
+			results: params(fn.Results, "result"),
+		})
+	}
+
+	fmt.Fprintf(out, "\n// %s mocks storage.%s. Each method records its call and returns what\n", name, name)
+	fmt.Fprintf(out, "// the field named after it returns, or zero values when that is nil.\n")
+	fmt.Fprintf(out, "type %s struct {\n\tRecorder\n\n", name)
+	for _, m := range methods {
+		fmt.Fprintf(out, "\t%sFunc func(%s) (%s)\n", m.name, join(m.params, false), join(m.results, false))
+	}
+	fmt.Fprintf(out, "}\n")
+
+	for _, m := range methods {
+		var args []string
+		for _, p := range m.params {
+			args = append(args, p.name)
+		}
+
+		fmt.Fprintf(out, "\nfunc (m *%s) %s(%s) (%s) {\n", name, m.name, join(m.params, true), join(m.results, true))
+		fmt.Fprintf(out, "\tm.record(%s)\n", strings.Join(append([]string{fmt.Sprintf("%q", m.name)}, args...), ", "))
+		fmt.Fprintf(out, "\tif m.%sFunc == nil {\n\t\treturn\n\t}\n\n", m.name)
+		fmt.Fprintf(out, "\treturn m.%sFunc(%s)\n}\n", m.name, strings.Join(args, ", "))
+	}
+}
+
+// params lists the fields of list, naming those without a name, or named
+// like the receiver, prefix and their position.
+func params(list *ast.FieldList, prefix string) []param {
+	if list == nil {
+		return nil
+	}
+
+	var params []param
+	for _, field := range list.List {
+		typ := types.ExprString(qualify(field.Type))
+		if len(field.Names) == 0 {
+			params = append(params, param{name: fmt.Sprintf("%s%d", prefix, len(params)), typ: typ})
+			continue
+		}
+
+		for _, name := range field.Names {
+			// m is the receiver of the mock's methods.
+			if name.Name == "m" || name.Name == "_" {
+				name = ast.NewIdent(fmt.Sprintf("%s%d", prefix, len(params)))
+			}
+
+			params = append(params, param{name: name.Name, typ: typ})
+		}
+	}
+
+	return params
+}
+
+// qualify returns expr with the exported types of package storage, like
+// Entry, written storage.Entry.
+func qualify(expr ast.Expr) ast.Expr {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if expr.IsExported() {
+			return &ast.SelectorExpr{X: ast.NewIdent("storage"), Sel: expr}
+		}
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: qualify(expr.X)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: expr.Len, Elt: qualify(expr.Elt)}
+	case *ast.MapType:
+		return &ast.MapType{Key: qualify(expr.Key), Value: qualify(expr.Value)}
+	}
+
+	return expr
+}
+
+func join(params []param, named bool) string {
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = p.typ
+		if named {
+			parts[i] = p.name + " " + p.typ
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,522 @@
+// Code generated by go run ./internal/gen; DO NOT EDIT.
+
+package storagemock
+
+import (
+	"context"
+
+	"github.com/morteza-shahrabi-farahani/golang-exercises/mastering-go/Phone-book/storage"
+)
+
+// Storage mocks storage.Storage. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Storage struct {
+	Recorder
+
+	ListFunc   func(context.Context) ([]storage.Entry, *storage.Error)
+	InsertFunc func(context.Context, *storage.Entry) (int64, *storage.Error)
+	DeleteFunc func(context.Context, int64) *storage.Error
+	CloseFunc  func() error
+}
+
+func (m *Storage) List(ctx context.Context) (result0 []storage.Entry, result1 *storage.Error) {
+	m.record("List", ctx)
+	if m.ListFunc == nil {
+		return
+	}
+
+	return m.ListFunc(ctx)
+}
+
+func (m *Storage) Insert(ctx context.Context, entry *storage.Entry) (result0 int64, result1 *storage.Error) {
+	m.record("Insert", ctx, entry)
+	if m.InsertFunc == nil {
+		return
+	}
+
+	return m.InsertFunc(ctx, entry)
+}
+
+func (m *Storage) Delete(ctx context.Context, id int64) (result0 *storage.Error) {
+	m.record("Delete", ctx, id)
+	if m.DeleteFunc == nil {
+		return
+	}
+
+	return m.DeleteFunc(ctx, id)
+}
+
+func (m *Storage) Close() (result0 error) {
+	m.record("Close")
+	if m.CloseFunc == nil {
+		return
+	}
+
+	return m.CloseFunc()
+}
+
+// Updater mocks storage.Updater. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Updater struct {
+	Recorder
+
+	UpdateFunc func(context.Context, *storage.Entry) *storage.Error
+}
+
+func (m *Updater) Update(ctx context.Context, entry *storage.Entry) (result0 *storage.Error) {
+	m.record("Update", ctx, entry)
+	if m.UpdateFunc == nil {
+		return
+	}
+
+	return m.UpdateFunc(ctx, entry)
+}
+
+// VersionedDeleter mocks storage.VersionedDeleter. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type VersionedDeleter struct {
+	Recorder
+
+	DeleteVersionFunc func(context.Context, int64, int64) *storage.Error
+}
+
+func (m *VersionedDeleter) DeleteVersion(ctx context.Context, id int64, version int64) (result0 *storage.Error) {
+	m.record("DeleteVersion", ctx, id, version)
+	if m.DeleteVersionFunc == nil {
+		return
+	}
+
+	return m.DeleteVersionFunc(ctx, id, version)
+}
+
+// Blocklist mocks storage.Blocklist. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Blocklist struct {
+	Recorder
+
+	BlockFunc   func(context.Context, string) *storage.Error
+	UnblockFunc func(context.Context, string) *storage.Error
+	BlockedFunc func(context.Context) ([]string, *storage.Error)
+}
+
+func (m *Blocklist) Block(ctx context.Context, number string) (result0 *storage.Error) {
+	m.record("Block", ctx, number)
+	if m.BlockFunc == nil {
+		return
+	}
+
+	return m.BlockFunc(ctx, number)
+}
+
+func (m *Blocklist) Unblock(ctx context.Context, number string) (result0 *storage.Error) {
+	m.record("Unblock", ctx, number)
+	if m.UnblockFunc == nil {
+		return
+	}
+
+	return m.UnblockFunc(ctx, number)
+}
+
+func (m *Blocklist) Blocked(ctx context.Context) (result0 []string, result1 *storage.Error) {
+	m.record("Blocked", ctx)
+	if m.BlockedFunc == nil {
+		return
+	}
+
+	return m.BlockedFunc(ctx)
+}
+
+// SpamReports mocks storage.SpamReports. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type SpamReports struct {
+	Recorder
+
+	ReportSpamFunc  func(context.Context, storage.SpamReport) *storage.Error
+	SpamReportsFunc func(context.Context, string) ([]storage.SpamReport, *storage.Error)
+}
+
+func (m *SpamReports) ReportSpam(ctx context.Context, report storage.SpamReport) (result0 *storage.Error) {
+	m.record("ReportSpam", ctx, report)
+	if m.ReportSpamFunc == nil {
+		return
+	}
+
+	return m.ReportSpamFunc(ctx, report)
+}
+
+func (m *SpamReports) SpamReports(ctx context.Context, number string) (result0 []storage.SpamReport, result1 *storage.Error) {
+	m.record("SpamReports", ctx, number)
+	if m.SpamReportsFunc == nil {
+		return
+	}
+
+	return m.SpamReportsFunc(ctx, number)
+}
+
+// SpamEraser mocks storage.SpamEraser. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type SpamEraser struct {
+	Recorder
+
+	EraseSpamReportsFunc func(context.Context, string) *storage.Error
+}
+
+func (m *SpamEraser) EraseSpamReports(ctx context.Context, number string) (result0 *storage.Error) {
+	m.record("EraseSpamReports", ctx, number)
+	if m.EraseSpamReportsFunc == nil {
+		return
+	}
+
+	return m.EraseSpamReportsFunc(ctx, number)
+}
+
+// Photos mocks storage.Photos. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Photos struct {
+	Recorder
+
+	SetPhotoFunc func(context.Context, int64, []byte) *storage.Error
+	PhotoFunc    func(context.Context, int64) ([]byte, *storage.Error)
+}
+
+func (m *Photos) SetPhoto(ctx context.Context, id int64, photo []byte) (result0 *storage.Error) {
+	m.record("SetPhoto", ctx, id, photo)
+	if m.SetPhotoFunc == nil {
+		return
+	}
+
+	return m.SetPhotoFunc(ctx, id, photo)
+}
+
+func (m *Photos) Photo(ctx context.Context, id int64) (result0 []byte, result1 *storage.Error) {
+	m.record("Photo", ctx, id)
+	if m.PhotoFunc == nil {
+		return
+	}
+
+	return m.PhotoFunc(ctx, id)
+}
+
+// CallLog mocks storage.CallLog. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type CallLog struct {
+	Recorder
+
+	LogCallFunc    func(context.Context, storage.Call) *storage.Error
+	CallsFunc      func(context.Context, int64) ([]storage.Call, *storage.Error)
+	EraseCallsFunc func(context.Context, int64) *storage.Error
+}
+
+func (m *CallLog) LogCall(ctx context.Context, call storage.Call) (result0 *storage.Error) {
+	m.record("LogCall", ctx, call)
+	if m.LogCallFunc == nil {
+		return
+	}
+
+	return m.LogCallFunc(ctx, call)
+}
+
+func (m *CallLog) Calls(ctx context.Context, id int64) (result0 []storage.Call, result1 *storage.Error) {
+	m.record("Calls", ctx, id)
+	if m.CallsFunc == nil {
+		return
+	}
+
+	return m.CallsFunc(ctx, id)
+}
+
+func (m *CallLog) EraseCalls(ctx context.Context, id int64) (result0 *storage.Error) {
+	m.record("EraseCalls", ctx, id)
+	if m.EraseCallsFunc == nil {
+		return
+	}
+
+	return m.EraseCallsFunc(ctx, id)
+}
+
+// Relationships mocks storage.Relationships. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Relationships struct {
+	Recorder
+
+	LinkFunc   func(context.Context, storage.Link) *storage.Error
+	UnlinkFunc func(context.Context, int64, int64) *storage.Error
+	LinksFunc  func(context.Context, int64) ([]storage.Link, *storage.Error)
+}
+
+func (m *Relationships) Link(ctx context.Context, link storage.Link) (result0 *storage.Error) {
+	m.record("Link", ctx, link)
+	if m.LinkFunc == nil {
+		return
+	}
+
+	return m.LinkFunc(ctx, link)
+}
+
+func (m *Relationships) Unlink(ctx context.Context, a int64, b int64) (result0 *storage.Error) {
+	m.record("Unlink", ctx, a, b)
+	if m.UnlinkFunc == nil {
+		return
+	}
+
+	return m.UnlinkFunc(ctx, a, b)
+}
+
+func (m *Relationships) Links(ctx context.Context, id int64) (result0 []storage.Link, result1 *storage.Error) {
+	m.record("Links", ctx, id)
+	if m.LinksFunc == nil {
+		return
+	}
+
+	return m.LinksFunc(ctx, id)
+}
+
+// Index mocks storage.Index. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Index struct {
+	Recorder
+
+	CandidatesFunc func(context.Context, string) ([]storage.Entry, bool, *storage.Error)
+}
+
+func (m *Index) Candidates(ctx context.Context, term string) (entries []storage.Entry, ok bool, err *storage.Error) {
+	m.record("Candidates", ctx, term)
+	if m.CandidatesFunc == nil {
+		return
+	}
+
+	return m.CandidatesFunc(ctx, term)
+}
+
+// Fingerprinter mocks storage.Fingerprinter. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Fingerprinter struct {
+	Recorder
+
+	FingerprintFunc func(context.Context) (string, *storage.Error)
+}
+
+func (m *Fingerprinter) Fingerprint(ctx context.Context) (result0 string, result1 *storage.Error) {
+	m.record("Fingerprint", ctx)
+	if m.FingerprintFunc == nil {
+		return
+	}
+
+	return m.FingerprintFunc(ctx)
+}
+
+// HealthChecker mocks storage.HealthChecker. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type HealthChecker struct {
+	Recorder
+
+	HealthFunc func(context.Context) []storage.HealthCheck
+}
+
+func (m *HealthChecker) Health(ctx context.Context) (result0 []storage.HealthCheck) {
+	m.record("Health", ctx)
+	if m.HealthFunc == nil {
+		return
+	}
+
+	return m.HealthFunc(ctx)
+}
+
+// Inspector mocks storage.Inspector. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Inspector struct {
+	Recorder
+
+	InspectFunc func(context.Context) (storage.Inspection, *storage.Error)
+}
+
+func (m *Inspector) Inspect(ctx context.Context) (result0 storage.Inspection, result1 *storage.Error) {
+	m.record("Inspect", ctx)
+	if m.InspectFunc == nil {
+		return
+	}
+
+	return m.InspectFunc(ctx)
+}
+
+// History mocks storage.History. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type History struct {
+	Recorder
+
+	EventsFunc    func(context.Context, int64) ([]storage.Event, *storage.Error)
+	EntriesAtFunc func(context.Context, int64) ([]storage.Entry, *storage.Error)
+	RevertFunc    func(context.Context, int64, bool) ([]storage.Event, *storage.Error)
+}
+
+func (m *History) Events(ctx context.Context, id int64) (result0 []storage.Event, result1 *storage.Error) {
+	m.record("Events", ctx, id)
+	if m.EventsFunc == nil {
+		return
+	}
+
+	return m.EventsFunc(ctx, id)
+}
+
+func (m *History) EntriesAt(ctx context.Context, seq int64) (result0 []storage.Entry, result1 *storage.Error) {
+	m.record("EntriesAt", ctx, seq)
+	if m.EntriesAtFunc == nil {
+		return
+	}
+
+	return m.EntriesAtFunc(ctx, seq)
+}
+
+func (m *History) Revert(ctx context.Context, seq int64, dryRun bool) (result0 []storage.Event, result1 *storage.Error) {
+	m.record("Revert", ctx, seq, dryRun)
+	if m.RevertFunc == nil {
+		return
+	}
+
+	return m.RevertFunc(ctx, seq, dryRun)
+}
+
+// HistoryEraser mocks storage.HistoryEraser. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type HistoryEraser struct {
+	Recorder
+
+	EraseHistoryFunc func(context.Context, int64, string) *storage.Error
+}
+
+func (m *HistoryEraser) EraseHistory(ctx context.Context, id int64, number string) (result0 *storage.Error) {
+	m.record("EraseHistory", ctx, id, number)
+	if m.EraseHistoryFunc == nil {
+		return
+	}
+
+	return m.EraseHistoryFunc(ctx, id, number)
+}
+
+// HistoryLoader mocks storage.HistoryLoader. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type HistoryLoader struct {
+	Recorder
+
+	LoadHistoryFunc func(context.Context, []storage.Event) *storage.Error
+}
+
+func (m *HistoryLoader) LoadHistory(ctx context.Context, events []storage.Event) (result0 *storage.Error) {
+	m.record("LoadHistory", ctx, events)
+	if m.LoadHistoryFunc == nil {
+		return
+	}
+
+	return m.LoadHistoryFunc(ctx, events)
+}
+
+// Transactional mocks storage.Transactional. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Transactional struct {
+	Recorder
+
+	BeginFunc func(context.Context) (storage.Tx, *storage.Error)
+}
+
+func (m *Transactional) Begin(ctx context.Context) (result0 storage.Tx, result1 *storage.Error) {
+	m.record("Begin", ctx)
+	if m.BeginFunc == nil {
+		return
+	}
+
+	return m.BeginFunc(ctx)
+}
+
+// Tx mocks storage.Tx. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Tx struct {
+	Recorder
+
+	InsertFunc   func(context.Context, *storage.Entry) (int64, *storage.Error)
+	UpdateFunc   func(context.Context, *storage.Entry) *storage.Error
+	DeleteFunc   func(context.Context, int64) *storage.Error
+	CommitFunc   func() *storage.Error
+	RollbackFunc func() *storage.Error
+}
+
+func (m *Tx) Insert(ctx context.Context, entry *storage.Entry) (result0 int64, result1 *storage.Error) {
+	m.record("Insert", ctx, entry)
+	if m.InsertFunc == nil {
+		return
+	}
+
+	return m.InsertFunc(ctx, entry)
+}
+
+func (m *Tx) Update(ctx context.Context, entry *storage.Entry) (result0 *storage.Error) {
+	m.record("Update", ctx, entry)
+	if m.UpdateFunc == nil {
+		return
+	}
+
+	return m.UpdateFunc(ctx, entry)
+}
+
+func (m *Tx) Delete(ctx context.Context, id int64) (result0 *storage.Error) {
+	m.record("Delete", ctx, id)
+	if m.DeleteFunc == nil {
+		return
+	}
+
+	return m.DeleteFunc(ctx, id)
+}
+
+func (m *Tx) Commit() (result0 *storage.Error) {
+	m.record("Commit")
+	if m.CommitFunc == nil {
+		return
+	}
+
+	return m.CommitFunc()
+}
+
+func (m *Tx) Rollback() (result0 *storage.Error) {
+	m.record("Rollback")
+	if m.RollbackFunc == nil {
+		return
+	}
+
+	return m.RollbackFunc()
+}
+
+// Writer mocks storage.Writer. Each method records its call and returns what
+// the field named after it returns, or zero values when that is nil.
+type Writer struct {
+	Recorder
+
+	InsertFunc func(context.Context, *storage.Entry) (int64, *storage.Error)
+	UpdateFunc func(context.Context, *storage.Entry) *storage.Error
+	DeleteFunc func(context.Context, int64) *storage.Error
+}
+
+func (m *Writer) Insert(ctx context.Context, entry *storage.Entry) (result0 int64, result1 *storage.Error) {
+	m.record("Insert", ctx, entry)
+	if m.InsertFunc == nil {
+		return
+	}
+
+	return m.InsertFunc(ctx, entry)
+}
+
+func (m *Writer) Update(ctx context.Context, entry *storage.Entry) (result0 *storage.Error) {
+	m.record("Update", ctx, entry)
+	if m.UpdateFunc == nil {
+		return
+	}
+
+	return m.UpdateFunc(ctx, entry)
+}
+
+func (m *Writer) Delete(ctx context.Context, id int64) (result0 *storage.Error) {
+	m.record("Delete", ctx, id)
+	if m.DeleteFunc == nil {
+		return
+	}
+
+	return m.DeleteFunc(ctx, id)
+}
@@ -0,0 +1,63 @@
+// Package storagemock has mocks of the interfaces of package storage, for
+// tests that need a backend to answer or fail in a certain way. Each mock has
+// a field per method, like ListFunc for List, and records the calls made to
+// it:
+//
+//	store := &storagemock.Storage{
+//		ListFunc: func(ctx context.Context) ([]storage.Entry, *storage.Error) {
+//			return nil, &storage.Error{Message: "down", StatusCode: 503}
+//		},
+//	}
+//	...
+//	if store.Called("List") != 1 { ... }
+//
+// A mock only implements one interface; a backend with optional features is
+// a struct embedding several of them. The mocks are generated from
+// storage.go, run go generate here after changing it.
+package storagemock
+
+import "sync"
+
+//go:generate go run ./internal/gen ../storage.go mocks.go
+
+// Call is a call made to a mock, with its arguments in order.
+type Call struct {
+	Method string
+	Args   []any
+}
+
+// Recorder records the calls made to a mock. Its zero value is ready to use.
+type Recorder struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (r *Recorder) record(method string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the calls made so far, oldest first.
+func (r *Recorder) Calls() []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Call(nil), r.calls...)
+}
+
+// Called returns how many times method was called.
+func (r *Recorder) Called(method string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, call := range r.calls {
+		if call.Method == method {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,174 @@
+// Phone book front end. Every URL is relative so the page works wherever
+// the API is mounted, including /books/{book}/ on multi-tenant servers.
+"use strict";
+
+const rows = document.querySelector("#entries tbody");
+const message = document.querySelector("#message");
+const editor = document.querySelector("#editor");
+const form = document.querySelector("#entry");
+const token = document.querySelector("#token");
+
+token.value = localStorage.getItem("phonebook-token") || "";
+token.addEventListener("change", () => {
+  localStorage.setItem("phonebook-token", token.value);
+  load();
+});
+
+async function api(method, path, body) {
+  const headers = {};
+  if (token.value) {
+    headers["Authorization"] = "Bearer " + token.value;
+  }
+
+  if (body !== undefined) {
+    headers["Content-Type"] = "application/json";
+    body = JSON.stringify(body);
+  }
+
+  const response = await fetch(path, { method, headers, body });
+  const loginURL = response.headers.get("X-Login-URL");
+  if (response.status === 401 && loginURL) {
+    const login = document.querySelector("#login");
+    login.href = loginURL + "?next=" + encodeURIComponent(location.pathname);
+    login.hidden = false;
+  }
+
+  const text = await response.text();
+  if (!response.ok) {
+    let error = text;
+    try {
+      error = JSON.parse(text).message || text;
+    } catch (e) {
+      // Most endpoints answer errors in plain text.
+    }
+
+    throw new Error(error || response.statusText);
+  }
+
+  return text ? JSON.parse(text) : null;
+}
+
+function show(text, isError) {
+  message.textContent = text;
+  message.className = isError ? "error" : "";
+}
+
+function render(entries) {
+  rows.replaceChildren();
+
+  for (const entry of entries) {
+    const row = document.querySelector("#row").content.cloneNode(true);
+    for (const field of ["name", "surname", "country", "company", "title"]) {
+      row.querySelector("." + field).textContent = entry[field] || "";
+    }
+
+    if (entry.nickname) {
+      row.querySelector(".name").textContent += " (" + entry.nickname + ")";
+    }
+
+    row.querySelector(".phone").textContent = entry.phone_number;
+
+    if (entry.photo) {
+      const img = document.createElement("img");
+      img.alt = "";
+      img.src = "photo/" + entry.id;
+      row.querySelector(".photo").append(img);
+    }
+
+    row.querySelector(".edit").addEventListener("click", () => edit(entry));
+    row.querySelector(".delete").addEventListener("click", () => remove(entry));
+    rows.append(row);
+  }
+
+  if (entries.length === 0) {
+    show("No entries.");
+  }
+}
+
+async function load(term) {
+  show("");
+  try {
+    const query = term ? "?search=" + encodeURIComponent(term) : "";
+    const result = await api("GET", "entries" + query);
+    render(result.entries || []);
+  } catch (error) {
+    show(error.message, true);
+  }
+}
+
+// editing is the entry the editor was opened for, null for a new one. The
+// fields the editor doesn't show are sent back as they were.
+let editing = null;
+
+function edit(entry) {
+  editing = entry || null;
+  form.reset();
+  document.querySelector("#editor-title").textContent = entry ? "Edit entry" : "Add entry";
+  for (const field of ["id", "version", "name", "surname", "nickname", "phone_number", "company", "title"]) {
+    form.elements[field].value = entry ? entry[field] || "" : "";
+  }
+
+  editor.showModal();
+}
+
+async function remove(entry) {
+  if (!confirm("Delete " + entry.name + " " + entry.surname + "?")) {
+    return;
+  }
+
+  try {
+    await api("DELETE", "delete/" + entry.id + "?version=" + entry.version);
+    show("Deleted " + entry.name + " " + entry.surname + ".");
+    load(document.querySelector("#search").elements.term.value);
+  } catch (error) {
+    show(error.message, true);
+  }
+}
+
+editor.addEventListener("close", async () => {
+  if (editor.returnValue !== "save") {
+    return;
+  }
+
+  const entry = {
+    ...editing,
+    name: form.elements.name.value,
+    surname: form.elements.surname.value,
+    nickname: form.elements.nickname.value,
+    phone_number: form.elements.phone_number.value,
+    company: form.elements.company.value,
+    title: form.elements.title.value,
+    // Saving fails instead of overwriting if someone else changed the entry
+    // since it was loaded.
+    version: Number(form.elements.version.value) || 0,
+  };
+
+  try {
+    const id = form.elements.id.value;
+    if (id) {
+      await api("PUT", "entries/" + id, entry);
+      show("Saved " + entry.name + " " + entry.surname + ".");
+    } else {
+      await api("POST", "insert", entry);
+      show("Added " + entry.name + " " + entry.surname + ".");
+    }
+
+    load(document.querySelector("#search").elements.term.value);
+  } catch (error) {
+    show(error.message, true);
+  }
+});
+
+document.querySelector("#search").addEventListener("submit", (event) => {
+  event.preventDefault();
+  load(event.target.elements.term.value);
+});
+
+document.querySelector("#clear").addEventListener("click", () => {
+  document.querySelector("#search").reset();
+  load();
+});
+
+document.querySelector("#add").addEventListener("click", () => edit(null));
+
+load();
@@ -0,0 +1,34 @@
+// Package web is the browser front end of the phone book: a single page
+// that lists, searches, adds, edits and deletes entries through the REST
+// API. Its files are embedded, so the server needs nothing on disk.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var files embed.FS
+
+// Handler serves the page at "/" and its assets under "/ui/". The page only
+// uses relative URLs, so it also works when mounted under a prefix such as
+// /books/{book}/.
+func Handler() http.Handler {
+	static, err := fs.Sub(files, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	assets := http.StripPrefix("/ui/", http.FileServer(http.FS(static)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.ServeFileFS(w, r, static, "index.html")
+			return
+		}
+
+		assets.ServeHTTP(w, r)
+	})
+}